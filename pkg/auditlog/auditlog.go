@@ -0,0 +1,143 @@
+// Package auditlog keeps an append-only trail of the scans: each run appends a JSON line recording when it
+// happened, where, with which parameters, the digest of its results and its summary, so the log proves when
+// every verification took place.
+//
+// The records can be hash-chained, each one holding the SHA-256 of the line before it, so editing, inserting
+// or removing a record breaks the chain at the next one, which Verify reports. The last record being only
+// protected by the next one, keep its hash elsewhere, like in a ticket, to also seal the end of the log.
+package auditlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+// Genesis is the previous hash of the first chained record of a log.
+var Genesis = strings.Repeat("0", 64)
+
+// Record is one line of the audit log, describing a scan.
+type Record struct {
+	Time     time.Time      `json:"time"`
+	Host     string         `json:"host,omitempty"`
+	User     string         `json:"user,omitempty"`
+	Version  string         `json:"version"`
+	Mode     string         `json:"mode"`
+	Args     []string       `json:"args"`              // command line, without the secrets
+	Results  string         `json:"results,omitempty"` // SHA-256 of the results, see the doc of the caller
+	Summary  map[string]any `json:"summary,omitempty"`
+	ExitCode int            `json:"exit_code"`
+	Prev     string         `json:"prev,omitempty"` // SHA-256 of the previous line, when chained
+}
+
+// LineHash returns the hexadecimal SHA-256 of a line of the log, without its newline, the Prev of the next record.
+func LineHash(line []byte) string {
+	sum := sha256.Sum256(bytes.TrimRight(line, "\r\n"))
+	return hex.EncodeToString(sum[:])
+}
+
+// Append appends r to the log at path, created if needed, setting its Prev to chain it to the last record
+// when chain is true. The file stays locked from reading its last line to writing r, so several processes
+// can append to the same log.
+func Append(path string, r Record, chain bool) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = hasher.WithLock(f, func() error {
+		if chain {
+			last, err := lastLine(f)
+			if err != nil {
+				return err
+			}
+			r.Prev = Genesis
+			if len(last) > 0 {
+				r.Prev = LineHash(last)
+			}
+		}
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(append(line, '\n'))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// lastLine returns the last non-empty line of f, read backwards from its end.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	var line []byte
+	buf := make([]byte, 4096)
+	for off := end; off > 0; {
+		n := min(int64(len(buf)), off)
+		off -= n
+		if _, err := f.ReadAt(buf[:n], off); err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = append(append([]byte{}, buf[:n]...), line...)
+		trimmed := bytes.TrimRight(line, "\r\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
+
+// Check is the result of Verify.
+type Check struct {
+	Records   int // records read
+	Chained   int // records chained to the line before them
+	Unchained int // records without Prev, which nothing protects
+}
+
+// Verify reads the log from r and checks that every chained record holds the hash of the line before it,
+// returning an error telling the first line where the chain is broken.
+func Verify(r io.Reader) (Check, error) {
+	var c Check
+	reader := bufio.NewReader(r)
+	prev := Genesis
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record Record
+			if err := json.Unmarshal(line, &record); err != nil {
+				return c, fmt.Errorf("line %d is not a record: %w", lineNumber, err)
+			}
+			c.Records++
+			switch record.Prev {
+			case "":
+				c.Unchained++
+			case prev:
+				c.Chained++
+			default:
+				return c, fmt.Errorf("line %d does not hold the hash of the line before it, the log was edited", lineNumber)
+			}
+			prev = LineHash(line)
+		}
+		if err == io.EOF {
+			return c, nil
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package auditlog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAppendVerify tests that chained records are verified and that editing or removing one breaks the chain.
+func TestAppendVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i, mode := range []string{"calculate", "check", "check"} {
+		r := Record{Time: time.Date(2026, 1, 2, 3, 4, i, 0, time.UTC), Version: "1.0", Mode: mode, Args: []string{"-c", "hashes.txt"},
+			Summary: map[string]any{"files": 3}, ExitCode: i}
+		if err := Append(path, r, i > 0); err != nil {
+			t.Fatalf("Append() returned an error: %v", err)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Verify(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Verify() returned an error: %v", err)
+	}
+	if c != (Check{Records: 3, Chained: 2, Unchained: 1}) {
+		t.Errorf("Verify() = %+v, expected 3 records, 2 chained", c)
+	}
+
+	lines := strings.SplitAfter(string(content), "\n")
+	edited := lines[0] + strings.Replace(lines[1], `"exit_code":1`, `"exit_code":0`, 1) + lines[2]
+	if _, err := Verify(strings.NewReader(edited)); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Verify() of an edited record returned %v, expected an error at line 3", err)
+	}
+	if _, err := Verify(strings.NewReader(lines[0] + lines[2])); err == nil {
+		t.Error("Verify() of a log with a removed record returned no error")
+	}
+	if _, err := Verify(strings.NewReader(lines[2])); err == nil {
+		t.Error("Verify() of a log without its first chained record returned no error")
+	}
+}
+
+// TestLastLine tests that the last line is found across the reads of the backward scan.
+func TestLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	last := strings.Repeat("x", 5000)
+	if err := os.WriteFile(path, []byte("first\n"+last+"\n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if line, err := lastLine(f); err != nil || string(line) != last {
+		t.Errorf("lastLine() = %d bytes, %v, expected the %d bytes of the last line", len(line), err, len(last))
+	}
+}
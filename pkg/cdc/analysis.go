@@ -0,0 +1,169 @@
+package cdc
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"os"
+	"sync"
+)
+
+// chunkKey identifies a chunk by the first half of its SHA256, plenty to tell apart the chunks of a dataset
+// while keeping the memory used by Analysis around 40 bytes per distinct chunk.
+type chunkKey [16]byte
+
+// chunkEntry records where a distinct chunk was found.
+type chunkEntry struct {
+	size   uint32
+	group  int32 // last group it was found in
+	groups int32 // number of groups it was found in
+}
+
+// Group is the part of a dataset, like a directory, whose data is compared with the other groups.
+type Group struct {
+	Name  string
+	Files int
+	Bytes int64 // total size of its files
+	// Unique is the size of its distinct chunks, the space its files take once deduplicated alone
+	Unique int64
+	// Exclusive is the size of the distinct chunks found in no other group, the space freed by removing it
+	// from the deduplicated dataset; Unique - Exclusive is the size of the data it shares with the others
+	Exclusive int64
+}
+
+// Shared returns the size of the distinct chunks of g also found in other groups.
+func (g Group) Shared() int64 {
+	return g.Unique - g.Exclusive
+}
+
+// Analysis computes the chunks of the files added to it, counting the space they take once deduplicated.
+// The files of a group must be added one group after the other, those of a group possibly concurrently.
+type Analysis struct {
+	avgSize int
+	mu      sync.Mutex
+	chunks  map[chunkKey]chunkEntry
+	groups  []Group
+}
+
+// NewAnalysis returns an Analysis cutting the files into chunks of avgSize bytes on average,
+// avgSize being valid for ValidateAvgSize.
+func NewAnalysis(avgSize int) *Analysis {
+	return &Analysis{avgSize: avgSize, chunks: make(map[chunkKey]chunkEntry)}
+}
+
+// StartGroup starts the group named name, the files added next belonging to it. The files of the
+// previous group must all have been added.
+func (a *Analysis) StartGroup(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.groups = append(a.groups, Group{Name: name})
+}
+
+// AddFile adds the file at path to the current group, returning the number of bytes read.
+func (a *Analysis) AddFile(ctx context.Context, path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return a.Add(ctx, f)
+}
+
+// Add adds the content read from r as a file of the current group, returning the number of bytes read.
+// When reading r fails, the chunks read until then stay counted.
+func (a *Analysis) Add(ctx context.Context, r io.Reader) (int64, error) {
+	chunker := NewChunker(r, a.avgSize)
+	// The chunks are recorded by batches, so the files chunked concurrently seldom wait for each other
+	type chunk struct {
+		key  chunkKey
+		size uint32
+	}
+	batch := make([]chunk, 0, 256)
+	var total int64
+	record := func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		g := int32(len(a.groups) - 1)
+		group := &a.groups[g]
+		for _, c := range batch {
+			group.Bytes += int64(c.size)
+			e, seen := a.chunks[c.key]
+			switch {
+			case !seen:
+				e = chunkEntry{size: c.size, group: g, groups: 1}
+			case e.group != g:
+				e.group = g
+				e.groups++
+			default:
+				continue // already counted in this group
+			}
+			a.chunks[c.key] = e
+			group.Unique += int64(c.size)
+		}
+		batch = batch[:0]
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		data, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+		sum := sha256.Sum256(data)
+		batch = append(batch, chunk{key: chunkKey(sum[:16]), size: uint32(len(data))})
+		total += int64(len(data))
+		if len(batch) == cap(batch) {
+			record()
+		}
+	}
+	record()
+	a.mu.Lock()
+	a.groups[len(a.groups)-1].Files++
+	a.mu.Unlock()
+	return total, nil
+}
+
+// Summary is the result of an Analysis.
+type Summary struct {
+	Files  int
+	Bytes  int64 // total size of the files
+	Chunks int   // number of distinct chunks
+	// Unique is the size of the distinct chunks, the space the files take once deduplicated
+	Unique int64
+	Groups []Group
+}
+
+// Saved returns the space saved by deduplicating the files.
+func (s Summary) Saved() int64 {
+	return s.Bytes - s.Unique
+}
+
+// Ratio returns the deduplication ratio, the size of the files over their deduplicated size.
+func (s Summary) Ratio() float64 {
+	if s.Unique == 0 {
+		return 1
+	}
+	return float64(s.Bytes) / float64(s.Unique)
+}
+
+// Summary returns the sizes of the files added so far, in total and by group.
+func (a *Analysis) Summary() Summary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := Summary{Chunks: len(a.chunks), Groups: append([]Group(nil), a.groups...)}
+	for _, e := range a.chunks {
+		s.Unique += int64(e.size)
+		if e.groups == 1 {
+			s.Groups[e.group].Exclusive += int64(e.size)
+		}
+	}
+	for _, g := range s.Groups {
+		s.Files += g.Files
+		s.Bytes += g.Bytes
+	}
+	return s
+}
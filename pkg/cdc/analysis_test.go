@@ -0,0 +1,54 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// TestAnalysis tests the sizes counted for groups sharing some of their data.
+func TestAnalysis(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	random := func(n int) []byte {
+		p := make([]byte, n)
+		rng.Read(p)
+		return p
+	}
+	common, ownA, ownB := random(256<<10), random(128<<10), random(64<<10)
+	a := NewAnalysis(DefaultAvgSize)
+	add := func(content []byte) {
+		if n, err := a.Add(context.Background(), bytes.NewReader(content)); err != nil || n != int64(len(content)) {
+			t.Fatalf("Add = %d, %v", n, err)
+		}
+	}
+	a.StartGroup("a")
+	add(common)
+	add(common) // a copy within the group
+	add(ownA)
+	a.StartGroup("b")
+	add(append(bytes.Clone(ownB), common...))
+	s := a.Summary()
+
+	if s.Files != 4 || s.Bytes != int64(3*len(common)+len(ownA)+len(ownB)) {
+		t.Errorf("Files = %d, Bytes = %d", s.Files, s.Bytes)
+	}
+	// The chunks at the junction of ownB and common are the only ones not found elsewhere
+	slack := int64(4 * 4 * DefaultAvgSize)
+	if want := int64(len(common) + len(ownA) + len(ownB)); s.Unique < want || s.Unique > want+slack {
+		t.Errorf("Unique = %d, want about %d", s.Unique, want)
+	}
+	if s.Saved() != s.Bytes-s.Unique || s.Ratio() < 2 {
+		t.Errorf("Saved = %d, Ratio = %.2f", s.Saved(), s.Ratio())
+	}
+	ga, gb := s.Groups[0], s.Groups[1]
+	if ga.Name != "a" || ga.Files != 3 || ga.Unique != int64(len(common)+len(ownA)) {
+		t.Errorf("group a = %+v", ga)
+	}
+	if ga.Exclusive < int64(len(ownA)) || ga.Exclusive > int64(len(ownA))+slack {
+		t.Errorf("group a exclusive = %d, want about %d", ga.Exclusive, len(ownA))
+	}
+	if gb.Shared() < int64(len(common))-slack || gb.Shared() > int64(len(common)) || gb.Shared() != ga.Shared() {
+		t.Errorf("group b shares %d bytes, group a %d, want about %d", gb.Shared(), ga.Shared(), len(common))
+	}
+}
@@ -0,0 +1,109 @@
+// Package cdc splits contents into chunks with FastCDC, the content-defined chunking of the deduplicating
+// backup tools and filers: the chunk boundaries depend on the bytes around them instead of their offsets, so
+// the data shared by two files is cut into the same chunks even when it is not at the same place in both.
+// Analysis counts the bytes the chunks of a dataset would take once deduplicated.
+package cdc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// DefaultAvgSize is the average chunk size used by default, the one of most deduplicating filers.
+const DefaultAvgSize = 8 << 10
+
+// gear holds the random values of the gear hash, derived from their index so the chunks never change.
+var gear = func() (g [256]uint64) {
+	for i := range g {
+		sum := sha256.Sum256([]byte{byte(i)})
+		g[i] = binary.LittleEndian.Uint64(sum[:])
+	}
+	return g
+}()
+
+// Chunker splits the content read from a reader into chunks of a quarter to 4 times an average size,
+// cutting where the gear hash of the last bytes has enough zero bits. Like FastCDC with normalized
+// chunking, more bits are required before the average size than after, so fewer chunks are very small
+// or very large.
+type Chunker struct {
+	r                         io.Reader
+	minSize, avgSize, maxSize int
+	maskS, maskL              uint64
+	buf                       []byte
+	start, end                int
+	eof                       bool
+}
+
+// ValidateAvgSize checks that avgSize is a power of two between 1 KiB and 1 MiB.
+func ValidateAvgSize(avgSize int) error {
+	if avgSize < 1<<10 || avgSize > 1<<20 || avgSize&(avgSize-1) != 0 {
+		return fmt.Errorf("the average chunk size must be a power of two between 1 KiB and 1 MiB, not %d bytes", avgSize)
+	}
+	return nil
+}
+
+// NewChunker returns a Chunker of the content of r with chunks of avgSize bytes on average,
+// avgSize being valid for ValidateAvgSize.
+func NewChunker(r io.Reader, avgSize int) *Chunker {
+	n := bits.TrailingZeros(uint(avgSize))
+	// The masks test the highest bits, the ones depending on the 64 last bytes
+	mask := func(ones int) uint64 { return ^uint64(0) << (64 - ones) }
+	return &Chunker{
+		r: r, minSize: avgSize / 4, avgSize: avgSize, maxSize: avgSize * 4,
+		maskS: mask(n + 2), maskL: mask(n - 2),
+		buf: make([]byte, avgSize*8),
+	}
+}
+
+// Next returns the next chunk, valid until the next call, or io.EOF once the content is exhausted.
+func (c *Chunker) Next() ([]byte, error) {
+	if c.end-c.start < c.maxSize && !c.eof {
+		// Keep at least a chunk of the largest size ahead
+		c.end = copy(c.buf, c.buf[c.start:c.end])
+		c.start = 0
+		for c.end < len(c.buf) && !c.eof {
+			n, err := c.r.Read(c.buf[c.end:])
+			c.end += n
+			if err == io.EOF {
+				c.eof = true
+			} else if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.start == c.end {
+		return nil, io.EOF
+	}
+	n := c.cut(c.buf[c.start:c.end])
+	chunk := c.buf[c.start : c.start+n]
+	c.start += n
+	return chunk, nil
+}
+
+// cut returns the size of the chunk starting data.
+func (c *Chunker) cut(data []byte) int {
+	n := len(data)
+	if n <= c.minSize {
+		return n
+	}
+	n = min(n, c.maxSize)
+	normal := min(n, c.avgSize)
+	var fp uint64
+	i := c.minSize
+	for ; i < normal; i++ {
+		fp = fp<<1 + gear[data[i]]
+		if fp&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = fp<<1 + gear[data[i]]
+		if fp&c.maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
@@ -0,0 +1,67 @@
+package cdc
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// chunks returns the chunks of content, copied.
+func chunks(t *testing.T, content []byte, avgSize int) [][]byte {
+	t.Helper()
+	var result [][]byte
+	c := NewChunker(bytes.NewReader(content), avgSize)
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return result
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		result = append(result, bytes.Clone(chunk))
+	}
+}
+
+// TestChunker tests the sizes of the chunks and that they are cut at the same places around an insertion.
+func TestChunker(t *testing.T) {
+	content := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(content)
+	const avgSize = 4 << 10
+	got := chunks(t, content, avgSize)
+	if !bytes.Equal(bytes.Join(got, nil), content) {
+		t.Fatal("the chunks do not make up the content")
+	}
+	for i, chunk := range got {
+		if len(chunk) > 4*avgSize || (len(chunk) < avgSize/4 && i < len(got)-1) {
+			t.Errorf("chunk %d has %d bytes, out of bounds", i, len(chunk))
+		}
+	}
+	if n := len(content) / len(got); n < avgSize/2 || n > 2*avgSize {
+		t.Errorf("average chunk size %d, far from %d", n, avgSize)
+	}
+
+	// After a few bytes inserted at the start, the chunks are the same past the first ones
+	shifted := chunks(t, append([]byte("inserted"), content...), avgSize)
+	seen := make(map[string]bool)
+	for _, chunk := range got {
+		seen[string(chunk)] = true
+	}
+	same := 0
+	for _, chunk := range shifted {
+		if seen[string(chunk)] {
+			same++
+		}
+	}
+	if same < len(got)-3 {
+		t.Errorf("only %d of %d chunks found again after an insertion", same, len(got))
+	}
+
+	if got := chunks(t, nil, avgSize); len(got) != 0 {
+		t.Errorf("empty content cut into %d chunks", len(got))
+	}
+	if err := ValidateAvgSize(3000); err == nil {
+		t.Error("ValidateAvgSize accepted a size that is not a power of two")
+	}
+}
@@ -0,0 +1,134 @@
+// Package client is a thin Go client of the REST API served by goDirHasher serve (see pkg/server).
+//
+// Uploaded content is streamed to the server with a chunked request body, the server hashing it as it
+// arrives, so memory stays bounded on both sides and a slow server slows down the reader (backpressure).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/server"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client sends requests to a goDirHasher server.
+type Client struct {
+	BaseURL    string       // e.g. http://localhost:8080
+	HTTPClient *http.Client // http.DefaultClient when nil
+}
+
+// New returns a Client of the server listening at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// HashReader streams the content of r to the server and returns its digest with algo,
+// the default algorithm of the server when empty.
+func (c *Client) HashReader(ctx context.Context, algo string, r io.Reader) (server.Digest, error) {
+	var d server.Digest
+	target := "/hash"
+	if algo != "" {
+		target += "?algo=" + url.QueryEscape(algo)
+	}
+	// hide the concrete type of r so the body is always streamed instead of buffered to compute its length
+	err := c.do(ctx, http.MethodPost, target, "application/octet-stream", struct{ io.Reader }{r}, http.StatusOK, &d)
+	return d, err
+}
+
+// HashPath starts a job hashing the file or directory path, relative to the server root.
+func (c *Client) HashPath(ctx context.Context, path, algo string) (server.Job, error) {
+	return c.post(ctx, "/jobs", map[string]string{"path": path, "algo": algo})
+}
+
+// Verify starts a job verifying the files listed in manifest, relative to the server root.
+// Relative paths of the manifest are resolved against baseDir, the directory of the manifest when empty.
+func (c *Client) Verify(ctx context.Context, manifest, baseDir, algo string) (server.Job, error) {
+	return c.post(ctx, "/verify", map[string]string{"manifest": manifest, "base_dir": baseDir, "algo": algo})
+}
+
+// Job returns the current state of the job id.
+func (c *Client) Job(ctx context.Context, id string) (server.Job, error) {
+	var job server.Job
+	err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), "", nil, http.StatusOK, &job)
+	return job, err
+}
+
+// Jobs returns all the jobs of the server, oldest first.
+func (c *Client) Jobs(ctx context.Context) ([]server.Job, error) {
+	var jobs []server.Job
+	err := c.do(ctx, http.MethodGet, "/jobs", "", nil, http.StatusOK, &jobs)
+	return jobs, err
+}
+
+// Wait polls the job id every interval until it is finished and returns its final state.
+func (c *Client) Wait(ctx context.Context, id string, interval time.Duration) (server.Job, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.Job(ctx, id)
+		if err != nil || job.Status != server.StatusRunning {
+			return job, err
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Manifest returns the files of the finished job id.
+func (c *Client) Manifest(ctx context.Context, id string) ([]server.FileResult, error) {
+	var files []server.FileResult
+	err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id)+"/manifest", "", nil, http.StatusOK, &files)
+	return files, err
+}
+
+func (c *Client) post(ctx context.Context, target string, req any) (server.Job, error) {
+	var job server.Job
+	body, err := json.Marshal(req)
+	if err != nil {
+		return job, err
+	}
+	err = c.do(ctx, http.MethodPost, target, "application/json", bytes.NewReader(body), http.StatusAccepted, &job)
+	return job, err
+}
+
+// do sends the request and decodes the JSON answer into v, any status other than want being an error.
+func (c *Client) do(ctx context.Context, method, target, contentType string, body io.Reader, want int, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+target, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != want {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s %s answered %s: %s", method, target, resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("%s %s answered %s", method, target, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding the answer of %s %s: %w", method, target, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/server"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestClient tests the client against a server hashing a temporary directory.
+func TestClient(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	srv, err := server.New(context.Background(), root, hasher.Options{})
+	if err != nil {
+		t.Fatalf("server.New() returned an error: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	c := New(ts.URL + "/")
+	ctx := context.Background()
+	const abcSHA256 = "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+
+	d, err := c.HashReader(ctx, "sha256", strings.NewReader("abc"))
+	if err != nil || d.Hash != abcSHA256 || d.Size != 3 {
+		t.Errorf("HashReader() = %+v, %v, expected the sha256 of abc", d, err)
+	}
+
+	job, err := c.HashPath(ctx, ".", "")
+	if err != nil {
+		t.Fatalf("HashPath() returned an error: %v", err)
+	}
+	if job, err = c.Wait(ctx, job.ID, 10*time.Millisecond); err != nil || job.Status != server.StatusDone {
+		t.Fatalf("Wait() = %+v, %v, expected a finished job", job, err)
+	}
+	files, err := c.Manifest(ctx, job.ID)
+	if err != nil || len(files) != 1 || files[0].Hash != abcSHA256 {
+		t.Errorf("Manifest() = %+v, %v, expected a.txt with the sha256 of abc", files, err)
+	}
+
+	if _, err := c.HashPath(ctx, "../outside", ""); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("HashPath() outside of the root returned %v, expected the error of the server", err)
+	}
+	if _, err := c.Job(ctx, "42"); err == nil {
+		t.Error("Job() of an unknown job did not return an error")
+	}
+}
@@ -0,0 +1,251 @@
+// Package config reads default values of command-line flags from configuration files and environment
+// variables, so the flags a team always uses can be shared instead of retyped.
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables setting flags: GODIRHASHER_MAX_BANDWIDTH sets -max-bandwidth.
+const EnvPrefix = "GODIRHASHER_"
+
+// FileName is the configuration file looked for in the current directory.
+const FileName = ".godirhasher.yaml"
+
+// Values are flag values keyed by flag name, repeatable flags having several values.
+type Values map[string][]string
+
+// ListFlag is implemented by the values of repeatable flags, like the -exclude patterns. They are set once per
+// item of a list, the items of a value being separated by commas, other flags taking a single value.
+type ListFlag interface {
+	flag.Value
+	IsListFlag() bool
+}
+
+// UserFile returns the configuration file of the user, godirhasher/config.yaml in os.UserConfigDir,
+// like ~/.config/godirhasher/config.yaml on Linux.
+func UserFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "godirhasher", "config.yaml"), nil
+}
+
+// Load parses the configuration file at path, it returns nil Values when the file does not exist.
+func Load(path string) (Values, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Parse reads a configuration in the subset of YAML made of a mapping of flag names to values,
+// with comments. The values are plain or quoted scalars, or lists, in block or flow style:
+//
+//	workers: 8
+//	algo: sha512
+//	exclude:
+//	  - '*.tmp'
+//	  - node_modules/**
+//	include: ["*.pdf", "*.odt"]
+func Parse(r io.Reader) (Values, error) {
+	values := make(Values)
+	list := "" // the key whose block list items follow
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if list == "" {
+				return nil, fmt.Errorf("line %d: list item without a key", lineNumber)
+			}
+			value, err := parseValue(strings.TrimSpace(trimmed[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			values[list] = append(values[list], value)
+			continue
+		}
+		if line != trimmed {
+			return nil, fmt.Errorf("line %d: only flag names are expected, without indentation", lineNumber)
+		}
+		key, value, ok := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected flag: value", lineNumber)
+		}
+		if _, seen := values[key]; seen {
+			return nil, fmt.Errorf("line %d: %s is set twice", lineNumber, key)
+		}
+		value = strings.TrimSpace(value)
+		list = ""
+		switch {
+		case value == "" || strings.HasPrefix(value, "#"):
+			list = key
+			values[key] = []string{}
+		case strings.HasPrefix(value, "["):
+			items, err := parseFlowList(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			values[key] = items
+		default:
+			scalar, err := parseValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			values[key] = []string{scalar}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parseValue returns the scalar s, unquoted, without the comment following it.
+func parseValue(s string) (string, error) {
+	value, rest, err := parseScalar(s, "")
+	if err != nil {
+		return "", err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after %q", rest, value)
+	}
+	return value, nil
+}
+
+// parseFlowList returns the items of the flow list s, like [a, 'b c'].
+func parseFlowList(s string) ([]string, error) {
+	items := []string{}
+	rest := strings.TrimSpace(s[1:])
+	for !strings.HasPrefix(rest, "]") {
+		item, after, err := parseScalar(rest, ",]")
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		rest = strings.TrimSpace(after)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, fmt.Errorf("unterminated list %s", s)
+		}
+	}
+	if rest = strings.TrimSpace(rest[1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return nil, fmt.Errorf("unexpected %q after the list", rest)
+	}
+	return items, nil
+}
+
+// parseScalar reads the scalar starting s, quoted or plain, a plain one ending at one of the delimiters
+// or at a comment. It returns the value and what follows it.
+func parseScalar(s, delimiters string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		// In single quotes, a quote is written twice
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+			} else if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+			} else {
+				return b.String(), s[i+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated quoted value %s", s)
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				value, err := strconv.Unquote(s[:i+1])
+				return value, s[i+1:], err
+			}
+		}
+		return "", "", fmt.Errorf("unterminated quoted value %s", s)
+	}
+	end := len(s)
+	if i := strings.IndexAny(s, delimiters); delimiters != "" && i >= 0 {
+		end = i
+	}
+	if i := strings.Index(s, " #"); i >= 0 && i < end {
+		end = i
+	}
+	return strings.TrimSpace(s[:end]), s[end:], nil
+}
+
+// FromEnv returns the values of the variables of environ, as returned by os.Environ, starting with prefix:
+// the rest of their name, in lowercase with underscores replaced by dashes, is the flag name.
+func FromEnv(prefix string, environ []string) Values {
+	values := make(Values)
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimPrefix(name, prefix)), "_", "-")
+		values[key] = []string{value}
+	}
+	return values
+}
+
+// Apply sets the flags of fs that are not set yet, on the command line or by a previous call, to their
+// values, so the sources are applied from the most to the least important one. An unknown flag or an
+// invalid value is reported with source, like the name of the configuration file.
+func Apply(fs *flag.FlagSet, values Values, source string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for name, items := range values {
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("%s: unknown flag -%s", source, name)
+		}
+		if list, ok := f.Value.(ListFlag); ok && list.IsListFlag() {
+			var split []string
+			for _, item := range items {
+				split = append(split, strings.Split(item, ",")...)
+			}
+			items = split
+		} else if len(items) != 1 {
+			return fmt.Errorf("%s: -%s takes a single value", source, name)
+		}
+		if set[name] {
+			continue
+		}
+		for _, item := range items {
+			if err := fs.Set(name, strings.TrimSpace(item)); err != nil {
+				return fmt.Errorf("%s: invalid value %q for -%s: %w", source, item, name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParse tests the scalars, the lists and the comments of the YAML subset read.
+func TestParse(t *testing.T) {
+	input := `# shared by the team
+workers: 8
+algo: sha512 # the default of the team
+exclude:
+  - '*.tmp'
+  - "node_modules/**"
+include: [ '*.pdf', 'it''s', "a, b" ]
+plain: true
+label: 'x # y'
+`
+	values, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse() returned %v", err)
+	}
+	want := Values{
+		"workers": {"8"},
+		"algo":    {"sha512"},
+		"exclude": {"*.tmp", "node_modules/**"},
+		"include": {"*.pdf", "it's", "a, b"},
+		"plain":   {"true"},
+		"label":   {"x # y"},
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("Parse() = %v, expected %v", values, want)
+	}
+	for _, invalid := range []string{"workers 8", "- a", "a: 1\na: 2", "a: 'open", "a: [b, c", "  a: 1", "a: 'b' c"} {
+		if _, err := Parse(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Parse(%q) accepted an invalid configuration", invalid)
+		}
+	}
+}
+
+// listFlag is a repeatable flag for the tests.
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+func (l *listFlag) IsListFlag() bool   { return true }
+
+// TestApply tests that the flags given on the command line or by a previous source are not overridden.
+func TestApply(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	workers := fs.Int("workers", 0, "")
+	algo := fs.String("algo", "sha256", "")
+	maxBandwidth := fs.String("max-bandwidth", "", "")
+	var exclude listFlag
+	fs.Var(&exclude, "exclude", "")
+	if err := fs.Parse([]string{"-algo", "md5"}); err != nil {
+		t.Fatal(err)
+	}
+	env := FromEnv(EnvPrefix, []string{"GODIRHASHER_MAX_BANDWIDTH=10M", "GODIRHASHER_EXCLUDE=*.tmp,*.bak", "HOME=/root", "GODIRHASHER_=x"})
+	if err := Apply(fs, env, "environment"); err != nil {
+		t.Fatalf("Apply() returned %v", err)
+	}
+	if err := Apply(fs, Values{"workers": {"8"}, "algo": {"sha512"}, "exclude": {"*.log"}, "max-bandwidth": {"1M"}}, "config.yaml"); err != nil {
+		t.Fatalf("Apply() returned %v", err)
+	}
+	if *workers != 8 || *algo != "md5" || *maxBandwidth != "10M" || exclude.String() != "*.tmp,*.bak" {
+		t.Errorf("Apply() set workers=%d algo=%s max-bandwidth=%s exclude=%v, expected 8, md5, 10M and *.tmp,*.bak", *workers, *algo, *maxBandwidth, exclude)
+	}
+	for _, values := range []Values{{"unknown": {"1"}}, {"max-bandwidth": {"1M", "2M"}}} {
+		if err := Apply(fs, values, "config.yaml"); err == nil {
+			t.Errorf("Apply(%v) accepted an invalid configuration", values)
+		}
+	}
+	other := flag.NewFlagSet("test", flag.ContinueOnError)
+	other.Int("workers", 0, "")
+	if err := Apply(other, Values{"workers": {"eight"}}, "config.yaml"); err == nil {
+		t.Error("Apply() accepted an invalid value")
+	}
+}
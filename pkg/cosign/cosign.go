@@ -0,0 +1,83 @@
+// Package cosign signs manifests and verifies their signatures with the cosign command of sigstore, so they
+// fit into a supply-chain attestation pipeline: keyless, with a short-lived certificate issued to the OIDC
+// identity of the signer and recorded in the Rekor transparency log, or with a key, a file or a KMS URI.
+//
+// The signature of a manifest, its certificate and its transparency log entry are kept in a bundle file next
+// to it, named after it with the BundleExt extension, as written by cosign sign-blob --bundle.
+package cosign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// BundleExt is the extension added to the name of a manifest to get the name of its bundle.
+const BundleExt = ".bundle"
+
+// Signer runs cosign to sign and verify the manifests.
+type Signer struct {
+	Command string // cosign when empty
+	Key     string // private key to sign and public key to verify, a file or a KMS URI, empty for keyless signing
+	// Identity and Issuer are the certificate identity, like an email or a workflow URL, and the OIDC issuer
+	// expected by Verify for keyless signatures
+	Identity string
+	Issuer   string
+}
+
+// BundlePath returns the path of the bundle of the manifest at path.
+func BundlePath(path string) string {
+	return path + BundleExt
+}
+
+// Sign signs the manifest at path, writing its bundle next to it. Keyless signing opens a browser or uses
+// the ambient OIDC token of the CI, like in GitHub Actions, and key based signing asks for the password of
+// the key, unless COSIGN_PASSWORD is set.
+func (s Signer) Sign(ctx context.Context, path string) error {
+	args := []string{"sign-blob", "--yes", "--bundle", BundlePath(path)}
+	if s.Key != "" {
+		args = append(args, "--key", s.Key)
+	}
+	return s.run(ctx, append(args, "--", path)...)
+}
+
+// Verify verifies the signature in the bundle of the manifest at path, with Key or, for keyless signatures,
+// the certificate issued to Identity by Issuer.
+func (s Signer) Verify(ctx context.Context, path string) error {
+	return s.VerifyBundle(ctx, path, BundlePath(path))
+}
+
+// VerifyBundle works like Verify with the signature in bundle, like the one of the manifest a private copy
+// at path was made from, so the content verified is the one read afterwards, whatever happens to the manifest.
+func (s Signer) VerifyBundle(ctx context.Context, path, bundle string) error {
+	args := []string{"verify-blob", "--bundle", bundle}
+	if s.Key != "" {
+		args = append(args, "--key", s.Key)
+	} else {
+		if s.Identity == "" || s.Issuer == "" {
+			return errors.New("verifying a keyless signature needs the identity of the signer and its OIDC issuer")
+		}
+		args = append(args, "--certificate-identity", s.Identity, "--certificate-oidc-issuer", s.Issuer)
+	}
+	if _, err := os.Stat(bundle); err != nil {
+		return fmt.Errorf("no signature %s: %w", bundle, err)
+	}
+	return s.run(ctx, append(args, "--", path)...)
+}
+
+// run runs cosign with args, its prompts and errors going to the terminal like the ones of ssh.
+func (s Signer) run(ctx context.Context, args ...string) error {
+	command := s.Command
+	if command == "" {
+		command = "cosign"
+	}
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Stdin = os.Stdin // password and consent prompts
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s: %w", command, args[0], err)
+	}
+	return nil
+}
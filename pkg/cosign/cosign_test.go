@@ -0,0 +1,102 @@
+package cosign
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeCosign writes a script standing for cosign in dir, recording its arguments in args.log and writing
+// the bundle after --bundle when signing, and returns its path.
+func fakeCosign(t *testing.T, dir string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake cosign is a shell script")
+	}
+	script := filepath.Join(dir, "cosign")
+	content := `#!/bin/sh
+echo "$@" >> "` + filepath.Join(dir, "args.log") + `"
+if [ "$1" = sign-blob ]; then echo '{}' > "$4"; fi
+`
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+// TestSignVerify tests the arguments given to cosign to sign and verify a manifest, with a key or keyless.
+func TestSignVerify(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "hashes.sha256")
+	if err := os.WriteFile(manifest, []byte("hash  file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	keyed := Signer{Command: fakeCosign(t, dir), Key: "cosign.key"}
+	if err := keyed.Verify(ctx, manifest); err == nil {
+		t.Error("Verify() of an unsigned manifest returned no error")
+	}
+	if err := keyed.Sign(ctx, manifest); err != nil {
+		t.Fatalf("Sign() returned an error: %v", err)
+	}
+	if _, err := os.Stat(BundlePath(manifest)); err != nil {
+		t.Errorf("Sign() wrote no bundle: %v", err)
+	}
+	keyless := Signer{Command: keyed.Command}
+	if err := keyless.Verify(ctx, manifest); err == nil {
+		t.Error("Verify() of a keyless signature without identity returned no error")
+	}
+	keyless.Identity, keyless.Issuer = "ci@example.com", "https://token.actions.githubusercontent.com"
+	for _, s := range []Signer{keyed, keyless} {
+		if err := s.Verify(ctx, manifest); err != nil {
+			t.Errorf("Verify() returned an error: %v", err)
+		}
+	}
+	log, err := os.ReadFile(filepath.Join(dir, "args.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := BundlePath(manifest)
+	expected := "sign-blob --yes --bundle " + bundle + " --key cosign.key -- " + manifest + "\n" +
+		"verify-blob --bundle " + bundle + " --key cosign.key -- " + manifest + "\n" +
+		"verify-blob --bundle " + bundle + " --certificate-identity ci@example.com --certificate-oidc-issuer https://token.actions.githubusercontent.com -- " + manifest + "\n"
+	if string(log) != expected {
+		t.Errorf("cosign was run with:\n%s\nexpected:\n%s", log, expected)
+	}
+
+	failing := Signer{Command: filepath.Join(dir, "missing-cosign"), Key: "cosign.key"}
+	if err := failing.Sign(ctx, manifest); err == nil || !strings.Contains(err.Error(), "sign-blob") {
+		t.Errorf("Sign() with a missing command returned %v, expected an error naming sign-blob", err)
+	}
+}
+
+// TestVerifyBundle tests that the copy of a manifest is verified with the bundle of the manifest.
+func TestVerifyBundle(t *testing.T) {
+	dir := t.TempDir()
+	manifest, copied := filepath.Join(dir, "hashes.sha256"), filepath.Join(dir, "copy")
+	for _, path := range []string{manifest, copied} {
+		if err := os.WriteFile(path, []byte("hash  file\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := Signer{Command: fakeCosign(t, dir), Key: "cosign.pub"}
+	ctx := context.Background()
+	if err := s.VerifyBundle(ctx, copied, BundlePath(manifest)); err == nil {
+		t.Error("VerifyBundle() without bundle returned no error")
+	}
+	if err := os.WriteFile(BundlePath(manifest), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.VerifyBundle(ctx, copied, BundlePath(manifest)); err != nil {
+		t.Errorf("VerifyBundle() returned an error: %v", err)
+	}
+	log, err := os.ReadFile(filepath.Join(dir, "args.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "verify-blob --bundle " + BundlePath(manifest) + " --key cosign.pub -- " + copied + "\n"; string(log) != expected {
+		t.Errorf("cosign was run with %q, expected %q", log, expected)
+	}
+}
@@ -0,0 +1,105 @@
+package hasher
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Algorithm identifies a hash function supported by the package.
+type Algorithm string
+
+// Supported algorithms, SHA256 being the default one.
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+	SHA1   Algorithm = "sha1"
+	MD5    Algorithm = "md5"
+	CRC32  Algorithm = "crc32" // the checksum of SFV files, it detects accidental corruption, not tampering
+	ED2K   Algorithm = "ed2k"  // the eDonkey2000 hash of ed2k links, based on the broken MD4
+	TTH    Algorithm = "tth"   // the Tiger tree hash of Direct Connect and Gnutella
+)
+
+// hashFuncs holds the constructor of each supported algorithm.
+var hashFuncs = map[Algorithm]func() hash.Hash{
+	SHA256: sha256.New,
+	SHA512: sha512.New,
+	SHA1:   sha1.New,
+	MD5:    md5.New,
+	CRC32:  func() hash.Hash { return crc32.NewIEEE() },
+	ED2K:   newED2K,
+	TTH:    newTTH,
+}
+
+// hashPools holds reusable hash instances for each supported algorithm.
+var hashPools = map[Algorithm]*sync.Pool{
+	SHA256: {New: func() any { return sha256.New() }},
+	SHA512: {New: func() any { return sha512.New() }},
+	SHA1:   {New: func() any { return sha1.New() }},
+	MD5:    {New: func() any { return md5.New() }},
+	CRC32:  {New: func() any { return crc32.NewIEEE() }},
+	ED2K:   {New: func() any { return newED2K() }},
+	TTH:    {New: func() any { return newTTH() }},
+}
+
+// ParseAlgorithm returns the Algorithm named s (case-insensitive).
+func ParseAlgorithm(s string) (Algorithm, error) {
+	a := Algorithm(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := hashPools[a]; !ok {
+		return "", fmt.Errorf("unsupported hash algorithm %q, expected one of %s", s, strings.Join(Algorithms(), ", "))
+	}
+	return a, nil
+}
+
+// Algorithms returns the names of the supported algorithms, sorted.
+func Algorithms() []string {
+	names := make([]string, 0, len(hashPools))
+	for a := range hashPools {
+		names = append(names, string(a))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getHash retrieves a reset hash instance from the pool of the algorithm (or New() if empty),
+// it must be given back with putHash once done.
+func getHash(a Algorithm) (hash.Hash, error) {
+	pool, ok := hashPools[a]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm %q", a)
+	}
+	h := pool.Get().(hash.Hash)
+	// Reset its internal state before reuse
+	h.Reset()
+	return h, nil
+}
+
+// putHash returns h to the pool of the algorithm.
+func putHash(a Algorithm, h hash.Hash) {
+	hashPools[a].Put(h)
+}
+
+// newHash returns the hash instance computing a, or its HMAC when key is not empty,
+// with the function to call once done with it.
+func newHash(a Algorithm, key []byte) (hash.Hash, func(), error) {
+	if len(key) == 0 {
+		h, err := getHash(a)
+		if err != nil {
+			return nil, nil, err
+		}
+		return h, func() { putHash(a, h) }, nil
+	}
+	newFunc, ok := hashFuncs[a]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported hash algorithm %q", a)
+	}
+	return hmac.New(newFunc, key), func() {}, nil
+}
@@ -0,0 +1,148 @@
+package hasher
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ArchiveSeparator separates the path of a zip archive from the path of a member in the results of HashArchive.
+const ArchiveSeparator = "!/"
+
+// IsArchive reports whether the name of the file at p is the one of an archive whose members
+// can be hashed by HashArchive: .tar, .tar.gz, .tgz or .zip.
+func IsArchive(p string) bool {
+	name := strings.ToLower(p)
+	return strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz") || isZip(name)
+}
+
+func isZip(p string) bool {
+	return strings.HasSuffix(strings.ToLower(p), ".zip")
+}
+
+// SplitArchivePath splits a path written by HashArchive for a zip member, like archive.zip!/inner/path,
+// into the path of the archive and the one of the member. ok is false for any other path.
+func SplitArchivePath(p string) (archive, member string, ok bool) {
+	archive, member, ok = strings.Cut(p, ArchiveSeparator)
+	if !ok || !isZip(archive) || member == "" {
+		return "", "", false
+	}
+	return archive, member, true
+}
+
+// HashArchive hashes every regular file stored in the archive at p instead of the archive itself.
+// The Path of the Results given to fn is the name of the member in a tar archive, so the results
+// are comparable with the ones of the directory that was archived, and archive.zip!/name for the
+// members of a zip archive. The include and exclude patterns of o apply to the member names.
+// Tar members are read one after the other, gzip compression being detected from the content,
+// while zip members are hashed by o.Workers goroutines. A corrupt archive stops the hashing
+// with an error, the members already hashed being reported.
+func (o Options) HashArchive(ctx context.Context, p string, fn func(Result)) error {
+	if isZip(p) {
+		return o.hashZip(ctx, p, fn)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var content io.Reader = r
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		defer gz.Close()
+		content = gz
+	}
+	return o.hashTar(ctx, tar.NewReader(content), fn)
+}
+
+// HashTar hashes the regular files of the tar archive read from r, like HashArchive does for a .tar file,
+// the Path of the Results given to fn being the names of the members. What follows the end of the archive
+// is left unread.
+func (o Options) HashTar(ctx context.Context, r io.Reader, fn func(Result)) error {
+	return o.hashTar(ctx, tar.NewReader(r), fn)
+}
+
+// hashTar hashes the regular files of tr one after the other, as a tar archive can only be read sequentially.
+func (o Options) hashTar(ctx context.Context, tr *tar.Reader, fn func(Result)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue // directories, links and devices have no content
+		}
+		name := memberName(hdr.Name)
+		if name == "" || !o.Filter.Keep(name) {
+			continue
+		}
+		hashes, size, err := hashContent(ctx, tr, hdr.Size, o)
+		fn(o.result(name, hashes, size, err))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// hashZip hashes the regular files of the zip archive at p concurrently.
+func (o Options) hashZip(ctx context.Context, p string, fn func(Result)) error {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	// built before hashing, the workers reading it concurrently
+	members := make(map[string]*zip.File, len(zr.File))
+	var names []string
+	for _, f := range zr.File {
+		name := memberName(f.Name)
+		if _, seen := members[name]; seen || !f.Mode().IsRegular() || name == "" || !o.Filter.Keep(name) {
+			continue
+		}
+		members[name] = f
+		names = append(names, name)
+	}
+	walk := func(ctx context.Context, found func(path string) error) error {
+		for _, name := range names {
+			if err := found(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	hashOne := func(ctx context.Context, name string) Result {
+		path := p + ArchiveSeparator + name
+		r, err := members[name].Open()
+		if err != nil {
+			return Result{Path: path, Err: err}
+		}
+		defer r.Close()
+		hashes, size, err := hashContent(ctx, r, int64(members[name].UncompressedSize64), o)
+		return o.result(path, hashes, size, err)
+	}
+	return o.hashTree(ctx, walk, hashOne, fn)
+}
+
+// memberName returns the cleaned name of an archive member, without the leading ./ or / written
+// by some archivers and with its .. elements kept inside the archive, or "" for the root itself.
+func memberName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,138 @@
+package hasher
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTar writes a tar archive of files, with a directory, a symlink and a ./ prefix like GNU tar.
+func writeTar(t *testing.T, path string, compress bool, files map[string]string) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "./project/", Typeflag: tar.TypeDir, Mode: 0o755})
+	tw.WriteHeader(&tar.Header{Name: "./project/link", Typeflag: tar.TypeSymlink, Linkname: "a.txt"})
+	for name, content := range files {
+		tw.WriteHeader(&tar.Header{Name: "./" + name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to write tar: %v", err)
+	}
+	data := buf.Bytes()
+	if compress {
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(data)
+		zw.Close()
+		data = gz.Bytes()
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+// TestHashArchive tests that the regular members of plain and gzipped tar archives are hashed with their names.
+func TestHashArchive(t *testing.T) {
+	files := map[string]string{"project/a.txt": "abc", "project/sub/b.log": "log", "project/sub/c.txt": ""}
+	dir := t.TempDir()
+	for _, name := range []string{"project.tar", "project.tar.gz"} {
+		path := filepath.Join(dir, name)
+		writeTar(t, path, name != "project.tar", files)
+		if !IsArchive(path) {
+			t.Errorf("IsArchive(%q) = false", path)
+		}
+		got := make(map[string]string)
+		opts := Options{LowerCase: true, Filter: PathFilter{Exclude: []string{"*.log"}}}
+		err := opts.HashArchive(context.Background(), path, func(r Result) {
+			got[r.Path] = r.Hash
+		})
+		if err != nil {
+			t.Fatalf("HashArchive(%s) returned an error: %v", name, err)
+		}
+		want := map[string]string{
+			"project/a.txt":     fmt.Sprintf("%x", sha256.Sum256([]byte("abc"))),
+			"project/sub/c.txt": fmt.Sprintf("%x", sha256.Sum256(nil)),
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("HashArchive(%s) = %v, expected %v", name, got, want)
+		}
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.tgz")
+	if err := os.WriteFile(corrupt, []byte{0x1f, 0x8b, 1, 2, 3}, 0o644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	if err := (Options{}).HashArchive(context.Background(), corrupt, func(Result) {}); err == nil {
+		t.Error("HashArchive() of a corrupt archive did not return an error")
+	}
+	if IsArchive("notes.txt") {
+		t.Error("IsArchive(notes.txt) = true")
+	}
+}
+
+// TestHashTar tests that the members of a tar archive read from a stream are hashed with their names.
+func TestHashTar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "layer.tar")
+	writeTar(t, path, false, map[string]string{"etc/hostname": "box"})
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got := make(map[string]string)
+	err = (Options{LowerCase: true}).HashTar(context.Background(), f, func(r Result) {
+		got[r.Path] = r.Hash
+	})
+	if err != nil {
+		t.Fatalf("HashTar() returned an error: %v", err)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte("box"))); len(got) != 1 || got["etc/hostname"] != want {
+		t.Errorf("HashTar() = %v, expected etc/hostname %s", got, want)
+	}
+}
+
+// TestHashArchiveZip tests that zip members are hashed concurrently with archive.zip!/member paths.
+func TestHashArchiveZip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.zip")
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.Create("project/")
+	for i := 0; i < 20; i++ {
+		w, _ := zw.Create(fmt.Sprintf("project/f%02d.txt", i))
+		fmt.Fprintf(w, "file %d", i)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	got := make(map[string]string)
+	err := Options{Workers: 4, LowerCase: true}.HashArchive(context.Background(), path, func(r Result) {
+		got[r.Path] = r.Hash
+	})
+	if err != nil {
+		t.Fatalf("HashArchive() returned an error: %v", err)
+	}
+	if len(got) != 20 {
+		t.Fatalf("HashArchive() hashed %d members, expected 20", len(got))
+	}
+	member := path + ArchiveSeparator + "project/f07.txt"
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte("file 7"))); got[member] != want {
+		t.Errorf("hash of %s = %q, expected %q", member, got[member], want)
+	}
+	if archive, inner, ok := SplitArchivePath(member); !ok || archive != path || inner != "project/f07.txt" {
+		t.Errorf("SplitArchivePath(%q) = %q, %q, %v", member, archive, inner, ok)
+	}
+	if _, _, ok := SplitArchivePath("dir!/file.txt"); ok {
+		t.Error("SplitArchivePath() split a path that is not in a zip archive")
+	}
+}
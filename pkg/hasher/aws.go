@@ -0,0 +1,146 @@
+package hasher
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// glacierPrefix starts the Glacier tree hashes, like glacier:HEX, and etagPrefix the ETags, followed by the
+// part size and a colon like etag8M:HEX-3, so they are never mistaken for digests.
+const (
+	glacierPrefix = "glacier:"
+	etagPrefix    = "etag"
+)
+
+// GlacierChunkSize is the size of the chunks of the tree hashes of AWS Glacier.
+const GlacierChunkSize = 1 << 20
+
+// The Glacier tree hash of a content, the x-amz-sha256-tree-hash of the uploads to Glacier, is the SHA256
+// Merkle tree over its chunks of GlacierChunkSize bytes, paired level by level with the hash of their
+// concatenation, an odd chunk at the end of a level moving up unchanged. Unlike the tree hashes of RFC 6962,
+// the leaves and the nodes have no prefix. An empty content has a single empty chunk.
+//
+// The ETag S3 gives to a content uploaded in parts of Options.ETagPartSize bytes is the MD5 of the MD5 of
+// every part, followed by a dash and the number of parts, or the MD5 of the content when it fits in a
+// single part, like the AWS CLI uploads it without multipart.
+
+// FormatGlacierHash returns the Glacier tree hash hex, prefixed so it can be verified.
+func FormatGlacierHash(hex string) string {
+	return glacierPrefix + hex
+}
+
+// IsGlacierHash reports whether hash is a tree hash written by FormatGlacierHash, in any case.
+func IsGlacierHash(hash string) bool {
+	return len(hash) > len(glacierPrefix) && strings.EqualFold(hash[:len(glacierPrefix)], glacierPrefix) &&
+		isHexString(hash[len(glacierPrefix):])
+}
+
+// FormatETag returns the ETag etag of a content uploaded in parts of partSize bytes, the part size being
+// written before it like FormatTreeHash does.
+func FormatETag(partSize int64, etag string) string {
+	return etagPrefix + formatBlockSize(partSize) + ":" + etag
+}
+
+// ParseETag returns the part size written by FormatETag before the ETag of hash, in any case.
+// ok is false when hash is not an ETag.
+func ParseETag(hash string) (partSize int64, ok bool) {
+	if len(hash) < len(etagPrefix) || !strings.EqualFold(hash[:len(etagPrefix)], etagPrefix) {
+		return 0, false
+	}
+	size, etag, found := strings.Cut(hash[len(etagPrefix):], ":")
+	if !found {
+		return 0, false
+	}
+	digest, parts, multipart := strings.Cut(etag, "-")
+	if !isHexString(digest) {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(parts); multipart && (err != nil || n < 1) {
+		return 0, false
+	}
+	return parseBlockSize(size)
+}
+
+// isAWSHash reports whether s is a tree hash written by FormatGlacierHash or an ETag written by FormatETag.
+func isAWSHash(s string) bool {
+	_, ok := ParseETag(s)
+	return ok || IsGlacierHash(s)
+}
+
+// hashGlacierReader computes the Glacier tree hash of everything read from r.
+func hashGlacierReader(ctx context.Context, r io.Reader, opts Options) ([]string, int64, error) {
+	level, total, err := hashParts(ctx, r, GlacierChunkSize, sha256.New)
+	if err != nil {
+		return nil, total, err
+	}
+	h := sha256.New()
+	for len(level) > 1 {
+		var up [][]byte
+		for i := 0; i+1 < len(level); i += 2 {
+			h.Reset()
+			h.Write(level[i])
+			h.Write(level[i+1])
+			up = append(up, h.Sum(nil))
+		}
+		if len(level)%2 == 1 {
+			up = append(up, level[len(level)-1])
+		}
+		level = up
+	}
+	return []string{FormatGlacierHash(formatHex(level[0], opts.LowerCase))}, total, nil
+}
+
+// hashETagReader computes the S3 ETag of everything read from r, uploaded in parts of opts.ETagPartSize bytes.
+func hashETagReader(ctx context.Context, r io.Reader, opts Options) ([]string, int64, error) {
+	parts, total, err := hashParts(ctx, r, opts.ETagPartSize, md5.New)
+	if err != nil {
+		return nil, total, err
+	}
+	etag := formatHex(parts[0], opts.LowerCase)
+	if len(parts) > 1 {
+		sum := md5.New()
+		for _, part := range parts {
+			sum.Write(part)
+		}
+		etag = formatHex(sum.Sum(nil), opts.LowerCase) + "-" + strconv.Itoa(len(parts))
+	}
+	return []string{FormatETag(opts.ETagPartSize, etag)}, total, nil
+}
+
+// hashParts returns the digest of each part of partSize bytes read from r, the last one being shorter and
+// an empty content having a single empty part, with the number of bytes read.
+func hashParts(ctx context.Context, r io.Reader, partSize int64, newHash func() hash.Hash) ([][]byte, int64, error) {
+	var sums [][]byte
+	var total int64
+	buf := bufferPool.Get().([]byte)
+	defer bufferPool.Put(buf)
+	cr := &ctxReader{ctx: ctx, r: throttled(ctx, r)}
+	for {
+		h := newHash()
+		n, err := io.CopyBuffer(cpuLimited(ctx, h), io.LimitReader(cr, partSize), buf)
+		total += n
+		if err != nil {
+			return nil, total, err
+		}
+		if n > 0 || len(sums) == 0 {
+			sums = append(sums, h.Sum(nil))
+		}
+		if n < partSize {
+			return sums, total, nil
+		}
+	}
+}
+
+// formatHex returns sum in hexadecimal, in uppercase unless lower is set.
+func formatHex(sum []byte, lower bool) string {
+	if lower {
+		return fmt.Sprintf("%x", sum)
+	}
+	return fmt.Sprintf("%X", sum)
+}
@@ -0,0 +1,105 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGlacierHash tests the Glacier tree hashes against the construction documented by AWS, computed by hand.
+func TestGlacierHash(t *testing.T) {
+	node := func(parts ...[]byte) []byte {
+		sum := sha256.Sum256(bytes.Join(parts, nil))
+		return sum[:]
+	}
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3*GlacierChunkSize/16+4)
+	chunks := [][]byte{content[:GlacierChunkSize], content[GlacierChunkSize : 2*GlacierChunkSize], content[2*GlacierChunkSize : 3*GlacierChunkSize], content[3*GlacierChunkSize:]}
+	tests := []struct {
+		content []byte
+		root    []byte
+	}{
+		{nil, node()},
+		{[]byte("abc"), node([]byte("abc"))},
+		{content[:3*GlacierChunkSize], node(node(node(chunks[0]), node(chunks[1])), node(chunks[2]))},
+		{content, node(node(node(chunks[0]), node(chunks[1])), node(node(chunks[2]), node(chunks[3])))},
+	}
+	dir := t.TempDir()
+	opts := NewOptions(WithGlacierTree(true), WithLowerCase(true))
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("file%d", i))
+		if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		want := fmt.Sprintf("glacier:%x", tt.root)
+		if r := opts.HashFile(context.Background(), path); r.Err != nil || r.Hash != want || r.Size != int64(len(tt.content)) {
+			t.Errorf("HashFile(%d bytes) = %q, %d, %v, expected %q", len(tt.content), r.Hash, r.Size, r.Err, want)
+		}
+		if v := (Options{}).VerifyFile(context.Background(), path, FileEntry{FilePath: path, Hash: strings.ToUpper(want)}); v.Err != nil {
+			t.Errorf("VerifyFile(%d bytes) returned an error: %v", len(tt.content), v.Err)
+		}
+	}
+	if err := NewOptions(WithGlacierTree(true), WithExtraAlgorithms(MD5)).Validate(); err == nil {
+		t.Error("Validate() accepted Glacier tree hashes with several algorithms")
+	}
+}
+
+// TestETag tests the ETags of single part and multipart uploads.
+func TestETag(t *testing.T) {
+	content := []byte("abcdefghij")
+	part := func(b []byte) []byte {
+		sum := md5.Sum(b)
+		return sum[:]
+	}
+	tests := []struct {
+		partSize int64
+		want     string
+	}{
+		{16, fmt.Sprintf("etag16:%X", part(content))},
+		{10, fmt.Sprintf("etag10:%X", part(content))},
+		{4, fmt.Sprintf("etag4:%X-3", part(bytes.Join([][]byte{part(content[:4]), part(content[4:8]), part(content[8:])}, nil)))},
+	}
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	for _, tt := range tests {
+		if r := NewOptions(WithETagPartSize(tt.partSize)).HashFile(context.Background(), path); r.Err != nil || r.Hash != tt.want {
+			t.Errorf("HashFile(part size %d) = %q, %v, expected %q", tt.partSize, r.Hash, r.Err, tt.want)
+		}
+		if v := (Options{}).VerifyFile(context.Background(), path, FileEntry{FilePath: path, Hash: tt.want}); v.Err != nil {
+			t.Errorf("VerifyFile(%q) returned an error: %v", tt.want, v.Err)
+		}
+	}
+}
+
+// TestParseETag tests the part sizes written before the ETags, and the manifests holding AWS hashes.
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		hash     string
+		partSize int64
+		ok       bool
+	}{
+		{FormatETag(8<<20, "AB12-3"), 8 << 20, true},
+		{"ETAG16M:ab12", 16 << 20, true},
+		{"etag8M:ab12-0", 0, false},
+		{"etag8M:ab12-x", 0, false},
+		{"etag8M:xyz", 0, false},
+		{"glacier:ab12", 0, false},
+	}
+	for _, tt := range tests {
+		partSize, ok := ParseETag(tt.hash)
+		if partSize != tt.partSize || ok != tt.ok {
+			t.Errorf("ParseETag(%q) = %d, %v, expected %d, %v", tt.hash, partSize, ok, tt.partSize, tt.ok)
+		}
+	}
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader("etag8M:ab12-3  big.img\nglacier:ab12  archive.tar\n"))
+	if err != nil || len(malformed) != 0 || len(entries) != 2 || entries[0].Hash != "ETAG8M:AB12-3" || entries[1].Hash != "GLACIER:AB12" {
+		t.Errorf("ParseHashFileDetailed() = %+v, %+v, %v, expected the AWS hashes", entries, malformed, err)
+	}
+}
@@ -0,0 +1,128 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ParseBandwidth returns the number of bytes per second of s, a number of bytes optionally followed by
+// K, M or G for KiB, MiB or GiB, like 100M or 1.5G. A trailing B or /s is accepted, as in 100MB/s.
+func ParseBandwidth(s string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "/S")
+	value = strings.TrimSuffix(value, "B")
+	shift := 0
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		}
+	}
+	if shift > 0 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 || math.IsInf(n, 0) || n*float64(int64(1)<<shift) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid bandwidth %q, expected a positive number of bytes per second like 100M", s)
+	}
+	return max(int64(n*float64(int64(1)<<shift)), 1), nil
+}
+
+// bandwidthBurst is how long the reads may go at full speed after an idle time, in seconds of bandwidth.
+const bandwidthBurst = 0.1
+
+// bandwidthLimiter is a token bucket filled with bytesPerSecond tokens per second, reading a byte taking a token.
+type bandwidthLimiter struct {
+	mu             sync.Mutex
+	bytesPerSecond float64
+	tokens         float64 // negative when the reads already done must be waited for
+	last           time.Time
+}
+
+// wait takes n tokens, waiting until the bucket is no longer in debt or ctx is cancelled.
+// The bytes being read before their tokens are taken, a read larger than the bucket only delays the next ones.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.bytesPerSecond, l.bytesPerSecond*bandwidthBurst)
+	l.last = now
+	l.tokens -= float64(n)
+	delay := time.Duration(-l.tokens / l.bytesPerSecond * float64(time.Second))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// bandwidthLimitKey is the context key of the token bucket set by LimitBandwidth.
+type bandwidthLimitKey struct{}
+
+// LimitBandwidth returns a context making the hashing functions called with it read at most bytesPerSecond
+// bytes per second from all the files together, so hashing can share a storage with other services.
+// It returns ctx unchanged when bytesPerSecond < 1. The hashing functions of Options apply
+// Options.MaxBandwidth this way.
+func LimitBandwidth(ctx context.Context, bytesPerSecond int64) context.Context {
+	if bytesPerSecond < 1 {
+		return ctx
+	}
+	rate := float64(bytesPerSecond)
+	return context.WithValue(ctx, bandwidthLimitKey{}, &bandwidthLimiter{bytesPerSecond: rate, tokens: rate * bandwidthBurst, last: time.Now()})
+}
+
+// limitBandwidth returns a context limited by LimitBandwidth to o.MaxBandwidth, unless ctx is already limited.
+func (o Options) limitBandwidth(ctx context.Context) context.Context {
+	if _, limited := ctx.Value(bandwidthLimitKey{}).(*bandwidthLimiter); limited {
+		return ctx
+	}
+	return LimitBandwidth(ctx, o.MaxBandwidth)
+}
+
+// waitBandwidth waits until n more bytes can be read within the bandwidth limit of ctx, if any.
+func waitBandwidth(ctx context.Context, n int) error {
+	if l, ok := ctx.Value(bandwidthLimitKey{}).(*bandwidthLimiter); ok && n > 0 {
+		return l.wait(ctx, n)
+	}
+	return nil
+}
+
+// throttledReader is an io.Reader waiting after each read for the bandwidth it used.
+type throttledReader struct {
+	ctx context.Context
+	r   io.Reader
+	l   *bandwidthLimiter
+}
+
+func (tr throttledReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		if werr := tr.l.wait(tr.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// throttled returns r reading within the bandwidth limit of ctx, r itself when there is none.
+func throttled(ctx context.Context, r io.Reader) io.Reader {
+	if l, ok := ctx.Value(bandwidthLimitKey{}).(*bandwidthLimiter); ok {
+		return throttledReader{ctx: ctx, r: r, l: l}
+	}
+	return r
+}
@@ -0,0 +1,49 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestParseBandwidth tests the bandwidth units accepted by ParseBandwidth.
+func TestParseBandwidth(t *testing.T) {
+	for input, want := range map[string]int64{"1000": 1000, "512k": 512 << 10, "100M": 100 << 20, "100MB/s": 100 << 20, "1.5G": 3 << 29} {
+		got, err := ParseBandwidth(input)
+		if err != nil || got != want {
+			t.Errorf("ParseBandwidth(%q) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "M", "0", "-1M", "fast", "1T"} {
+		if _, err := ParseBandwidth(input); err == nil {
+			t.Errorf("ParseBandwidth(%q) should fail", input)
+		}
+	}
+}
+
+// TestLimitBandwidth tests that the reads are slowed down to the bandwidth and can be cancelled while waiting.
+func TestLimitBandwidth(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 300<<10)
+	opts := NewOptions(WithMaxBandwidth(1 << 20))
+	start := time.Now()
+	result := opts.HashReader(context.Background(), bytes.NewReader(content))
+	if result.Err != nil || result.Size != int64(len(content)) {
+		t.Fatalf("HashReader() = %d bytes, %v", result.Size, result.Err)
+	}
+	// the first 0.1s of bandwidth is read at once, the remaining 200KiB take about 0.2s
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("reading 300KiB at 1MiB/s took %v, want at least 150ms", elapsed)
+	}
+	if result := NewOptions().HashReader(context.Background(), bytes.NewReader(content)); result.Hash != opts.HashReader(context.Background(), bytes.NewReader(content)).Hash {
+		t.Error("the throttled hash differs from the unthrottled one")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result = NewOptions(WithMaxBandwidth(1<<10)).HashReader(ctx, bytes.NewReader(content))
+	if !errors.Is(result.Err, context.DeadlineExceeded) {
+		t.Errorf("HashReader() with a timeout = %v, want %v", result.Err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,136 @@
+package hasher
+
+import (
+	"context"
+	"crypto/sha256"
+	"io"
+	"math/bits"
+	"strings"
+)
+
+// btv2Prefix starts the BitTorrent v2 pieces roots, like btv2:HEX, so they are never mistaken for digests.
+const btv2Prefix = "btv2:"
+
+// BTv2BlockSize is the size of the blocks hashed as the leaves of the merkle trees of BitTorrent v2.
+const BTv2BlockSize = 16 << 10
+
+// The pieces root of a file in a BitTorrent v2 torrent (BEP 52) is the root of the SHA256 merkle tree over its
+// blocks of BTv2BlockSize bytes, the last one being shorter, each node being the hash of the concatenation of
+// its children. The number of leaves is the next power of two, the leaves past the end of the file being 32
+// zero bytes. It does not depend on the piece length of the torrent, so a manifest of the roots verifies the
+// files of any torrent made from them. An empty file has no pieces root in a torrent, it gets a root of zeros.
+//
+// The piece layer of a file larger than a piece is the layer of the tree whose nodes each cover a piece,
+// listed in the piece layers of the torrent so the peers can verify the pieces they download.
+
+// FormatBTv2Hash returns the pieces root hex, prefixed so it can be verified.
+func FormatBTv2Hash(hex string) string {
+	return btv2Prefix + hex
+}
+
+// IsBTv2Hash reports whether hash is a pieces root written by FormatBTv2Hash, in any case.
+func IsBTv2Hash(hash string) bool {
+	return len(hash) == len(btv2Prefix)+2*sha256.Size && strings.EqualFold(hash[:len(btv2Prefix)], btv2Prefix) &&
+		isHexString(hash[len(btv2Prefix):])
+}
+
+// BTv2Tree computes the merkle tree of BitTorrent v2 of the content written to it, keeping only the nodes
+// not yet paired, so the memory used does not grow with the size of the content beyond its piece layer.
+type BTv2Tree struct {
+	pieceLevel int // level of the nodes of the piece layer, -1 when it is not kept
+	block      []byte
+	stack      []btv2Node // nodes not yet paired, by decreasing level
+	layer      []byte
+	size       int64
+}
+
+type btv2Node struct {
+	level int
+	sum   [sha256.Size]byte
+}
+
+// NewBTv2Tree returns a BTv2Tree keeping the piece layer of pieces of pieceLength bytes, a power of two
+// of at least BTv2BlockSize, or not keeping it when pieceLength is 0.
+func NewBTv2Tree(pieceLength int64) *BTv2Tree {
+	t := &BTv2Tree{pieceLevel: -1, block: make([]byte, 0, BTv2BlockSize)}
+	if pieceLength >= BTv2BlockSize {
+		t.pieceLevel = bits.TrailingZeros64(uint64(pieceLength / BTv2BlockSize))
+	}
+	return t
+}
+
+// Write adds p to the content, it never returns an error.
+func (t *BTv2Tree) Write(p []byte) (int, error) {
+	n := len(p)
+	t.size += int64(n)
+	for len(p) > 0 {
+		k := copy(t.block[len(t.block):cap(t.block)], p)
+		t.block, p = t.block[:len(t.block)+k], p[k:]
+		if len(t.block) == BTv2BlockSize {
+			t.stack, t.layer = t.push(t.stack, t.layer, btv2Node{sum: sha256.Sum256(t.block)})
+			t.block = t.block[:0]
+		}
+	}
+	return n, nil
+}
+
+// push adds the node n to stack, pairing it with the nodes of the same level, the nodes of the piece
+// level being appended to layer.
+func (t *BTv2Tree) push(stack []btv2Node, layer []byte, n btv2Node) ([]btv2Node, []byte) {
+	for {
+		if n.level == t.pieceLevel {
+			layer = append(layer, n.sum[:]...)
+		}
+		if len(stack) == 0 || stack[len(stack)-1].level != n.level {
+			return append(stack, n), layer
+		}
+		left := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n = btv2Node{level: n.level + 1, sum: sha256.Sum256(append(left.sum[:], n.sum[:]...))}
+	}
+}
+
+// Size returns the number of bytes written.
+func (t *BTv2Tree) Size() int64 {
+	return t.size
+}
+
+// Sum returns the pieces root of the content written so far, and its piece layer when the tree keeps it
+// and the content is larger than a piece.
+func (t *BTv2Tree) Sum() (root []byte, layer []byte) {
+	stack, layer := append([]btv2Node(nil), t.stack...), append([]byte(nil), t.layer...)
+	if len(t.block) > 0 {
+		stack, layer = t.push(stack, layer, btv2Node{sum: sha256.Sum256(t.block)})
+	}
+	if len(stack) == 0 {
+		return make([]byte, sha256.Size), nil
+	}
+	// The last node is paired with the root of a tree of zero leaves of its level, up to the root
+	var zero [sha256.Size]byte
+	zeroLevel := 0
+	for len(stack) > 1 {
+		last := stack[len(stack)-1]
+		for ; zeroLevel < last.level; zeroLevel++ {
+			zero = sha256.Sum256(append(zero[:], zero[:]...))
+		}
+		stack = stack[:len(stack)-1]
+		stack, layer = t.push(stack, layer, btv2Node{level: last.level + 1, sum: sha256.Sum256(append(last.sum[:], zero[:]...))})
+	}
+	if t.pieceLevel < 0 || t.size <= int64(BTv2BlockSize)<<t.pieceLevel {
+		layer = nil
+	}
+	return stack[0].sum[:], layer
+}
+
+// hashBTv2Reader computes the BitTorrent v2 pieces root of everything read from r.
+func hashBTv2Reader(ctx context.Context, r io.Reader, opts Options) ([]string, int64, error) {
+	tree := NewBTv2Tree(0)
+	buf := bufferPool.Get().([]byte)
+	defer bufferPool.Put(buf)
+	n, err := io.CopyBuffer(cpuLimited(ctx, tree), &ctxReader{ctx: ctx, r: throttled(ctx, r)}, buf)
+	if err != nil {
+		return nil, n, err
+	}
+	root, _ := tree.Sum()
+	return []string{FormatBTv2Hash(formatHex(root, opts.LowerCase))}, n, nil
+}
@@ -0,0 +1,66 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBTv2Tree tests the pieces roots and the piece layers against the merkle trees of BEP 52, computed by hand.
+func TestBTv2Tree(t *testing.T) {
+	node := func(parts ...[]byte) []byte {
+		sum := sha256.Sum256(bytes.Join(parts, nil))
+		return sum[:]
+	}
+	zero := make([]byte, sha256.Size)
+	content := bytes.Repeat([]byte("0123456789abcdef"), 5*BTv2BlockSize/16+1)
+	var leaves [][]byte
+	for i := 0; i < len(content); i += BTv2BlockSize {
+		leaves = append(leaves, node(content[i:min(i+BTv2BlockSize, len(content))]))
+	}
+	pad := node(zero, zero)
+	tests := []struct {
+		size  int
+		root  []byte
+		layer []byte // with pieces of 2 blocks
+	}{
+		{0, zero, nil},
+		{100, node(content[:100]), nil},
+		{2 * BTv2BlockSize, node(leaves[0], leaves[1]), nil},
+		{3 * BTv2BlockSize, node(node(leaves[0], leaves[1]), node(leaves[2], zero)),
+			append(node(leaves[0], leaves[1]), node(leaves[2], zero)...)},
+		{len(content), node(node(node(leaves[0], leaves[1]), node(leaves[2], leaves[3])), node(node(leaves[4], leaves[5]), pad)),
+			bytes.Join([][]byte{node(leaves[0], leaves[1]), node(leaves[2], leaves[3]), node(leaves[4], leaves[5])}, nil)},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		tree := NewBTv2Tree(2 * BTv2BlockSize)
+		tree.Write(content[:tt.size/2])
+		tree.Write(content[tt.size/2 : tt.size])
+		root, layer := tree.Sum()
+		if !bytes.Equal(root, tt.root) || !bytes.Equal(layer, tt.layer) {
+			t.Errorf("Sum() of %d bytes = %x, %x, expected %x, %x", tt.size, root, layer, tt.root, tt.layer)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("file%d", tt.size))
+		if err := os.WriteFile(path, content[:tt.size], 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		want := fmt.Sprintf("btv2:%X", tt.root)
+		if r := NewOptions(WithBitTorrentV2(true)).HashFile(context.Background(), path); r.Err != nil || r.Hash != want {
+			t.Errorf("HashFile(%d bytes) = %q, %v, expected %q", tt.size, r.Hash, r.Err, want)
+		}
+		if v := (Options{}).VerifyFile(context.Background(), path, FileEntry{FilePath: path, Hash: want}); v.Err != nil {
+			t.Errorf("VerifyFile(%d bytes) returned an error: %v", tt.size, v.Err)
+		}
+	}
+	if !IsBTv2Hash(fmt.Sprintf("BTV2:%x", zero)) || IsBTv2Hash("btv2:ab12") {
+		t.Error("IsBTv2Hash() does not recognize the pieces roots")
+	}
+	if err := NewOptions(WithBitTorrentV2(true), WithGlacierTree(true)).Validate(); err == nil {
+		t.Error("Validate() accepted BitTorrent v2 roots with Glacier tree hashes")
+	}
+}
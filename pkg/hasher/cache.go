@@ -0,0 +1,152 @@
+package hasher
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// cacheVersion is increased each time the cache file format changes, older files are then ignored
+const cacheVersion = 1
+
+// cacheEntry is what the cache remembers about a file.
+type cacheEntry struct {
+	Size    int64
+	ModTime int64 // modification time in Unix nanoseconds
+	Hash    string
+}
+
+// cacheFile is the content of a cache file.
+type cacheFile struct {
+	Version int
+	Entries map[string]cacheEntry // keyed by algorithm and absolute path
+}
+
+// HashCache is a persistent cache of file hashes keyed by path, size and modification time,
+// so files that did not change since the previous run are not read again.
+// A file rewritten with the same size and modification time (or silently corrupted on disk)
+// is not detected, so the cache should not be used to verify the integrity of the content.
+// It is safe for concurrent use.
+type HashCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+	hits    int
+	misses  int
+}
+
+// OpenCache loads the cache stored in the file at path, a missing file giving an empty cache.
+func OpenCache(path string) (*HashCache, error) {
+	c := &HashCache{path: path, entries: make(map[string]cacheEntry)}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return c, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var content cacheFile
+	if err := gob.NewDecoder(f).Decode(&content); err != nil {
+		return nil, fmt.Errorf("error reading hash cache %s: %w", path, err)
+	}
+	if content.Version == cacheVersion && content.Entries != nil {
+		c.entries = content.Entries
+	}
+	return c, nil
+}
+
+// cacheKey returns the key of the file at path for the algorithm.
+func cacheKey(algorithm Algorithm, path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return string(algorithm) + ":" + path
+}
+
+// Lookup returns the uppercase hash remembered for the file at path, if its size and
+// modification time, given by info, did not change since it was stored.
+func (c *HashCache) Lookup(algorithm Algorithm, path string, info fs.FileInfo) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[cacheKey(algorithm, path)]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		c.misses++
+		return "", false
+	}
+	c.hits++
+	return entry.Hash, true
+}
+
+// Store remembers the hash of the file at path, with the size and modification time given by info.
+func (c *HashCache) Store(algorithm Algorithm, path string, info fs.FileInfo, hash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[cacheKey(algorithm, path)] = cacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    strings.ToUpper(hash),
+	}
+	c.dirty = true
+}
+
+// Stats returns the number of lookups that found a valid hash and the number that did not.
+func (c *HashCache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Save writes the cache to its file if it changed, replacing the previous content atomically.
+func (c *HashCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if err := gob.NewEncoder(tmp).Encode(cacheFile{Version: cacheVersion, Entries: c.entries}); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// hashFileCached returns the hash of the file at path from the cache when it did not change,
+// and computes and stores it otherwise.
+func hashFileCached(c *HashCache, path string, opts Options, compute func() (string, int64, error)) (string, int64, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", 0, false, err
+	}
+	algorithm := opts.algorithm()
+	if hash, ok := c.Lookup(algorithm, path, info); ok {
+		if opts.LowerCase {
+			hash = strings.ToLower(hash)
+		}
+		return hash, info.Size(), true, nil
+	}
+	hash, size, err := compute()
+	if err == nil {
+		// keep the size and time read before hashing, so a file modified meanwhile is hashed again next time
+		c.Store(algorithm, path, info, hash)
+	}
+	return hash, size, false, err
+}
@@ -0,0 +1,62 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHashCache tests that unchanged files are taken from the cache and changed ones are read again.
+func TestHashCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	cachePath := filepath.Join(dir, "hashes.cache")
+	if err := os.WriteFile(path, []byte("This is a test file for SHA256 hashing."), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	const expected = "B52E9CC162A479840A909B2CFD9D0F1C5D29055A303BB389090236005D87E0E5"
+
+	cache, err := OpenCache(cachePath)
+	if err != nil {
+		t.Fatalf("OpenCache() on a missing file returned an error: %v", err)
+	}
+	r := NewOptions(WithCache(cache)).HashFile(context.Background(), path)
+	if r.Err != nil || r.Hash != expected || r.Cached {
+		t.Fatalf("HashFile() = %q, cached %v, %v, expected %q read from the file", r.Hash, r.Cached, r.Err, expected)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+
+	// A new cache loaded from the file gives the hash without reading the file
+	cache, err = OpenCache(cachePath)
+	if err != nil {
+		t.Fatalf("OpenCache() returned an error: %v", err)
+	}
+	r = NewOptions(WithCache(cache), WithLowerCase(true)).HashFile(context.Background(), path)
+	if r.Err != nil || !r.Cached || r.Hash != "b52e9cc162a479840a909b2cfd9d0f1c5d29055a303bb389090236005d87e0e5" {
+		t.Errorf("HashFile() = %q, cached %v, %v, expected the lowercase hash from the cache", r.Hash, r.Cached, r.Err)
+	}
+	// Another algorithm is not mixed up with the cached SHA256
+	if r = NewOptions(WithCache(cache), WithAlgorithm(MD5)).HashFile(context.Background(), path); r.Cached {
+		t.Error("HashFile() with MD5 returned the cached SHA256 hash")
+	}
+
+	// A modified file is read again
+	if err := os.WriteFile(path, []byte("This is a modified test file."), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to change the file times: %v", err)
+	}
+	r = NewOptions(WithCache(cache)).HashFile(context.Background(), path)
+	if r.Err != nil || r.Cached || r.Hash == expected {
+		t.Errorf("HashFile() = %q, cached %v, %v, expected the new hash read from the file", r.Hash, r.Cached, r.Err)
+	}
+	if hits, misses := cache.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("Stats() = %d, %d, expected 1 hit and 2 misses", hits, misses)
+	}
+}
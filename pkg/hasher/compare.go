@@ -0,0 +1,126 @@
+package hasher
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Comparison is the outcome of CompareDirs, paths being relative to the compared
+// directories, slash separated and sorted.
+type Comparison struct {
+	Identical []string // same content on both sides
+	Different []string // present on both sides with different contents
+	OnlyA     []string // only in the first directory
+	OnlyB     []string // only in the second directory
+	Moved     []Move   // moved or renamed, sorted by their path in the first directory
+	Errors    []Result // files that could not be read, on either side
+}
+
+// Move is a file found under another path in the second directory, with the same content.
+type Move struct {
+	From string // the path in the first directory
+	To   string // the path in the second directory
+}
+
+// Equal reports whether both directories have exactly the same files with the same contents.
+func (c Comparison) Equal() bool {
+	return len(c.Different) == 0 && len(c.OnlyA) == 0 && len(c.OnlyB) == 0 && len(c.Moved) == 0 && len(c.Errors) == 0
+}
+
+// CompareDirs hashes the trees below dirA and dirB concurrently, each with opts.Workers
+// goroutines, and compares them file by file, for instance to verify a copy or a migration.
+// A file only in dirA having the content of a file only in dirB is reported as moved, the paths
+// being paired in order when there are several copies. Empty files are never reported as moved.
+// Files that cannot be read are reported in Comparison.Errors. When ctx is cancelled,
+// ctx.Err() is returned.
+func CompareDirs(ctx context.Context, dirA, dirB string, opts Options) (Comparison, error) {
+	var c Comparison
+	if err := opts.Validate(); err != nil {
+		return c, err
+	}
+	var sides [2]map[string]Result
+	var wg sync.WaitGroup
+	for i, dir := range []string{dirA, dirB} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// per-file errors are also in the results, they are reported below
+			results, _ := HashDir(ctx, dir, opts)
+			side := make(map[string]Result, len(results))
+			for _, r := range results {
+				rel, err := filepath.Rel(dir, r.Path)
+				if err != nil || rel == "." {
+					rel = filepath.Base(r.Path)
+				}
+				side[filepath.ToSlash(rel)] = r
+			}
+			sides[i] = side
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return c, err
+	}
+
+	for rel, a := range sides[0] {
+		b, inB := sides[1][rel]
+		switch {
+		case a.Err != nil:
+			c.Errors = append(c.Errors, a)
+		case !inB:
+			c.OnlyA = append(c.OnlyA, rel)
+		case b.Err != nil:
+			// reported when going through the second directory
+		case a.Hash == b.Hash:
+			c.Identical = append(c.Identical, rel)
+		default:
+			c.Different = append(c.Different, rel)
+		}
+	}
+	for rel, b := range sides[1] {
+		if b.Err != nil {
+			c.Errors = append(c.Errors, b)
+		} else if _, inA := sides[0][rel]; !inA {
+			c.OnlyB = append(c.OnlyB, rel)
+		}
+	}
+	for _, list := range [][]string{c.Identical, c.Different, c.OnlyA, c.OnlyB} {
+		sort.Strings(list)
+	}
+	c.OnlyA, c.OnlyB, c.Moved = pairMoves(c.OnlyA, c.OnlyB, sides[0], sides[1])
+	sort.Slice(c.Errors, func(i, j int) bool { return c.Errors[i].Path < c.Errors[j].Path })
+	return c, nil
+}
+
+// pairMoves pairs the sorted paths only in the first directory with the ones only in the second directory
+// having the same non-empty content in their results a and b, returning the paths left unpaired and the moves.
+func pairMoves(onlyA, onlyB []string, a, b map[string]Result) ([]string, []string, []Move) {
+	byHash := make(map[string][]string)
+	for _, rel := range onlyB {
+		if r := b[rel]; r.Size > 0 {
+			byHash[r.Hash] = append(byHash[r.Hash], rel)
+		}
+	}
+	var moves []Move
+	moved := make(map[string]bool)
+	var restA []string
+	for _, rel := range onlyA {
+		r := a[rel]
+		if candidates := byHash[r.Hash]; r.Size > 0 && len(candidates) > 0 {
+			moves = append(moves, Move{From: rel, To: candidates[0]})
+			moved[candidates[0]] = true
+			byHash[r.Hash] = candidates[1:]
+			continue
+		}
+		restA = append(restA, rel)
+	}
+	var restB []string
+	for _, rel := range onlyB {
+		if !moved[rel] {
+			restB = append(restB, rel)
+		}
+	}
+	return restA, restB, moves
+}
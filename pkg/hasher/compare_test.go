@@ -0,0 +1,59 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestCompareDirs tests the identical, different, missing and moved files found by CompareDirs.
+func TestCompareDirs(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	files := []struct {
+		dir, rel, content string
+	}{
+		{dirA, "same.txt", "same"},
+		{dirB, "same.txt", "same"},
+		{dirA, "sub/changed.txt", "old"},
+		{dirB, "sub/changed.txt", "new"},
+		{dirA, "only-a.txt", "a"},
+		{dirB, "sub/only-b.txt", "b"},
+		{dirA, "photos/2019/cat.jpg", "cat"},
+		{dirB, "cats/cat.jpg", "cat"},
+		{dirA, "empty-a", ""},
+		{dirB, "empty-b", ""},
+	}
+	for _, f := range files {
+		full := filepath.Join(f.dir, filepath.FromSlash(f.rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %q: %v", f.rel, err)
+		}
+		if err := os.WriteFile(full, []byte(f.content), 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %v", f.rel, err)
+		}
+	}
+
+	c, err := CompareDirs(context.Background(), dirA, dirB, Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("CompareDirs() returned an error: %v", err)
+	}
+	expected := Comparison{
+		Identical: []string{"same.txt"},
+		Different: []string{"sub/changed.txt"},
+		OnlyA:     []string{"empty-a", "only-a.txt"},
+		OnlyB:     []string{"empty-b", "sub/only-b.txt"},
+		Moved:     []Move{{From: "photos/2019/cat.jpg", To: "cats/cat.jpg"}},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("CompareDirs() = %+v, expected %+v", c, expected)
+	}
+	if c.Equal() {
+		t.Error("Equal() is true for different directories")
+	}
+
+	if c, err = CompareDirs(context.Background(), dirA, dirA, Options{}); err != nil || !c.Equal() || len(c.Identical) != 5 {
+		t.Errorf("CompareDirs() of a directory with itself = %+v, %v, expected 5 identical files", c, err)
+	}
+}
@@ -0,0 +1,105 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrCopyMismatch is the error of the files whose copy still differs from the source after Recopy.
+var ErrCopyMismatch = errors.New("copy differs from the source")
+
+// CopyFile copies the file src to dst, creating the missing directories, through a temporary file renamed
+// over dst so an interrupted copy never leaves a truncated dst. The copy keeps the permissions and the
+// modification time of src, and stops as soon as ctx is cancelled.
+func CopyFile(ctx context.Context, src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+	if _, err = io.Copy(out, &ctxReader{ctx: ctx, r: in}); err != nil {
+		return fmt.Errorf("copying %s: %w", src, err)
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(out.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = os.Chtimes(out.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// Repair is the outcome of copying again a file with Recopy.
+type Repair struct {
+	Path     string // the slash separated path relative to the directories
+	Attempts int    // the number of copies made, at most 1 + the retries
+	Err      error  // nil when the last copy is identical to the source
+}
+
+// Recopy copies again from dirA to dirB the files at the slash separated relative paths rels, like the
+// ones that CompareDirs found different or missing in a copy, and hashes both sides again with opts.
+// A copy still differing from the source, reported with ErrCopyMismatch, or that failed is made again
+// up to retries times, but not when the source cannot be read or ctx is cancelled.
+// It returns a Repair for each path, in the order of rels.
+func Recopy(ctx context.Context, dirA, dirB string, rels []string, retries int, opts Options) []Repair {
+	repairs := make([]Repair, 0, len(rels))
+	for _, rel := range rels {
+		src, dst := filepath.Join(dirA, filepath.FromSlash(rel)), filepath.Join(dirB, filepath.FromSlash(rel))
+		r := Repair{Path: rel}
+		for r.Attempts <= retries {
+			r.Attempts++
+			r.Err = recopyFile(ctx, src, dst, opts)
+			var pathErr *fs.PathError
+			if r.Err == nil || ctx.Err() != nil || (errors.As(r.Err, &pathErr) && pathErr.Path == src) {
+				break
+			}
+		}
+		repairs = append(repairs, r)
+	}
+	return repairs
+}
+
+// recopyFile copies src to dst and checks that both have the same hash with opts.
+func recopyFile(ctx context.Context, src, dst string, opts Options) error {
+	if err := CopyFile(ctx, src, dst); err != nil {
+		return err
+	}
+	a, b := opts.HashFile(ctx, src), opts.HashFile(ctx, dst)
+	switch {
+	case a.Err != nil:
+		return a.Err
+	case b.Err != nil:
+		return b.Err
+	case a.Hash != b.Hash:
+		return ErrCopyMismatch
+	}
+	return nil
+}
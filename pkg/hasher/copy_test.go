@@ -0,0 +1,79 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCopyFile tests that the copy has the content, the permissions and the modification time of the source.
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.txt"), filepath.Join(dir, "copy", "sub", "dst.txt")
+	if err := os.WriteFile(src, []byte("content"), 0o640); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set the modification time: %v", err)
+	}
+	if err := CopyFile(context.Background(), src, dst); err != nil {
+		t.Fatalf("CopyFile() returned an error: %v", err)
+	}
+	data, err := os.ReadFile(dst)
+	if err != nil || string(data) != "content" {
+		t.Fatalf("The copy contains %q, %v, expected %q", data, err, "content")
+	}
+	info, err := os.Stat(dst)
+	if err != nil || !info.ModTime().Equal(modTime) || info.Mode().Perm() != 0o640 {
+		t.Errorf("The copy has %v, %v, %v, expected %v and %v", info.ModTime(), info.Mode().Perm(), err, modTime, os.FileMode(0o640))
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dst)); len(entries) != 1 {
+		t.Errorf("CopyFile() left %d files in the directory, expected only the copy", len(entries))
+	}
+}
+
+// TestRecopy tests that the different and missing files of a copy are copied again and verified, a missing source not being retried.
+func TestRecopy(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	for rel, content := range map[string]string{"changed.txt": "new", "sub/missing.txt": "missing"} {
+		full := filepath.Join(dirA, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dirB, "changed.txt"), []byte("old"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	repairs := Recopy(context.Background(), dirA, dirB, []string{"changed.txt", "sub/missing.txt", "gone.txt"}, 2, Options{})
+	if len(repairs) != 3 || repairs[0].Err != nil || repairs[1].Err != nil || repairs[0].Attempts != 1 {
+		t.Fatalf("Recopy() returned %+v, expected two copies at the first attempt", repairs)
+	}
+	if r := repairs[2]; r.Path != "gone.txt" || r.Err == nil || r.Attempts != 1 {
+		t.Errorf("Recopy() returned %+v for a missing source, expected an error without retrying", r)
+	}
+	c, err := CompareDirs(context.Background(), dirA, dirB, Options{})
+	if err != nil || !c.Equal() {
+		t.Errorf("CompareDirs() returned %+v, %v after Recopy(), expected identical directories", c, err)
+	}
+}
+
+// TestRecopyRetries tests that a copy failing every time is made again up to the number of retries.
+func TestRecopyRetries(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(dirA, "blocked.txt"), []byte("blocked"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// A directory in the way of the copy cannot be replaced
+	if err := os.MkdirAll(filepath.Join(dirB, "blocked.txt", "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if r := Recopy(context.Background(), dirA, dirB, []string{"blocked.txt"}, 2, Options{})[0]; r.Err == nil || r.Attempts != 3 {
+		t.Errorf("Recopy() returned %+v, expected an error after 3 attempts", r)
+	}
+}
@@ -0,0 +1,55 @@
+package hasher
+
+import (
+	"context"
+	"io"
+	"runtime"
+)
+
+// DefaultWorkerCount returns the number of files read concurrently when Options.Workers is not set:
+// two per CPU, so reads waiting for the storage do not leave CPUs idle, and at least DefaultWorkers.
+func DefaultWorkerCount() int {
+	return max(DefaultWorkers, 2*runtime.NumCPU())
+}
+
+// cpuLimitKey is the context key of the CPU semaphore set by LimitCPU.
+type cpuLimitKey struct{}
+
+// LimitCPU returns a context making the hashing functions called with it compute at most n digests at the
+// same time, whatever the number of files read concurrently, runtime.NumCPU() being used when n < 1.
+// Reading a file does not count: a goroutine waiting for the storage leaves its CPU to another one.
+// The hashing functions of Options running a pool of Workers goroutines apply Options.CPUWorkers this way.
+func LimitCPU(ctx context.Context, n int) context.Context {
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	return context.WithValue(ctx, cpuLimitKey{}, make(chan struct{}, n))
+}
+
+// limitCPU returns a context limited by LimitCPU to o.CPUWorkers, unless ctx is already limited.
+func (o Options) limitCPU(ctx context.Context) context.Context {
+	if _, limited := ctx.Value(cpuLimitKey{}).(chan struct{}); limited {
+		return ctx
+	}
+	return LimitCPU(ctx, o.CPUWorkers)
+}
+
+// cpuWriter holds a slot of the CPU semaphore while writing to the hashes.
+type cpuWriter struct {
+	w   io.Writer
+	sem chan struct{}
+}
+
+func (cw cpuWriter) Write(p []byte) (int, error) {
+	cw.sem <- struct{}{}
+	defer func() { <-cw.sem }()
+	return cw.w.Write(p)
+}
+
+// cpuLimited returns w writing within the CPU limit of ctx, w itself when there is none.
+func cpuLimited(ctx context.Context, w io.Writer) io.Writer {
+	if sem, ok := ctx.Value(cpuLimitKey{}).(chan struct{}); ok {
+		return cpuWriter{w: w, sem: sem}
+	}
+	return w
+}
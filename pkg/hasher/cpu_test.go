@@ -0,0 +1,58 @@
+package hasher
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyWriter records the highest number of concurrent writes.
+type concurrencyWriter struct {
+	current, highest atomic.Int32
+}
+
+func (w *concurrencyWriter) Write(p []byte) (int, error) {
+	n := w.current.Add(1)
+	for {
+		highest := w.highest.Load()
+		if n <= highest || w.highest.CompareAndSwap(highest, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	w.current.Add(-1)
+	return len(p), nil
+}
+
+// TestLimitCPU tests that the writes to the hashes are limited to the number of CPU workers.
+func TestLimitCPU(t *testing.T) {
+	ctx := LimitCPU(context.Background(), 2)
+	w := &concurrencyWriter{}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limited := cpuLimited(ctx, w)
+			for range 5 {
+				limited.Write([]byte("chunk"))
+			}
+		}()
+	}
+	wg.Wait()
+	if highest := w.highest.Load(); highest > 2 {
+		t.Errorf("%d concurrent writes with LimitCPU(2)", highest)
+	}
+	if cpuLimited(context.Background(), w) != w {
+		t.Error("cpuLimited() wrapped the writer without a limit in the context")
+	}
+	if got := (Options{}).limitCPU(ctx); got != ctx {
+		t.Error("limitCPU() replaced the limit already set in the context")
+	}
+	if DefaultWorkerCount() < DefaultWorkers || DefaultWorkerCount() < 2*runtime.NumCPU() {
+		t.Errorf("DefaultWorkerCount() = %d, expected at least %d and 2 per CPU", DefaultWorkerCount(), DefaultWorkers)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package hasher
+
+import "io/fs"
+
+// device is not available on this platform, so Walker.OneFileSystem has no effect.
+func device(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// inode is not available on this platform, so Options.HardLinks has no effect.
+func inode(info fs.FileInfo) (fileID, uint64, bool) {
+	return fileID{}, 0, false
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package hasher
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// device returns the identifier of the filesystem holding the file described by info.
+func device(info fs.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Dev), true
+}
+
+// inode returns the identifier of the file described by info on its filesystem, with its number of hard links.
+func inode(info fs.FileInfo) (fileID, uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, 0, false
+	}
+	return fileID{dev: uint64(st.Dev), ino: uint64(st.Ino)}, uint64(st.Nlink), true
+}
@@ -0,0 +1,94 @@
+package hasher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirHash computes a single deterministic digest for the whole tree below root,
+// covering both the relative paths and the contents of the files reported by w.
+// Like the H1 hash of golang.org/x/mod/sumdb/dirhash, it is the SHA-256 of a summary
+// listing, in path order, one "<lowercase hex sha256>  <slash separated path>" line per file.
+// Files are hashed with up to workers goroutines, any file error makes the whole digest fail.
+func DirHash(ctx context.Context, root string, w Walker, workers int) ([]byte, error) {
+	return dirHashSummary(ctx, root, "", w, workers)
+}
+
+// H1Prefix is the prefix of the hashes in the format used by go.sum files.
+const H1Prefix = "h1:"
+
+// DirHashH1 returns the digest of the tree below root in the exact "h1:<base64>" format
+// of golang.org/x/mod/sumdb/dirhash.HashDir. When prefix is not empty, it is prepended
+// with a "/" to every file path of the summary: using "module@version" as prefix on the
+// extracted module directory gives the hash found in go.sum for this module version.
+func DirHashH1(ctx context.Context, root, prefix string, w Walker, workers int) (string, error) {
+	if prefix != "" {
+		prefix += "/"
+	}
+	sum, err := dirHashSummary(ctx, root, prefix, w, workers)
+	if err != nil {
+		return "", err
+	}
+	return FormatH1(sum), nil
+}
+
+// FormatH1 returns the "h1:<base64>" representation of a DirHash digest.
+func FormatH1(sum []byte) string {
+	return H1Prefix + base64.StdEncoding.EncodeToString(sum)
+}
+
+// ParseH1 decodes a "h1:<base64>" hash into the raw digest.
+func ParseH1(h1 string) ([]byte, error) {
+	if !strings.HasPrefix(h1, H1Prefix) {
+		return nil, fmt.Errorf("not an h1 hash: %q", h1)
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(h1, H1Prefix))
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("malformed h1 hash: %q", h1)
+	}
+	return sum, nil
+}
+
+// dirHashSummary does the actual work of DirHash, prefix being prepended to every relative path.
+func dirHashSummary(ctx context.Context, root, prefix string, w Walker, workers int) ([]byte, error) {
+	type fileHash struct {
+		name string
+		hash string
+	}
+	var files []fileHash
+	var firstErr error
+	err := HashTree(ctx, root, w, workers, func(r Result) {
+		if r.Err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error hashing %s: %w", r.Path, r.Err)
+			}
+			return
+		}
+		rel, err := filepath.Rel(root, r.Path)
+		if err != nil || rel == "." {
+			rel = filepath.Base(r.Path)
+		}
+		files = append(files, fileHash{name: prefix + filepath.ToSlash(rel), hash: strings.ToLower(r.Hash)})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+	summary := sha256.New()
+	for _, f := range files {
+		if strings.Contains(f.name, "\n") {
+			return nil, fmt.Errorf("dirhash: filenames with newlines are not supported: %q", f.name)
+		}
+		fmt.Fprintf(summary, "%s  %s\n", f.hash, f.name)
+	}
+	return summary.Sum(nil), nil
+}
@@ -0,0 +1,75 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDirHash tests that the tree digest follows the summary format and is deterministic.
+func TestDirHash(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	content := "This is a test file for SHA256 hashing."
+	for _, rel := range []string{"b.txt", "sub/a.txt"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(rel)), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %v", rel, err)
+		}
+	}
+
+	got, err := DirHash(context.Background(), root, Walker{}, 4)
+	if err != nil {
+		t.Fatalf("DirHash() returned an error: %v", err)
+	}
+	fileHash := "b52e9cc162a479840a909b2cfd9d0f1c5d29055a303bb389090236005d87e0e5"
+	summary := fileHash + "  b.txt\n" + fileHash + "  sub/a.txt\n"
+	expected := sha256.Sum256([]byte(summary))
+	if !bytes.Equal(got, expected[:]) {
+		t.Errorf("DirHash() = %X, expected %X", got, expected)
+	}
+
+	// renaming a file must change the digest
+	if err := os.Rename(filepath.Join(root, "b.txt"), filepath.Join(root, "c.txt")); err != nil {
+		t.Fatalf("Failed to rename file: %v", err)
+	}
+	renamed, err := DirHash(context.Background(), root, Walker{}, 4)
+	if err != nil {
+		t.Fatalf("DirHash() returned an error: %v", err)
+	}
+	if bytes.Equal(got, renamed) {
+		t.Error("DirHash() did not change after renaming a file")
+	}
+}
+
+// TestDirHashH1 tests the go.sum compatible "h1:" format with a module@version prefix.
+func TestDirHashH1(t *testing.T) {
+	root := t.TempDir()
+	content := "This is a test file for SHA256 hashing."
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	got, err := DirHashH1(context.Background(), root, "example.com/mod@v1.0.0", Walker{}, 2)
+	if err != nil {
+		t.Fatalf("DirHashH1() returned an error: %v", err)
+	}
+	summary := "b52e9cc162a479840a909b2cfd9d0f1c5d29055a303bb389090236005d87e0e5  example.com/mod@v1.0.0/go.mod\n"
+	sum := sha256.Sum256([]byte(summary))
+	expected := "h1:" + base64.StdEncoding.EncodeToString(sum[:])
+	if got != expected {
+		t.Errorf("DirHashH1() = %q, expected %q", got, expected)
+	}
+
+	parsed, err := ParseH1(got)
+	if err != nil || !bytes.Equal(parsed, sum[:]) {
+		t.Errorf("ParseH1(%q) = %X, %v, expected %X", got, parsed, err, sum)
+	}
+	if _, err := ParseH1("h2:abc"); err == nil {
+		t.Error("ParseH1() did not return an error for a non h1 hash")
+	}
+}
@@ -0,0 +1,112 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// DuplicateSet is a group of files having the same size and the same hash.
+type DuplicateSet struct {
+	Hash  string
+	Size  int64    // size of each file
+	Paths []string // sorted, at least two of them
+}
+
+// Wasted returns the space used by the extra copies of the set.
+func (d DuplicateSet) Wasted() int64 {
+	return d.Size * int64(len(d.Paths)-1)
+}
+
+// FindDuplicates walks roots as described by opts and returns the sets of files with identical contents,
+// sorted by decreasing wasted space. Only the files sharing their size with another file are hashed,
+// and empty files are ignored. Files that could not be read are left out of the sets and their
+// errors are joined in the returned error. When ctx is cancelled, ctx.Err() is returned.
+func FindDuplicates(ctx context.Context, roots []string, opts Options) ([]DuplicateSet, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	var errs []error
+	onError := func(path string, err error) {
+		errs = append(errs, fmt.Errorf("error accessing %s: %w", path, err))
+	}
+
+	// Group the files by size first, a file with a unique size cannot have a duplicate
+	bySize := make(map[int64][]string)
+	seen := make(map[string]bool)
+	w := opts.Walker(onError)
+	for _, root := range roots {
+		err := w.Walk(ctx, root, func(path string) error {
+			if seen[path] {
+				return nil // same file given twice through overlapping roots
+			}
+			seen[path] = true
+			info, err := os.Stat(path)
+			if err != nil {
+				onError(path, err)
+				return nil
+			}
+			if info.Size() > 0 {
+				bySize[info.Size()] = append(bySize[info.Size()], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paths := make(chan string, opts.workers())
+	go func() {
+		defer close(paths)
+		for _, candidates := range bySize {
+			if len(candidates) < 2 {
+				continue
+			}
+			for _, path := range candidates {
+				select {
+				case paths <- path:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	type key struct {
+		size int64
+		hash string
+	}
+	groups := make(map[key][]string)
+	for r := range opts.HashFiles(ctx, paths) {
+		if r.Err != nil {
+			if ctx.Err() == nil || !errors.Is(r.Err, ctx.Err()) {
+				errs = append(errs, r.Err)
+			}
+			continue
+		}
+		// the size read while hashing is used, in case the file changed since the walk
+		k := key{size: r.Size, hash: r.Hash}
+		groups[k] = append(groups[k], r.Path)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var sets []DuplicateSet
+	for k, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		sort.Strings(group)
+		sets = append(sets, DuplicateSet{Hash: k.hash, Size: k.size, Paths: group})
+	}
+	sort.Slice(sets, func(i, j int) bool {
+		if wi, wj := sets[i].Wasted(), sets[j].Wasted(); wi != wj {
+			return wi > wj
+		}
+		return sets[i].Paths[0] < sets[j].Paths[0]
+	})
+	return sets, errors.Join(errs...)
+}
@@ -0,0 +1,48 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFindDuplicates tests the grouping of identical files and the wasted space.
+func TestFindDuplicates(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":       "duplicated content",
+		"sub/b.txt":   "duplicated content",
+		"sub/c.txt":   "duplicated content",
+		"d.txt":       "same size content!", // same size as the duplicates, different content
+		"unique.txt":  "unique",
+		"empty1.txt":  "",
+		"empty2.txt":  "",
+		"other/x.bin": "xy",
+		"other/y.bin": "xy",
+	}
+	for rel, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %q: %v", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %v", rel, err)
+		}
+	}
+
+	sets, err := FindDuplicates(context.Background(), []string{root}, Options{Workers: 3})
+	if err != nil {
+		t.Fatalf("FindDuplicates() returned an error: %v", err)
+	}
+	if len(sets) != 2 {
+		t.Fatalf("FindDuplicates() returned %d sets, expected 2: %+v", len(sets), sets)
+	}
+	first := sets[0]
+	if len(first.Paths) != 3 || first.Paths[0] != filepath.Join(root, "a.txt") || first.Wasted() != 36 {
+		t.Errorf("Unexpected first set %+v with %d bytes wasted", first, first.Wasted())
+	}
+	if second := sets[1]; len(second.Paths) != 2 || second.Size != 2 || second.Wasted() != 2 {
+		t.Errorf("Unexpected second set %+v", second)
+	}
+}
@@ -0,0 +1,166 @@
+package hasher
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// ed2kChunkSize is the size of the chunks of the eDonkey2000 hashes.
+const ed2kChunkSize = 9728000
+
+// The ed2k hash of a content is the MD4 of the content when it is smaller than a chunk of ed2kChunkSize bytes,
+// and the MD4 of the MD4 of every chunk otherwise. A content whose size is a multiple of the chunk size gets the
+// MD4 of an empty chunk after the others, like the original eDonkey and eMule clients and RHash compute it.
+
+// ed2kDigest computes the ed2k hash of the content written to it.
+type ed2kDigest struct {
+	chunk  md4Digest // of the current chunk
+	n      int       // bytes in the current chunk
+	chunks md4Digest // of the MD4 of the chunks already complete
+	count  int       // chunks already complete
+}
+
+func newED2K() hash.Hash {
+	d := &ed2kDigest{}
+	d.Reset()
+	return d
+}
+
+func (d *ed2kDigest) Reset() {
+	d.chunk.Reset()
+	d.chunks.Reset()
+	d.n, d.count = 0, 0
+}
+
+func (d *ed2kDigest) Size() int      { return md4Size }
+func (d *ed2kDigest) BlockSize() int { return md4BlockSize }
+
+func (d *ed2kDigest) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		k := min(len(p), ed2kChunkSize-d.n)
+		d.chunk.Write(p[:k])
+		d.n += k
+		p = p[k:]
+		if d.n == ed2kChunkSize {
+			d.chunks.Write(d.chunk.Sum(nil))
+			d.chunk.Reset()
+			d.n = 0
+			d.count++
+		}
+	}
+	return n, nil
+}
+
+func (d *ed2kDigest) Sum(b []byte) []byte {
+	if d.count == 0 {
+		return d.chunk.Sum(b)
+	}
+	chunks := d.chunks
+	chunks.Write(d.chunk.Sum(nil))
+	return chunks.Sum(b)
+}
+
+// NewMD4 returns a hash.Hash computing the MD4 digest, for the formats built on it like ed2k links and the
+// control files of zsync. MD4 is broken, it must not be used to detect tampering.
+func NewMD4() hash.Hash {
+	d := &md4Digest{}
+	d.Reset()
+	return d
+}
+
+// md4Size and md4BlockSize are the sizes of the digest and the blocks of MD4.
+const (
+	md4Size      = 16
+	md4BlockSize = 64
+)
+
+// md4Digest computes the MD4 digest of RFC 1320, only used by the ed2k hashes, MD4 being broken otherwise.
+type md4Digest struct {
+	s   [4]uint32
+	x   [md4BlockSize]byte
+	nx  int
+	len uint64
+}
+
+func (d *md4Digest) Reset() {
+	d.s = [4]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476}
+	d.nx, d.len = 0, 0
+}
+
+func (d *md4Digest) Size() int      { return md4Size }
+func (d *md4Digest) BlockSize() int { return md4BlockSize }
+
+func (d *md4Digest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.len += uint64(n)
+	if d.nx > 0 {
+		k := copy(d.x[d.nx:], p)
+		d.nx += k
+		p = p[k:]
+		if d.nx == md4BlockSize {
+			d.block(d.x[:])
+			d.nx = 0
+		}
+	}
+	for len(p) >= md4BlockSize {
+		d.block(p[:md4BlockSize])
+		p = p[md4BlockSize:]
+	}
+	d.nx += copy(d.x[:], p)
+	return n, nil
+}
+
+func (d *md4Digest) Sum(b []byte) []byte {
+	c := *d // so the caller can keep writing
+	var pad [md4BlockSize + 8]byte
+	pad[0] = 0x80
+	padding := (md4BlockSize + 56 - int(c.len%md4BlockSize)) % md4BlockSize
+	if padding == 0 {
+		padding = md4BlockSize
+	}
+	binary.LittleEndian.PutUint64(pad[padding:], c.len<<3)
+	c.Write(pad[:padding+8])
+	for _, s := range c.s {
+		b = binary.LittleEndian.AppendUint32(b, s)
+	}
+	return b
+}
+
+// md4Shifts and md4Order are the rotations and the order of the words of the 3 rounds of MD4.
+var (
+	md4Shifts = [3][4]int{{3, 7, 11, 19}, {3, 5, 9, 13}, {3, 9, 11, 15}}
+	md4Order  = [3][16]int{
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		{0, 4, 8, 12, 1, 5, 9, 13, 2, 6, 10, 14, 3, 7, 11, 15},
+		{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15},
+	}
+)
+
+func (d *md4Digest) block(p []byte) {
+	var x [16]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(p[4*i:])
+	}
+	a, b, c, e := d.s[0], d.s[1], d.s[2], d.s[3]
+	for round := 0; round < 3; round++ {
+		for i, k := range md4Order[round] {
+			var f uint32
+			switch round {
+			case 0:
+				f = (b & c) | (^b & e)
+			case 1:
+				f = ((b & c) | (b & e) | (c & e)) + 0x5a827999
+			case 2:
+				f = (b ^ c ^ e) + 0x6ed9eba1
+			}
+			a = bits.RotateLeft32(a+f+x[k], md4Shifts[round][i%4])
+			a, b, c, e = e, a, b, c
+		}
+	}
+	d.s[0] += a
+	d.s[1] += b
+	d.s[2] += c
+	d.s[3] += e
+}
@@ -0,0 +1,66 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestMD4 tests the MD4 of the ed2k hashes against the test suite of RFC 1320.
+func TestMD4(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "31d6cfe0d16ae931b73c59d7e0c089c0"},
+		{"a", "bde52cb31de33e46245e05fbdbd6fb24"},
+		{"abc", "a448017aaf21d8525fc10ae87aa6729d"},
+		{"message digest", "d9130a8164549fe818874806e1c7014b"},
+		{"abcdefghijklmnopqrstuvwxyz", "d79e1c308aa5bbcdeea8ed63df412da9"},
+		{"12345678901234567890123456789012345678901234567890123456789012345678901234567890", "e33b4ddc9c38f2199c3e7b164fcc0536"},
+	}
+	for _, tt := range tests {
+		var d md4Digest
+		d.Reset()
+		d.Write([]byte(tt.input))
+		if got := hex.EncodeToString(d.Sum(nil)); got != tt.want {
+			t.Errorf("MD4(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestED2K tests the ed2k hashes of contents smaller than a chunk, of several chunks and of a multiple of
+// the chunk size, against the MD4 of the MD4 of the chunks computed by hand.
+func TestED2K(t *testing.T) {
+	md4 := func(p []byte) []byte {
+		var d md4Digest
+		d.Reset()
+		d.Write(p)
+		return d.Sum(nil)
+	}
+	content := bytes.Repeat([]byte("0123456789"), 2*ed2kChunkSize/10+7)
+	first, second := content[:ed2kChunkSize], content[ed2kChunkSize:2*ed2kChunkSize]
+	tests := []struct {
+		name    string
+		content []byte
+		want    []byte
+	}{
+		{"empty", nil, md4(nil)},
+		{"small", []byte("abc"), md4([]byte("abc"))},
+		{"one chunk", first, md4(append(md4(first), md4(nil)...))},
+		{"two chunks", content[:2*ed2kChunkSize], md4(bytes.Join([][]byte{md4(first), md4(second), md4(nil)}, nil))},
+		{"more", content, md4(bytes.Join([][]byte{md4(first), md4(second), md4(content[2*ed2kChunkSize:])}, nil))},
+	}
+	for _, tt := range tests {
+		h := newED2K()
+		// Written in uneven parts so the chunks are split across the writes
+		for p := tt.content; len(p) > 0; {
+			k := min(len(p), 1<<20+3)
+			h.Write(p[:k])
+			p = p[k:]
+		}
+		if got := h.Sum(nil); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: ed2k = %x, want %x", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package hasher
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+)
+
+// ExtraFiles returns the files found by w below root that are not listed, the listed paths being relative
+// to root, or absolute, like the ones of a manifest checked from root. The extra files are returned sorted,
+// relative to root as they would be written in such a manifest.
+func ExtraFiles(ctx context.Context, w Walker, root string, listed []string) ([]string, error) {
+	return Options{}.ExtraFiles(ctx, w, root, listed)
+}
+
+// ExtraFiles works like the ExtraFiles function, the listed paths and the files found being compared in the
+// Unicode normalization form o.NormalizePaths.
+func (o Options) ExtraFiles(ctx context.Context, w Walker, root string, listed []string) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(listed))
+	for _, path := range listed {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(absRoot, path)
+		}
+		known[NormalizePath(filepath.Clean(path), o.NormalizePaths)] = true
+	}
+	var extra []string
+	err = w.Walk(ctx, root, func(path string) error {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if !known[NormalizePath(abs, o.NormalizePaths)] {
+			rel, err := filepath.Rel(absRoot, abs)
+			if err != nil {
+				return err
+			}
+			extra = append(extra, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(extra)
+	return extra, nil
+}
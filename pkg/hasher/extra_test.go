@@ -0,0 +1,32 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtraFiles tests that the files not listed are found, whether the listed paths are relative or absolute.
+func TestExtraFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "sub/c.txt", "sub/d.txt", "skip.tmp"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listed := []string{"a.txt", "./sub/../b.txt", filepath.Join(root, "sub", "c.txt"), "missing.txt"}
+	w := Walker{Filter: PathFilter{Exclude: []string{"*.tmp"}}}
+	extra, err := ExtraFiles(context.Background(), w, root, listed)
+	if err != nil {
+		t.Fatalf("ExtraFiles() returned %v", err)
+	}
+	if want := fmt.Sprint([]string{filepath.Join("sub", "d.txt")}); fmt.Sprint(extra) != want {
+		t.Errorf("ExtraFiles() = %v, expected %v", extra, want)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build linux && (amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+
+package hasher
+
+import (
+	"os"
+	"syscall"
+)
+
+// The advices of posix_fadvise.
+const (
+	fadvSequential = 2 // POSIX_FADV_SEQUENTIAL
+	fadvDontNeed   = 4 // POSIX_FADV_DONTNEED
+)
+
+// fadvise gives advice about the length bytes of f from offset, to the end of the file when length is 0.
+// The advice being only a hint, errors are ignored.
+func fadvise(f *os.File, offset, length int64, advice int) {
+	syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), uintptr(offset), uintptr(length), uintptr(advice), 0, 0)
+}
@@ -0,0 +1,14 @@
+//go:build !linux || !(amd64 || arm64 || loong64 || mips64 || mips64le || ppc64 || ppc64le || riscv64 || s390x)
+
+package hasher
+
+import "os"
+
+// The advices of posix_fadvise, ignored on this platform.
+const (
+	fadvSequential = 2
+	fadvDontNeed   = 4
+)
+
+// fadvise is not available on this platform, the kernel manages the page cache alone.
+func fadvise(f *os.File, offset, length int64, advice int) {}
@@ -0,0 +1,258 @@
+package hasher
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PathFilter decides which paths are kept while walking a directory.
+// Patterns use the path.Match syntax, extended with "**" to match any
+// number of path segments (e.g. "node_modules/**" or "**/*.log").
+// A pattern without a "/" is matched against the base name only.
+type PathFilter struct {
+	Include []string // if not empty, only files matching one of these patterns are kept
+	Exclude []string // files and directories matching one of these patterns are skipped
+	// Extensions, if not empty, only keeps the files whose name ends with one of these extensions,
+	// like ".jpg" or "tar.gz", in any case
+	Extensions []string
+	MinSize    int64 // if > 0, the smaller files are skipped, see KeepSize
+	MaxSize    int64 // if > 0, the larger files are skipped, see KeepSize
+	// NewerThan, if not zero, only keeps the files modified after it, see ParseNewerThan
+	NewerThan time.Time
+}
+
+// IsEmpty reports whether the filter has no patterns at all.
+func (f PathFilter) IsEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0 && len(f.Extensions) == 0 && f.MinSize < 1 && f.MaxSize < 1 &&
+		f.NewerThan.IsZero()
+}
+
+// Validate checks that every pattern of the filter is syntactically valid.
+func (f PathFilter) Validate() error {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		for _, seg := range strings.Split(strings.Trim(p, "/"), "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return &PatternError{Pattern: p, Err: err}
+			}
+		}
+	}
+	if f.MinSize > 0 && f.MaxSize > 0 && f.MinSize > f.MaxSize {
+		return fmt.Errorf("the minimum size %d is larger than the maximum size %d", f.MinSize, f.MaxSize)
+	}
+	return nil
+}
+
+// Excluded reports whether relPath (relative to the walked root) matches an exclude pattern.
+// When a directory is excluded, the whole subtree below it should be skipped.
+func (f PathFilter) Excluded(relPath string) bool {
+	for _, p := range f.Exclude {
+		if MatchPattern(p, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Included reports whether the file at relPath should be kept according to the include patterns
+// and the extensions. It always returns true when none of them was given.
+func (f PathFilter) Included(relPath string) bool {
+	if !f.hasExtension(relPath) {
+		return false
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, p := range f.Include {
+		if MatchPattern(p, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keep reports whether the file at relPath passes both the exclude and the include patterns.
+func (f PathFilter) Keep(relPath string) bool {
+	return !f.Excluded(relPath) && f.Included(relPath)
+}
+
+// KeepListed works like Keep for the path of a file listed in a manifest, which is also left out when one of its
+// parent directories is excluded, as it would have been skipped with its directory while walking.
+func (f PathFilter) KeepListed(relPath string) bool {
+	for dir := filepath.ToSlash(relPath); dir != "." && dir != "/" && dir != ""; dir = path.Dir(dir) {
+		if f.Excluded(dir) {
+			return false
+		}
+	}
+	return f.Included(relPath)
+}
+
+// hasExtension reports whether the name of the file at relPath ends with one of f.Extensions, or true without them.
+func (f PathFilter) hasExtension(relPath string) bool {
+	if len(f.Extensions) == 0 {
+		return true
+	}
+	name := strings.ToLower(path.Base(filepath.ToSlash(relPath)))
+	for _, ext := range f.Extensions {
+		ext = strings.ToLower(ext)
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSizeLimits reports whether f keeps the files by size, so the walk needs their size.
+func (f PathFilter) HasSizeLimits() bool {
+	return f.MinSize > 0 || f.MaxSize > 0
+}
+
+// keepsInfo reports whether f keeps the files by size or modification time, so the walk needs their fs.FileInfo.
+func (f PathFilter) keepsInfo() bool {
+	return f.HasSizeLimits() || !f.NewerThan.IsZero()
+}
+
+// KeepInfo reports whether the file described by info passes the size limits and was modified after f.NewerThan.
+func (f PathFilter) KeepInfo(info fs.FileInfo) bool {
+	return f.KeepSize(info.Size()) && (f.NewerThan.IsZero() || info.ModTime().After(f.NewerThan))
+}
+
+// KeepSize reports whether a file of size bytes is within f.MinSize and f.MaxSize, both included.
+func (f PathFilter) KeepSize(size int64) bool {
+	return (f.MinSize < 1 || size >= f.MinSize) && (f.MaxSize < 1 || size <= f.MaxSize)
+}
+
+// ParseSize parses a number of bytes, optionally followed by K, M, G or T for KiB, MiB, GiB and TiB,
+// like 500K or 1.5G, to set PathFilter.MinSize and MaxSize.
+func ParseSize(s string) (int64, error) {
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(s)), "B")
+	shift := 0
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'K':
+			shift = 10
+		case 'M':
+			shift = 20
+		case 'G':
+			shift = 30
+		case 'T':
+			shift = 40
+		}
+	}
+	if shift > 0 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || n*float64(int64(1)<<shift) >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q, expected a number of bytes like 500K or 2G", s)
+	}
+	return int64(n * float64(int64(1)<<shift)), nil
+}
+
+// newerThanLayouts are the timestamps accepted by ParseNewerThan, the ones without time zone being local times.
+var newerThanLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+// ParseNewerThan parses the point in time after which the files are kept with PathFilter.NewerThan: a duration
+// before now, like 24h or 7d, a timestamp, like 2024-05-31 or 2024-05-31T22:00:00Z, or the path of a reference
+// file whose modification time is used, like the manifest of the previous run.
+func ParseNewerThan(s string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.ParseFloat(days, 64); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n * float64(24*time.Hour))), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range newerThanLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	info, err := os.Stat(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a duration like 24h or 7d, a timestamp like 2024-05-31T22:00:00 nor a reference file: %w", s, err)
+	}
+	return info.ModTime(), nil
+}
+
+// PatternError is returned when a glob pattern is malformed.
+type PatternError struct {
+	Pattern string
+	Err     error
+}
+
+func (e *PatternError) Error() string {
+	return "invalid pattern " + e.Pattern + ": " + e.Err.Error()
+}
+
+func (e *PatternError) Unwrap() error { return e.Err }
+
+// MatchPattern reports whether relPath matches the glob pattern.
+// Paths are compared using forward slashes whatever the OS.
+// A pattern without "/" matches the base name of relPath, a pattern starting
+// with "/" is anchored at the walked root, any other pattern may match
+// starting at any directory level (so "node_modules/**" also skips "a/node_modules").
+func MatchPattern(pattern, relPath string) bool {
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+	pathSegs := strings.Split(relPath, "/")
+
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, pathSegs[len(pathSegs)-1])
+		return ok
+	}
+	anchored := strings.HasPrefix(pattern, "/")
+	patSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+	if anchored {
+		return matchSegments(patSegs, pathSegs)
+	}
+	for i := range pathSegs {
+		if matchSegments(patSegs, pathSegs[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches slash-separated pattern segments against path segments,
+// "**" matching zero or more path segments.
+func matchSegments(pat, segs []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			// collapse consecutive "**"
+			for len(pat) > 0 && pat[0] == "**" {
+				pat = pat[1:]
+			}
+			if len(pat) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pat, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], segs[0]); !ok {
+			return false
+		}
+		pat = pat[1:]
+		segs = segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -0,0 +1,150 @@
+package hasher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMatchPattern tests the glob matching used by the directory walker filters.
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.tmp", "file.tmp", true},
+		{"*.tmp", "dir/sub/file.tmp", true},
+		{"*.tmp", "file.txt", false},
+		{"node_modules/**", "node_modules", true},
+		{"node_modules/**", "node_modules/pkg/index.js", true},
+		{"node_modules/**", "web/node_modules/pkg/index.js", true},
+		{"node_modules/**", "my_node_modules/index.js", false},
+		{"/build/*.o", "build/main.o", true},
+		{"/build/*.o", "src/build/main.o", false},
+		{"**/*.log", "a/b/c/app.log", true},
+		{"docs/**/*.md", "docs/README.md", true},
+		{"docs/**/*.md", "docs/a/b/guide.md", true},
+		{"docs/**/*.md", "docs/a/b/guide.txt", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, expected %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestPathFilter tests the combination of include and exclude patterns.
+func TestPathFilter(t *testing.T) {
+	f := PathFilter{
+		Include: []string{"*.pdf", "*.jpg"},
+		Exclude: []string{"tmp/**", "draft-*"},
+	}
+	if err := f.Validate(); err != nil {
+		t.Fatalf("Validate() returned an error: %v", err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"report.pdf", true},
+		{"photos/cat.jpg", true},
+		{"notes.txt", false},
+		{"tmp/report.pdf", false},
+		{"draft-report.pdf", false},
+	}
+	for _, tt := range tests {
+		if got := f.Keep(tt.path); got != tt.want {
+			t.Errorf("Keep(%q) = %v, expected %v", tt.path, got, tt.want)
+		}
+	}
+
+	if err := (PathFilter{Exclude: []string{"[abc"}}).Validate(); err == nil {
+		t.Error("Validate() did not return an error for a malformed pattern")
+	}
+}
+
+// TestKeepListed tests that the files listed below an excluded directory are left out.
+func TestKeepListed(t *testing.T) {
+	f := PathFilter{Include: []string{"**/*.iso"}, Exclude: []string{"draft-*", "/old"}}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"images/debian.iso", true},
+		{"/srv/images/debian.iso", true},
+		{"images/debian.iso.sha256", false},
+		{"draft-images/debian.iso", false},
+		{"images/draft-debian.iso", false},
+		{"old/debian.iso", false},
+		{"images/old/debian.iso", true},
+	}
+	for _, tt := range tests {
+		if got := f.KeepListed(tt.path); got != tt.want {
+			t.Errorf("KeepListed(%q) = %v, expected %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestExtensionsAndSizes tests the extensions, in any case and with or without a dot, and the size limits.
+func TestExtensionsAndSizes(t *testing.T) {
+	f := PathFilter{Extensions: []string{".jpg", "PNG", "tar.gz"}, MinSize: 10, MaxSize: 100}
+	for path, want := range map[string]bool{"a/photo.JPG": true, "b.png": true, "c.tar.gz": true, "d.gz": false, "jpg": false, "e.jpeg": false} {
+		if got := f.Keep(path); got != want {
+			t.Errorf("Keep(%q) = %v, expected %v", path, got, want)
+		}
+	}
+	for size, want := range map[int64]bool{9: false, 10: true, 100: true, 101: false} {
+		if got := f.KeepSize(size); got != want {
+			t.Errorf("KeepSize(%d) = %v, expected %v", size, got, want)
+		}
+	}
+	if err := (PathFilter{MinSize: 2, MaxSize: 1}).Validate(); err == nil {
+		t.Error("Validate() accepted a minimum size larger than the maximum size")
+	}
+}
+
+// TestParseSize tests the units of the sizes.
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"0": 0, "512": 512, "4K": 4 << 10, "1.5M": 3 << 19, "2GB": 2 << 30, "1t": 1 << 40} {
+		if got, err := ParseSize(s); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, expected %d", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "-1K", "ten", "1P"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("ParseSize(%q) accepted an invalid size", s)
+		}
+	}
+}
+
+// TestParseNewerThan tests the durations, the timestamps and the reference files.
+func TestParseNewerThan(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	reference := filepath.Join(t.TempDir(), "previous.sha256")
+	if err := os.WriteFile(reference, nil, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	modTime := now.Add(-3 * time.Hour)
+	if err := os.Chtimes(reference, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set the modification time: %v", err)
+	}
+	for s, want := range map[string]time.Time{
+		"24h":                  now.Add(-24 * time.Hour),
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"2024-05-31":           time.Date(2024, 5, 31, 0, 0, 0, 0, time.Local),
+		"2024-05-31 22:30":     time.Date(2024, 5, 31, 22, 30, 0, 0, time.Local),
+		"2024-05-31T22:00:00Z": time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC),
+		reference:              modTime,
+	} {
+		if got, err := ParseNewerThan(s, now); err != nil || !got.Equal(want) {
+			t.Errorf("ParseNewerThan(%q) = %v, %v, expected %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"", "-1h", "yesterday", filepath.Join(t.TempDir(), "missing")} {
+		if _, err := ParseNewerThan(s, now); err == nil {
+			t.Errorf("ParseNewerThan(%q) accepted an invalid value", s)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package hasher
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FlushInterval is the longest time the lines written to a FlushWriter stay buffered.
+const FlushInterval = time.Second
+
+// flushBufferSize is the size of the buffer of a FlushWriter, written before it overflows.
+const flushBufferSize = 64 << 10
+
+// FlushWriter buffers the lines of a manifest, writing them at most FlushInterval after they were written so
+// the lines of the files hashed show up as they complete, without a write for each of them. With a number of
+// lines, they are also written every that many lines and the file is synced to disk each time, so a crash,
+// even of the machine, loses at most the lines written since. Each line must be written with a single Write,
+// they are written whole, so the lines of a LockedWriter below are still never interleaved with others.
+type FlushWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	f     *os.File // synced with each write when every > 0
+	every int
+	lines int
+	buf   []byte
+	timer *time.Timer
+	err   error
+}
+
+// NewFlushWriter returns a FlushWriter writing to w, the writer of the file f, every lines and syncing f
+// each time when every > 0.
+func NewFlushWriter(w io.Writer, f *os.File, every int) *FlushWriter {
+	return &FlushWriter{w: w, f: f, every: every, buf: make([]byte, 0, flushBufferSize)}
+}
+
+// Write buffers the line p, returning the error of a previous write if any.
+func (w *FlushWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(w.buf) > 0 && len(w.buf)+len(p) > cap(w.buf) {
+		w.flush()
+	}
+	w.buf = append(w.buf, p...)
+	w.lines++
+	switch {
+	case w.every > 0 && w.lines >= w.every:
+		w.flush()
+	case w.timer == nil:
+		w.timer = time.AfterFunc(FlushInterval, func() { w.Flush() })
+	}
+	return len(p), w.err
+}
+
+// Flush writes the buffered lines, syncing the file with a number of lines.
+func (w *FlushWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+	return w.err
+}
+
+// Close writes the buffered lines, the file itself being closed by the caller.
+func (w *FlushWriter) Close() error {
+	return w.Flush()
+}
+
+func (w *FlushWriter) flush() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if len(w.buf) == 0 || w.err != nil {
+		return
+	}
+	_, w.err = w.w.Write(w.buf)
+	w.buf, w.lines = w.buf[:0], 0
+	if w.err == nil && w.every > 0 && w.f != nil {
+		w.err = w.f.Sync()
+	}
+}
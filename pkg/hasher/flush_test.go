@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFlushWriter tests that the lines are written every N lines, after FlushInterval and on Close.
+func TestFlushWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer f.Close()
+	content := func() string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		return string(data)
+	}
+
+	w := NewFlushWriter(NewLockedWriter(f), f, 2)
+	w.Write([]byte("line 1\n"))
+	if got := content(); got != "" {
+		t.Errorf("after 1 line, file = %q, want nothing yet", got)
+	}
+	w.Write([]byte("line 2\n"))
+	if got := content(); got != "line 1\nline 2\n" {
+		t.Errorf("after 2 lines, file = %q", got)
+	}
+	w.Write([]byte("line 3\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := content(); got != "line 1\nline 2\nline 3\n" {
+		t.Errorf("after Close, file = %q", got)
+	}
+
+	// Without a number of lines, the lines are written after FlushInterval
+	w = NewFlushWriter(f, f, 0)
+	w.Write([]byte("line 4\n"))
+	deadline := time.Now().Add(FlushInterval + 2*time.Second)
+	for content() != "line 1\nline 2\nline 3\nline 4\n" {
+		if time.Now().After(deadline) {
+			t.Fatalf("line not written after %s, file = %q", FlushInterval, content())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	w.Close()
+}
@@ -0,0 +1,106 @@
+package hasher
+
+import (
+	"context"
+	"io/fs"
+	"strings"
+)
+
+// GetSHA256FS returns the sha256 hash of the file name in fsys.
+// It allows hashing embedded filesystems, archives mounted as fs.FS or fstest.MapFS in tests.
+func GetSHA256FS(fsys fs.FS, name string) (string, error) {
+	result := Options{}.HashFileFS(context.Background(), fsys, name)
+	return result.Hash, result.Err
+}
+
+// HashFileFS works like HashFile for the file name in fsys.
+func (o Options) HashFileFS(ctx context.Context, fsys fs.FS, name string) Result {
+	if err := ctx.Err(); err != nil {
+		return Result{Path: name, Err: err}
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return Result{Path: name, Err: err}
+	}
+	defer f.Close()
+	var size int64
+	if o.GitBlob {
+		info, err := f.Stat()
+		if err != nil {
+			return Result{Path: name, Err: err}
+		}
+		size = info.Size()
+	}
+	hashes, size, err := hashContent(ctx, f, size, o)
+	return o.result(name, hashes, size, err)
+}
+
+// WalkFS works like Walk for the tree starting at root in fsys, paths using forward slashes
+// as required by fs.FS. Symbolic links are not followed, whatever Symlinks.
+func (w Walker) WalkFS(ctx context.Context, fsys fs.FS, root string, fn func(path string) error) error {
+	var ignorer *Ignorer
+	if !w.NoIgnore {
+		ignorer = NewIgnorerFS(fsys, root)
+	}
+	return fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if w.OnError != nil {
+				w.OnError(path, err)
+			}
+			return nil // Don't stop the walk, just skip this file/dir
+		}
+		if path == root && !d.IsDir() {
+			return fn(path)
+		}
+		// Apply the include/exclude patterns and .hashignore rules relative to the walked directory
+		relPath := path
+		if root != "." {
+			relPath = strings.TrimPrefix(strings.TrimPrefix(path, root), "/")
+		}
+		if d.IsDir() {
+			if path != root && (w.Filter.Excluded(relPath) || (ignorer != nil && ignorer.Ignored(relPath, true))) {
+				return fs.SkipDir
+			}
+			if ignorer != nil {
+				if err := ignorer.LoadDir(relPath); err != nil && w.OnError != nil {
+					w.OnError(path+"/"+IgnoreFileName, err)
+				}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil // symlinks and special files cannot be read through fs.FS
+		}
+		if ignorer != nil && ignorer.Ignored(relPath, false) {
+			return nil
+		}
+		if !w.Filter.Keep(relPath) {
+			return nil
+		}
+		return fn(path)
+	})
+}
+
+// HashTreeFS works like HashTree for the tree starting at root in fsys.
+func (o Options) HashTreeFS(ctx context.Context, fsys fs.FS, root string, w Walker, fn func(Result)) error {
+	walk := func(ctx context.Context, found func(path string) error) error {
+		return w.WalkFS(ctx, fsys, root, found)
+	}
+	hashOne := func(ctx context.Context, name string) Result {
+		return o.HashFileFS(ctx, fsys, name)
+	}
+	return o.hashTree(ctx, walk, hashOne, fn)
+}
+
+// HashDirFS works like HashDir for the tree starting at root in fsys, use "." for the whole filesystem.
+func HashDirFS(ctx context.Context, fsys fs.FS, root string, opts Options) ([]Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return collectResults(ctx, func(onError func(path string, err error), fn func(Result)) error {
+		return opts.HashTreeFS(ctx, fsys, root, opts.Walker(onError), fn)
+	})
+}
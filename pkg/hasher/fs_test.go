@@ -0,0 +1,50 @@
+package hasher
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+// TestHashDirFS tests hashing an in-memory filesystem with filters and a .hashignore file.
+func TestHashDirFS(t *testing.T) {
+	content := []byte("This is a test file for SHA256 hashing.")
+	fsys := fstest.MapFS{
+		"docs/a.txt":                {Data: content},
+		"docs/b.tmp":                {Data: []byte("temporary")},
+		"docs/cache/c.txt":          {Data: []byte("cached")},
+		"docs/" + IgnoreFileName:    {Data: []byte("cache/\n")},
+		"docs/sub/d.txt":            {Data: content},
+		"other/not-walked-here.txt": {Data: content},
+	}
+
+	hash, err := GetSHA256FS(fsys, "docs/a.txt")
+	if err != nil {
+		t.Fatalf("GetSHA256FS() returned an error: %v", err)
+	}
+	expectedHash := "B52E9CC162A479840A909B2CFD9D0F1C5D29055A303BB389090236005D87E0E5"
+	if hash != expectedHash {
+		t.Errorf("GetSHA256FS() = %q, expected %q", hash, expectedHash)
+	}
+
+	results, err := HashDirFS(context.Background(), fsys, "docs", NewOptions(WithExclude("*.tmp")))
+	if err != nil {
+		t.Fatalf("HashDirFS() returned an error: %v", err)
+	}
+	expected := []string{"docs/" + IgnoreFileName, "docs/a.txt", "docs/sub/d.txt"}
+	if len(results) != len(expected) {
+		t.Fatalf("HashDirFS() returned %+v, expected paths %v", results, expected)
+	}
+	for i, r := range results {
+		if r.Path != expected[i] {
+			t.Errorf("Result %d: path %q, expected %q", i, r.Path, expected[i])
+		}
+	}
+	if results[1].Hash != expectedHash || results[1].Size != int64(len(content)) {
+		t.Errorf("Unexpected result for %s: %+v", results[1].Path, results[1])
+	}
+
+	if _, err := GetSHA256FS(fsys, "missing.txt"); err == nil {
+		t.Error("GetSHA256FS() for a missing file did not return an error")
+	}
+}
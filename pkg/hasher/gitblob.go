@@ -0,0 +1,83 @@
+package hasher
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errGitBlobSize is returned by HashReader with GitBlob, a git blob hash starting with the size of the content.
+var errGitBlobSize = errors.New("git blob hashes need the size of the content, hash a file instead")
+
+// gitBlobHeader returns what git hashes before the content of a blob of size bytes.
+func gitBlobHeader(size int64) string {
+	return "blob " + strconv.FormatInt(size, 10) + "\x00"
+}
+
+// hashContent works like hashReader for content of size bytes, which is hashed like git hashes blobs when
+// opts.GitBlob is set: the header "blob <size>\0" is hashed first, and reading another number of bytes
+// is an error as the header would be wrong. The returned size does not count the header.
+func hashContent(ctx context.Context, r io.Reader, size int64, opts Options) ([]string, int64, error) {
+	if !opts.GitBlob {
+		return hashReader(ctx, r, opts)
+	}
+	header := gitBlobHeader(size)
+	hashes, n, err := hashReader(ctx, io.MultiReader(strings.NewReader(header), r), opts)
+	n = max(n-int64(len(header)), 0)
+	if err == nil && n != size {
+		return nil, n, fmt.Errorf("size changed while hashing, %d bytes read instead of %d", n, size)
+	}
+	return hashes, n, err
+}
+
+// gitLsFilesLine matches the lines of git ls-files -s: mode, object name, stage, a tab and the path.
+var gitLsFilesLine = regexp.MustCompile(`^([0-7]{6}) ([0-9a-fA-F]{40}|[0-9a-fA-F]{64}) [0-3]\t(.+)$`)
+
+// IsGitLsFiles reports whether the content of r starts with a line of git ls-files -s, without consuming it.
+func IsGitLsFiles(r *bufio.Reader) bool {
+	line, _ := r.Peek(256)
+	first, _, _ := strings.Cut(string(line), "\n")
+	return gitLsFilesLine.MatchString(first)
+}
+
+// ParseGitLsFiles parses the output of git ls-files -s (or --stage), the object names of the blobs being the
+// git blob hashes of the files. Submodules, whose object name is a commit, are skipped, and the paths quoted
+// by git when they contain special characters are unquoted. Other lines are returned as malformed.
+func ParseGitLsFiles(reader io.Reader) ([]FileEntry, []MalformedLine, error) {
+	scanner := bufio.NewScanner(reader)
+	var entries []FileEntry
+	var malformed []MalformedLine
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		m := gitLsFilesLine.FindStringSubmatch(line)
+		if m == nil {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+			continue
+		}
+		if m[1] == "160000" {
+			continue // a submodule
+		}
+		path := m[3]
+		if strings.HasPrefix(path, `"`) {
+			unquoted, err := strconv.Unquote(path)
+			if err != nil {
+				malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+				continue
+			}
+			path = unquoted
+		}
+		entries = append(entries, FileEntry{Hash: strings.ToUpper(m[2]), FilePath: filepath.FromSlash(path)})
+	}
+	return entries, malformed, scanner.Err()
+}
@@ -0,0 +1,63 @@
+package hasher
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestGitBlob tests that files are hashed with the object names git gives to their blobs.
+func TestGitBlob(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content   string
+		algorithm Algorithm
+		want      string
+	}{
+		{"", SHA1, "E69DE29BB2D1D6434B8B29AE775AD8C2E48C5391"},
+		{"hello\n", SHA1, "CE013625030BA8DBA906F756967F9E9CA394464A"},
+		{"hello\n", SHA256, "2CF8D83D9EE29543B34A87727421FDECB7E3F3A183D337639025DE576DB9EBB4"},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, tt.content+string(tt.algorithm))
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		result := NewOptions(WithAlgorithm(tt.algorithm), WithGitBlob(true)).HashFile(context.Background(), path)
+		if result.Err != nil || result.Hash != tt.want || result.Size != int64(len(tt.content)) {
+			t.Errorf("test %d: HashFile() = %q, %d, %v, expected %q", i, result.Hash, result.Size, result.Err, tt.want)
+		}
+	}
+	if result := NewOptions(WithGitBlob(true)).HashReader(context.Background(), strings.NewReader("abc")); result.Err == nil {
+		t.Error("HashReader() with GitBlob did not return an error")
+	}
+}
+
+// TestParseGitLsFiles tests the parsing of the output of git ls-files -s.
+func TestParseGitLsFiles(t *testing.T) {
+	content := "100644 ce013625030ba8dba906f756967f9e9ca394464a 0\ta.txt\n" +
+		"100644 c1b0730e0133447badcfd47fd144e254807b06e1 0\t\"sub dir/\\303\\251.txt\"\n" +
+		"160000 4b825dc642cb6eb9a060e54bf8d69288fbee4904 0\tvendor/lib\n" +
+		"ce013625030ba8dba906f756967f9e9ca394464a  a.txt\n"
+	if !IsGitLsFiles(bufio.NewReader(strings.NewReader(content))) {
+		t.Error("IsGitLsFiles() does not recognize the output of git ls-files -s")
+	}
+	entries, malformed, err := ParseGitLsFiles(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseGitLsFiles() returned an error: %v", err)
+	}
+	expected := []FileEntry{
+		{Hash: "CE013625030BA8DBA906F756967F9E9CA394464A", FilePath: "a.txt"},
+		{Hash: "C1B0730E0133447BADCFD47FD144E254807B06E1", FilePath: filepath.FromSlash("sub dir/é.txt")},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ParseGitLsFiles() = %+v, expected %+v", entries, expected)
+	}
+	if len(malformed) != 1 || malformed[0].LineNumber != 4 {
+		t.Errorf("ParseGitLsFiles() malformed lines = %+v, expected line 4", malformed)
+	}
+}
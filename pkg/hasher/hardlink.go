@@ -0,0 +1,59 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// fileID identifies a file by its device and inode, the names of a file having several hard links sharing it.
+type fileID struct {
+	dev, ino uint64
+}
+
+// linkedFile is a file with several hard links, hashed once through the first of its names found.
+type linkedFile struct {
+	done   chan struct{} // closed once result is set
+	result Result
+}
+
+// hashLinksOnce returns hashOne hashing the files having several hard links only once, through the first of
+// their names, the other names getting the same Result with LinkOf set to this first name. The files that
+// could not be hashed through their first name are hashed again through the other ones.
+func hashLinksOnce(hashOne func(ctx context.Context, path string) Result) func(ctx context.Context, path string) Result {
+	var mu sync.Mutex
+	linked := make(map[fileID]*linkedFile)
+	return func(ctx context.Context, path string) Result {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return hashOne(ctx, path)
+		}
+		id, links, ok := inode(info)
+		if !ok || links < 2 {
+			return hashOne(ctx, path)
+		}
+		mu.Lock()
+		first, seen := linked[id]
+		if !seen {
+			first = &linkedFile{done: make(chan struct{})}
+			linked[id] = first
+		}
+		mu.Unlock()
+		if !seen {
+			first.result = hashOne(ctx, path)
+			close(first.done)
+			return first.result
+		}
+		select {
+		case <-first.done:
+		case <-ctx.Done():
+			return Result{Path: path, Err: ctx.Err()}
+		}
+		if first.result.Err != nil {
+			return hashOne(ctx, path)
+		}
+		result := first.result
+		result.Path, result.LinkOf, result.Cached = path, first.result.Path, false
+		return result
+	}
+}
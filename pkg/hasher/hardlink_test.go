@@ -0,0 +1,58 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestHashLinksOnce tests that the names of a file with several hard links get its hash, the file being read once.
+func TestHashLinksOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hard links are only detected on unix")
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("linked content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.txt", "c.txt"} {
+		if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, name)); err != nil {
+			t.Skipf("cannot create hard links: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "d.txt"), []byte("linked content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make(map[string]Result)
+	err := NewOptions(WithHardLinks(true), WithWorkers(3)).HashTree(context.Background(), root, Walker{}, func(r Result) {
+		results[filepath.Base(r.Path)] = r
+	})
+	if err != nil {
+		t.Fatalf("HashTree() returned %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("HashTree() returned %d results, expected 4", len(results))
+	}
+	read := 0
+	for name, r := range results {
+		if r.Err != nil || r.Hash != results["d.txt"].Hash {
+			t.Errorf("result of %s = %+v, expected the hash of d.txt", name, r)
+		}
+		if r.LinkOf == "" {
+			read++
+		} else if name == "d.txt" || filepath.Dir(r.LinkOf) != root {
+			t.Errorf("result of %s = %+v, expected a link to another name of a.txt", name, r)
+		}
+	}
+	if read != 2 {
+		t.Errorf("HashTree() read %d files, expected a.txt or one of its links and d.txt", read)
+	}
+
+	results = make(map[string]Result)
+	if err := HashTree(context.Background(), root, Walker{}, 2, func(r Result) { results[filepath.Base(r.Path)] = r }); err != nil || results["b.txt"].LinkOf != "" {
+		t.Errorf("HashTree() without HardLinks = %+v, %v, expected every file to be read", results, err)
+	}
+}
@@ -0,0 +1,210 @@
+package hasher
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HashdeepHeader is the first line of the files written by hashdeep and md5deep -z.
+const HashdeepHeader = "%%%% HASHDEEP-1.0"
+
+// HashdeepEntry is a file listed in a hashdeep file.
+type HashdeepEntry struct {
+	Size   int64
+	Hashes map[Algorithm]string // uppercase, for the supported algorithms of the file
+	Path   string
+}
+
+// IsHashdeep reports whether the content of r starts with the hashdeep header, without consuming it.
+func IsHashdeep(r *bufio.Reader) bool {
+	header, _ := r.Peek(len(HashdeepHeader))
+	return string(header) == HashdeepHeader
+}
+
+// WriteHashdeepHeader writes the header of a hashdeep file having a column for each algorithm,
+// with the directory and the command line it was invoked from, like hashdeep does.
+func WriteHashdeepHeader(w io.Writer, algorithms []Algorithm, invokedFrom, command string) error {
+	columns := []string{"size"}
+	for _, algorithm := range algorithms {
+		columns = append(columns, string(algorithm))
+	}
+	columns = append(columns, "filename")
+	_, err := fmt.Fprintf(w, "%s\n%%%%%%%% %s\n## Invoked from: %s\n## $ %s\n##\n", HashdeepHeader, strings.Join(columns, ","), invokedFrom, command)
+	return err
+}
+
+// FormatHashdeepLine returns the line of a file in a hashdeep file, the hashes being given
+// in the order of the header columns. hashdeep writes lowercase digests.
+func FormatHashdeepLine(size int64, hashes []string, path string) string {
+	var sb strings.Builder
+	sb.WriteString(strconv.FormatInt(size, 10))
+	for _, hash := range hashes {
+		sb.WriteByte(',')
+		sb.WriteString(strings.ToLower(hash))
+	}
+	sb.WriteByte(',')
+	sb.WriteString(path)
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// ParseHashdeep parses a hashdeep file and returns the algorithms of its columns supported by the package,
+// in the order of the columns, and its entries. Columns of other algorithms, like tiger or whirlpool,
+// are ignored. The header may be repeated, as when hashdeep files are concatenated, but must keep the
+// same columns. Lines that do not match the header are returned as malformed.
+func ParseHashdeep(reader io.Reader) ([]Algorithm, []HashdeepEntry, []MalformedLine, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var algorithms []Algorithm
+	var columns []string
+	var entries []HashdeepEntry
+	var malformed []MalformedLine
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case line == HashdeepHeader || line == "" || strings.HasPrefix(line, "##"):
+			continue
+		case strings.HasPrefix(line, "%%%% "):
+			spec := strings.Split(strings.TrimPrefix(line, "%%%% "), ",")
+			if len(spec) < 3 || spec[0] != "size" || spec[len(spec)-1] != "filename" {
+				return nil, nil, nil, fmt.Errorf("line %d: invalid hashdeep columns %q", lineNumber, line)
+			}
+			if columns != nil && strings.Join(columns, ",") != strings.Join(spec[1:len(spec)-1], ",") {
+				return nil, nil, nil, fmt.Errorf("line %d: the hashdeep columns change from %s", lineNumber, strings.Join(columns, ","))
+			}
+			columns = spec[1 : len(spec)-1]
+			algorithms = nil
+			for _, name := range columns {
+				if algorithm, err := ParseAlgorithm(name); err == nil {
+					algorithms = append(algorithms, algorithm)
+				}
+			}
+			if len(algorithms) == 0 {
+				return nil, nil, nil, fmt.Errorf("line %d: no supported algorithm in the hashdeep columns %s", lineNumber, strings.Join(columns, ","))
+			}
+			continue
+		case columns == nil:
+			return nil, nil, nil, fmt.Errorf("line %d: missing hashdeep header", lineNumber)
+		}
+		// the file name is last and may contain commas
+		fields := strings.SplitN(line, ",", len(columns)+2)
+		if len(fields) != len(columns)+2 || fields[len(fields)-1] == "" {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil || size < 0 {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+			continue
+		}
+		entry := HashdeepEntry{Size: size, Hashes: make(map[Algorithm]string, len(algorithms)), Path: fields[len(fields)-1]}
+		valid := true
+		for i, name := range columns {
+			if algorithm, err := ParseAlgorithm(name); err == nil {
+				if !isHexString(fields[i+1]) {
+					valid = false
+				}
+				entry.Hashes[algorithm] = strings.ToUpper(fields[i+1])
+			}
+		}
+		if !valid {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return algorithms, entries, malformed, err
+	}
+	if columns == nil {
+		return nil, nil, nil, fmt.Errorf("missing hashdeep header")
+	}
+	return algorithms, entries, malformed, nil
+}
+
+// AuditMove is a file whose content is known under another path.
+type AuditMove struct {
+	Path      string
+	KnownPath string
+}
+
+// Audit is the outcome of AuditHashdeep, with the audit semantics of hashdeep -a. Paths are sorted.
+type Audit struct {
+	Matched []string    // files having the size and hashes of a known file with the same path
+	Moved   []AuditMove // files having the size and hashes of known files with other paths
+	New     []string    // files whose content is not known
+	Missing []string    // known files whose content was not found under any path
+	Errors  []Result    // files that could not be read
+}
+
+// Passed reports whether every file was matched and every known file found, as hashdeep requires.
+func (a Audit) Passed() bool {
+	return len(a.Moved) == 0 && len(a.New) == 0 && len(a.Missing) == 0 && len(a.Errors) == 0
+}
+
+// AuditHashdeep compares the results of hashing a tree with algorithms, the ones returned by ParseHashdeep,
+// against the known entries. A file is identified by its size and all its hashes, and paths are compared
+// after filepath.Clean, so the tree must be hashed from the same directory and arguments as the known set.
+func AuditHashdeep(known []HashdeepEntry, algorithms []Algorithm, results []Result) Audit {
+	contentKey := func(size int64, hash func(Algorithm) string) string {
+		var sb strings.Builder
+		sb.WriteString(strconv.FormatInt(size, 10))
+		for _, algorithm := range algorithms {
+			sb.WriteByte(',')
+			sb.WriteString(strings.ToUpper(hash(algorithm)))
+		}
+		return sb.String()
+	}
+	byContent := make(map[string][]string, len(known)) // known paths by content
+	byPath := make(map[string]string, len(known))      // content by known path
+	for _, e := range known {
+		key := contentKey(e.Size, func(a Algorithm) string { return e.Hashes[a] })
+		path := filepath.Clean(e.Path)
+		byContent[key] = append(byContent[key], path)
+		byPath[path] = key
+	}
+	var a Audit
+	used := make(map[string]bool) // contents found in the tree
+	for _, r := range results {
+		if r.Err != nil {
+			a.Errors = append(a.Errors, r)
+			continue
+		}
+		key := contentKey(r.Size, func(algorithm Algorithm) string {
+			if r.Hashes != nil {
+				return r.Hashes[algorithm]
+			}
+			return r.Hash
+		})
+		path := filepath.Clean(r.Path)
+		paths, isKnown := byContent[key]
+		switch {
+		case !isKnown:
+			a.New = append(a.New, r.Path)
+			continue
+		case byPath[path] == key:
+			a.Matched = append(a.Matched, r.Path)
+		default:
+			a.Moved = append(a.Moved, AuditMove{Path: r.Path, KnownPath: paths[0]})
+		}
+		used[key] = true
+	}
+	for path, key := range byPath {
+		if !used[key] {
+			a.Missing = append(a.Missing, path)
+		}
+	}
+	sort.Strings(a.Matched)
+	sort.Slice(a.Moved, func(i, j int) bool { return a.Moved[i].Path < a.Moved[j].Path })
+	sort.Strings(a.New)
+	sort.Strings(a.Missing)
+	sort.Slice(a.Errors, func(i, j int) bool { return a.Errors[i].Path < a.Errors[j].Path })
+	return a
+}
@@ -0,0 +1,80 @@
+package hasher
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	abcMD5    = "900150983CD24FB0D6963F7D28E17F72"
+	abcSHA256 = "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+)
+
+// TestHashdeepRoundTrip tests that a hashdeep file written by the package is parsed back.
+func TestHashdeepRoundTrip(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteHashdeepHeader(&sb, []Algorithm{MD5, SHA256}, "/home/user", "goDirHasher -hashdeep data"); err != nil {
+		t.Fatalf("WriteHashdeepHeader() returned an error: %v", err)
+	}
+	sb.WriteString(FormatHashdeepLine(3, []string{abcMD5, abcSHA256}, "data/a,b.txt"))
+	content := sb.String()
+	if !strings.HasPrefix(content, "%%%% HASHDEEP-1.0\n%%%% size,md5,sha256,filename\n## Invoked from: /home/user\n") ||
+		!strings.HasSuffix(content, "\n3,"+strings.ToLower(abcMD5)+","+strings.ToLower(abcSHA256)+",data/a,b.txt\n") {
+		t.Fatalf("unexpected hashdeep file:\n%s", content)
+	}
+	if !IsHashdeep(bufio.NewReader(strings.NewReader(content))) {
+		t.Error("IsHashdeep() does not recognize the header")
+	}
+
+	algorithms, entries, malformed, err := ParseHashdeep(strings.NewReader(content + "not,a line\r\n"))
+	if err != nil {
+		t.Fatalf("ParseHashdeep() returned an error: %v", err)
+	}
+	expected := []HashdeepEntry{{Size: 3, Hashes: map[Algorithm]string{MD5: abcMD5, SHA256: abcSHA256}, Path: "data/a,b.txt"}}
+	if !reflect.DeepEqual(algorithms, []Algorithm{MD5, SHA256}) || !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ParseHashdeep() = %v, %+v, expected %v, %+v", algorithms, entries, []Algorithm{MD5, SHA256}, expected)
+	}
+	if len(malformed) != 1 || malformed[0].LineNumber != 7 {
+		t.Errorf("ParseHashdeep() malformed lines = %+v, expected line 7", malformed)
+	}
+	if _, _, _, err := ParseHashdeep(strings.NewReader("3,abc,file\n")); err == nil {
+		t.Error("ParseHashdeep() accepted a file without header")
+	}
+}
+
+// TestAuditHashdeep tests the matched, moved, new and missing files of an audit.
+func TestAuditHashdeep(t *testing.T) {
+	hashes := func(c byte) map[Algorithm]string {
+		return map[Algorithm]string{MD5: strings.Repeat(string(c), 32), SHA256: strings.Repeat(string(c), 64)}
+	}
+	known := []HashdeepEntry{
+		{Size: 1, Hashes: hashes('A'), Path: "data/same.txt"},
+		{Size: 2, Hashes: hashes('B'), Path: "data/old-name.txt"},
+		{Size: 3, Hashes: hashes('C'), Path: "data/deleted.txt"},
+		{Size: 4, Hashes: hashes('D'), Path: "./data/changed.txt"},
+	}
+	results := []Result{
+		{Path: "data/same.txt", Size: 1, Hash: strings.Repeat("A", 32), Hashes: hashes('A')},
+		{Path: "data/new-name.txt", Size: 2, Hash: strings.Repeat("B", 32), Hashes: hashes('B')},
+		{Path: "data/changed.txt", Size: 4, Hash: strings.Repeat("E", 32), Hashes: hashes('E')},
+		{Path: "data/added.txt", Size: 4, Hash: strings.Repeat("D", 32), Hashes: map[Algorithm]string{MD5: strings.Repeat("D", 32), SHA256: strings.Repeat("F", 64)}},
+	}
+	a := AuditHashdeep(known, []Algorithm{MD5, SHA256}, results)
+	expected := Audit{
+		Matched: []string{"data/same.txt"},
+		Moved:   []AuditMove{{Path: "data/new-name.txt", KnownPath: "data/old-name.txt"}},
+		New:     []string{"data/added.txt", "data/changed.txt"},
+		Missing: []string{"data/changed.txt", "data/deleted.txt"},
+	}
+	if !reflect.DeepEqual(a, expected) {
+		t.Errorf("AuditHashdeep() = %+v, expected %+v", a, expected)
+	}
+	if a.Passed() {
+		t.Error("Passed() is true for an audit with moved, new and missing files")
+	}
+	if !AuditHashdeep(known[:1], []Algorithm{MD5, SHA256}, results[:1]).Passed() {
+		t.Error("Passed() is false for an audit where every file matched")
+	}
+}
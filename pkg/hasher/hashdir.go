@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// DefaultWorkers is the smallest number of files read concurrently when Options.Workers is not set,
+// see DefaultWorkerCount.
+const DefaultWorkers = 15
+
+// HashDir walks root and hashes every file found as described by opts,
+// using a pool of opts.Workers goroutines.
+// It returns one Result per file, sorted by path. Files or directories that could not be
+// read are reported as Results with Err set, and all these errors are joined in the returned
+// error, so a nil error means every file was hashed successfully. When ctx is cancelled,
+// the results gathered so far are returned with ctx.Err().
+func HashDir(ctx context.Context, root string, opts Options) ([]Result, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return collectResults(ctx, func(onError func(path string, err error), fn func(Result)) error {
+		return opts.HashTree(ctx, root, opts.Walker(onError), fn)
+	})
+}
+
+// collectResults gathers the results of hashTree, with the paths reported to onError, as described in HashDir.
+func collectResults(ctx context.Context, hashTree func(onError func(path string, err error), fn func(Result)) error) ([]Result, error) {
+	var results []Result
+	var walkErrors []Result
+	err := hashTree(func(path string, err error) {
+		// called from the walking goroutine, merged with the results once done
+		walkErrors = append(walkErrors, Result{Path: path, Err: err})
+	}, func(r Result) {
+		if r.Err != nil && ctx.Err() != nil && errors.Is(r.Err, ctx.Err()) {
+			return // interrupted while hashing this file
+		}
+		results = append(results, r)
+	})
+	results = append(results, walkErrors...)
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	if err != nil {
+		return results, err
+	}
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestHashDir tests that HashDir returns sorted results and collects the errors.
+func TestHashDir(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{"z.txt", "a.txt", "dir/m.txt", "dir/skip.log"} {
+		full := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %q: %v", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(rel), 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %v", rel, err)
+		}
+	}
+
+	results, err := HashDir(context.Background(), root, Options{Workers: 2, Filter: PathFilter{Exclude: []string{"*.log"}}})
+	if err != nil {
+		t.Fatalf("HashDir() returned an error: %v", err)
+	}
+	expected := []string{"a.txt", "dir/m.txt", "z.txt"}
+	if len(results) != len(expected) {
+		t.Fatalf("HashDir() returned %d results, expected %d", len(results), len(expected))
+	}
+	for i, r := range results {
+		if r.Path != filepath.Join(root, filepath.FromSlash(expected[i])) {
+			t.Errorf("Result %d: path %q, expected %q", i, r.Path, expected[i])
+		}
+		if r.Err != nil || len(r.Hash) != 64 || r.Size == 0 {
+			t.Errorf("Result %d: unexpected hash %q, size %d, error %v", i, r.Hash, r.Size, r.Err)
+		}
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("Permission errors cannot be tested as root")
+	}
+	if err := os.Chmod(filepath.Join(root, "a.txt"), 0o000); err != nil {
+		t.Fatalf("Failed to change file mode: %v", err)
+	}
+	defer os.Chmod(filepath.Join(root, "a.txt"), 0o644)
+	results, err = HashDir(context.Background(), root, Options{})
+	if err == nil {
+		t.Error("HashDir() did not return an error for an unreadable file")
+	}
+	if len(results) != 4 || results[0].Err == nil {
+		t.Errorf("HashDir() did not report the unreadable file in its results: %+v", results)
+	}
+}
@@ -2,36 +2,32 @@ package hasher
 
 import (
 	"bufio"
+	"context"
 	"crypto/md5" // Keeping MD5 for now, but focus is on SHA256
-	"crypto/sha256"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"io/fs"
+	"iter"
 	"log"
 	"os"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
 )
 
 // FileEntry represents a single line with a hash and file path.
 type FileEntry struct {
 	Hash     string
 	FilePath string
-}
-
-// sha256HashPool holds reusable SHA-256 hash instances.
-var sha256HashPool = sync.Pool{
-	New: func() any {
-		// On pool miss, allocate a fresh hasher.
-		return sha256.New()
-	},
-}
-
-// Buffer pool for I/O operations
-var bufferPool = sync.Pool{
-	New: func() any {
-		return make([]byte, 64*1024) // 64KB buffer, adjustable
-	},
+	Size     int64 // the size of the file recorded in the manifest when HasSize is set, see SizedHash
+	HasSize  bool
+	// ModTime and Mode are the modification time and the permissions of the file recorded in the
+	// manifest when HasMetadata is set, see MetadataHash
+	ModTime     time.Time
+	Mode        fs.FileMode
+	HasMetadata bool
 }
 
 // GetMD5 returns md5 hash of a file
@@ -52,76 +48,448 @@ func GetMD5(path string) (string, error) {
 // GetSHA256 returns sha256 hash of a file at the given path.
 // It uses a sync.Pool for hashers and a buffer pool for efficiency.
 func GetSHA256(path string) (string, error) {
+	return GetSHA256Ctx(context.Background(), path)
+}
+
+// GetSHA256Ctx works like GetSHA256 but stops reading the file and returns ctx.Err()
+// as soon as ctx is cancelled, so long computations on huge files can be aborted.
+func GetSHA256Ctx(ctx context.Context, path string) (string, error) {
+	result := HashFile(ctx, path)
+	return result.Hash, result.Err
+}
+
+// HashFile returns the sha256 hash of the file at path with the number of bytes read,
+// stopping as soon as ctx is cancelled. Use Options.HashFile for other algorithms.
+func HashFile(ctx context.Context, path string) Result {
+	return Options{}.HashFile(ctx, path)
+}
+
+// GetHMAC returns the HMAC of the file at path computed with key and the hash function algo,
+// in uppercase hexadecimal. Unlike a plain digest, it cannot be forged without the key.
+func GetHMAC(path string, key []byte, algo Algorithm) (string, error) {
+	result := NewOptions(WithAlgorithm(algo), WithHMACKey(key)).HashFile(context.Background(), path)
+	return result.Hash, result.Err
+}
+
+// hashFile does the actual work for HashFile, returning one hash per algorithm of opts.algorithms().
+func hashFile(ctx context.Context, path string, opts Options) ([]string, int64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	if opts.DirectIO {
+		// Fall back to reading the file through the page cache when O_DIRECT is not supported
+		if f, err := openDirect(path); err == nil {
+			defer f.Close()
+			return hashDirect(ctx, f, path, opts)
+		}
+	}
 	f, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return nil, 0, err
 	}
 	defer f.Close()
+	var r io.Reader = f
+	if opts.Fadvise {
+		defer adviseFile(f)()
+		r = &droppingReader{f: f}
+	}
+	if opts.Sparse {
+		if info, err := f.Stat(); err == nil && isSparse(info) {
+			r = &sparseReader{f: f, size: info.Size()}
+		}
+	}
+	// The errors of the reads tell where they failed, like on bad sectors
+	r = &offsetReader{path: path, r: r}
+	if (!opts.GitBlob && opts.MmapThreshold < 1 && opts.TreeChunkSize < 1 && opts.QuickSize < 1) || opts.ownAlgorithm() > 0 {
+		return hashReader(ctx, r, opts)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if opts.QuickSize > 0 {
+		return hashQuickFile(ctx, &offsetReaderAt{path: path, r: f}, info.Size(), opts)
+	}
+	if opts.TreeChunkSize > 0 && info.Size() > opts.TreeChunkSize {
+		return hashTreeFile(ctx, &offsetReaderAt{path: path, r: f}, info.Size(), opts)
+	}
+	if opts.MmapThreshold > 0 && info.Size() >= opts.MmapThreshold {
+		// Fall back to reading the file when it cannot be mapped, like on some network filesystems
+		if data, unmap, err := mmapFile(f, info.Size()); err == nil {
+			defer unmap()
+			return hashBytes(ctx, data, path, opts)
+		}
+	}
+	return hashContent(ctx, r, info.Size(), opts)
+}
 
-	// Retrieve a hasher from the pool (or New() if empty)
-	shaWriter := sha256HashPool.Get().(hash.Hash)
-	// Reset its internal state before reuse
-	shaWriter.Reset()
-	// Return it to the pool when done
-	defer sha256HashPool.Put(shaWriter)
+// hashReader computes the hashes of everything read from r, one per algorithm of opts.algorithms(),
+// reading the content only once. It uses a sync.Pool for hashers and a buffer pool for efficiency.
+func hashReader(ctx context.Context, r io.Reader, opts Options) ([]string, int64, error) {
+	if opts.TreeChunkSize > 0 {
+		return hashTreeReader(ctx, r, opts)
+	}
+	if opts.QuickSize > 0 {
+		return hashQuickReader(ctx, r, opts)
+	}
+	if opts.GlacierTree {
+		return hashGlacierReader(ctx, r, opts)
+	}
+	if opts.ETagPartSize > 0 {
+		return hashETagReader(ctx, r, opts)
+	}
+	if opts.BitTorrentV2 {
+		return hashBTv2Reader(ctx, r, opts)
+	}
+	w, sums, release, err := newHashWriter(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+
+	// Get buffer from the pool of its size
+	buf := getBuffer(opts.BufferSize)
+	defer putBuffer(buf) // Return buffer to pool
 
 	// Wrap in a buffered reader to reduce syscalls
-	br := bufio.NewReader(f)
-	// Get buffer from pool
-	buf := bufferPool.Get().([]byte)
-	defer bufferPool.Put(buf) // Return buffer to pool
+	br := bufio.NewReader(r)
+	// Copy content to the hashers
+	n, err := io.CopyBuffer(cpuLimited(ctx, w), &ctxReader{ctx: ctx, r: throttled(ctx, br)}, buf)
+	if err != nil {
+		return nil, n, err
+	}
+	return sums(), n, nil
+}
 
-	// Copy file content to the hasher
-	if _, err := io.CopyBuffer(shaWriter, br, buf); err != nil {
-		return "", err
+// newHashWriter returns the writer feeding the hash of every algorithm of opts, the function
+// returning their sums formatted as hexadecimal strings and the one to call once done.
+func newHashWriter(opts Options) (io.Writer, func() []string, func(), error) {
+	algorithms := opts.algorithms()
+	writers := make([]io.Writer, len(algorithms))
+	hashWriters := make([]hash.Hash, len(algorithms))
+	releases := make([]func(), 0, len(algorithms))
+	release := func() {
+		// Return the hashers to the pool
+		for _, r := range releases {
+			r()
+		}
+	}
+	for i, algorithm := range algorithms {
+		// Retrieve a hasher from the pool (or New() if empty), or a new HMAC when a key is set
+		hashWriter, r, err := newHash(algorithm, opts.HMACKey)
+		if err != nil {
+			release()
+			return nil, nil, nil, err
+		}
+		releases = append(releases, r)
+		hashWriters[i], writers[i] = hashWriter, hashWriter
+	}
+	w := writers[0]
+	if len(writers) > 1 {
+		w = io.MultiWriter(writers...)
 	}
+	sums := func() []string {
+		// Calculate the final hash sums and format them as hexadecimal strings
+		hashes := make([]string, len(hashWriters))
+		for i, hashWriter := range hashWriters {
+			if opts.LowerCase {
+				hashes[i] = fmt.Sprintf("%x", hashWriter.Sum(nil))
+			} else {
+				hashes[i] = fmt.Sprintf("%X", hashWriter.Sum(nil))
+			}
+		}
+		return hashes
+	}
+	return w, sums, release, nil
+}
+
+// ctxReader is an io.Reader returning the context error once it is cancelled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// MalformedLine describes a line of a hash file that does not follow the "hash  filepath" format.
+// It is the error yielded by ParseHashFileIter for such lines.
+type MalformedLine struct {
+	LineNumber int
+	Text       string
+}
 
-	// Calculate the final hash sum
-	sum := shaWriter.Sum(nil)
-	// Format the hash as a hexadecimal string
-	return fmt.Sprintf("%X", sum), nil
+func (m *MalformedLine) Error() string {
+	return fmt.Sprintf("improperly formatted hash line %d: %q", m.LineNumber, m.Text)
 }
 
 // ParseHashFile reads a file line by line, expecting each line to be in
 // the format "hash filepath". It returns a slice of FileEntry structs.
 // It takes an io.Reader for flexibility (can read from file, stdin, etc.).
+// Lines with an incorrect format are logged and skipped.
+// The files written on Windows are read too: the byte order mark starting the file and the carriage
+// returns ending the lines are ignored, and the "hash *filepath" lines of binary mode are accepted.
 func ParseHashFile(reader io.Reader) ([]FileEntry, error) {
+	entries, malformed, err := ParseHashFileDetailed(reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range malformed {
+		// Log a warning for lines that don't match the expected format
+		log.Printf("Warning: Skipping line %d due to incorrect format: %s\n", m.LineNumber, m.Text)
+	}
+	return entries, nil
+}
+
+// ParseHashFileDetailed works like ParseHashFile but, instead of logging them,
+// returns the lines that could not be parsed so the caller can decide how to report them.
+// File names escaped like GNU coreutils do, on lines starting with a backslash, are unescaped.
+func ParseHashFileDetailed(reader io.Reader) ([]FileEntry, []MalformedLine, error) {
+	return parseHashFile(reader, parseStrict)
+}
+
+// ParseHashFileZero works like ParseHashFileDetailed for the NUL terminated lines written
+// by sha256sum -z, whose file names are not escaped and may contain newlines.
+func ParseHashFileZero(reader io.Reader) ([]FileEntry, []MalformedLine, error) {
+	return parseHashFile(reader, parseZero)
+}
+
+// ParseHashFileLenient works like ParseHashFileDetailed but also accepts the lines of the tools separating
+// the hash from the file name with a single space or with tabs, "hash filepath", optionally followed by
+// the binary mode star. The lines in the sha256sum format are read first as such, so a file name starting
+// with a star or a space is kept when it follows two spaces.
+func ParseHashFileLenient(reader io.Reader) ([]FileEntry, []MalformedLine, error) {
+	return parseHashFile(reader, parseLenient)
+}
+
+// ScanHashFile works like ParseHashFileDetailed but calls fn with each entry as soon as it is read,
+// instead of returning them all, so huge hash files can be processed without holding them in memory.
+// It stops at the first error returned by fn and returns it.
+func ScanHashFile(reader io.Reader, fn func(FileEntry) error) ([]MalformedLine, error) {
+	return collectMalformed(reader, parseStrict, fn)
+}
+
+// ScanHashFileZero works like ScanHashFile for the NUL terminated lines read by ParseHashFileZero.
+func ScanHashFileZero(reader io.Reader, fn func(FileEntry) error) ([]MalformedLine, error) {
+	return collectMalformed(reader, parseZero, fn)
+}
+
+// ScanHashFileLenient works like ScanHashFile for the lines read by ParseHashFileLenient.
+func ScanHashFileLenient(reader io.Reader, fn func(FileEntry) error) ([]MalformedLine, error) {
+	return collectMalformed(reader, parseLenient, fn)
+}
+
+// parseMode selects how the lines of a hash file are read.
+type parseMode int
+
+const (
+	parseStrict  parseMode = iota // lines in the sha256sum format
+	parseZero                     // NUL terminated lines, whose file names are not escaped
+	parseLenient                  // lines in the sha256sum format or separated by any spaces and tabs
+)
+
+// errStopIteration stops the scan of a hash file when the loop over ParseHashFileIter is left.
+var errStopIteration = errors.New("iteration stopped")
+
+// ParseHashFileIter returns an iterator over the entries of the hash file read from reader, in the format
+// of ParseHashFileDetailed, reading a line only when the previous entry has been processed:
+//
+//	for entry, err := range hasher.ParseHashFileIter(r) {
+//		var malformed *hasher.MalformedLine
+//		if errors.As(err, &malformed) {
+//			continue // skip the lines that are not "hash  filepath"
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Each malformed line is yielded as a *MalformedLine error and the iteration goes on, any other error
+// ends it.
+func ParseHashFileIter(reader io.Reader) iter.Seq2[FileEntry, error] {
+	return func(yield func(FileEntry, error) bool) {
+		err := scanHashFile(reader, parseStrict, func(entry FileEntry) error {
+			if !yield(entry, nil) {
+				return errStopIteration
+			}
+			return nil
+		}, func(m MalformedLine) error {
+			if !yield(FileEntry{}, &m) {
+				return errStopIteration
+			}
+			return nil
+		})
+		if err != nil && err != errStopIteration {
+			yield(FileEntry{}, err)
+		}
+	}
+}
+
+// parseHashFile does the actual work of ParseHashFileDetailed and ParseHashFileZero.
+func parseHashFile(reader io.Reader, mode parseMode) ([]FileEntry, []MalformedLine, error) {
 	var entries []FileEntry
+	malformed, err := collectMalformed(reader, mode, func(entry FileEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, malformed, nil
+}
+
+// collectMalformed scans the hash file read from reader, calling fn with each entry and returning the malformed lines.
+func collectMalformed(reader io.Reader, mode parseMode, fn func(FileEntry) error) ([]MalformedLine, error) {
+	var malformed []MalformedLine
+	err := scanHashFile(reader, mode, fn, func(m MalformedLine) error {
+		malformed = append(malformed, m)
+		return nil
+	})
+	return malformed, err
+}
+
+// utf8BOM is the byte order mark some Windows programs write at the start of UTF-8 files.
+const utf8BOM = "\uFEFF"
+
+// scanHashFile does the actual work of ScanHashFile, ScanHashFileZero and ParseHashFileIter, calling fn
+// with each entry and onMalformed with each malformed line, until one of them returns an error.
+func scanHashFile(reader io.Reader, mode parseMode, fn func(FileEntry) error, onMalformed func(MalformedLine) error) error {
 	scanner := bufio.NewScanner(reader)
+	zero := mode == parseZero
 
-	// Set the scanner to split by lines
-	scanner.Split(bufio.ScanLines)
+	// Set the scanner to split by lines, or by NUL bytes
+	if zero {
+		scanner.Split(ScanNUL)
+	} else {
+		scanner.Split(bufio.ScanLines)
+	}
 
 	lineNumber := 0
 	for scanner.Scan() {
 		lineNumber++
 		line := scanner.Text()
-		// Skip empty lines and lines starting with # (comments)
-		line = strings.TrimSpace(line)
-		if len(line) == 0 || strings.HasPrefix(line, "#") {
+		if lineNumber == 1 {
+			// Notepad and PowerShell start the files they write in UTF-8 with a byte order mark
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+		// Skip empty lines and lines starting with # (comments), the file name is kept as is
+		// after the separator since it may start or end with spaces
+		line = strings.TrimLeft(line, " \t")
+		if !zero {
+			line = strings.TrimRight(line, "\r")
+		}
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "#") {
 			continue
 		}
+		escaped := !zero && strings.HasPrefix(line, "\\")
+		if escaped {
+			line = line[1:]
+		}
 
-		// Split the line into hash and file path by the first two spaces (standard sha256sum format)
+		// Split the line into hash and file path by the first two spaces (standard sha256sum format),
+		// the hash being followed by the metadata of the file when it was recorded, or by a space
+		// and a star for the files hashed in binary mode, like the Windows builds of sha256sum write them
 		parts := strings.SplitN(line, "  ", 2)
-		if len(parts) != 2 {
-			// Log a warning and skip lines that don't match the expected format
-			log.Printf("Warning: Skipping line %d due to incorrect format: %s\n", lineNumber, line)
+		if star := strings.Index(line, " *"); star >= 0 && (len(parts) != 2 || star < len(parts[0])) {
+			parts = []string{line[:star], line[star+2:]}
+		}
+		entry, ok := parseHashField(parts[0])
+		ok = ok && len(parts) == 2 && len(strings.TrimSpace(parts[1])) > 0
+		var filePath string
+		if ok {
+			filePath = parts[1]
+		} else if mode == parseLenient {
+			var hash string
+			hash, filePath, ok = splitLenient(line)
+			entry = FileEntry{Hash: strings.ToUpper(hash)}
+		}
+		if !ok {
+			// Remember lines that don't match the expected format and skip them
+			if err := onMalformed(MalformedLine{LineNumber: lineNumber, Text: line}); err != nil {
+				return err
+			}
 			continue
 		}
+		if escaped {
+			var ok bool
+			if filePath, ok = unescapePath(filePath); !ok {
+				if err := onMalformed(MalformedLine{LineNumber: lineNumber, Text: line}); err != nil {
+					return err
+				}
+				continue
+			}
+		}
 
-		// Create a FileEntry struct and append it to the slice
-		// Trim spaces from hash and filepath parts
-		entries = append(entries, FileEntry{
-			Hash:     strings.ToUpper(strings.TrimSpace(parts[0])), // Ensure hash is uppercase
-			FilePath: strings.TrimSpace(parts[1]),
-		})
+		// Complete the FileEntry struct and hand it over
+		entry.FilePath = filePath
+		if err := fn(entry); err != nil {
+			return err
+		}
 	}
 
 	// Check for errors during scanning
 	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading lines: %w", err)
+		return fmt.Errorf("error reading lines: %w", err)
 	}
 
-	return entries, nil
+	return nil
+}
+
+// splitLenient splits the line of ParseHashFileLenient into the hash and the file name, separated by
+// spaces or tabs and an optional binary mode star.
+func splitLenient(line string) (hash, filePath string, ok bool) {
+	end := strings.IndexAny(line, " \t")
+	if end < 0 || !isHexString(line[:end]) {
+		return "", "", false
+	}
+	filePath = strings.TrimPrefix(strings.TrimLeft(line[end:], " \t"), "*")
+	return line[:end], filePath, len(strings.TrimSpace(filePath)) > 0
+}
+
+// parseHashField parses the hash field of a manifest line: the hash, optionally followed by the size
+// of the file written by SizedHash, or by its size, modification time and mode written by MetadataHash.
+func parseHashField(field string) (FileEntry, bool) {
+	fields := strings.Split(strings.TrimSpace(field), " ")
+	hash := fields[0]
+	if !(isHexString(hash) || isTreeHash(hash) || isQuickHash(hash) || isAWSHash(hash) || IsBTv2Hash(hash)) || len(fields) == 3 || len(fields) > 4 {
+		return FileEntry{}, false
+	}
+	entry := FileEntry{Hash: strings.ToUpper(hash)} // Ensure hash is uppercase
+	if len(fields) > 1 {
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || size < 0 {
+			return FileEntry{}, false
+		}
+		entry.Size, entry.HasSize = size, true
+	}
+	if len(fields) == 4 {
+		modTime, err := parseTimestamp(fields[2])
+		if err != nil {
+			return FileEntry{}, false
+		}
+		mode, err := strconv.ParseUint(fields[3], 8, 32)
+		if err != nil || mode > 0o7777 {
+			return FileEntry{}, false
+		}
+		entry.ModTime, entry.Mode, entry.HasMetadata = modTime, fileMode(uint32(mode)), true
+	}
+	return entry, true
+}
+
+// isHexString reports whether s is a non-empty string of hexadecimal digits.
+func isHexString(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
 }
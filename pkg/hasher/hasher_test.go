@@ -1,8 +1,10 @@
 package hasher
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -190,3 +192,148 @@ func TestGetSHA256AndParseHashFileIntegration(t *testing.T) {
 		t.Errorf("Parsed file path %q does not match dummy file path %q", parsedEntry.FilePath, dummyFilePath)
 	}
 }
+
+// TestParseHashFileDetailed tests that malformed lines are reported with their line number.
+func TestParseHashFileDetailed(t *testing.T) {
+	input := `# comment
+ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789  file1.txt
+not a hash line
+XYZ  file2.txt
+FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210  file3.txt
+`
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseHashFileDetailed() returned an error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("ParseHashFileDetailed() returned %d entries, expected 2", len(entries))
+	}
+	if len(malformed) != 2 {
+		t.Fatalf("ParseHashFileDetailed() returned %d malformed lines, expected 2", len(malformed))
+	}
+	if malformed[0].LineNumber != 3 || malformed[1].LineNumber != 4 {
+		t.Errorf("Malformed line numbers are %d and %d, expected 3 and 4", malformed[0].LineNumber, malformed[1].LineNumber)
+	}
+}
+
+// TestParseHashFileWindows tests the byte order mark, the CRLF line endings and the binary mode marker of Windows manifests.
+func TestParseHashFileWindows(t *testing.T) {
+	input := "\uFEFFABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789 *file1.txt\r\n" +
+		"FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210  *star.txt\r\n" +
+		"FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210 *two  spaces.txt\r\n"
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader(input))
+	if err != nil || len(malformed) != 0 {
+		t.Fatalf("ParseHashFileDetailed() returned %v and the malformed lines %v", err, malformed)
+	}
+	want := []string{"file1.txt", "*star.txt", "two  spaces.txt"}
+	if len(entries) != len(want) {
+		t.Fatalf("ParseHashFileDetailed() returned %d entries, expected %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.FilePath != want[i] || len(entry.Hash) != 64 {
+			t.Errorf("entry %d = %+v, expected the file %q", i, entry, want[i])
+		}
+	}
+}
+
+// TestParseHashFileLenient tests that single spaces and tabs separate the hash from the file name, the lines
+// in the sha256sum format being read as such.
+func TestParseHashFileLenient(t *testing.T) {
+	input := "ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789 one space.txt\n" +
+		"ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789\t\t*tab.bin\n" +
+		"abcdef0123456789abcdef0123456789abcdef0123456789abcdef0123456789  *star.txt\n" +
+		"not-a-hash file.txt\n" +
+		"ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789   \n"
+	entries, malformed, err := ParseHashFileLenient(strings.NewReader(input))
+	if err != nil || len(malformed) != 2 {
+		t.Fatalf("ParseHashFileLenient() returned %v and the malformed lines %v, expected 2", err, malformed)
+	}
+	want := []string{"one space.txt", "tab.bin", "*star.txt"}
+	if len(entries) != len(want) {
+		t.Fatalf("ParseHashFileLenient() returned %d entries, expected %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.FilePath != want[i] || entry.Hash != "ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789" {
+			t.Errorf("entry %d = %+v, expected the file %q", i, entry, want[i])
+		}
+	}
+	if _, malformed, _ := ParseHashFileDetailed(strings.NewReader(input)); len(malformed) != 4 {
+		t.Errorf("ParseHashFileDetailed() returned %d malformed lines, expected 4", len(malformed))
+	}
+}
+
+// TestScanHashFile tests that the entries are handed over one at a time and that an error of the callback stops the scan.
+func TestScanHashFile(t *testing.T) {
+	input := `ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789  file1.txt
+not a hash line
+FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210  file2.txt
+`
+	var paths []string
+	malformed, err := ScanHashFile(strings.NewReader(input), func(entry FileEntry) error {
+		paths = append(paths, entry.FilePath)
+		return nil
+	})
+	if err != nil || len(malformed) != 1 || strings.Join(paths, ",") != "file1.txt,file2.txt" {
+		t.Errorf("ScanHashFile() = %v, %v with %v, expected file1.txt and file2.txt and 1 malformed line", malformed, err, paths)
+	}
+	stop := errors.New("stop")
+	calls := 0
+	_, err = ScanHashFile(strings.NewReader(input), func(FileEntry) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ScanHashFile() = %v after %d calls, expected the error of the first call", err, calls)
+	}
+}
+
+// TestParseHashFileIter tests that malformed lines are yielded as errors and that breaking out of the loop stops the scan.
+func TestParseHashFileIter(t *testing.T) {
+	input := `ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789  file1.txt
+not a hash line
+FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210FEDCBA9876543210  file2.txt
+`
+	var paths []string
+	var lines []int
+	for entry, err := range ParseHashFileIter(strings.NewReader(input)) {
+		var malformed *MalformedLine
+		if errors.As(err, &malformed) {
+			lines = append(lines, malformed.LineNumber)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseHashFileIter() yielded an error: %v", err)
+		}
+		paths = append(paths, entry.FilePath)
+	}
+	if strings.Join(paths, ",") != "file1.txt,file2.txt" || len(lines) != 1 || lines[0] != 2 {
+		t.Errorf("ParseHashFileIter() yielded %v and malformed lines %v, expected file1.txt, file2.txt and line 2", paths, lines)
+	}
+	count := 0
+	for range ParseHashFileIter(strings.NewReader(input)) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("ParseHashFileIter() yielded %d entries after a break, expected 1", count)
+	}
+}
+
+// TestGetHMAC tests the keyed hashes against a known HMAC-SHA256 value.
+func TestGetHMAC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fox.txt")
+	if err := os.WriteFile(path, []byte("The quick brown fox jumps over the lazy dog"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	got, err := GetHMAC(path, []byte("key"), SHA256)
+	if err != nil {
+		t.Fatalf("GetHMAC() returned an error: %v", err)
+	}
+	if expected := "F7BC83F430538424B13298E6AA6FB143EF4D59A14946175997479DBC2D1A3CD8"; got != expected {
+		t.Errorf("GetHMAC() = %q, expected %q", got, expected)
+	}
+	plain, err := GetSHA256(path)
+	if err != nil || plain == got {
+		t.Errorf("GetSHA256() = %q, %v, expected a different plain digest", plain, err)
+	}
+}
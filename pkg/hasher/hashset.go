@@ -0,0 +1,142 @@
+package hasher
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// HashSet is a set of reference digests, like a list of indicators of compromise or the NSRL reference
+// data set, looked up by their hexadecimal value in any case, whatever the algorithm that produced them.
+type HashSet struct {
+	digests map[string]struct{}
+	lengths map[int]int // number of digests of each length, to tell which algorithms the set covers
+}
+
+// NewHashSet returns an empty HashSet.
+func NewHashSet() *HashSet {
+	return &HashSet{digests: make(map[string]struct{}), lengths: make(map[int]int)}
+}
+
+// Len returns the number of digests in the set.
+func (s *HashSet) Len() int {
+	return len(s.digests)
+}
+
+// Contains reports whether hash is in the set.
+func (s *HashSet) Contains(hash string) bool {
+	_, ok := s.digests[strings.ToLower(hash)]
+	return ok
+}
+
+// Covers reports whether the set holds digests of the length of those of algorithm, so its files can be looked up.
+func (s *HashSet) Covers(algorithm Algorithm) bool {
+	h, err := getHash(algorithm)
+	if err != nil {
+		return false
+	}
+	defer putHash(algorithm, h)
+	return s.lengths[2*h.Size()] > 0
+}
+
+// add adds the hexadecimal digest hash to the set, reporting whether it is one.
+func (s *HashSet) add(hash string) bool {
+	if len(hash) < 8 || len(hash)%2 != 0 {
+		return false
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return false
+	}
+	hash = strings.ToLower(hash)
+	if _, ok := s.digests[hash]; !ok {
+		s.digests[hash] = struct{}{}
+		s.lengths[len(hash)]++
+	}
+	return true
+}
+
+// Load adds to the set the digests read from r, returning how many lines held one and the lines holding none.
+// NSRL RDS files, CSV files starting with a quoted header like "SHA-1","MD5","CRC32","FileName",...,
+// give the digests of every column named after an algorithm. Other files give the first field of each line,
+// so plain lists of digests, sha256sum manifests and CSV exports of IOC feeds can be loaded as they are,
+// blank lines and comments starting with # being ignored.
+func (s *HashSet) Load(r io.Reader) (int, []MalformedLine, error) {
+	reader := bufio.NewReader(r)
+	if first, _ := reader.Peek(1); len(first) == 1 && first[0] == '"' {
+		return s.loadRDS(reader)
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var malformed []MalformedLine
+	count, lineNumber := 0, 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// The digest of an escaped sha256sum line starts after the backslash
+		field := strings.TrimPrefix(line, "\\")
+		if i := strings.IndexAny(field, " \t,;"); i >= 0 {
+			field = field[:i]
+		}
+		if s.add(field) {
+			count++
+		} else {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+		}
+	}
+	return count, malformed, scanner.Err()
+}
+
+// loadRDS adds the digests of an NSRL RDS file, see Load.
+func (s *HashSet) loadRDS(r io.Reader) (int, []MalformedLine, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	var columns []int
+	for i, name := range header {
+		if _, err := ParseAlgorithm(strings.ReplaceAll(name, "-", "")); err == nil {
+			columns = append(columns, i)
+		}
+	}
+	if len(columns) == 0 {
+		return 0, nil, errors.New("no hash column in the header of the NSRL RDS file")
+	}
+	var malformed []MalformedLine
+	count := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return count, malformed, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			malformed = append(malformed, MalformedLine{LineNumber: parseErr.StartLine, Text: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return count, malformed, err
+		}
+		line, _ := reader.FieldPos(0)
+		added := false
+		for _, i := range columns {
+			if i < len(record) && s.add(record[i]) {
+				added = true
+			}
+		}
+		if added {
+			count++
+		} else {
+			malformed = append(malformed, MalformedLine{LineNumber: line, Text: strings.Join(record, ",")})
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestHashSetLoad tests that plain lists of digests and manifests are loaded by their first field.
+func TestHashSetLoad(t *testing.T) {
+	list := "# IOC list\n" + strings.ToLower(abcSHA256) + "\n\n\\" + abcMD5 + "  some\\nname.txt\nsha256,filename\n" +
+		abcSHA256 + ",duplicate.exe\n"
+	s := NewHashSet()
+	count, malformed, err := s.Load(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	if count != 3 || s.Len() != 2 {
+		t.Errorf("Load() = %d digests in a set of %d, expected 3 in a set of 2", count, s.Len())
+	}
+	if len(malformed) != 1 || malformed[0].LineNumber != 5 {
+		t.Errorf("Load() malformed lines = %+v, expected line 5", malformed)
+	}
+	if !s.Contains(abcSHA256) || !s.Contains(strings.ToLower(abcMD5)) || s.Contains(abcMD5[:8]) {
+		t.Error("Contains() does not find the loaded digests in any case, or finds a prefix")
+	}
+	if !s.Covers(SHA256) || !s.Covers(MD5) || s.Covers(SHA1) {
+		t.Error("Covers() does not report the algorithms of the loaded digests")
+	}
+}
+
+// TestHashSetLoadRDS tests that every hash column of an NSRL RDS file is loaded.
+func TestHashSetLoadRDS(t *testing.T) {
+	rds := `"SHA-1","MD5","CRC32","FileName","FileSize","ProductCode","OpSystemCode","SpecialCode"
+"A9993E364706816ABA3E25717850C26C9CD0D89D","900150983CD24FB0D6963F7D28E17F72","352441C2","abc, ""quoted"".txt",3,1,"358",""
+"","","","nothing",0,1,"358",""
+`
+	s := NewHashSet()
+	count, malformed, err := s.Load(strings.NewReader(rds))
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+	if count != 1 || s.Len() != 3 {
+		t.Errorf("Load() = %d records in a set of %d, expected 1 in a set of 3", count, s.Len())
+	}
+	if len(malformed) != 1 || malformed[0].LineNumber != 3 {
+		t.Errorf("Load() malformed lines = %+v, expected line 3", malformed)
+	}
+	for _, hash := range []string{"a9993e364706816aba3e25717850c26c9cd0d89d", abcMD5, "352441c2"} {
+		if !s.Contains(hash) {
+			t.Errorf("Contains(%q) = false, expected true", hash)
+		}
+	}
+	if !s.Covers(SHA1) || !s.Covers(CRC32) || s.Covers(SHA256) {
+		t.Error("Covers() does not report the algorithms of the RDS columns")
+	}
+
+	if _, _, err := NewHashSet().Load(strings.NewReader("\"FileName\",\"FileSize\"\n\"a\",1\n")); err == nil {
+		t.Error("Load() accepted an RDS file without hash column")
+	}
+}
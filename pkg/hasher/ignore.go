@@ -0,0 +1,165 @@
+package hasher
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the name of the files listing paths to skip, using the gitignore syntax.
+const IgnoreFileName = ".hashignore"
+
+// ignoreRule is a single parsed line of an ignore file.
+type ignoreRule struct {
+	segments []string // pattern split on "/"
+	negate   bool     // line started with "!"
+	dirOnly  bool     // line ended with "/"
+	anchored bool     // pattern contains a "/" so it is relative to the ignore file directory
+}
+
+// match reports whether relPath (relative to the directory of the ignore file) matches the rule.
+func (r ignoreRule) match(relPath string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	pathSegs := strings.Split(relPath, "/")
+	if !r.anchored {
+		ok, _ := path.Match(r.segments[0], pathSegs[len(pathSegs)-1])
+		return ok
+	}
+	return matchSegments(r.segments, pathSegs)
+}
+
+// parseIgnoreRules reads gitignore-like rules: blank lines and lines starting with # are skipped,
+// a leading "!" re-includes a path, a trailing "/" only matches directories and a pattern
+// containing a "/" is anchored to the directory holding the ignore file.
+func parseIgnoreRules(reader io.Reader) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			// "\#" and "\!" escape the special first character
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if len(line) == 0 {
+			continue
+		}
+		rule.anchored = strings.Contains(line, "/")
+		rule.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		for _, seg := range rule.segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, &PatternError{Pattern: line, Err: err}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Ignorer applies the .hashignore files found while walking a directory tree.
+// Rules of an ignore file apply to the directory holding it and all its subdirectories,
+// rules found deeper in the tree take precedence over the ones found above.
+type Ignorer struct {
+	open  func(relDir string) (io.ReadCloser, error) // opens the ignore file of a directory relative to root
+	rules map[string][]ignoreRule                    // keyed by the slash separated directory path relative to root, "" for root
+}
+
+// NewIgnorer returns an Ignorer for the directory tree starting at root.
+func NewIgnorer(root string) *Ignorer {
+	return &Ignorer{
+		open: func(relDir string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(root, relDir, IgnoreFileName))
+		},
+		rules: make(map[string][]ignoreRule),
+	}
+}
+
+// NewIgnorerFS returns an Ignorer for the directory tree starting at root in fsys.
+func NewIgnorerFS(fsys fs.FS, root string) *Ignorer {
+	return &Ignorer{
+		open: func(relDir string) (io.ReadCloser, error) {
+			return fsys.Open(path.Join(root, filepath.ToSlash(relDir), IgnoreFileName))
+		},
+		rules: make(map[string][]ignoreRule),
+	}
+}
+
+// LoadDir reads the ignore file of the directory relDir (relative to root), if there is one.
+// It must be called when entering a directory, before checking the paths it contains.
+func (ig *Ignorer) LoadDir(relDir string) error {
+	f, err := ig.open(relDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	rules, err := parseIgnoreRules(f)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		ig.rules[cleanRel(relDir)] = rules
+	}
+	return nil
+}
+
+// Ignored reports whether relPath (relative to root) is ignored by the loaded rules.
+func (ig *Ignorer) Ignored(relPath string, isDir bool) bool {
+	relPath = cleanRel(relPath)
+	if relPath == "" || len(ig.rules) == 0 {
+		return false
+	}
+	ignored := false
+	// evaluate rules from the root down to the parent directory of relPath, the last match wins
+	dir := ""
+	rest := relPath
+	for {
+		for _, rule := range ig.rules[dir] {
+			if rule.match(rest, isDir) {
+				ignored = !rule.negate
+			}
+		}
+		i := strings.Index(rest, "/")
+		if i < 0 {
+			break
+		}
+		if dir == "" {
+			dir = rest[:i]
+		} else {
+			dir = dir + "/" + rest[:i]
+		}
+		rest = rest[i+1:]
+	}
+	return ignored
+}
+
+// cleanRel converts a relative OS path to a slash separated path, "" meaning the root itself.
+func cleanRel(relPath string) string {
+	relPath = strings.Trim(filepath.ToSlash(relPath), "/")
+	if relPath == "." {
+		return ""
+	}
+	return relPath
+}
@@ -0,0 +1,58 @@
+package hasher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIgnorer tests that .hashignore files at the root and in subdirectories are honored.
+func TestIgnorer(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(rel, content string) {
+		full := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %q: %v", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %v", rel, err)
+		}
+	}
+	writeFile(IgnoreFileName, "# build outputs\n*.o\ncache/\n/dist\n!keep.o\n")
+	writeFile("src/"+IgnoreFileName, "generated/*.go\n!important.o\n")
+
+	ig := NewIgnorer(root)
+	if err := ig.LoadDir("."); err != nil {
+		t.Fatalf("LoadDir(.) returned an error: %v", err)
+	}
+	if err := ig.LoadDir("src"); err != nil {
+		t.Fatalf("LoadDir(src) returned an error: %v", err)
+	}
+	if err := ig.LoadDir("src/missing"); err != nil {
+		t.Errorf("LoadDir() on a directory without ignore file returned an error: %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"main.o", false, true},
+		{"keep.o", false, false},
+		{"src/lib.o", false, true},
+		{"src/important.o", false, false},
+		{"cache", true, true},
+		{"src/cache", true, true},
+		{"cache", false, false}, // "cache/" only matches directories
+		{"dist", true, true},
+		{"src/dist", true, false}, // "/dist" is anchored at the root
+		{"src/generated/api.go", false, true},
+		{"generated/api.go", false, false},
+		{"src/main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := ig.Ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, expected %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package hasher
+
+import (
+	"fmt"
+	"os"
+)
+
+// LockedWriter writes to a manifest shared by several processes, like the per-share scans of a NAS appending
+// to the same file: each Write holds an exclusive advisory lock on the file, so the lines written with a single
+// Write, opened with os.O_APPEND, are never interleaved with the lines of the other writers using the lock.
+type LockedWriter struct {
+	f *os.File
+}
+
+// NewLockedWriter returns a LockedWriter writing to f, which should be opened with os.O_APPEND.
+func NewLockedWriter(f *os.File) *LockedWriter {
+	return &LockedWriter{f: f}
+}
+
+// Write writes p to the file while holding its lock, waiting for the other writers to release it.
+func (w *LockedWriter) Write(p []byte) (n int, err error) {
+	err = WithLock(w.f, func() error {
+		n, err = w.f.Write(p)
+		return err
+	})
+	return n, err
+}
+
+// WithLock calls fn while holding the exclusive advisory lock of f used by LockedWriter, for the writers
+// that read the file before appending to it.
+func WithLock(f *os.File, fn func() error) error {
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("locking %s: %w", f.Name(), err)
+	}
+	err := fn()
+	if unlockErr := unlockFile(f); err == nil && unlockErr != nil {
+		err = fmt.Errorf("unlocking %s: %w", f.Name(), unlockErr)
+	}
+	return err
+}
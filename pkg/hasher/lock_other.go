@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package hasher
+
+import "os"
+
+// lockFile does nothing, this platform has no advisory locks: the writes only rely on os.O_APPEND.
+func lockFile(f *os.File) error { return nil }
+
+// unlockFile does nothing, see lockFile.
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,46 @@
+package hasher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestLockedWriter tests that the lines appended by concurrent writers to the same manifest are never interleaved.
+func TestLockedWriter(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "hashes.sha256")
+	const writers, lines = 4, 200
+	var wg sync.WaitGroup
+	for i := range writers {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			t.Fatalf("Failed to open the manifest: %v", err)
+		}
+		defer f.Close()
+		w := NewLockedWriter(f)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range lines {
+				line := FormatLine(strings.Repeat(fmt.Sprintf("%X", i), 64), fmt.Sprintf("share%d/file%d", i, j), false)
+				if _, err := w.Write([]byte(line)); err != nil {
+					t.Errorf("Write() returned an error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Failed to open the manifest: %v", err)
+	}
+	defer f.Close()
+	entries, malformed, err := ParseHashFileDetailed(f)
+	if err != nil || len(malformed) > 0 || len(entries) != writers*lines {
+		t.Errorf("ParseHashFileDetailed() returned %d entries, %d malformed lines, %v, expected %d entries", len(entries), len(malformed), err, writers*lines)
+	}
+}
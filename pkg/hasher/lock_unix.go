@@ -0,0 +1,23 @@
+//go:build unix
+
+package hasher
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile waits for an exclusive flock on f, only honored by the other processes using it.
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile releases the flock of f.
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,140 @@
+package hasher
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pathEscaper escapes the characters that would break a manifest line, like GNU coreutils.
+var pathEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// FormatLine returns the manifest line of the file at path, in the format of sha256sum: "hash  path\n".
+// Like GNU coreutils, when path contains a backslash, a newline or a carriage return, these characters
+// are escaped and the line starts with a backslash. With zero, the line ends with a NUL byte instead
+// and path is never escaped, like with sha256sum -z.
+// Several hashes can be written on the same line by joining them with two spaces.
+func FormatLine(hash, path string, zero bool) string {
+	if zero {
+		return hash + "  " + path + "\x00"
+	}
+	if strings.ContainsAny(path, "\\\n\r") {
+		return `\` + hash + "  " + pathEscaper.Replace(path) + "\n"
+	}
+	return hash + "  " + path + "\n"
+}
+
+// FormatEntry returns the manifest line of e like FormatLine, with the size and the metadata it records,
+// so the entries read from a manifest can be written back as they were.
+func FormatEntry(e FileEntry, zero bool) string {
+	hash := e.Hash
+	switch {
+	case e.HasMetadata:
+		hash = MetadataHash(e.Hash, e.Size, e.ModTime, e.Mode)
+	case e.HasSize:
+		hash = SizedHash(e.Hash, e.Size)
+	}
+	return FormatLine(hash, e.FilePath, zero)
+}
+
+// SizedHash returns hash followed by a space and size, to be given to FormatLine so the manifest records
+// the size of the file: check mode then reports a file whose size changed without reading it.
+// sha256sum cannot read such lines.
+func SizedHash(hash string, size int64) string {
+	return hash + " " + strconv.FormatInt(size, 10)
+}
+
+// MetadataHash returns hash followed by the size, the modification time in seconds and nanoseconds and the
+// permissions in octal, like 0755, of a file, separated by spaces, to be given to FormatLine so the manifest
+// records them: check mode then also reports the files whose modification time or permissions changed.
+// sha256sum cannot read such lines.
+func MetadataHash(hash string, size int64, modTime time.Time, mode fs.FileMode) string {
+	return fmt.Sprintf("%s %d %d.%09d %04o", hash, size, modTime.Unix(), modTime.Nanosecond(), unixMode(mode))
+}
+
+// unixMode returns the permission bits of mode, with the setuid, setgid and sticky bits, as unix writes them.
+func unixMode(mode fs.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		bits |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		bits |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		bits |= 0o1000
+	}
+	return bits
+}
+
+// fileMode reverses unixMode.
+func fileMode(bits uint32) fs.FileMode {
+	mode := fs.FileMode(bits & 0o777)
+	if bits&0o4000 != 0 {
+		mode |= fs.ModeSetuid
+	}
+	if bits&0o2000 != 0 {
+		mode |= fs.ModeSetgid
+	}
+	if bits&0o1000 != 0 {
+		mode |= fs.ModeSticky
+	}
+	return mode
+}
+
+// PermissionBits returns the permissions of mode compared by check mode, see MetadataHash.
+func PermissionBits(mode fs.FileMode) fs.FileMode {
+	return fileMode(unixMode(mode))
+}
+
+// EscapePath returns path escaped like in FormatLine, prefixed with a backslash when it had to be escaped,
+// the way GNU coreutils print the file names in their check results.
+func EscapePath(path string) string {
+	if strings.ContainsAny(path, "\\\n\r") {
+		return `\` + pathEscaper.Replace(path)
+	}
+	return path
+}
+
+// unescapePath reverses the escaping of FormatLine, reporting false for an invalid escape sequence.
+func unescapePath(s string) (string, bool) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", false
+		}
+		switch s[i] {
+		case '\\':
+			sb.WriteByte('\\')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// ScanNUL is a bufio.SplitFunc returning the NUL terminated records of its input,
+// like the file lists written by find -print0.
+func ScanNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
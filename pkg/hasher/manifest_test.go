@@ -0,0 +1,112 @@
+package hasher
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFormatLineRoundTrip tests that file names with newlines, backslashes and spaces survive a manifest.
+func TestFormatLineRoundTrip(t *testing.T) {
+	const hash = "ABCDEF0123456789"
+	names := []string{"plain.txt", " leading space.txt", "trailing space ", "new\nline.txt", `back\slash`, "cr\r.txt"}
+	for _, zero := range []bool{false, true} {
+		var sb strings.Builder
+		for _, name := range names {
+			sb.WriteString(FormatLine(hash, name, zero))
+		}
+		parse := ParseHashFileDetailed
+		if zero {
+			parse = ParseHashFileZero
+		}
+		entries, malformed, err := parse(strings.NewReader(sb.String()))
+		if err != nil || len(malformed) != 0 || len(entries) != len(names) {
+			t.Fatalf("parsing the manifest (zero %v) returned %d entries, %v, %v", zero, len(entries), malformed, err)
+		}
+		for i, e := range entries {
+			if e.Hash != hash || e.FilePath != names[i] {
+				t.Errorf("entry %d (zero %v) = %q %q, expected %q", i, zero, e.Hash, e.FilePath, names[i])
+			}
+		}
+	}
+
+	if got := FormatLine(hash, "new\nline", false); got != `\`+hash+`  new\nline`+"\n" {
+		t.Errorf("FormatLine() = %q, expected the GNU coreutils escaping", got)
+	}
+	if _, malformed, _ := ParseHashFileDetailed(strings.NewReader(`\` + hash + `  bad\escape` + "\n")); len(malformed) != 1 {
+		t.Error("ParseHashFileDetailed() accepted an invalid escape sequence")
+	}
+}
+
+// TestEscapePath tests the escaping of the file names printed in check results.
+func TestEscapePath(t *testing.T) {
+	if got := EscapePath("plain.txt"); got != "plain.txt" {
+		t.Errorf("EscapePath() = %q, expected the name unchanged", got)
+	}
+	if got := EscapePath("new\nline"); got != `\new\nline` {
+		t.Errorf("EscapePath() = %q, expected %q", got, `\new\nline`)
+	}
+}
+
+// TestSizedHash tests that the sizes recorded in a manifest are parsed, and that invalid ones are malformed.
+func TestSizedHash(t *testing.T) {
+	const hash = "ABCDEF0123456789"
+	manifest := FormatLine(SizedHash(hash, 1234), "sized 42.txt", false) + FormatLine(hash, "42  unsized.txt", false) +
+		FormatLine(SizedHash(hash, 0), "new\nline", false) + hash + " -1  negative.txt\n" + hash + " big  word.txt\n"
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader(manifest))
+	if err != nil || len(entries) != 3 || len(malformed) != 2 {
+		t.Fatalf("ParseHashFileDetailed() = %v, %v, %v, expected 3 entries and 2 malformed lines", entries, malformed, err)
+	}
+	want := []FileEntry{
+		{Hash: hash, FilePath: "sized 42.txt", Size: 1234, HasSize: true},
+		{Hash: hash, FilePath: "42  unsized.txt"},
+		{Hash: hash, FilePath: "new\nline", Size: 0, HasSize: true},
+	}
+	for i, e := range entries {
+		if e != want[i] {
+			t.Errorf("entry %d = %+v, expected %+v", i, e, want[i])
+		}
+	}
+}
+
+// TestMetadataHash tests that the size, modification time and permissions recorded in a manifest are parsed back.
+func TestMetadataHash(t *testing.T) {
+	const hash = "ABCDEF0123456789"
+	modTime := time.Unix(1700000000, 123456789)
+	mode := fs.ModeSetuid | 0o755
+	line := FormatLine(MetadataHash(hash, 42, modTime, mode|fs.ModeDir), "bin/tool", false)
+	if want := hash + " 42 1700000000.123456789 4755  bin/tool\n"; line != want {
+		t.Errorf("FormatLine(MetadataHash()) = %q, expected %q", line, want)
+	}
+	manifest := line + hash + " 42 1700000000  three.txt\n" + hash + " 42 1700000000 0999  mode.txt\n" + hash + " 42 now 0644  time.txt\n"
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader(manifest))
+	if err != nil || len(entries) != 1 || len(malformed) != 3 {
+		t.Fatalf("ParseHashFileDetailed() = %v, %v, %v, expected 1 entry and 3 malformed lines", entries, malformed, err)
+	}
+	e := entries[0]
+	if !e.HasSize || e.Size != 42 || !e.HasMetadata || !e.ModTime.Equal(modTime) || e.Mode != mode || e.FilePath != "bin/tool" {
+		t.Errorf("entry = %+v, expected size 42, modification time %v and mode %v", e, modTime, mode)
+	}
+	if got := PermissionBits(mode | fs.ModeDir); got != mode {
+		t.Errorf("PermissionBits() = %v, expected %v", got, mode)
+	}
+}
+
+// TestFormatEntry tests that the lines of a manifest, with their sizes and metadata, are written back as they were read.
+func TestFormatEntry(t *testing.T) {
+	const hash = "ABCDEF0123456789"
+	manifest := FormatLine(hash, "plain.txt", false) + FormatLine(SizedHash(hash, 42), "sized.txt", false) +
+		FormatLine(MetadataHash(hash, 7, time.Unix(1700000000, 5), 0o640), "new\nline", false)
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader(manifest))
+	if err != nil || len(malformed) != 0 || len(entries) != 3 {
+		t.Fatalf("ParseHashFileDetailed() = %v, %v, %v, expected 3 entries", entries, malformed, err)
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(FormatEntry(e, false))
+	}
+	if sb.String() != manifest {
+		t.Errorf("FormatEntry() wrote %q, expected %q", sb.String(), manifest)
+	}
+}
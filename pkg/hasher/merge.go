@@ -0,0 +1,130 @@
+package hasher
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// EntryConflict is a path listed with different hashes by two of the manifests merged by MergeEntries.
+type EntryConflict struct {
+	Path       string
+	Hash       string // the hash kept, from the first manifest listing the path
+	Index      int    // the index of this first manifest
+	Other      string // the hash of the other manifest, which is dropped
+	OtherIndex int
+}
+
+// MergeEntries merges the entries of several manifests, in order. A path listed again, compared after
+// filepath.Clean, is dropped: silently when it has the same hash, as when manifests overlap, and
+// otherwise reported as a conflict, the first hash being the one kept.
+func MergeEntries(manifests ...[]FileEntry) ([]FileEntry, []EntryConflict) {
+	var merged []FileEntry
+	var conflicts []EntryConflict
+	merger := NewEntryMerger()
+	for i, entries := range manifests {
+		for _, e := range entries {
+			keep, conflict := merger.Add(i, e)
+			if conflict != nil {
+				conflicts = append(conflicts, *conflict)
+			}
+			if keep {
+				merged = append(merged, e)
+			}
+		}
+	}
+	return merged, conflicts
+}
+
+// EntryMerger merges the entries of several manifests like MergeEntries, one entry at a time,
+// so they can be processed while the manifests are still being read. Only the paths and the hashes
+// of the entries are kept. The hashes computed for the entries kept can be recorded with Verified,
+// to tell which of the hashes of a conflict the file matches without reading it again.
+// It is safe for concurrent use.
+type EntryMerger struct {
+	mu         sync.Mutex
+	seen       map[string]listedEntry
+	duplicates int
+}
+
+// listedEntry is the hash of a path seen by an EntryMerger, with the index of its manifest.
+type listedEntry struct {
+	hash     string
+	index    int
+	verified bool
+	actual   string // the hash computed when verified, only kept when it is not hash
+}
+
+// NewEntryMerger returns an empty EntryMerger.
+func NewEntryMerger() *EntryMerger {
+	return &EntryMerger{seen: make(map[string]listedEntry)}
+}
+
+// Add records the entry e of the manifest index and reports whether it should be kept, being the first
+// one listing its path. The conflict with the entry kept is returned when the path was listed with another hash.
+func (m *EntryMerger) Add(index int, e FileEntry) (bool, *EntryConflict) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path := filepath.Clean(e.FilePath)
+	first, ok := m.seen[path]
+	if !ok {
+		m.seen[path] = listedEntry{hash: e.Hash, index: index}
+		return true, nil
+	}
+	m.duplicates++
+	if strings.EqualFold(first.hash, e.Hash) {
+		return false, nil
+	}
+	return false, &EntryConflict{Path: e.FilePath, Hash: first.hash, Index: first.index, Other: e.Hash, OtherIndex: index}
+}
+
+// Duplicates returns the number of entries dropped so far, their path being listed again.
+func (m *EntryMerger) Duplicates() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.duplicates
+}
+
+// Verified records actual, the hash computed for the entry kept for path, empty when the file could not be read.
+func (m *EntryMerger) Verified(path, actual string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	e, ok := m.seen[path]
+	if !ok || actual == "" {
+		return
+	}
+	e.verified = true
+	if !strings.EqualFold(actual, e.hash) {
+		e.actual = actual
+	}
+	m.seen[path] = e
+}
+
+// Actual returns the hash recorded with Verified for path, empty when it was not verified.
+func (m *EntryMerger) Actual(path string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := m.seen[filepath.Clean(path)]
+	switch {
+	case !e.verified:
+		return ""
+	case e.actual != "":
+		return e.actual
+	}
+	return e.hash
+}
+
+// Matching returns which of the hashes of the conflict c the content hashed as actual matches:
+// c.Hash, c.Other, or an empty string when it matches none of them or actual is empty.
+func (c EntryConflict) Matching(actual string) string {
+	switch {
+	case actual == "":
+		return ""
+	case strings.EqualFold(actual, c.Hash):
+		return c.Hash
+	case strings.EqualFold(actual, c.Other):
+		return c.Other
+	}
+	return ""
+}
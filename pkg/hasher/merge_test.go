@@ -0,0 +1,59 @@
+package hasher
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMergeEntries tests that duplicated paths are dropped and that different hashes are reported.
+func TestMergeEntries(t *testing.T) {
+	merged, conflicts := MergeEntries(
+		[]FileEntry{{Hash: "AA", FilePath: "a.txt"}, {Hash: "BB", FilePath: "sub/b.txt"}},
+		[]FileEntry{{Hash: "aa", FilePath: "./a.txt"}, {Hash: "CC", FilePath: "c.txt"}},
+		[]FileEntry{{Hash: "DD", FilePath: "sub//b.txt"}},
+	)
+	want := []FileEntry{{Hash: "AA", FilePath: "a.txt"}, {Hash: "BB", FilePath: "sub/b.txt"}, {Hash: "CC", FilePath: "c.txt"}}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("MergeEntries() = %v, expected %v", merged, want)
+	}
+	wantConflicts := []EntryConflict{{Path: "sub//b.txt", Hash: "BB", Index: 0, Other: "DD", OtherIndex: 2}}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("MergeEntries() conflicts = %v, expected %v", conflicts, wantConflicts)
+	}
+}
+
+// TestEntryMergerVerified tests that the duplicated paths are counted and that the hash computed for a path
+// tells which of its conflicting hashes is right.
+func TestEntryMergerVerified(t *testing.T) {
+	m := NewEntryMerger()
+	var conflicts []EntryConflict
+	for _, e := range []FileEntry{
+		{Hash: "AA", FilePath: "a.txt"}, {Hash: "BB", FilePath: "b.txt"}, {Hash: "CC", FilePath: "c.txt"},
+		{Hash: "aa", FilePath: "./a.txt"}, {Hash: "B2", FilePath: "b.txt"}, {Hash: "C2", FilePath: "c.txt"},
+	} {
+		if _, conflict := m.Add(0, e); conflict != nil {
+			conflicts = append(conflicts, *conflict)
+		}
+	}
+	if m.Duplicates() != 3 || len(conflicts) != 2 {
+		t.Fatalf("Add() found %d duplicates and %v, want 3 and 2 conflicts", m.Duplicates(), conflicts)
+	}
+	m.Verified("a.txt", "aa")
+	m.Verified("b.txt", "b2")
+	m.Verified("c.txt", "")
+	m.Verified("unknown.txt", "DD")
+	for path, want := range map[string]string{"a.txt": "AA", "b.txt": "b2", "c.txt": "", "unknown.txt": ""} {
+		if got := m.Actual(path); got != want {
+			t.Errorf("Actual(%s) = %q, want %q", path, got, want)
+		}
+	}
+	if got := conflicts[0].Matching(m.Actual("b.txt")); got != "B2" {
+		t.Errorf("Matching() of b.txt = %q, want the other hash B2", got)
+	}
+	if got := conflicts[0].Matching("BB"); got != "BB" {
+		t.Errorf("Matching(BB) = %q, want the hash kept", got)
+	}
+	if got := conflicts[1].Matching(m.Actual("c.txt")); got != "" {
+		t.Errorf("Matching() of the unread c.txt = %q, want none", got)
+	}
+}
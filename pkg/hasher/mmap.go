@@ -0,0 +1,63 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime/debug"
+	"unsafe"
+)
+
+// DefaultMmapThreshold is a file size from which memory mapping usually reads faster than read calls.
+const DefaultMmapThreshold = 16 << 20
+
+// mmapChunkSize is the amount of mapped memory hashed between two checks of the context.
+const mmapChunkSize = 4 << 20
+
+// errMmapFault is the error of a memory mapped file whose pages could not be read, like the ones past
+// its end when it is truncated while it is mapped, or the ones on bad sectors.
+var errMmapFault = errors.New("fault reading the memory mapped file, truncated while hashed or unreadable")
+
+// hashBytes works like hashContent for the content of the memory mapped file at path, which is hashed
+// without being copied to a buffer first. A page that cannot be read, which would otherwise kill the
+// process with a SIGBUS, makes it return a *ReadError.
+func hashBytes(ctx context.Context, data []byte, path string, opts Options) (hashes []string, n int64, err error) {
+	w, sums, release, err := newHashWriter(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+	// The fault happens in this goroutine, the one writing the mapped pages to the hashes
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	base := uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+	defer func() {
+		if r := recover(); r != nil {
+			fault, ok := r.(interface{ Addr() uintptr })
+			if !ok {
+				panic(r)
+			}
+			offset := n
+			if addr := fault.Addr(); addr >= base && addr-base < uintptr(len(data)) {
+				offset = int64(addr - base)
+			}
+			hashes, err = nil, &ReadError{Path: path, Offset: offset, Err: errMmapFault}
+		}
+	}()
+	w = cpuLimited(ctx, w)
+	if opts.GitBlob {
+		io.WriteString(w, gitBlobHeader(int64(len(data))))
+	}
+	for rest := data; len(rest) > 0; {
+		if err := ctx.Err(); err != nil {
+			return nil, n, err
+		}
+		chunk := rest[:min(len(rest), mmapChunkSize)]
+		if err := waitBandwidth(ctx, len(chunk)); err != nil {
+			return nil, n, err
+		}
+		w.Write(chunk) // hashes never return an error
+		n += int64(len(chunk))
+		rest = rest[len(chunk):]
+	}
+	return sums(), n, nil
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package hasher
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile is not available on this platform, the files are always read.
+func mmapFile(f *os.File, size int64) ([]byte, func(), error) {
+	return nil, nil, errors.ErrUnsupported
+}
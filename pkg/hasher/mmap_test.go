@@ -0,0 +1,72 @@
+package hasher
+
+import (
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeRandomFile writes size random bytes to a new file in dir and returns its path.
+func writeRandomFile(tb testing.TB, dir string, size int) string {
+	tb.Helper()
+	content := make([]byte, size)
+	rand.Read(content)
+	path := filepath.Join(dir, "random.bin")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		tb.Fatalf("Failed to write file: %v", err)
+	}
+	return path
+}
+
+// TestMmap tests that memory mapped files get the hashes of the files that are read.
+func TestMmap(t *testing.T) {
+	path := writeRandomFile(t, t.TempDir(), 3*mmapChunkSize+123) // several chunks
+	for _, opts := range []Options{
+		NewOptions(),
+		NewOptions(WithAlgorithm(MD5), WithExtraAlgorithms(SHA1)),
+		NewOptions(WithAlgorithm(SHA1), WithGitBlob(true)),
+	} {
+		read := opts.HashFile(context.Background(), path)
+		opts.MmapThreshold = 1
+		mapped := opts.HashFile(context.Background(), path)
+		if read.Err != nil || mapped.Err != nil || mapped.Hash != read.Hash || mapped.Size != read.Size || len(mapped.Hashes) != len(read.Hashes) {
+			t.Errorf("memory mapped file hashed as %+v, read as %+v", mapped, read)
+		}
+		for algorithm, hash := range read.Hashes {
+			if mapped.Hashes[algorithm] != hash {
+				t.Errorf("%s of the memory mapped file = %s, expected %s", algorithm, mapped.Hashes[algorithm], hash)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := NewOptions(WithMmap(1)).HashFile(ctx, path); result.Err == nil {
+		t.Error("HashFile() of a memory mapped file with a cancelled context did not return an error")
+	}
+}
+
+// BenchmarkHashFile compares reading a large file through the buffer with memory mapping it,
+// the file being in the page cache after the first iteration.
+func BenchmarkHashFile(b *testing.B) {
+	const size = 64 << 20
+	path := writeRandomFile(b, b.TempDir(), size)
+	for _, bm := range []struct {
+		name string
+		opts Options
+	}{
+		{"read", NewOptions()},
+		{"mmap", NewOptions(WithMmap(DefaultMmapThreshold))},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetBytes(size)
+			for b.Loop() {
+				if result := bm.opts.HashFile(context.Background(), path); result.Err != nil {
+					b.Fatal(result.Err)
+				}
+			}
+		})
+	}
+}
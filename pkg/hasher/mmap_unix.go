@@ -0,0 +1,23 @@
+//go:build unix
+
+package hasher
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the size bytes of f in memory read-only, returning the function unmapping them.
+// Reading the pages past the end of the file, when it is truncated while it is mapped, raises a SIGBUS,
+// recovered by hashBytes.
+func mmapFile(f *os.File, size int64) ([]byte, func(), error) {
+	if size <= 0 || int64(int(size)) != size {
+		return nil, nil, errors.New("size cannot be mapped")
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() { syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package hasher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestHashBytesTruncated tests that a file truncated while it is mapped is reported as a read error
+// instead of killing the process with a SIGBUS.
+func TestHashBytesTruncated(t *testing.T) {
+	path := writeRandomFile(t, t.TempDir(), 3*mmapChunkSize)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, unmap, err := mmapFile(f, 3*mmapChunkSize)
+	if err != nil {
+		t.Skipf("mmapFile() = %v", err)
+	}
+	defer unmap()
+	if err := os.Truncate(path, mmapChunkSize); err != nil {
+		t.Fatal(err)
+	}
+	hashes, _, err := hashBytes(context.Background(), data, path, Options{})
+	var readErr *ReadError
+	if !errors.As(err, &readErr) || readErr.Path != path || readErr.Offset < mmapChunkSize || readErr.Offset >= 3*mmapChunkSize {
+		t.Fatalf("hashBytes() of a truncated file = %v, %v, want a read error past the new end", hashes, err)
+	}
+	// The pages still backed by the file are read as usual
+	if _, _, err := hashBytes(context.Background(), data[:mmapChunkSize], path, Options{}); err != nil {
+		t.Errorf("hashBytes() of the pages left = %v, want no error", err)
+	}
+}
@@ -0,0 +1,161 @@
+package hasher
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Normalization is the Unicode normalization form of the paths written in manifests and looked up in check mode.
+// macOS writes the file names decomposed (NFD) while Linux and Windows keep them as typed, usually composed (NFC),
+// so the names with accents of a manifest created on one system are not found on a copy of the files on another.
+type Normalization string
+
+const (
+	NormalizeNone Normalization = "none" // paths are used as they are, the default
+	NormalizeNFC  Normalization = "nfc"  // canonical composition, é being one character
+	NormalizeNFD  Normalization = "nfd"  // canonical decomposition, é being e followed by a combining acute accent
+)
+
+// ParseNormalization returns the Normalization named s, NormalizeNone for an empty s.
+func ParseNormalization(s string) (Normalization, error) {
+	switch form := Normalization(strings.ToLower(s)); form {
+	case "", NormalizeNone:
+		return NormalizeNone, nil
+	case NormalizeNFC, NormalizeNFD:
+		return form, nil
+	default:
+		return "", fmt.Errorf("unknown normalization form %q, use nfc, nfd or none", s)
+	}
+}
+
+// lookupPath returns the name of the file listed as path, in the form o.NormalizePaths or else in the other form
+// when a file exists under that name, path as it is listed otherwise.
+func (o Options) lookupPath(path string) string {
+	other := NormalizeNFD
+	switch o.NormalizePaths {
+	case NormalizeNFC:
+	case NormalizeNFD:
+		other = NormalizeNFC
+	default:
+		return path
+	}
+	for _, form := range []Normalization{o.NormalizePaths, other} {
+		if name := NormalizePath(path, form); name != path {
+			if _, err := os.Lstat(name); err == nil {
+				return name
+			}
+		}
+	}
+	return path
+}
+
+// The constants of the algorithmic decomposition of the Hangul syllables.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// NormalizePath returns path in the Unicode normalization form, unchanged with NormalizeNone.
+// Invalid UTF-8 sequences are kept as they are.
+func NormalizePath(path string, form Normalization) string {
+	if form != NormalizeNFC && form != NormalizeNFD {
+		return path
+	}
+	ascii := true
+	for i := 0; i < len(path); i++ {
+		if path[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii || !utf8.ValidString(path) {
+		return path
+	}
+	runes := decompose(path)
+	if form == NormalizeNFC {
+		runes = compose(runes)
+	}
+	return string(runes)
+}
+
+// decompose returns the canonical decomposition of s, in canonical order.
+func decompose(s string) []rune {
+	runes := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch d, ok := decompositions[r]; {
+		case r >= hangulSBase && r < hangulSBase+hangulSCount:
+			index := r - hangulSBase
+			runes = append(runes, hangulLBase+index/hangulNCount, hangulVBase+(index%hangulNCount)/hangulTCount)
+			if t := index % hangulTCount; t != 0 {
+				runes = append(runes, hangulTBase+t)
+			}
+		case ok:
+			runes = append(runes, []rune(d)...)
+		default:
+			runes = append(runes, r)
+		}
+	}
+	// Sort each sequence of combining characters by combining class, keeping the order of the ones of a class
+	for start := 0; start < len(runes); {
+		if combiningClasses[runes[start]] == 0 {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(runes) && combiningClasses[runes[end]] != 0 {
+			end++
+		}
+		slices.SortStableFunc(runes[start:end], func(a, b rune) int {
+			return int(combiningClasses[a]) - int(combiningClasses[b])
+		})
+		start = end
+	}
+	return runes
+}
+
+// compose returns the canonical composition of the decomposed runes, combining each character with the last
+// starter when no character between them blocks it.
+func compose(runes []rune) []rune {
+	composed := make([]rune, 0, len(runes))
+	starter := -1
+	lastClass := -1 // class of the last character kept after the starter, -1 when next to it
+	for _, r := range runes {
+		class := int(combiningClasses[r])
+		if starter >= 0 && (lastClass == -1 || (lastClass != 0 && lastClass < class)) {
+			if c, ok := composePair(composed[starter], r); ok {
+				composed[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter, lastClass = len(composed), -1
+		} else {
+			lastClass = class
+		}
+		composed = append(composed, r)
+	}
+	return composed
+}
+
+// composePair returns the primary composite of a followed by b.
+func composePair(a, b rune) (rune, bool) {
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+b-hangulVBase)*hangulTCount, true
+	}
+	if a >= hangulSBase && a < hangulSBase+hangulSCount && (a-hangulSBase)%hangulTCount == 0 &&
+		b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + b - hangulTBase, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
@@ -0,0 +1,67 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNormalizePath tests the composition and decomposition of accents, reordered marks and Hangul syllables.
+func TestNormalizePath(t *testing.T) {
+	for _, tc := range []struct{ path, nfc, nfd string }{
+		{"plain/ascii.txt", "plain/ascii.txt", "plain/ascii.txt"},
+		{"caf\u00e9.txt", "caf\u00e9.txt", "cafe\u0301.txt"},
+		{"cafe\u0301.txt", "caf\u00e9.txt", "cafe\u0301.txt"},
+		{"c\u0301\u0327", "\u1e09", "c\u0327\u0301"},        // the cedilla is ordered before the acute accent
+		{"q\u0307\u0323", "q\u0323\u0307", "q\u0323\u0307"}, // no precomposed q with these marks
+		{"\uac01", "\uac01", "\u1100\u1161\u11a8"},
+		{"\u212b", "\u00c5", "A\u030a"}, // the angstrom sign is a singleton, never composed back
+		{"bad\xffe\u0301", "bad\xffe\u0301", "bad\xffe\u0301"},
+	} {
+		if got := NormalizePath(tc.path, NormalizeNFC); got != tc.nfc {
+			t.Errorf("NormalizePath(%+q, nfc) = %+q, expected %+q", tc.path, got, tc.nfc)
+		}
+		if got := NormalizePath(tc.path, NormalizeNFD); got != tc.nfd {
+			t.Errorf("NormalizePath(%+q, nfd) = %+q, expected %+q", tc.path, got, tc.nfd)
+		}
+		if got := NormalizePath(tc.path, NormalizeNone); got != tc.path {
+			t.Errorf("NormalizePath(%+q, none) = %+q, expected it unchanged", tc.path, got)
+		}
+	}
+	if _, err := ParseNormalization("NFKC"); err == nil {
+		t.Error("ParseNormalization() accepted an unsupported form")
+	}
+}
+
+// TestVerifyFilesNormalized tests that a manifest written with decomposed names verifies composed ones.
+func TestVerifyFilesNormalized(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "caf\u00e9.txt"), []byte("coffee"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	hash := NewOptions().HashFile(ctx, filepath.Join(root, "caf\u00e9.txt")).Hash
+	entry := FileEntry{Hash: hash, FilePath: "café.txt"}
+	for _, form := range []Normalization{NormalizeNFC, NormalizeNFD} {
+		entries := make(chan FileEntry, 1)
+		entries <- entry
+		close(entries)
+		opts := NewOptions(WithNormalizePaths(form))
+		for r := range opts.VerifyFiles(ctx, root, entries) {
+			if !r.OK() {
+				t.Errorf("VerifyFiles() with %s = %v, expected the composed name to be found", form, r.Err)
+			}
+		}
+		extra, err := opts.ExtraFiles(ctx, Walker{}, root, []string{entry.FilePath})
+		if err != nil || len(extra) != 0 {
+			t.Errorf("ExtraFiles() with %s = %q, %v, expected no extra file", form, extra, err)
+		}
+	}
+	entries := make(chan FileEntry, 1)
+	entries <- entry
+	close(entries)
+	if r := <-NewOptions().VerifyFiles(ctx, root, entries); r.OK() {
+		t.Error("VerifyFiles() found the decomposed name without normalization")
+	}
+}
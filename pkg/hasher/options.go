@@ -0,0 +1,415 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultBufferSize is the size of the buffer used to read the files when Options.BufferSize is not set.
+const DefaultBufferSize = 64 * 1024
+
+// Buffer pool for I/O operations with the default buffer size
+var bufferPool = sync.Pool{
+	New: func() any {
+		return make([]byte, DefaultBufferSize)
+	},
+}
+
+// sizedPools holds a pool of buffers for each other size asked with Options.BufferSize, and alignedPools
+// the aligned buffers of the reads with O_DIRECT, keyed by size, so the files reuse the buffers of the
+// files read before them instead of allocating their own.
+var sizedPools, alignedPools sync.Map // int to *sync.Pool
+
+// getBuffer returns a buffer of size bytes, DefaultBufferSize when size < 1, to give back with putBuffer.
+func getBuffer(size int) []byte {
+	if size < 1 || size == DefaultBufferSize {
+		return bufferPool.Get().([]byte)
+	}
+	return poolOf(&sizedPools, size, func() []byte { return make([]byte, size) }).Get().([]byte)
+}
+
+// putBuffer gives back buf, returned by getBuffer, to the pool of its size.
+func putBuffer(buf []byte) {
+	if len(buf) == DefaultBufferSize {
+		bufferPool.Put(buf)
+		return
+	}
+	if pool, ok := sizedPools.Load(len(buf)); ok {
+		pool.(*sync.Pool).Put(buf)
+	}
+}
+
+// poolOf returns the pool of the buffers of size bytes in pools, allocating them with alloc.
+func poolOf(pools *sync.Map, size int, alloc func() []byte) *sync.Pool {
+	if pool, ok := pools.Load(size); ok {
+		return pool.(*sync.Pool)
+	}
+	pool, _ := pools.LoadOrStore(size, &sync.Pool{New: func() any { return alloc() }})
+	return pool.(*sync.Pool)
+}
+
+// Options configures how files are hashed and which files are found when walking directories.
+// The zero value hashes with SHA256, reading DefaultWorkerCount() files concurrently while computing
+// at most runtime.NumCPU() digests at the same time, with a DefaultBufferSize buffer,
+// and writes the hashes in uppercase hexadecimal.
+// Options can be filled directly or built with NewOptions and the With... functions.
+type Options struct {
+	Algorithm       Algorithm     // hash function, SHA256 when empty
+	ExtraAlgorithms []Algorithm   // other hash functions computed in the same read, see Result.Hashes
+	BufferSize      int           // size of the read buffer, DefaultBufferSize when < 1
+	Workers         int           // number of files read concurrently, DefaultWorkerCount() when < 1
+	CPUWorkers      int           // number of digests computed at the same time, runtime.NumCPU() when < 1
+	MaxBandwidth    int64         // bytes per second read from all the files together, see LimitBandwidth, no limit when < 1
+	FollowSymlinks  bool          // same as Symlinks set to SymlinksFollow, when Symlinks is not set
+	Symlinks        SymlinkPolicy // what to do with the symlinks found while walking, see SymlinkPolicy
+	MaxDepth        int           // only walk the files at most MaxDepth levels below each root, no limit when < 1
+	OneFileSystem   bool          // do not walk into directories on another filesystem, unix only
+	Streams         bool          // also hash the alternate data streams of the files found while walking, Windows only
+	Special         SpecialPolicy // what to do with named pipes, sockets and devices, SpecialSkip when empty
+	Filter          PathFilter    // include/exclude patterns applied while walking
+	NoIgnore        bool          // do not honor the .hashignore files found in the tree
+	LowerCase       bool          // write hashes in lowercase hexadecimal, like sha256sum
+	Cache           *HashCache    // reuse the hashes of unchanged files, nil to always read the files
+	HMACKey         []byte        // compute keyed HMACs instead of plain digests when not empty
+	GitBlob         bool          // hash "blob <size>\0" before the content, giving the object names of git
+	MmapThreshold   int64         // memory map the files of at least this size instead of reading them, never when < 1
+	Fadvise         bool          // advise the kernel the files are read sequentially and drop them from the page cache
+	DirectIO        bool          // read the files with O_DIRECT, bypassing the page cache, Linux only
+	TreeChunkSize   int64         // compute tree hashes over chunks of this size, hashed in parallel, see FormatTreeHash
+	QuickSize       int64         // compute quick hashes over the first and last bytes of this size, see FormatQuickHash
+	GlacierTree     bool          // compute the SHA256 tree hashes of AWS Glacier, see FormatGlacierHash
+	ETagPartSize    int64         // compute the ETags S3 gives to uploads in parts of this size, see FormatETag
+	BitTorrentV2    bool          // compute the pieces roots of BitTorrent v2, see FormatBTv2Hash
+	HardLinks       bool          // read the files having several hard links once, see Result.LinkOf, unix only
+	Sparse          bool          // skip the holes of sparse files, hashed as zeros without reading them, Linux only
+	NormalizePaths  Normalization // Unicode form of the listed paths looked up by VerifyFiles and ExtraFiles
+	Stable          bool          // report the files changed while hashed, or locked on Windows, with ErrUnstable
+	StableRetries   int           // with Stable, the number of times an unstable file is hashed again
+}
+
+// Option is a functional option for NewOptions.
+type Option func(*Options)
+
+// NewOptions returns the default Options modified by opts.
+func NewOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithAlgorithm selects the hash function.
+func WithAlgorithm(a Algorithm) Option {
+	return func(o *Options) { o.Algorithm = a }
+}
+
+// WithExtraAlgorithms computes other digests in the same read, see Result.Hashes.
+func WithExtraAlgorithms(algorithms ...Algorithm) Option {
+	return func(o *Options) { o.ExtraAlgorithms = append(o.ExtraAlgorithms, algorithms...) }
+}
+
+// WithBufferSize sets the size of the buffer used to read each file.
+func WithBufferSize(size int) Option {
+	return func(o *Options) { o.BufferSize = size }
+}
+
+// WithWorkers sets the number of files hashed concurrently.
+func WithWorkers(n int) Option {
+	return func(o *Options) { o.Workers = n }
+}
+
+// WithCPUWorkers sets the number of digests computed at the same time, see LimitCPU.
+func WithCPUWorkers(n int) Option {
+	return func(o *Options) { o.CPUWorkers = n }
+}
+
+// WithMaxBandwidth limits the bytes per second read from all the files together, see LimitBandwidth.
+func WithMaxBandwidth(bytesPerSecond int64) Option {
+	return func(o *Options) { o.MaxBandwidth = bytesPerSecond }
+}
+
+// WithFollowSymlinks makes the walk enter symlinked directories.
+func WithFollowSymlinks(follow bool) Option {
+	return func(o *Options) { o.FollowSymlinks = follow }
+}
+
+// WithSymlinks selects what to do with the symlinks found while walking.
+func WithSymlinks(policy SymlinkPolicy) Option {
+	return func(o *Options) { o.Symlinks = policy }
+}
+
+// WithMaxDepth only walks the files at most depth levels below each root, 1 meaning no recursion.
+func WithMaxDepth(depth int) Option {
+	return func(o *Options) { o.MaxDepth = depth }
+}
+
+// WithOneFileSystem keeps the walk on the filesystem of each root, like du -x.
+func WithOneFileSystem(one bool) Option {
+	return func(o *Options) { o.OneFileSystem = one }
+}
+
+// WithStreams also hashes the NTFS alternate data streams of each file found while walking,
+// as FILE:STREAM paths, see Walker.Streams. Windows only.
+func WithStreams(streams bool) Option {
+	return func(o *Options) { o.Streams = streams }
+}
+
+// WithSpecial selects what to do with the named pipes, sockets and devices found while walking.
+func WithSpecial(policy SpecialPolicy) Option {
+	return func(o *Options) { o.Special = policy }
+}
+
+// WithInclude adds patterns that files must match to be hashed.
+func WithInclude(patterns ...string) Option {
+	return func(o *Options) { o.Filter.Include = append(o.Filter.Include, patterns...) }
+}
+
+// WithExclude adds patterns of files and directories to skip.
+func WithExclude(patterns ...string) Option {
+	return func(o *Options) { o.Filter.Exclude = append(o.Filter.Exclude, patterns...) }
+}
+
+// WithExtensions only keeps the files whose name ends with one of these extensions, see PathFilter.Extensions.
+func WithExtensions(extensions ...string) Option {
+	return func(o *Options) { o.Filter.Extensions = append(o.Filter.Extensions, extensions...) }
+}
+
+// WithSizeRange only keeps the files of at least minSize and at most maxSize bytes, a limit < 1 being ignored.
+func WithSizeRange(minSize, maxSize int64) Option {
+	return func(o *Options) { o.Filter.MinSize, o.Filter.MaxSize = minSize, maxSize }
+}
+
+// WithNewerThan only keeps the files modified after t, see ParseNewerThan.
+func WithNewerThan(t time.Time) Option {
+	return func(o *Options) { o.Filter.NewerThan = t }
+}
+
+// WithNoIgnore disables the .hashignore files.
+func WithNoIgnore(noIgnore bool) Option {
+	return func(o *Options) { o.NoIgnore = noIgnore }
+}
+
+// WithLowerCase writes hashes in lowercase hexadecimal.
+func WithLowerCase(lower bool) Option {
+	return func(o *Options) { o.LowerCase = lower }
+}
+
+// WithCache reuses the hashes stored in c for the files whose size and modification time did not change.
+func WithCache(c *HashCache) Option {
+	return func(o *Options) { o.Cache = c }
+}
+
+// WithHMACKey computes HMACs keyed with key instead of plain digests.
+func WithHMACKey(key []byte) Option {
+	return func(o *Options) { o.HMACKey = key }
+}
+
+// WithGitBlob hashes the files like git hashes blobs, giving their object names with SHA1 or SHA256.
+func WithGitBlob(gitBlob bool) Option {
+	return func(o *Options) { o.GitBlob = gitBlob }
+}
+
+// WithMmap memory maps the files of at least threshold bytes instead of reading them, DefaultMmapThreshold
+// being a good start, falling back to reading the files that cannot be mapped. Mapping is only done on unix.
+func WithMmap(threshold int64) Option {
+	return func(o *Options) { o.MmapThreshold = threshold }
+}
+
+// WithFadvise advises the kernel that the files are read sequentially, and drops their pages from the page cache
+// as they are hashed, so scanning large trees does not evict the pages used by other workloads. Linux only.
+func WithFadvise(fadvise bool) Option {
+	return func(o *Options) { o.Fadvise = fadvise }
+}
+
+// WithDirectIO reads the files with O_DIRECT, bypassing the page cache, falling back to reading them through
+// it on the filesystems not supporting O_DIRECT, like tmpfs. Linux only.
+func WithDirectIO(direct bool) Option {
+	return func(o *Options) { o.DirectIO = direct }
+}
+
+// WithTreeChunkSize computes the tree hashes of the files over chunks of size bytes instead of their digests,
+// the chunks of a large file being hashed in parallel. The hashes start with the chunk size, see FormatTreeHash.
+func WithTreeChunkSize(size int64) Option {
+	return func(o *Options) { o.TreeChunkSize = size }
+}
+
+// WithQuickSize computes the quick hashes of the files over their size and their first and last size bytes
+// instead of their digests, for a fast scan finding the files that probably did not change. The hashes
+// start with the sample size, see FormatQuickHash.
+func WithQuickSize(size int64) Option {
+	return func(o *Options) { o.QuickSize = size }
+}
+
+// WithGlacierTree computes the SHA256 tree hashes of AWS Glacier of the files instead of their digests, so they
+// can be compared with the checksums Glacier reports. The hashes start with glacier:, see FormatGlacierHash.
+func WithGlacierTree(glacier bool) Option {
+	return func(o *Options) { o.GlacierTree = glacier }
+}
+
+// WithETagPartSize computes the ETags S3 gives to the files uploaded in parts of size bytes instead of their
+// digests, DefaultPartSize of pkg/s3 being the one of the AWS CLI. The hashes start with the part size, see FormatETag.
+func WithETagPartSize(size int64) Option {
+	return func(o *Options) { o.ETagPartSize = size }
+}
+
+// WithBitTorrentV2 computes the BitTorrent v2 pieces roots of the files instead of their digests, so they can be
+// compared with the file tree of a v2 torrent. The hashes start with btv2:, see FormatBTv2Hash.
+func WithBitTorrentV2(btv2 bool) Option {
+	return func(o *Options) { o.BitTorrentV2 = btv2 }
+}
+
+// WithHardLinks reads the files having several hard links once, their other names getting the same hash,
+// see Result.LinkOf.
+func WithHardLinks(once bool) Option {
+	return func(o *Options) { o.HardLinks = once }
+}
+
+// WithSparse skips the holes of sparse files, like virtual machine disk images, found with SEEK_DATA and
+// SEEK_HOLE: they are hashed as the zeros they read as, without reading them. Linux only.
+func WithSparse(sparse bool) Option {
+	return func(o *Options) { o.Sparse = sparse }
+}
+
+// WithNormalizePaths looks up the files listed in the Unicode normalization form, falling back to the other
+// form, so manifests written on macOS verify the copies of their files on Linux, see Normalization.
+func WithNormalizePaths(form Normalization) Option {
+	return func(o *Options) { o.NormalizePaths = form }
+}
+
+// WithStable reports the files whose size or modification time changed while they were hashed, or that were
+// locked by the program writing them on Windows, with ErrUnstable, after hashing them again up to retries times.
+func WithStable(stable bool, retries int) Option {
+	return func(o *Options) { o.Stable, o.StableRetries = stable, retries }
+}
+
+// Validate checks the algorithm and the filter patterns.
+func (o Options) Validate() error {
+	for _, algorithm := range o.algorithms() {
+		if _, ok := hashPools[algorithm]; !ok {
+			return fmt.Errorf("unsupported hash algorithm %q", algorithm)
+		}
+	}
+	if o.TreeChunkSize > 0 && (len(o.HMACKey) > 0 || o.GitBlob) {
+		return fmt.Errorf("tree hashes cannot be keyed or computed like git blobs")
+	}
+	if o.QuickSize > 0 && (o.TreeChunkSize > 0 || o.GitBlob) {
+		return fmt.Errorf("quick hashes cannot be tree hashes or computed like git blobs")
+	}
+	if own := o.ownAlgorithm(); own > 1 || (own == 1 && (len(o.algorithms()) > 1 || len(o.HMACKey) > 0 || o.GitBlob || o.TreeChunkSize > 0 || o.QuickSize > 0)) {
+		return fmt.Errorf("Glacier tree hashes, S3 ETags and BitTorrent v2 roots have their own algorithm, they cannot be combined with each other, other algorithms, keys, git blobs, tree or quick hashes")
+	}
+	if o.DirectIO && (o.MmapThreshold > 0 || o.TreeChunkSize > 0 || o.QuickSize > 0 || o.ownAlgorithm() > 0) {
+		return fmt.Errorf("direct I/O cannot be used with memory mapping, tree, quick, AWS or BitTorrent hashes")
+	}
+	if _, err := ParseSymlinkPolicy(string(o.Symlinks)); err != nil {
+		return err
+	}
+	if _, err := ParseSpecialPolicy(string(o.Special)); err != nil {
+		return err
+	}
+	return o.Filter.Validate()
+}
+
+// Walker returns a Walker finding the files selected by these options,
+// onError being called for each path that cannot be accessed (it may be nil).
+func (o Options) Walker(onError func(path string, err error)) Walker {
+	special, _ := ParseSpecialPolicy(string(o.Special)) // like -special=ERROR, checked by Validate
+	return Walker{Filter: o.Filter, NoIgnore: o.NoIgnore, Symlinks: o.Symlinks, FollowSymlinks: o.FollowSymlinks,
+		MaxDepth: o.MaxDepth, OneFileSystem: o.OneFileSystem, Streams: o.Streams, Special: special, OnError: onError}
+}
+
+// HashFile returns the hash of the file at path with the number of bytes read,
+// stopping as soon as ctx is cancelled.
+// When o.Cache is set and the file did not change, the cached hash is returned without reading it,
+// the cache being only used without ExtraAlgorithms, HMACKey, GitBlob, TreeChunkSize, QuickSize, the AWS and BitTorrent hashes.
+// With SymlinksRecord, the hash of a symlink is the one of its target path.
+// With Stable, the files that changed while they were read are reported with ErrUnstable.
+func (o Options) HashFile(ctx context.Context, path string) Result {
+	ctx = o.limitBandwidth(ctx)
+	if resolveSymlinks(o.Symlinks, o.FollowSymlinks) == SymlinksRecord {
+		if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return o.hashLink(ctx, path)
+		}
+	}
+	if o.Cache != nil && len(o.algorithms()) == 1 && len(o.HMACKey) == 0 && !o.GitBlob && o.TreeChunkSize < 1 && o.QuickSize < 1 && o.ownAlgorithm() == 0 {
+		hash, size, cached, err := hashFileCached(o.Cache, path, o, func() (string, int64, error) {
+			hashes, size, err := hashFileStable(ctx, path, o)
+			if err != nil {
+				return "", size, err
+			}
+			return hashes[0], size, nil
+		})
+		return Result{Path: path, Hash: hash, Size: size, Cached: cached, Err: err}
+	}
+	hashes, size, err := hashFileStable(ctx, path, o)
+	return o.result(path, hashes, size, err)
+}
+
+// HashReader returns the hash of everything read from r until EOF, with the number of bytes read.
+// The Path of the returned Result is empty. With GitBlob, an error is returned as the size is not known.
+func (o Options) HashReader(ctx context.Context, r io.Reader) Result {
+	if o.GitBlob {
+		return Result{Err: errGitBlobSize}
+	}
+	hashes, size, err := hashReader(o.limitBandwidth(ctx), r, o)
+	return o.result("", hashes, size, err)
+}
+
+// result builds the Result of the file at path from the hashes computed for o.algorithms().
+func (o Options) result(path string, hashes []string, size int64, err error) Result {
+	r := Result{Path: path, Size: size, Err: err}
+	if err != nil || len(hashes) == 0 {
+		return r
+	}
+	r.Hash = hashes[0]
+	if len(hashes) > 1 {
+		r.Hashes = make(map[Algorithm]string, len(hashes))
+		for i, algorithm := range o.algorithms() {
+			r.Hashes[algorithm] = hashes[i]
+		}
+	}
+	return r
+}
+
+// ownAlgorithm returns how many of the hashes having their own algorithm, ignoring the algorithms of o, are selected.
+func (o Options) ownAlgorithm() int {
+	n := 0
+	for _, selected := range []bool{o.GlacierTree, o.ETagPartSize > 0, o.BitTorrentV2} {
+		if selected {
+			n++
+		}
+	}
+	return n
+}
+
+func (o Options) algorithm() Algorithm {
+	if o.Algorithm == "" {
+		return SHA256
+	}
+	return o.Algorithm
+}
+
+// algorithms returns the algorithm followed by the extra ones, without duplicates.
+func (o Options) algorithms() []Algorithm {
+	algorithms := []Algorithm{o.algorithm()}
+	for _, extra := range o.ExtraAlgorithms {
+		if !slices.Contains(algorithms, extra) {
+			algorithms = append(algorithms, extra)
+		}
+	}
+	return algorithms
+}
+
+func (o Options) workers() int {
+	if o.Workers < 1 {
+		return DefaultWorkerCount()
+	}
+	return o.Workers
+}
@@ -0,0 +1,104 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestOptionsHashFile tests the algorithms, buffer sizes and hex case of the options.
+func TestOptionsHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("This is a test file for SHA256 hashing."), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"default", NewOptions(), "B52E9CC162A479840A909B2CFD9D0F1C5D29055A303BB389090236005D87E0E5"},
+		{"lowercase small buffer", NewOptions(WithLowerCase(true), WithBufferSize(7)), "b52e9cc162a479840a909b2cfd9d0f1c5d29055a303bb389090236005d87e0e5"},
+		{"md5", NewOptions(WithAlgorithm(MD5)), mustMD5(t, path)},
+	}
+	for _, tt := range tests {
+		r := tt.opts.HashFile(context.Background(), path)
+		if r.Err != nil {
+			t.Fatalf("%s: HashFile() returned an error: %v", tt.name, r.Err)
+		}
+		if r.Hash != tt.want {
+			t.Errorf("%s: HashFile() = %q, expected %q", tt.name, r.Hash, tt.want)
+		}
+	}
+
+	if _, err := ParseAlgorithm("SHA512"); err != nil {
+		t.Errorf("ParseAlgorithm(SHA512) returned an error: %v", err)
+	}
+	if _, err := ParseAlgorithm("crc64"); err == nil {
+		t.Error("ParseAlgorithm(crc64) did not return an error")
+	}
+	if err := NewOptions(WithAlgorithm("nope")).Validate(); err == nil {
+		t.Error("Validate() did not return an error for an unsupported algorithm")
+	}
+}
+
+func mustMD5(t *testing.T, path string) string {
+	h, err := GetMD5(path)
+	if err != nil {
+		t.Fatalf("GetMD5() returned an error: %v", err)
+	}
+	return h
+}
+
+// TestOptionsExtraAlgorithms tests that several digests are computed in a single read.
+func TestOptionsExtraAlgorithms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("This is a test file for SHA256 hashing."), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	r := NewOptions(WithExtraAlgorithms(MD5, SHA256)).HashFile(context.Background(), path)
+	if r.Err != nil {
+		t.Fatalf("HashFile() returned an error: %v", r.Err)
+	}
+	if r.Hash != "B52E9CC162A479840A909B2CFD9D0F1C5D29055A303BB389090236005D87E0E5" {
+		t.Errorf("HashFile() = %q, expected the SHA256 of the file", r.Hash)
+	}
+	if len(r.Hashes) != 2 || r.Hashes[SHA256] != r.Hash || r.Hashes[MD5] != mustMD5(t, path) {
+		t.Errorf("HashFile() hashes = %v, expected the SHA256 and MD5 of the file", r.Hashes)
+	}
+	if r = NewOptions().HashFile(context.Background(), path); r.Hashes != nil {
+		t.Errorf("HashFile() without extra algorithms filled Hashes: %v", r.Hashes)
+	}
+	if err := NewOptions(WithExtraAlgorithms("crc64")).Validate(); err == nil {
+		t.Error("Validate() did not return an error for an unsupported extra algorithm")
+	}
+}
+
+// TestGetBuffer tests that the buffers of every size are given back to the pool of their size.
+func TestGetBuffer(t *testing.T) {
+	for _, size := range []int{0, DefaultBufferSize, 1 << 20, 12345} {
+		want := size
+		if size < 1 {
+			want = DefaultBufferSize
+		}
+		buf := getBuffer(size)
+		if len(buf) != want {
+			t.Errorf("getBuffer(%d) returned %d bytes, want %d", size, len(buf), want)
+		}
+		putBuffer(buf)
+	}
+	// A buffer of a size asked once is reused instead of allocated for each file, most of the time
+	// with the race detector, which makes sync.Pool drop some of the buffers put back
+	putBuffer(getBuffer(1 << 20))
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range 100 {
+		putBuffer(getBuffer(1 << 20))
+	}
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated >= 50<<20 {
+		t.Errorf("100 getBuffer(1 MiB) allocated %d bytes, want the pooled buffer reused", allocated)
+	}
+}
@@ -0,0 +1,68 @@
+package hasher
+
+import "sync"
+
+// OrderedResults puts the results of the files hashed concurrently back in the order the files were found in,
+// like the order of an explicit list of files, holding the results of the files done before the ones found
+// earlier. A file found several times gets its results in the order they are added.
+type OrderedResults struct {
+	mu      sync.Mutex
+	order   []string // the files found whose result was not returned yet
+	pending map[string][]Result
+}
+
+// NewOrderedResults returns an empty OrderedResults.
+func NewOrderedResults() *OrderedResults {
+	return &OrderedResults{pending: make(map[string][]Result)}
+}
+
+// Found records that the file at path was found, before it is hashed.
+func (o *OrderedResults) Found(path string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.order = append(o.order, path)
+}
+
+// Add adds the result of a file found, returning the results that are now in order, none when files found
+// before it are not done yet.
+func (o *OrderedResults) Add(r Result) []Result {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[r.Path] = append(o.pending[r.Path], r)
+	var ready []Result
+	for len(o.order) > 0 {
+		results := o.pending[o.order[0]]
+		if len(results) == 0 {
+			break
+		}
+		ready = append(ready, results[0])
+		o.pop(o.order[0])
+		o.order = o.order[1:]
+	}
+	return ready
+}
+
+// Rest returns the results still held, in the order their files were found, once no more results come,
+// like when the files found last were not hashed after an interruption.
+func (o *OrderedResults) Rest() []Result {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var rest []Result
+	for _, path := range o.order {
+		if results := o.pending[path]; len(results) > 0 {
+			rest = append(rest, results[0])
+			o.pop(path)
+		}
+	}
+	o.order = nil
+	return rest
+}
+
+// pop removes the first pending result of path.
+func (o *OrderedResults) pop(path string) {
+	if results := o.pending[path]; len(results) > 1 {
+		o.pending[path] = results[1:]
+	} else {
+		delete(o.pending, path)
+	}
+}
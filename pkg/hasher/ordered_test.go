@@ -0,0 +1,48 @@
+package hasher
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestOrderedResults tests that the results are returned in the order the files were found in.
+func TestOrderedResults(t *testing.T) {
+	paths := func(results []Result) []string {
+		var p []string
+		for _, r := range results {
+			p = append(p, r.Path+":"+r.Hash)
+		}
+		return p
+	}
+	o := NewOrderedResults()
+	for _, path := range []string{"c", "a", "b", "a", "d"} {
+		o.Found(path)
+	}
+	steps := []struct {
+		add  Result
+		want []string
+	}{
+		{Result{Path: "a", Hash: "1"}, nil},
+		{Result{Path: "b", Hash: "2"}, nil},
+		{Result{Path: "c", Hash: "3"}, []string{"c:3", "a:1", "b:2"}},
+		{Result{Path: "d", Hash: "4"}, nil},
+		{Result{Path: "a", Hash: "5"}, []string{"a:5", "d:4"}},
+	}
+	for i, step := range steps {
+		if got := paths(o.Add(step.add)); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: Add(%s) = %v, want %v", i, step.add.Path, got, step.want)
+		}
+	}
+
+	// The files found but never done do not hold back the rest
+	o.Found("e")
+	o.Found("f")
+	o.Found("g")
+	if got := o.Add(Result{Path: "g", Hash: "6"}); len(got) != 0 {
+		t.Errorf("Add(g) = %v, want nothing before e", paths(got))
+	}
+	o.Add(Result{Path: "f", Hash: "7"})
+	if got, want := paths(o.Rest()), []string{"f:7", "g:6"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rest() = %v, want %v", got, want)
+	}
+}
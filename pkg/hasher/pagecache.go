@@ -0,0 +1,88 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// Scanning terabytes of files fills the page cache with content read only once, evicting the pages
+// other workloads of the host need. With Options.Fadvise, the kernel is told the files are read
+// sequentially and their pages are dropped as soon as they are hashed. With Options.DirectIO,
+// the files are read with O_DIRECT, bypassing the page cache entirely.
+
+// fadviseDropSize is the amount of content read between two requests to drop it from the page cache.
+const fadviseDropSize = 8 << 20
+
+// directAlign is the alignment of the buffers, offsets and sizes of the O_DIRECT reads, the logical
+// block size of most devices being at most 4 KiB.
+const directAlign = 4096
+
+// directBufferSize is the smallest read size with O_DIRECT, every read going to the device.
+const directBufferSize = 1 << 20
+
+// droppingReader reads a file sequentially, asking the kernel to drop the pages it read from the page cache.
+type droppingReader struct {
+	f             *os.File
+	read, dropped int64
+}
+
+func (dr *droppingReader) Read(p []byte) (int, error) {
+	n, err := dr.f.Read(p)
+	dr.read += int64(n)
+	if dr.read-dr.dropped >= fadviseDropSize {
+		fadvise(dr.f, dr.dropped, dr.read-dr.dropped, fadvDontNeed)
+		dr.dropped = dr.read
+	}
+	return n, err
+}
+
+// adviseFile tells the kernel f is going to be read sequentially, returning the function
+// dropping all its pages from the page cache once it is hashed.
+func adviseFile(f *os.File) func() {
+	fadvise(f, 0, 0, fadvSequential)
+	return func() { fadvise(f, 0, 0, fadvDontNeed) }
+}
+
+// alignedBuffer returns a buffer of size bytes, rounded up to directAlign, starting at a multiple of directAlign.
+func alignedBuffer(size int) []byte {
+	size = (size + directAlign - 1) &^ (directAlign - 1)
+	buf := make([]byte, size+directAlign)
+	offset := (directAlign - int(uintptr(unsafe.Pointer(&buf[0]))&(directAlign-1))) & (directAlign - 1)
+	return buf[offset : offset+size]
+}
+
+// hashDirect works like hashContent for the file at path opened with O_DIRECT as f, read in aligned buffers
+// of at least directBufferSize bytes.
+func hashDirect(ctx context.Context, f *os.File, path string, opts Options) ([]string, int64, error) {
+	w, sums, release, err := newHashWriter(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+	w = cpuLimited(ctx, w)
+	var size int64
+	if opts.GitBlob {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		size = info.Size()
+		io.WriteString(w, gitBlobHeader(size))
+	}
+	bufSize := max(opts.BufferSize, directBufferSize)
+	pool := poolOf(&alignedPools, bufSize, func() []byte { return alignedBuffer(bufSize) })
+	buf := pool.Get().([]byte)
+	defer pool.Put(buf)
+	// The file is the source itself, so every read goes to the aligned buffer
+	n, err := io.CopyBuffer(w, &ctxReader{ctx: ctx, r: throttled(ctx, &offsetReader{path: path, r: f})}, buf)
+	if err != nil {
+		return nil, n, err
+	}
+	if opts.GitBlob && n != size {
+		return nil, n, fmt.Errorf("size changed while hashing, %d bytes read instead of %d", n, size)
+	}
+	return sums(), n, nil
+}
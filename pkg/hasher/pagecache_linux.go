@@ -0,0 +1,11 @@
+package hasher
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirect opens path for reading with O_DIRECT, which some filesystems like tmpfs refuse.
+func openDirect(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDONLY|syscall.O_DIRECT, 0)
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package hasher
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// openDirect is not available on this platform, the files are read through the page cache.
+func openDirect(path string) (*os.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: path, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,56 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"unsafe"
+)
+
+// TestAlignedBuffer tests that the O_DIRECT buffers are aligned and rounded up.
+func TestAlignedBuffer(t *testing.T) {
+	for _, size := range []int{1, directAlign, directBufferSize + 1} {
+		buf := alignedBuffer(size)
+		if uintptr(unsafe.Pointer(&buf[0]))%directAlign != 0 || len(buf)%directAlign != 0 || len(buf) < size {
+			t.Errorf("alignedBuffer(%d) has %d bytes at %p", size, len(buf), &buf[0])
+		}
+	}
+}
+
+// TestPageCacheOptions tests that advising the kernel or reading with O_DIRECT gives the same hashes,
+// the temporary directory falling back to reading through the page cache when it is on tmpfs.
+func TestPageCacheOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.bin")
+	content := bytes.Repeat([]byte("0123456789abcdef"), (fadviseDropSize+directBufferSize)/16+1)
+	if err := os.WriteFile(path, content[:len(content)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, gitBlob := range []bool{false, true} {
+		want := NewOptions(WithGitBlob(gitBlob)).HashFile(ctx, path)
+		for name, opts := range map[string]Options{
+			"fadvise": NewOptions(WithGitBlob(gitBlob), WithFadvise(true)),
+			"direct":  NewOptions(WithGitBlob(gitBlob), WithDirectIO(true)),
+			"both":    NewOptions(WithGitBlob(gitBlob), WithDirectIO(true), WithFadvise(true)),
+		} {
+			if got := opts.HashFile(ctx, path); got.Err != nil || got.Hash != want.Hash || got.Size != want.Size {
+				t.Errorf("%s, git blob %v: HashFile() = %s, %d, %v, want %s, %d", name, gitBlob, got.Hash, got.Size, got.Err, want.Hash, want.Size)
+			}
+		}
+		// hashDirect reads files opened without O_DIRECT too, whatever their filesystem
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes, size, err := hashDirect(ctx, f, path, NewOptions(WithGitBlob(gitBlob)))
+		f.Close()
+		if err != nil || hashes[0] != want.Hash || size != want.Size {
+			t.Errorf("git blob %v: hashDirect() = %v, %d, %v, want %s", gitBlob, hashes, size, err, want.Hash)
+		}
+	}
+	if err := NewOptions(WithDirectIO(true), WithMmap(DefaultMmapThreshold)).Validate(); err == nil {
+		t.Error("Validate() should refuse direct I/O with memory mapping")
+	}
+}
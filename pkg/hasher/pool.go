@@ -0,0 +1,136 @@
+package hasher
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result holds the outcome of hashing a single file.
+type Result struct {
+	Path string // The file path being processed
+	Hash string // The calculated hash, empty when Err is not nil
+	Size int64  // The number of bytes read from the file
+	Err  error  // Any error encountered
+	// Hashes holds every digest, keyed by algorithm, when Options.ExtraAlgorithms is set
+	Hashes map[Algorithm]string
+	// Cached is true when Hash comes from Options.Cache, the file was then not read
+	Cached bool
+	// LinkOf is the path of the file this one is a hard link to, already hashed with Options.HardLinks,
+	// the file was then not read
+	LinkOf string
+	// Elapsed is the time taken to hash the file, set by the worker pools
+	Elapsed time.Duration
+}
+
+// HashFiles hashes the files received on paths using at most workers concurrent goroutines
+// and sends a Result for each of them on the returned channel. The channel is closed once
+// paths is closed and all pending files are done, or once ctx is cancelled: files not
+// started yet are then dropped and the ones in progress are reported with ctx.Err().
+func HashFiles(ctx context.Context, paths <-chan string, workers int) <-chan Result {
+	if workers < 1 {
+		workers = 1
+	}
+	return Options{Workers: workers}.HashFiles(ctx, paths)
+}
+
+// HashFiles works like the HashFiles function, hashing as described by o with o.Workers goroutines.
+// With o.HardLinks, the files having several hard links are only read once, see Result.LinkOf.
+func (o Options) HashFiles(ctx context.Context, paths <-chan string) <-chan Result {
+	return o.hashPaths(ctx, paths, o.hashLocal())
+}
+
+// hashLocal returns the function hashing the local files for the worker pools, reading the hard links
+// of a file only once with o.HardLinks.
+func (o Options) hashLocal() func(ctx context.Context, path string) Result {
+	if o.HardLinks {
+		return hashLinksOnce(o.HashFile)
+	}
+	return o.HashFile
+}
+
+// hashPaths runs the worker pool of HashFiles, each path being hashed with hashOne.
+// The workers read the files concurrently, but only o.CPUWorkers of them compute digests at the same time
+// and all of them read at most o.MaxBandwidth bytes per second.
+func (o Options) hashPaths(ctx context.Context, paths <-chan string, hashOne func(ctx context.Context, path string) Result) <-chan Result {
+	ctx = o.limitBandwidth(o.limitCPU(ctx))
+	workers := o.workers()
+	results := make(chan Result, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case path, ok := <-paths:
+					if !ok {
+						return
+					}
+					start := time.Now()
+					result := hashOne(ctx, path)
+					result.Elapsed = time.Since(start)
+					results <- result
+				}
+			}
+		}()
+	}
+	// Close the result channel after all workers finish
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// HashTree walks root with w and hashes every file found using up to workers goroutines.
+// fn is called from a single goroutine with each Result as soon as it is available,
+// so results arrive in completion order, not in walk order.
+// It returns ctx.Err() when ctx is cancelled before the whole tree is processed.
+func HashTree(ctx context.Context, root string, w Walker, workers int, fn func(Result)) error {
+	if workers < 1 {
+		workers = 1
+	}
+	return Options{Workers: workers, Symlinks: w.symlinks()}.HashTree(ctx, root, w, fn)
+}
+
+// HashTree works like the HashTree function, hashing as described by o with o.Workers goroutines.
+// The files are found by w, which is usually built with o.Walker.
+func (o Options) HashTree(ctx context.Context, root string, w Walker, fn func(Result)) error {
+	walk := func(ctx context.Context, found func(path string) error) error {
+		return w.Walk(ctx, root, found)
+	}
+	return o.hashTree(ctx, walk, o.hashLocal(), fn)
+}
+
+// hashTree streams the paths found by walk to the worker pool, each path being hashed with hashOne.
+func (o Options) hashTree(ctx context.Context, walk func(ctx context.Context, found func(path string) error) error,
+	hashOne func(ctx context.Context, path string) Result, fn func(Result)) error {
+	workers := o.workers()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	paths := make(chan string, workers)
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(paths)
+		walkErr <- walk(ctx, func(path string) error {
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	for result := range o.hashPaths(ctx, paths, hashOne) {
+		fn(result)
+	}
+	if err := <-walkErr; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,76 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestHashTree tests that a directory tree is walked with its filters and every file is hashed.
+func TestHashTree(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":           "This is a test file for SHA256 hashing.",
+		"sub/b.txt":       "This is a test file for SHA256 hashing.",
+		"sub/skip.tmp":    "temporary",
+		"cache/c.txt":     "cached",
+		IgnoreFileName:    "cache/\n",
+		"sub/deeper/d.md": "markdown",
+	}
+	for rel, content := range files {
+		full := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatalf("Failed to create directory for %q: %v", rel, err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %q: %v", rel, err)
+		}
+	}
+
+	w := Walker{Filter: PathFilter{Exclude: []string{"*.tmp"}}}
+	var got []string
+	err := HashTree(context.Background(), root, w, 3, func(r Result) {
+		if r.Err != nil {
+			t.Errorf("Unexpected error for %s: %v", r.Path, r.Err)
+		}
+		rel, _ := filepath.Rel(root, r.Path)
+		got = append(got, filepath.ToSlash(rel))
+		if rel == "a.txt" && r.Hash != "B52E9CC162A479840A909B2CFD9D0F1C5D29055A303BB389090236005D87E0E5" {
+			t.Errorf("Unexpected hash %s for %s", r.Hash, rel)
+		}
+	})
+	if err != nil {
+		t.Fatalf("HashTree() returned an error: %v", err)
+	}
+	sort.Strings(got)
+	expected := []string{IgnoreFileName, "a.txt", "sub/b.txt", "sub/deeper/d.md"}
+	if len(got) != len(expected) {
+		t.Fatalf("HashTree() reported %v, expected %v", got, expected)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("HashTree() reported %v, expected %v", got, expected)
+			break
+		}
+	}
+}
+
+// TestHashTreeCancelled tests that a cancelled context stops the processing with its error.
+func TestHashTreeCancelled(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("content"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := HashTree(ctx, root, Walker{}, 2, func(r Result) {})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("HashTree() with a cancelled context returned %v, expected %v", err, context.Canceled)
+	}
+	if _, err := GetSHA256Ctx(ctx, filepath.Join(root, "a.txt")); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetSHA256Ctx() with a cancelled context returned %v, expected %v", err, context.Canceled)
+	}
+}
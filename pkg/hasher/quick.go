@@ -0,0 +1,129 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// quickPrefix starts the quick hashes, followed by the sample size and a colon, like quick16M:HEX.
+const quickPrefix = "quick"
+
+// DefaultQuickSize is the size of the samples hashed at the start and at the end of the files by the quick hashes.
+const DefaultQuickSize = 16 << 20
+
+// The quick hash of a content of size bytes is the digest of size as a big endian uint64, followed by
+// its first and its last Options.QuickSize bytes, or by the whole content when it is not larger than
+// twice QuickSize. Only reading the start and the end of the files, it is a fast way to find the files
+// that probably did not change in huge archives, like videos, but says nothing about the rest of them.
+
+// FormatQuickHash returns the quick hash hex computed with samples of sampleSize bytes, the sample size
+// being written before it like FormatTreeHash does, so quick hashes are never mistaken for digests.
+func FormatQuickHash(sampleSize int64, hex string) string {
+	return quickPrefix + formatBlockSize(sampleSize) + ":" + hex
+}
+
+// ParseQuickHash returns the sample size written by FormatQuickHash before the hexadecimal digits of hash,
+// in any case. ok is false when hash is not a quick hash.
+func ParseQuickHash(hash string) (sampleSize int64, ok bool) {
+	if len(hash) < len(quickPrefix) || !strings.EqualFold(hash[:len(quickPrefix)], quickPrefix) {
+		return 0, false
+	}
+	size, hex, found := strings.Cut(hash[len(quickPrefix):], ":")
+	if !found || !isHexString(hex) {
+		return 0, false
+	}
+	return parseBlockSize(size)
+}
+
+// isQuickHash reports whether s is a quick hash written by FormatQuickHash.
+func isQuickHash(s string) bool {
+	_, ok := ParseQuickHash(s)
+	return ok
+}
+
+// hashQuickFile computes the quick hashes of the size bytes of r, reading only its samples.
+func hashQuickFile(ctx context.Context, r io.ReaderAt, size int64, opts Options) ([]string, int64, error) {
+	sample := opts.QuickSize
+	if size <= 2*sample {
+		return hashQuickSamples(ctx, size, opts, throttled(ctx, io.NewSectionReader(r, 0, size)))
+	}
+	return hashQuickSamples(ctx, size, opts, throttled(ctx, io.NewSectionReader(r, 0, sample)), throttled(ctx, io.NewSectionReader(r, size-sample, sample)))
+}
+
+// hashQuickReader computes the quick hashes of everything read from r, keeping its first and its last
+// samples in memory as the size is only known at the end.
+func hashQuickReader(ctx context.Context, r io.Reader, opts Options) ([]string, int64, error) {
+	sample := int(opts.QuickSize)
+	head := make([]byte, 0, sample)
+	tail := make([]byte, sample) // ring buffer of the last bytes read after the head
+	var tailPos, tailLen int
+	buf := bufferPool.Get().([]byte)
+	defer bufferPool.Put(buf)
+	var total int64
+	cr := &ctxReader{ctx: ctx, r: throttled(ctx, r)}
+	for {
+		n, err := cr.Read(buf)
+		total += int64(n)
+		p := buf[:n]
+		if room := sample - len(head); room > 0 {
+			k := min(room, len(p))
+			head, p = append(head, p[:k]...), p[k:]
+		}
+		if len(p) > sample {
+			p = p[len(p)-sample:]
+		}
+		for len(p) > 0 {
+			k := copy(tail[tailPos:], p)
+			tailPos, tailLen, p = (tailPos+k)%sample, min(tailLen+k, sample), p[k:]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, total, err
+		}
+	}
+	// The bytes read after the head, or the last sample of a large content, in order
+	start := (tailPos - tailLen + sample) % sample
+	last := append(make([]byte, 0, tailLen), tail[start:min(start+tailLen, sample)]...)
+	if start+tailLen > sample {
+		last = append(last, tail[:tailPos]...)
+	}
+	return hashQuickSamples(ctx, total, opts, bytes.NewReader(head), bytes.NewReader(last))
+}
+
+// hashQuickSamples computes the quick hashes of a content of size bytes from its samples, the whole
+// content for a small one.
+func hashQuickSamples(ctx context.Context, size int64, opts Options, samples ...io.Reader) ([]string, int64, error) {
+	w, sums, release, err := newHashWriter(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+	w = cpuLimited(ctx, w)
+	var header [8]byte
+	binary.BigEndian.PutUint64(header[:], uint64(size))
+	w.Write(header[:])
+	buf := bufferPool.Get().([]byte)
+	defer bufferPool.Put(buf)
+	var n int64
+	for _, r := range samples {
+		copied, err := io.CopyBuffer(w, &ctxReader{ctx: ctx, r: r}, buf)
+		n += copied
+		if err != nil {
+			return nil, n, err
+		}
+	}
+	if n != min(size, 2*opts.QuickSize) {
+		return nil, n, fmt.Errorf("size changed while hashing, %d bytes sampled instead of %d", n, min(size, 2*opts.QuickSize))
+	}
+	hashes := sums()
+	for i, hash := range hashes {
+		hashes[i] = FormatQuickHash(opts.QuickSize, hash)
+	}
+	return hashes, size, nil
+}
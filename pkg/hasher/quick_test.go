@@ -0,0 +1,57 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestQuickHash tests the quick hashes of files and readers against the documented construction,
+// for contents smaller and larger than the samples and than the read buffer.
+func TestQuickHash(t *testing.T) {
+	const sample = 1024
+	content := make([]byte, 3*DefaultBufferSize+7)
+	for i := range content {
+		content[i] = byte(i * 7)
+	}
+	dir := t.TempDir()
+	opts := NewOptions(WithQuickSize(sample))
+	for _, size := range []int{0, 100, sample, 2 * sample, 2*sample + 1, DefaultBufferSize + sample/2, len(content)} {
+		data := content[:size]
+		sampled := data
+		if size > 2*sample {
+			sampled = append(append([]byte(nil), data[:sample]...), data[size-sample:]...)
+		}
+		var header [8]byte
+		binary.BigEndian.PutUint64(header[:], uint64(size))
+		want := fmt.Sprintf("quick1K:%X", sha256.Sum256(append(header[:], sampled...)))
+
+		path := filepath.Join(dir, fmt.Sprintf("file%d", size))
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if got := opts.HashFile(context.Background(), path); got.Err != nil || got.Hash != want || got.Size != int64(size) {
+			t.Errorf("HashFile() of %d bytes = %q, %d, %v, expected %q", size, got.Hash, got.Size, got.Err, want)
+		}
+		if got := opts.HashReader(context.Background(), bytes.NewReader(data)); got.Err != nil || got.Hash != want || got.Size != int64(size) {
+			t.Errorf("HashReader() of %d bytes = %q, %d, %v, expected %q", size, got.Hash, got.Size, got.Err, want)
+		}
+	}
+
+	if sampleSize, ok := ParseQuickHash("QUICK16m:0aF"); !ok || sampleSize != 16<<20 {
+		t.Errorf("ParseQuickHash() = %d, %v, expected %d", sampleSize, ok, 16<<20)
+	}
+	for _, hash := range []string{"tree16M:0AF", "0AF", "quick:0AF", "quick16M:XYZ"} {
+		if _, ok := ParseQuickHash(hash); ok {
+			t.Errorf("ParseQuickHash(%q) accepted a hash that is not a quick hash", hash)
+		}
+	}
+	if err := NewOptions(WithQuickSize(sample), WithTreeChunkSize(sample)).Validate(); err == nil {
+		t.Error("Validate() should refuse quick tree hashes")
+	}
+}
@@ -0,0 +1,53 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReadError is the error of a file opened but failing to be read, with the offset of the read that failed.
+// On a local disk, it is most often an I/O error of bad sectors, the offset telling where they are in the file.
+type ReadError struct {
+	Path   string
+	Offset int64 // the offset in the file of the first byte that could not be read
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("read error at offset %d: %v", e.Offset, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// offsetReader reads the file at path sequentially from r, returning its read errors as a *ReadError.
+type offsetReader struct {
+	path   string
+	r      io.Reader
+	offset int64
+}
+
+func (or *offsetReader) Read(p []byte) (int, error) {
+	n, err := or.r.Read(p)
+	or.offset += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = &ReadError{Path: or.path, Offset: or.offset, Err: err}
+	}
+	return n, err
+}
+
+// offsetReaderAt reads the file at path from r, returning its read errors as a *ReadError.
+type offsetReaderAt struct {
+	path string
+	r    io.ReaderAt
+}
+
+func (ora *offsetReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := ora.r.ReadAt(p, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = &ReadError{Path: ora.path, Offset: off + int64(n), Err: err}
+	}
+	return n, err
+}
@@ -0,0 +1,53 @@
+package hasher
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// failingReaderAt returns err for the reads past size.
+type failingReaderAt struct {
+	size int64
+	err  error
+}
+
+func (f failingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := int(max(min(int64(len(p)), f.size-off), 0))
+	clear(p[:n])
+	if n < len(p) {
+		return n, f.err
+	}
+	return n, nil
+}
+
+// TestReadError tests that the read errors of a file tell where they failed, EOF being left alone.
+func TestReadError(t *testing.T) {
+	errBadSector := errors.New("input/output error")
+	r := &offsetReader{path: "disk.img", r: io.MultiReader(strings.NewReader("0123456789"), iotest.ErrReader(errBadSector))}
+	n, err := io.Copy(io.Discard, r)
+	var readErr *ReadError
+	if !errors.As(err, &readErr) || readErr.Path != "disk.img" || readErr.Offset != 10 || n != 10 {
+		t.Fatalf("Copy() = %d, %v, want 10 bytes and a read error at offset 10", n, err)
+	}
+	if !errors.Is(err, errBadSector) || err.Error() != "read error at offset 10: input/output error" {
+		t.Errorf("error %q does not wrap %q", err, errBadSector)
+	}
+	if _, err := io.Copy(io.Discard, &offsetReader{path: "ok", r: strings.NewReader("content")}); err != nil {
+		t.Errorf("Copy() of a readable file = %v, want no error", err)
+	}
+
+	ra := &offsetReaderAt{path: "disk.img", r: failingReaderAt{size: 5000, err: errBadSector}}
+	buf := make([]byte, 4096)
+	if _, err := ra.ReadAt(buf, 0); err != nil {
+		t.Errorf("ReadAt(0) = %v, want no error", err)
+	}
+	if _, err := ra.ReadAt(buf, 4096); !errors.As(err, &readErr) || readErr.Offset != 5000 {
+		t.Errorf("ReadAt(4096) = %v, want a read error at offset 5000", err)
+	}
+	if _, err := (&offsetReaderAt{r: strings.NewReader("short")}).ReadAt(buf, 0); err != io.EOF {
+		t.Errorf("ReadAt() past the end = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,56 @@
+package hasher
+
+import (
+	"hash/maphash"
+	"path/filepath"
+)
+
+// repeatBits and repeatProbes size a RepeatFilter: 10 bits and 7 probes per path miss about 1% of the
+// paths listed once, which are then merged like the repeated ones.
+const (
+	repeatBits   = 10
+	repeatProbes = 7
+)
+
+// RepeatFilter finds the paths listed several times by a manifest too large to keep its paths in memory.
+// A first reading of the manifest records its paths with Add, in a Bloom filter of about 10 bits per path,
+// and the paths Add found again, with rare paths listed once, are the only ones Repeated reports: only
+// those need to be given to an EntryMerger during a second reading. It is not safe for concurrent use.
+type RepeatFilter struct {
+	seed     maphash.Seed
+	bits     []uint64
+	repeated map[string]bool
+}
+
+// NewRepeatFilter returns a RepeatFilter sized for about n paths. More paths are still found, with more
+// paths listed once reported as repeated.
+func NewRepeatFilter(n int) *RepeatFilter {
+	words := max(n*repeatBits/64, 1)
+	return &RepeatFilter{seed: maphash.MakeSeed(), bits: make([]uint64, words), repeated: make(map[string]bool)}
+}
+
+// Add records path, compared after filepath.Clean.
+func (f *RepeatFilter) Add(path string) {
+	path = filepath.Clean(path)
+	h := maphash.String(f.seed, path)
+	// The probes are derived from the two halves of the hash, h1 + i*h2
+	h1, h2 := h&0xffffffff, h>>32|1
+	m := uint64(len(f.bits)) * 64
+	seen := true
+	for i := range uint64(repeatProbes) {
+		bit := (h1 + i*h2) % m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			seen = false
+			f.bits[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	if seen {
+		f.repeated[path] = true
+	}
+}
+
+// Repeated reports whether path may be listed several times: always when it was added again, and
+// rarely when it was added once.
+func (f *RepeatFilter) Repeated(path string) bool {
+	return f.repeated[filepath.Clean(path)]
+}
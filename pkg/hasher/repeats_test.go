@@ -0,0 +1,31 @@
+package hasher
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestRepeatFilter tests that every path added again is reported as repeated, and few of the other ones.
+func TestRepeatFilter(t *testing.T) {
+	const n = 100_000
+	f := NewRepeatFilter(n)
+	for i := range n {
+		f.Add(fmt.Sprintf("dir/file-%d.txt", i))
+	}
+	f.Add("./dir/file-42.txt")
+	f.Add("dir//file-4242.txt")
+	for _, path := range []string{"dir/file-42.txt", "dir/file-4242.txt", "./dir//file-42.txt"} {
+		if !f.Repeated(path) {
+			t.Errorf("Repeated(%q) = false, want true", path)
+		}
+	}
+	falsePositives := 0
+	for i := range n {
+		if i != 42 && i != 4242 && f.Repeated(fmt.Sprintf("dir/file-%d.txt", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > n/20 {
+		t.Errorf("Repeated() reported %d of %d paths added once, want at most %d", falsePositives, n, n/20)
+	}
+}
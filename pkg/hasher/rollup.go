@@ -0,0 +1,83 @@
+package hasher
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DirSummary aggregates the files of a directory and of its subdirectories.
+type DirSummary struct {
+	Path  string // slash separated, "." for the files listed without a directory
+	Files int
+	Bytes int64
+	// The SHA-256 of the DirHash listing of the files below Path, relative to it: for SHA-256 file
+	// hashes, it is the DirHash of the directory
+	Hash []byte
+}
+
+// SummarizeDirs returns the summaries of the directories of the files hashed, sorted by path, every
+// directory above a file being summarized up to the top one, "." for relative paths or "/".
+// It localizes which subtree of a large manifest changed, the hashes of the other ones being the same.
+func SummarizeDirs(results []Result) ([]DirSummary, error) {
+	type dirState struct {
+		summary DirSummary
+		listing hash.Hash
+	}
+	files := make([]Result, 0, len(results))
+	for _, r := range results {
+		if r.Err == nil {
+			r.Path = filepath.ToSlash(r.Path)
+			files = append(files, r)
+		}
+	}
+	// In path order, the files of each directory are listed in the order of their relative paths
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	dirs := make(map[string]*dirState)
+	for _, f := range files {
+		if strings.Contains(f.Path, "\n") {
+			return nil, fmt.Errorf("dirhash: filenames with newlines are not supported: %q", f.Path)
+		}
+		for dir := f.Path; dir != "." && dir != "/"; {
+			dir = parentDir(dir)
+			state, ok := dirs[dir]
+			if !ok {
+				state = &dirState{summary: DirSummary{Path: dir}, listing: sha256.New()}
+				dirs[dir] = state
+			}
+			rel := f.Path
+			switch dir {
+			case ".":
+			case "/":
+				rel = f.Path[1:]
+			default:
+				rel = f.Path[len(dir)+1:]
+			}
+			state.summary.Files++
+			state.summary.Bytes += f.Size
+			fmt.Fprintf(state.listing, "%s  %s\n", strings.ToLower(f.Hash), rel)
+		}
+	}
+	summaries := make([]DirSummary, 0, len(dirs))
+	for _, state := range dirs {
+		state.summary.Hash = state.listing.Sum(nil)
+		summaries = append(summaries, state.summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Path < summaries[j].Path })
+	return summaries, nil
+}
+
+// parentDir returns the directory of the slash separated path, "." or "/" at the top.
+func parentDir(path string) string {
+	switch i := strings.LastIndex(path, "/"); i {
+	case -1:
+		return "."
+	case 0:
+		return "/"
+	default:
+		return path[:i]
+	}
+}
@@ -0,0 +1,57 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSummarizeDirs tests the counts of each directory and that their digests are the DirHash of the directories.
+func TestSummarizeDirs(t *testing.T) {
+	root := t.TempDir()
+	for rel, content := range map[string]string{"a.txt": "a", "sub/b.txt": "bb", "sub/deep/c.txt": "ccc", "sub-x/d.txt": "dddd"} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var results []Result
+	if err := HashTree(context.Background(), root, Walker{}, 2, func(r Result) {
+		rel, _ := filepath.Rel(root, r.Path)
+		r.Path = rel
+		results = append(results, r)
+	}); err != nil {
+		t.Fatalf("HashTree() returned %v", err)
+	}
+
+	summaries, err := SummarizeDirs(results)
+	if err != nil {
+		t.Fatalf("SummarizeDirs() returned %v", err)
+	}
+	want := []DirSummary{{Path: ".", Files: 4, Bytes: 10}, {Path: "sub", Files: 2, Bytes: 5}, {Path: "sub-x", Files: 1, Bytes: 4}, {Path: "sub/deep", Files: 1, Bytes: 3}}
+	if len(summaries) != len(want) {
+		t.Fatalf("SummarizeDirs() = %+v, expected %d directories", summaries, len(want))
+	}
+	for i, s := range summaries {
+		if s.Path != want[i].Path || s.Files != want[i].Files || s.Bytes != want[i].Bytes {
+			t.Errorf("SummarizeDirs()[%d] = %+v, expected %+v", i, s, want[i])
+		}
+		dirHash, err := DirHash(context.Background(), filepath.Join(root, filepath.FromSlash(s.Path)), Walker{}, 2)
+		if err != nil {
+			t.Fatalf("DirHash() returned %v", err)
+		}
+		if !bytes.Equal(s.Hash, dirHash) {
+			t.Errorf("SummarizeDirs() hash of %s = %X, expected its DirHash %X", s.Path, s.Hash, dirHash)
+		}
+	}
+
+	absolute, err := SummarizeDirs([]Result{{Path: "/srv/a", Hash: "AA", Size: 1}})
+	if err != nil || len(absolute) != 2 || absolute[0].Path != "/" || absolute[1].Path != "/srv" {
+		t.Errorf("SummarizeDirs() = %+v, %v, expected / and /srv", absolute, err)
+	}
+}
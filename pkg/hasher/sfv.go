@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"bufio"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// SFVExt is the extension of Simple File Verification files, which list the CRC32 of files.
+const SFVExt = ".sfv"
+
+// IsSFV reports whether the file at path is an SFV file, judging by its extension.
+func IsSFV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), SFVExt)
+}
+
+// FormatSFVLine returns the line of a file in an SFV file: its path, a space and its CRC32 in uppercase.
+// Paths are written with forward slashes, as most SFV tools accept them.
+func FormatSFVLine(hash, path string) string {
+	return filepath.ToSlash(path) + " " + strings.ToUpper(hash) + "\n"
+}
+
+// ParseSFV parses an SFV file, ignoring its ; comments. The CRC32 being the last field, file names may
+// contain spaces, and the backslashes written by Windows tools are taken as path separators.
+// Lines without a file name and an 8 digit hexadecimal CRC32 are returned as malformed.
+func ParseSFV(reader io.Reader) ([]FileEntry, []MalformedLine, error) {
+	scanner := bufio.NewScanner(reader)
+	var entries []FileEntry
+	var malformed []MalformedLine
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		i := strings.LastIndexAny(line, " \t")
+		if i < 0 {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+			continue
+		}
+		name, crc := strings.TrimSpace(line[:i]), line[i+1:]
+		if name == "" || len(crc) != 8 || !isHexString(crc) {
+			malformed = append(malformed, MalformedLine{LineNumber: lineNumber, Text: line})
+			continue
+		}
+		entries = append(entries, FileEntry{
+			Hash:     strings.ToUpper(crc),
+			FilePath: filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")),
+		})
+	}
+	return entries, malformed, scanner.Err()
+}
@@ -0,0 +1,45 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSFV tests the CRC32 of a file and the parsing of the SFV line written for it.
+func TestSFV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disc 1.iso")
+	if err := os.WriteFile(path, []byte("123456789"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	result := NewOptions(WithAlgorithm(CRC32)).HashFile(context.Background(), path)
+	if result.Err != nil || result.Hash != "CBF43926" { // the check value of CRC-32/IEEE
+		t.Fatalf("HashFile() with CRC32 = %q, %v, expected CBF43926", result.Hash, result.Err)
+	}
+	line := FormatSFVLine(strings.ToLower(result.Hash), "music/disc 1.iso")
+	if line != "music/disc 1.iso CBF43926\n" {
+		t.Errorf("FormatSFVLine() = %q", line)
+	}
+	if !IsSFV("album.SFV") || IsSFV("album.sha256") {
+		t.Error("IsSFV() does not recognize SFV files by their extension")
+	}
+
+	content := "; Generated by a Windows tool\r\n" + line + `cd 2\track 01.flac 0a1b2c3d` + "\nno-crc.txt\nbad.txt XYZ\n"
+	entries, malformed, err := ParseSFV(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ParseSFV() returned an error: %v", err)
+	}
+	expected := []FileEntry{
+		{Hash: "CBF43926", FilePath: filepath.FromSlash("music/disc 1.iso")},
+		{Hash: "0A1B2C3D", FilePath: filepath.FromSlash("cd 2/track 01.flac")},
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("ParseSFV() = %+v, expected %+v", entries, expected)
+	}
+	if len(malformed) != 2 || malformed[0].LineNumber != 4 || malformed[1].LineNumber != 5 {
+		t.Errorf("ParseSFV() malformed lines = %+v, expected lines 4 and 5", malformed)
+	}
+}
@@ -0,0 +1,42 @@
+package hasher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SidecarExt returns the extension of the sidecar files holding the digests of algorithm, like .sha256.
+func SidecarExt(algorithm Algorithm) string {
+	return "." + string(algorithm)
+}
+
+// IsSidecar reports whether the file at path is a sidecar file of algorithm, judging by its extension.
+func IsSidecar(path string, algorithm Algorithm) bool {
+	return strings.HasSuffix(path, SidecarExt(algorithm))
+}
+
+// WriteSidecar writes hash in the sha256sum format to the sidecar file of the file at path, path.sha256
+// for SHA256, which can be checked with sha256sum -c from the directory of the file.
+func WriteSidecar(path, hash string, algorithm Algorithm) error {
+	line := FormatLine(hash, filepath.Base(path), false)
+	return os.WriteFile(path+SidecarExt(algorithm), []byte(line), 0o644)
+}
+
+// ReadSidecar parses the sidecar file at path, the relative paths of its entries being
+// made relative to the current directory like the sidecar path itself.
+func ReadSidecar(path string) ([]FileEntry, []MalformedLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	entries, malformed, err := ParseHashFileDetailed(f)
+	dir := filepath.Dir(path)
+	for i, e := range entries {
+		if !filepath.IsAbs(e.FilePath) {
+			entries[i].FilePath = filepath.Join(dir, e.FilePath)
+		}
+	}
+	return entries, malformed, err
+}
@@ -0,0 +1,31 @@
+package hasher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSidecar tests that a written sidecar is read back with the path of the file next to it.
+func TestSidecar(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "clip.mov")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	const abcSHA256 = "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+	if err := WriteSidecar(path, abcSHA256, SHA256); err != nil {
+		t.Fatalf("WriteSidecar() returned an error: %v", err)
+	}
+	content, err := os.ReadFile(path + ".sha256")
+	if err != nil || string(content) != abcSHA256+"  clip.mov\n" {
+		t.Fatalf("sidecar content = %q, %v, expected the sha256sum line of clip.mov", content, err)
+	}
+	if !IsSidecar(path+".sha256", SHA256) || IsSidecar(path, SHA256) {
+		t.Error("IsSidecar() does not recognize the sidecar by its extension")
+	}
+	entries, malformed, err := ReadSidecar(path + ".sha256")
+	if err != nil || len(malformed) != 0 || len(entries) != 1 || entries[0].FilePath != path || entries[0].Hash != abcSHA256 {
+		t.Errorf("ReadSidecar() = %+v, %+v, %v, expected one entry for %s", entries, malformed, err, path)
+	}
+}
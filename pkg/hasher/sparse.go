@@ -0,0 +1,57 @@
+package hasher
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// sparseReader reads a sparse file, the holes being read as zeros without reading them from f.
+type sparseReader struct {
+	f    *os.File
+	size int64 // the size of the file when it was opened
+	off  int64
+	end  int64 // the end of the data or of the hole at off
+	hole bool
+}
+
+func (s *sparseReader) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		return 0, io.EOF
+	}
+	if s.off >= s.end {
+		if err := s.nextRegion(); err != nil {
+			return 0, err
+		}
+	}
+	p = p[:min(int64(len(p)), s.end-s.off)]
+	if s.hole {
+		clear(p)
+		s.off += int64(len(p))
+		return len(p), nil
+	}
+	n, err := s.f.ReadAt(p, s.off)
+	s.off += int64(n)
+	if errors.Is(err, io.EOF) && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// nextRegion finds whether s.off is in a hole or in data, and where it ends.
+func (s *sparseReader) nextRegion() error {
+	data, err := seekData(s.f, s.off)
+	if err != nil {
+		return err
+	}
+	if data > s.off {
+		s.hole, s.end = true, min(data, s.size)
+		return nil
+	}
+	hole, err := seekHole(s.f, s.off)
+	if err != nil {
+		return err
+	}
+	s.hole, s.end = false, min(hole, s.size)
+	return nil
+}
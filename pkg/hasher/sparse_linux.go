@@ -0,0 +1,41 @@
+//go:build linux
+
+package hasher
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// The whence values of lseek finding the data and the holes of sparse files.
+const (
+	seekDataWhence = 3 // SEEK_DATA
+	seekHoleWhence = 4 // SEEK_HOLE
+)
+
+// isSparse reports whether the file described by info uses less blocks than its size, so it has holes.
+func isSparse(info fs.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && info.Mode().IsRegular() && st.Blocks*512 < info.Size()
+}
+
+// seekData returns the offset of the first data byte of f at or after offset, the size of f when only a hole follows.
+func seekData(f *os.File, offset int64) (int64, error) {
+	data, err := f.Seek(offset, seekDataWhence)
+	if errors.Is(err, syscall.ENXIO) {
+		// No data after offset
+		info, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return max(info.Size(), offset), nil
+	}
+	return data, err
+}
+
+// seekHole returns the offset of the first hole of f at or after offset, the end of the file being a hole.
+func seekHole(f *os.File, offset int64) (int64, error) {
+	return f.Seek(offset, seekHoleWhence)
+}
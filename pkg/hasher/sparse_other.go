@@ -0,0 +1,18 @@
+//go:build !linux
+
+package hasher
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// isSparse reports false, the holes of sparse files cannot be found on this platform.
+func isSparse(info fs.FileInfo) bool { return false }
+
+// seekData is not used on this platform, f is read entirely.
+func seekData(f *os.File, offset int64) (int64, error) { return offset, nil }
+
+// seekHole is not used on this platform, f is read entirely.
+func seekHole(f *os.File, offset int64) (int64, error) { return f.Seek(0, io.SeekEnd) }
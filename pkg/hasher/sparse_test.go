@@ -0,0 +1,57 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSparse tests that sparse files hash the same with and without skipping their holes.
+func TestSparse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.img")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A hole, 1 MiB of data, then a hole up to the end of the file
+	if _, err := f.WriteAt(bytes.Repeat([]byte("data"), 1<<18), 4<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(16 << 20); err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isSparse(info) {
+		t.Skip("sparse files are not supported by this platform or filesystem")
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	content, err := io.ReadAll(&sparseReader{f: in, size: info.Size()})
+	if err != nil {
+		t.Fatalf("sparseReader returned %v", err)
+	}
+	want, _ := os.ReadFile(path)
+	if !bytes.Equal(content, want) {
+		t.Errorf("sparseReader read %d bytes different from the content of the file, %d bytes", len(content), len(want))
+	}
+
+	plain := NewOptions().HashFile(context.Background(), path)
+	sparse := NewOptions(WithSparse(true)).HashFile(context.Background(), path)
+	if plain.Err != nil || sparse.Err != nil {
+		t.Fatalf("HashFile() returned %v and %v", plain.Err, sparse.Err)
+	}
+	if sparse.Hash != plain.Hash || sparse.Size != info.Size() {
+		t.Errorf("HashFile() with WithSparse = %s, %d bytes, expected %s, %d bytes", sparse.Hash, sparse.Size, plain.Hash, info.Size())
+	}
+}
@@ -0,0 +1,57 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// SpecialPolicy tells what to do with the special files found while walking a directory:
+// named pipes, sockets, devices and other non-regular files, which may block forever when read.
+// A special file given as root is always hashed, whatever the policy.
+type SpecialPolicy string
+
+// Supported special file policies.
+const (
+	// SpecialSkip ignores the special files, reporting them to Walker.OnSpecial. It is the default.
+	SpecialSkip SpecialPolicy = "skip"
+	// SpecialError reports each special file to Walker.OnError with an error wrapping ErrSpecialFile.
+	SpecialError SpecialPolicy = "error"
+)
+
+// ErrSpecialFile is wrapped by the errors reported for the special files with SpecialError.
+var ErrSpecialFile = errors.New("special file")
+
+// ParseSpecialPolicy returns the SpecialPolicy named s (case-insensitive), an empty s giving SpecialSkip.
+func ParseSpecialPolicy(s string) (SpecialPolicy, error) {
+	p := SpecialPolicy(strings.ToLower(strings.TrimSpace(s)))
+	switch p {
+	case "":
+		return SpecialSkip, nil
+	case SpecialSkip, SpecialError:
+		return p, nil
+	}
+	return "", fmt.Errorf("unsupported special file policy %q, expected one of skip, error", s)
+}
+
+// isSpecial reports whether a file of type mode cannot be hashed safely:
+// anything else than a regular file, a directory or a symlink.
+func isSpecial(mode fs.FileMode) bool {
+	return mode.Type()&^(fs.ModeDir|fs.ModeSymlink) != 0
+}
+
+// specialKind returns a readable name for the type of a special file.
+func specialKind(mode fs.FileMode) string {
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	}
+	return "irregular file"
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package hasher
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// TestWalkerSpecialFiles tests that named pipes are skipped or reported as errors instead of being opened.
+func TestWalkerSpecialFiles(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0o644); err != nil {
+		t.Skipf("Named pipes are not supported here: %v", err)
+	}
+
+	var found, skipped []string
+	var errs []error
+	w := Walker{
+		OnError:   func(path string, err error) { errs = append(errs, err) },
+		OnSpecial: func(path string, mode fs.FileMode) { skipped = append(skipped, filepath.Base(path)) },
+	}
+	walk := func() {
+		found, skipped, errs = nil, nil, nil
+		err := w.Walk(context.Background(), root, func(path string) error {
+			found = append(found, filepath.Base(path))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() returned an error: %v", err)
+		}
+	}
+
+	walk()
+	if len(found) != 1 || found[0] != "a.txt" || len(skipped) != 1 || skipped[0] != "pipe" || len(errs) != 0 {
+		t.Errorf("Walk() skipping special files found %v, skipped %v with errors %v", found, skipped, errs)
+	}
+
+	w.Special = SpecialError
+	walk()
+	if len(found) != 1 || len(skipped) != 0 || len(errs) != 1 || !errors.Is(errs[0], ErrSpecialFile) {
+		t.Errorf("Walk() with SpecialError found %v, skipped %v with errors %v", found, skipped, errs)
+	}
+
+	// the policy of the options is case-insensitive, like the -special flag
+	opts := NewOptions(WithSpecial("ERROR"))
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate() of -special=ERROR returned an error: %v", err)
+	}
+	w = opts.Walker(w.OnError)
+	w.OnSpecial = func(path string, mode fs.FileMode) { skipped = append(skipped, filepath.Base(path)) }
+	walk()
+	if len(found) != 1 || len(skipped) != 0 || len(errs) != 1 || !errors.Is(errs[0], ErrSpecialFile) {
+		t.Errorf("Walk() with the ERROR policy found %v, skipped %v with errors %v", found, skipped, errs)
+	}
+}
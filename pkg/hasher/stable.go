@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrUnstable is the error of the files that changed while they were hashed, or that were locked by the
+// program writing them, with Options.Stable. Their hash would be neither right nor reproducible.
+var ErrUnstable = errors.New("file changed while being hashed")
+
+// stableRetryDelay is the time waited before hashing an unstable file again, multiplied by the attempt number.
+const stableRetryDelay = 500 * time.Millisecond
+
+// hashFileStable hashes the file at path like hashFile and, with o.Stable, checks that its size and its
+// modification time did not change while it was read, hashing it again up to o.StableRetries times.
+func hashFileStable(ctx context.Context, path string, o Options) ([]string, int64, error) {
+	if !o.Stable {
+		return hashFile(ctx, path, o)
+	}
+	for attempt := 1; ; attempt++ {
+		before, err := os.Stat(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		hashes, size, err := hashFile(ctx, path, o)
+		if err != nil && !isLocked(err) {
+			return nil, size, err
+		}
+		if err == nil {
+			after, statErr := os.Stat(path)
+			if statErr != nil {
+				return nil, size, statErr
+			}
+			if after.Size() == before.Size() && after.ModTime().Equal(before.ModTime()) {
+				return hashes, size, nil
+			}
+			err = fmt.Errorf("%w: size %d to %d, modified %s", ErrUnstable, before.Size(), after.Size(), after.ModTime().Format(time.RFC3339Nano))
+		} else {
+			err = fmt.Errorf("%w: %w", ErrUnstable, err)
+		}
+		if attempt > o.StableRetries {
+			return nil, size, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, size, ctx.Err()
+		case <-time.After(time.Duration(attempt) * stableRetryDelay):
+		}
+	}
+}
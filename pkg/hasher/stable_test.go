@@ -0,0 +1,38 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHashFileStable tests that a file appended to while it is read is reported as unstable, unlike an unchanged one.
+func TestHashFileStable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "live.log")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("line\n"), 64<<10), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if r := NewOptions(WithStable(true, 0)).HashFile(ctx, path); r.Err != nil {
+		t.Fatalf("HashFile() of an unchanged file returned %v", r.Err)
+	}
+
+	// Read at 640 KiB/s, the 320 KiB of the file take half a second, enough to append to it meanwhile
+	opts := NewOptions(WithStable(true, 0), WithMaxBandwidth(640<<10), WithBufferSize(16<<10))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		f.WriteString("appended while hashed\n")
+	}()
+	if r := opts.HashFile(ctx, path); !errors.Is(r.Err, ErrUnstable) || r.Hash != "" {
+		t.Errorf("HashFile() of a file appended to = %q, %v, expected ErrUnstable", r.Hash, r.Err)
+	}
+}
@@ -0,0 +1,17 @@
+package hasher
+
+import (
+	"errors"
+	"syscall"
+)
+
+// The errors of the files opened or locked by another process without sharing them.
+const (
+	errorSharingViolation syscall.Errno = 32 // ERROR_SHARING_VIOLATION
+	errorLockViolation    syscall.Errno = 33 // ERROR_LOCK_VIOLATION
+)
+
+// isLocked reports whether err comes from a file locked by the program writing it.
+func isLocked(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
@@ -0,0 +1,65 @@
+package hasher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Storage is the kind of storage holding the files, which decides how many of them are read concurrently.
+type Storage string
+
+// Storage kinds. StorageAuto is resolved by DetectStorage.
+const (
+	StorageAuto    Storage = "auto"
+	StorageHDD     Storage = "hdd"     // spinning disks, read sequentially to avoid seeking between files
+	StorageSSD     Storage = "ssd"     // SSD and NVMe, which serve many concurrent reads
+	StorageNetwork Storage = "network" // NFS, SMB or other remote filesystems, where many reads hide the latency
+)
+
+// ParseStorage returns the Storage named s, StorageAuto when s is empty.
+func ParseStorage(s string) (Storage, error) {
+	switch st := Storage(strings.ToLower(strings.TrimSpace(s))); st {
+	case "":
+		return StorageAuto, nil
+	case StorageAuto, StorageHDD, StorageSSD, StorageNetwork:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown storage %q, expected one of auto, hdd, ssd or network", s)
+	}
+}
+
+// DetectStorage returns the kind of storage holding path: StorageNetwork for remote filesystems, and for
+// block devices StorageHDD or StorageSSD depending on whether the kernel reports them as rotational, only
+// SATA and SAS disks being trusted to be spinning. It returns StorageSSD, the default behavior, when the
+// kind cannot be found, as for virtual disks or on other systems than Linux.
+func DetectStorage(path string) Storage {
+	if st, ok := detectStorage(path); ok {
+		return st
+	}
+	return StorageSSD
+}
+
+// Workers returns the number of files to read concurrently from this storage: one on spinning disks,
+// where concurrent reads make the heads seek between files, DefaultWorkerCount() on SSD and twice as
+// many on network filesystems, to keep enough requests in flight.
+func (s Storage) Workers() int {
+	switch s {
+	case StorageHDD:
+		return 1
+	case StorageNetwork:
+		return 2 * DefaultWorkerCount()
+	default:
+		return DefaultWorkerCount()
+	}
+}
+
+// BufferSize returns the size of the buffer used to read each file from this storage: larger than
+// DefaultBufferSize on spinning disks and network filesystems, to read in fewer, longer requests.
+func (s Storage) BufferSize() int {
+	switch s {
+	case StorageHDD, StorageNetwork:
+		return 1 << 20
+	default:
+		return DefaultBufferSize
+	}
+}
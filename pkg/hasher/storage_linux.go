@@ -0,0 +1,77 @@
+package hasher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// networkFilesystems are the magic numbers returned by statfs for remote filesystems.
+var networkFilesystems = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517b:     true, // SMB
+	0xff534d42: true, // CIFS
+	0xfe534d42: true, // SMB2
+	0x00c36400: true, // Ceph
+	0x65735546: true, // FUSE, like sshfs or s3fs
+}
+
+// virtualDiskVendors are the vendors of the SCSI disks emulated by hypervisors and clouds, whose rotational
+// flag says nothing about the storage behind them.
+var virtualDiskVendors = []string{"QEMU", "VMWARE", "MSFT", "GOOGLE", "AMAZON", "VBOX", "XEN"}
+
+// detectStorage finds the kind of storage of path from its filesystem type, then from the rotational
+// flag of its block device in /sys, the one of its disk for a partition.
+func detectStorage(path string) (Storage, bool) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return "", false
+	}
+	if networkFilesystems[uint32(fs.Type)] {
+		return StorageNetwork, true
+	}
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return "", false
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	return blockStorage(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+}
+
+// blockStorage finds the kind of storage of the block device whose directory in /sys is device.
+// Only the SATA and SAS disks, named sd, are reported as spinning disks: virtio, Xen, device mapper and
+// software RAID devices, like the SCSI disks emulated by hypervisors, often claim to be rotational
+// whatever their storage is, and reading them one file at a time would be much slower.
+func blockStorage(device string) (Storage, bool) {
+	disk, err := filepath.EvalSymlinks(device)
+	if err != nil {
+		return "", false
+	}
+	if _, err := os.Stat(filepath.Join(disk, "partition")); err == nil {
+		disk = filepath.Dir(disk)
+	}
+	data, err := os.ReadFile(filepath.Join(disk, "queue", "rotational"))
+	if err != nil {
+		return "", false
+	}
+	switch strings.TrimSpace(string(data)) {
+	case "0":
+		return StorageSSD, true
+	case "1":
+		if !strings.HasPrefix(filepath.Base(disk), "sd") {
+			return "", false
+		}
+		vendor, _ := os.ReadFile(filepath.Join(disk, "device", "vendor"))
+		for _, virtual := range virtualDiskVendors {
+			if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(string(vendor))), virtual) {
+				return "", false
+			}
+		}
+		return StorageHDD, true
+	}
+	return "", false
+}
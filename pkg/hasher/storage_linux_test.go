@@ -0,0 +1,51 @@
+package hasher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBlockStorage tests that only the rotational SATA and SAS disks are taken for spinning disks,
+// not the virtual disks of VMs that claim to be rotational too.
+func TestBlockStorage(t *testing.T) {
+	sys := t.TempDir()
+	disk := func(name, rotational, vendor string) string {
+		dir := filepath.Join(sys, "devices", name)
+		for file, content := range map[string]string{"queue/rotational": rotational + "\n", "device/vendor": vendor + "\n", name + "1/partition": "1\n"} {
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// The partition is a link in /sys/dev/block to a directory below the one of its disk
+		link := filepath.Join(sys, "dev", name+"1")
+		if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.Join(dir, name+"1"), link); err != nil {
+			t.Fatal(err)
+		}
+		return link
+	}
+	for _, tc := range []struct {
+		name, rotational, vendor string
+		want                     Storage
+		ok                       bool
+	}{
+		{"sda", "1", "ATA     ", StorageHDD, true},
+		{"sdb", "0", "ATA", StorageSSD, true},
+		{"sdc", "1", "QEMU", "", false},
+		{"vda", "1", "0x1af4", "", false},
+		{"nvme0n1", "0", "", StorageSSD, true},
+	} {
+		if got, ok := blockStorage(disk(tc.name, tc.rotational, tc.vendor)); got != tc.want || ok != tc.ok {
+			t.Errorf("blockStorage(%s) = %q, %v, want %q, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+	if _, ok := blockStorage(filepath.Join(sys, "dev", "missing")); ok {
+		t.Error("blockStorage() of a missing device found a storage")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package hasher
+
+// detectStorage cannot find the kind of storage on this system.
+func detectStorage(string) (Storage, bool) {
+	return "", false
+}
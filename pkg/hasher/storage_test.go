@@ -0,0 +1,46 @@
+package hasher
+
+import "testing"
+
+// TestParseStorage tests the storage names accepted by ParseStorage.
+func TestParseStorage(t *testing.T) {
+	for input, want := range map[string]Storage{"": StorageAuto, "auto": StorageAuto, "HDD": StorageHDD, " ssd": StorageSSD, "network": StorageNetwork} {
+		got, err := ParseStorage(input)
+		if err != nil || got != want {
+			t.Errorf("ParseStorage(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseStorage("tape"); err == nil {
+		t.Error("ParseStorage(tape) should fail")
+	}
+}
+
+// TestStorageWorkers tests that spinning disks are read sequentially with larger buffers.
+func TestStorageWorkers(t *testing.T) {
+	if got := StorageHDD.Workers(); got != 1 {
+		t.Errorf("StorageHDD.Workers() = %d, want 1", got)
+	}
+	if got := StorageSSD.Workers(); got != DefaultWorkerCount() {
+		t.Errorf("StorageSSD.Workers() = %d, want %d", got, DefaultWorkerCount())
+	}
+	if got := StorageNetwork.Workers(); got <= StorageSSD.Workers() {
+		t.Errorf("StorageNetwork.Workers() = %d, want more than on SSD", got)
+	}
+	if got := StorageSSD.BufferSize(); got != DefaultBufferSize {
+		t.Errorf("StorageSSD.BufferSize() = %d, want %d", got, DefaultBufferSize)
+	}
+	if got := StorageHDD.BufferSize(); got <= DefaultBufferSize {
+		t.Errorf("StorageHDD.BufferSize() = %d, want more than %d", got, DefaultBufferSize)
+	}
+}
+
+// TestDetectStorage tests that a kind of storage is always found, even for a missing path.
+func TestDetectStorage(t *testing.T) {
+	for _, path := range []string{t.TempDir(), "does-not-exist"} {
+		switch got := DetectStorage(path); got {
+		case StorageHDD, StorageSSD, StorageNetwork:
+		default:
+			t.Errorf("DetectStorage(%q) = %q", path, got)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package hasher
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// alternateStreams is not available on this platform, only NTFS having alternate data streams.
+func alternateStreams(path string) ([]string, error) {
+	return nil, &fs.PathError{Op: "streams", Path: path, Err: errors.ErrUnsupported}
+}
@@ -0,0 +1,47 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestWalkStreams tests that the alternate data streams are walked after their file on Windows,
+// and reported as unsupported elsewhere.
+func TestWalkStreams(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a.txt")
+	if err := os.WriteFile(path, []byte("content"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS == "windows" {
+		if err := os.WriteFile(path+":Zone.Identifier", []byte("[ZoneTransfer]\r\nZoneId=3\r\n"), 0o644); err != nil {
+			t.Skipf("cannot write an alternate data stream: %v", err)
+		}
+	}
+	var found []string
+	var walkErr error
+	w := Walker{Streams: true, OnError: func(path string, err error) { walkErr = err }}
+	if err := w.Walk(context.Background(), root, func(path string) error {
+		found = append(found, filepath.Base(path))
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk() returned %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		if len(found) != 1 || !errors.Is(walkErr, errors.ErrUnsupported) {
+			t.Errorf("Walk() found %v and reported %v, expected a.txt and an unsupported error", found, walkErr)
+		}
+		return
+	}
+	if len(found) != 2 || found[1] != "a.txt:Zone.Identifier" || walkErr != nil {
+		t.Fatalf("Walk() found %v and reported %v, expected a.txt and its stream", found, walkErr)
+	}
+	result := NewOptions().HashFile(context.Background(), path+":Zone.Identifier")
+	if result.Err != nil || result.Size != 26 {
+		t.Errorf("HashFile() of the stream = %+v, expected its 26 bytes", result)
+	}
+}
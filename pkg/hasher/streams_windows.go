@@ -0,0 +1,54 @@
+package hasher
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = kernel32.NewProc("FindNextStreamW")
+)
+
+// win32FindStreamData is the WIN32_FIND_STREAM_DATA filled by FindFirstStreamW and FindNextStreamW.
+type win32FindStreamData struct {
+	StreamSize int64
+	StreamName [syscall.MAX_PATH + 36]uint16
+}
+
+// alternateStreams returns the names of the alternate data streams of the file at path, without the
+// unnamed stream holding its content.
+func alternateStreams(path string) ([]string, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "FindFirstStreamW", Path: path, Err: err}
+	}
+	var data win32FindStreamData
+	// FindStreamInfoStandard is the only information level
+	h, _, err := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		if errors.Is(err, syscall.ERROR_HANDLE_EOF) {
+			return nil, nil // directories and some filesystems have no stream
+		}
+		return nil, &fs.PathError{Op: "FindFirstStreamW", Path: path, Err: err}
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+	var streams []string
+	for {
+		// The streams are named ":NAME:$DATA", the unnamed one "::$DATA"
+		name := strings.TrimPrefix(syscall.UTF16ToString(data.StreamName[:]), ":")
+		if name, ok := strings.CutSuffix(name, ":$DATA"); ok && name != "" {
+			streams = append(streams, name)
+		}
+		if ok, _, err := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data))); ok == 0 {
+			if errors.Is(err, syscall.ERROR_HANDLE_EOF) {
+				return streams, nil
+			}
+			return nil, &fs.PathError{Op: "FindNextStreamW", Path: path, Err: err}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy tells what to do with the symbolic links found while walking a directory.
+// A symlink given as root is always followed, whatever the policy.
+type SymlinkPolicy string
+
+// Supported symlink policies.
+const (
+	// SymlinksDefault hashes the target content of symlinked files and skips symlinked directories.
+	SymlinksDefault SymlinkPolicy = ""
+	// SymlinksSkip ignores every symlink.
+	SymlinksSkip SymlinkPolicy = "skip"
+	// SymlinksFollow hashes symlinked files and walks symlinked directories, skipping the links
+	// to a directory already walked or to one of their ancestors, so loops are not walked forever.
+	// Files outside the tree may be hashed, at their link location.
+	SymlinksFollow SymlinkPolicy = "follow"
+	// SymlinksRecord reports every symlink as a file whose content is its target path,
+	// without reading the target or entering symlinked directories.
+	SymlinksRecord SymlinkPolicy = "record"
+)
+
+// ParseSymlinkPolicy returns the SymlinkPolicy named s (case-insensitive), an empty s giving SymlinksDefault.
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	p := SymlinkPolicy(strings.ToLower(strings.TrimSpace(s)))
+	switch p {
+	case SymlinksDefault, SymlinksSkip, SymlinksFollow, SymlinksRecord:
+		return p, nil
+	}
+	return "", fmt.Errorf("unsupported symlink policy %q, expected one of skip, follow, record", s)
+}
+
+// resolveSymlinks returns policy, the legacy follow flag meaning SymlinksFollow when no policy is set.
+func resolveSymlinks(policy SymlinkPolicy, follow bool) SymlinkPolicy {
+	if policy == SymlinksDefault && follow {
+		return SymlinksFollow
+	}
+	return policy
+}
+
+// isAncestor reports whether the directory dir is path itself or one of its parents.
+func isAncestor(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// hashLink returns the hash of the target path of the symlink at path, the Size being the length of the target.
+func (o Options) hashLink(ctx context.Context, path string) Result {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return Result{Path: path, Err: err}
+	}
+	hashes, size, err := hashContent(ctx, strings.NewReader(target), int64(len(target)), o)
+	return o.result(path, hashes, size, err)
+}
@@ -0,0 +1,218 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// treePrefix starts the tree hashes, followed by the chunk size and a colon, like tree64M:HEX.
+const treePrefix = "tree"
+
+// FormatTreeHash returns the tree hash hex of content split in chunks of chunkSize bytes, the chunk
+// size being written before it, in K, M or G when it is a whole number of them, so it can be verified.
+func FormatTreeHash(chunkSize int64, hex string) string {
+	return treePrefix + formatBlockSize(chunkSize) + ":" + hex
+}
+
+// ParseTreeHash returns the chunk size written by FormatTreeHash before the hexadecimal digits of hash,
+// in any case. ok is false when hash is not a tree hash.
+func ParseTreeHash(hash string) (chunkSize int64, ok bool) {
+	if len(hash) < len(treePrefix) || !strings.EqualFold(hash[:len(treePrefix)], treePrefix) {
+		return 0, false
+	}
+	size, hex, found := strings.Cut(hash[len(treePrefix):], ":")
+	if !found || !isHexString(hex) {
+		return 0, false
+	}
+	return parseBlockSize(size)
+}
+
+// formatBlockSize returns size in K, M or G when it is a whole number of them, in bytes otherwise.
+func formatBlockSize(size int64) string {
+	for _, unit := range []struct {
+		suffix string
+		shift  uint
+	}{{"G", 30}, {"M", 20}, {"K", 10}} {
+		if size%(1<<unit.shift) == 0 {
+			return strconv.FormatInt(size>>unit.shift, 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// parseBlockSize returns the positive size written by formatBlockSize, in any case.
+func parseBlockSize(size string) (int64, bool) {
+	if size == "" {
+		return 0, false
+	}
+	var shift uint
+	switch strings.ToUpper(size[len(size)-1:]) {
+	case "K":
+		shift = 10
+	case "M":
+		shift = 20
+	case "G":
+		shift = 30
+	}
+	if shift > 0 {
+		size = size[:len(size)-1]
+	}
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 1 || n > (1<<62)>>shift {
+		return 0, false
+	}
+	return n << shift, true
+}
+
+// isTreeHash reports whether s is a tree hash written by FormatTreeHash.
+func isTreeHash(s string) bool {
+	_, ok := ParseTreeHash(s)
+	return ok
+}
+
+// The tree hash of a content is the Merkle tree hash of RFC 6962 over its chunks of Options.TreeChunkSize
+// bytes, the last one being shorter and an empty content having a single empty chunk:
+//
+//	leaf = H(0x00 || chunk)
+//	node = H(0x01 || left || right)
+//
+// The nodes are paired level by level, an odd node at the end of a level moving up unchanged, until the
+// root remains. The chunks being hashed independently, the ones of a file are hashed in parallel.
+
+// hashTreeFile computes the tree hashes of the size bytes of r, hashing GOMAXPROCS chunks concurrently.
+func hashTreeFile(ctx context.Context, r io.ReaderAt, size int64, opts Options) ([]string, int64, error) {
+	chunkSize := opts.TreeChunkSize
+	count := int((size + chunkSize - 1) / chunkSize)
+	leaves := make([][][]byte, count)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		next     int
+		total    int64
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for range min(count, runtime.GOMAXPROCS(0)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				next++
+				mu.Unlock()
+				if i >= count || ctx.Err() != nil {
+					return
+				}
+				offset := int64(i) * chunkSize
+				sums, n, err := hashLeaf(ctx, io.NewSectionReader(r, offset, min(chunkSize, size-offset)), opts)
+				mu.Lock()
+				total += n
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+				leaves[i] = sums
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, total, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, total, err
+	}
+	if total != size {
+		return nil, total, fmt.Errorf("size changed while hashing, %d bytes read instead of %d", total, size)
+	}
+	return treeHashes(leaves, opts), total, nil
+}
+
+// hashTreeReader computes the tree hashes of everything read from r, one chunk after the other.
+func hashTreeReader(ctx context.Context, r io.Reader, opts Options) ([]string, int64, error) {
+	var leaves [][][]byte
+	var total int64
+	for {
+		sums, n, err := hashLeaf(ctx, io.LimitReader(r, opts.TreeChunkSize), opts)
+		total += n
+		if err != nil {
+			return nil, total, err
+		}
+		if n > 0 || len(leaves) == 0 {
+			leaves = append(leaves, sums)
+		}
+		if n < opts.TreeChunkSize {
+			return treeHashes(leaves, opts), total, nil
+		}
+	}
+}
+
+// hashLeaf returns the leaf hash of the chunk read from r for each algorithm of opts, with its size.
+func hashLeaf(ctx context.Context, r io.Reader, opts Options) ([][]byte, int64, error) {
+	algorithms := opts.algorithms()
+	writers := make([]io.Writer, len(algorithms))
+	hashWriters := make([]hash.Hash, len(algorithms))
+	for i, algorithm := range algorithms {
+		h, release, err := newHash(algorithm, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer release()
+		h.Write([]byte{0x00})
+		hashWriters[i], writers[i] = h, h
+	}
+	buf := bufferPool.Get().([]byte)
+	defer bufferPool.Put(buf)
+	n, err := io.CopyBuffer(cpuLimited(ctx, io.MultiWriter(writers...)), &ctxReader{ctx: ctx, r: throttled(ctx, r)}, buf)
+	if err != nil {
+		return nil, n, err
+	}
+	sums := make([][]byte, len(hashWriters))
+	for i, h := range hashWriters {
+		sums[i] = h.Sum(nil)
+	}
+	return sums, n, nil
+}
+
+// treeHashes returns the formatted root of the tree of each algorithm of opts, leaves holding
+// the leaf hashes of every algorithm for each chunk.
+func treeHashes(leaves [][][]byte, opts Options) []string {
+	algorithms := opts.algorithms()
+	hashes := make([]string, len(algorithms))
+	for i, algorithm := range algorithms {
+		level := make([][]byte, len(leaves))
+		for j, sums := range leaves {
+			level[j] = sums[i]
+		}
+		h := hashFuncs[algorithm]()
+		for len(level) > 1 {
+			var up [][]byte
+			for j := 0; j+1 < len(level); j += 2 {
+				h.Reset()
+				h.Write([]byte{0x01})
+				h.Write(level[j])
+				h.Write(level[j+1])
+				up = append(up, h.Sum(nil))
+			}
+			if len(level)%2 == 1 {
+				up = append(up, level[len(level)-1])
+			}
+			level = up
+		}
+		format := "%X"
+		if opts.LowerCase {
+			format = "%x"
+		}
+		hashes[i] = FormatTreeHash(opts.TreeChunkSize, fmt.Sprintf(format, level[0]))
+	}
+	return hashes
+}
@@ -0,0 +1,96 @@
+package hasher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestTreeHash tests the tree hashes of files against the documented construction, computed by hand.
+func TestTreeHash(t *testing.T) {
+	node := func(parts ...[]byte) []byte {
+		sum := sha256.Sum256(bytes.Join(parts, nil))
+		return sum[:]
+	}
+	leaf := func(chunk string) []byte { return node([]byte{0x00}, []byte(chunk)) }
+	tests := []struct {
+		content string
+		root    []byte
+	}{
+		{"", leaf("")},
+		{"abc", leaf("abc")},
+		{"abcd", leaf("abcd")},
+		{"abcdefghij", node([]byte{0x01}, node([]byte{0x01}, leaf("abcd"), leaf("efgh")), leaf("ij"))},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("file%d", i))
+		if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		opts := NewOptions(WithTreeChunkSize(4))
+		want := fmt.Sprintf("tree4:%X", tt.root)
+		parallel := opts.HashFile(context.Background(), path)
+		if parallel.Err != nil || parallel.Hash != want || parallel.Size != int64(len(tt.content)) {
+			t.Errorf("HashFile(%q) = %q, %d, %v, expected %q", tt.content, parallel.Hash, parallel.Size, parallel.Err, want)
+		}
+		sequential := opts.HashReader(context.Background(), strings.NewReader(tt.content))
+		if sequential.Err != nil || sequential.Hash != want {
+			t.Errorf("HashReader(%q) = %q, %v, expected %q", tt.content, sequential.Hash, sequential.Err, want)
+		}
+	}
+
+	// many chunks hashed concurrently, with several algorithms
+	path := writeRandomFile(t, dir, 1000*1024+7)
+	opts := NewOptions(WithTreeChunkSize(16*1024), WithExtraAlgorithms(MD5))
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer f.Close()
+	parallel, sequential := opts.HashFile(context.Background(), path), opts.HashReader(context.Background(), f)
+	if parallel.Err != nil || parallel.Hashes[SHA256] != sequential.Hashes[SHA256] || parallel.Hashes[MD5] != sequential.Hashes[MD5] {
+		t.Errorf("HashFile() = %+v, HashReader() = %+v, expected the same tree hashes", parallel, sequential)
+	}
+	if err := NewOptions(WithTreeChunkSize(4), WithGitBlob(true)).Validate(); err == nil {
+		t.Error("Validate() accepted tree hashes of git blobs")
+	}
+}
+
+// TestParseTreeHash tests the chunk sizes written before tree hashes.
+func TestParseTreeHash(t *testing.T) {
+	tests := []struct {
+		hash      string
+		chunkSize int64
+		ok        bool
+	}{
+		{FormatTreeHash(64<<20, "AB12"), 64 << 20, true},
+		{"TREE64M:AB12", 64 << 20, true},
+		{FormatTreeHash(1536, "ab12"), 1536, true},
+		{FormatTreeHash(1000, "ab12"), 1000, true},
+		{"tree1G:ab12", 1 << 30, true},
+		{"tree0:ab12", 0, false},
+		{"tree64M:xyz", 0, false},
+		{"AB12", 0, false},
+	}
+	for _, tt := range tests {
+		chunkSize, ok := ParseTreeHash(tt.hash)
+		if chunkSize != tt.chunkSize || ok != tt.ok {
+			t.Errorf("ParseTreeHash(%q) = %d, %v, expected %d, %v", tt.hash, chunkSize, ok, tt.chunkSize, tt.ok)
+		}
+	}
+	for chunkSize, want := range map[int64]string{64 << 20: "tree64M:AB12", 1536: "tree1536:AB12", 2048: "tree2K:AB12"} {
+		if got := FormatTreeHash(chunkSize, "AB12"); got != want {
+			t.Errorf("FormatTreeHash(%d) = %q, expected %q", chunkSize, got, want)
+		}
+	}
+	entries, malformed, err := ParseHashFileDetailed(strings.NewReader("tree64M:ab12  big.img\n"))
+	if err != nil || len(malformed) != 0 || len(entries) != 1 || entries[0].Hash != "TREE64M:AB12" {
+		t.Errorf("ParseHashFileDetailed() = %+v, %+v, %v, expected the tree hash of big.img", entries, malformed, err)
+	}
+}
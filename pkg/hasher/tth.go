@@ -0,0 +1,238 @@
+package hasher
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// tthLeafSize is the size of the leaves of the Tiger tree hashes.
+const tthLeafSize = 1024
+
+// The Tiger tree hash (TTH) of a content, the one of Direct Connect and Gnutella, is the THEX Merkle tree hash
+// over its leaves of tthLeafSize bytes with the Tiger hash: each leaf gives Tiger(0x00 || leaf) and two nodes
+// give Tiger(0x01 || left || right), an odd node at the end of a level moving up unchanged. An empty content
+// has a single empty leaf. The clients show it in base32, the manifests in hexadecimal like the other digests.
+
+// tthDigest computes the Tiger tree hash of the content written to it, keeping only the nodes not yet paired.
+type tthDigest struct {
+	leaf   []byte
+	leaves int64
+	stack  []tthNode // by decreasing level
+}
+
+type tthNode struct {
+	level int
+	sum   [tigerSize]byte
+}
+
+func newTTH() hash.Hash {
+	d := &tthDigest{leaf: make([]byte, 0, tthLeafSize)}
+	d.Reset()
+	return d
+}
+
+func (d *tthDigest) Reset() {
+	d.leaf, d.leaves, d.stack = d.leaf[:0], 0, d.stack[:0]
+}
+
+func (d *tthDigest) Size() int      { return tigerSize }
+func (d *tthDigest) BlockSize() int { return tthLeafSize }
+
+func (d *tthDigest) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		k := copy(d.leaf[len(d.leaf):cap(d.leaf)], p)
+		d.leaf, p = d.leaf[:len(d.leaf)+k], p[k:]
+		if len(d.leaf) == tthLeafSize {
+			d.stack = pushTTH(d.stack, tthNode{sum: tthHash(0x00, d.leaf)})
+			d.leaf = d.leaf[:0]
+			d.leaves++
+		}
+	}
+	return n, nil
+}
+
+func (d *tthDigest) Sum(b []byte) []byte {
+	stack := append([]tthNode(nil), d.stack...)
+	if len(d.leaf) > 0 || d.leaves == 0 {
+		stack = pushTTH(stack, tthNode{sum: tthHash(0x00, d.leaf)})
+	}
+	// The nodes left are the ones moving up unchanged, paired from the right
+	root := stack[len(stack)-1].sum
+	for i := len(stack) - 2; i >= 0; i-- {
+		root = tthHash(0x01, stack[i].sum[:], root[:])
+	}
+	return append(b, root[:]...)
+}
+
+// pushTTH adds the node n to stack, pairing it with the nodes of the same level.
+func pushTTH(stack []tthNode, n tthNode) []tthNode {
+	for len(stack) > 0 && stack[len(stack)-1].level == n.level {
+		left := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		n = tthNode{level: n.level + 1, sum: tthHash(0x01, left.sum[:], n.sum[:])}
+	}
+	return append(stack, n)
+}
+
+// tthHash returns the Tiger hash of prefix followed by parts.
+func tthHash(prefix byte, parts ...[]byte) [tigerSize]byte {
+	t := newTiger()
+	t.Write([]byte{prefix})
+	for _, p := range parts {
+		t.Write(p)
+	}
+	var sum [tigerSize]byte
+	t.Sum(sum[:0])
+	return sum
+}
+
+// tigerSize and tigerBlockSize are the sizes of the digest and the blocks of Tiger.
+const (
+	tigerSize      = 24
+	tigerBlockSize = 64
+)
+
+// tigerDigest computes the Tiger/192 hash of Anderson and Biham, with the padding of the original Tiger.
+type tigerDigest struct {
+	s   [3]uint64
+	x   [tigerBlockSize]byte
+	nx  int
+	len uint64
+}
+
+func newTiger() *tigerDigest {
+	d := &tigerDigest{}
+	d.Reset()
+	return d
+}
+
+func (d *tigerDigest) Reset() {
+	d.s = [3]uint64{0x0123456789abcdef, 0xfedcba9876543210, 0xf096a5b4c3b2e187}
+	d.nx, d.len = 0, 0
+}
+
+func (d *tigerDigest) Size() int      { return tigerSize }
+func (d *tigerDigest) BlockSize() int { return tigerBlockSize }
+
+func (d *tigerDigest) Write(p []byte) (int, error) {
+	n := len(p)
+	d.len += uint64(n)
+	if d.nx > 0 {
+		k := copy(d.x[d.nx:], p)
+		d.nx += k
+		p = p[k:]
+		if d.nx == tigerBlockSize {
+			tigerCompress(&d.s, d.x[:], &tigerTable)
+			d.nx = 0
+		}
+	}
+	for len(p) >= tigerBlockSize {
+		tigerCompress(&d.s, p[:tigerBlockSize], &tigerTable)
+		p = p[tigerBlockSize:]
+	}
+	d.nx += copy(d.x[:], p)
+	return n, nil
+}
+
+func (d *tigerDigest) Sum(b []byte) []byte {
+	c := *d // so the caller can keep writing
+	var pad [tigerBlockSize + 8]byte
+	pad[0] = 0x01 // 0x80 being the padding of Tiger2
+	padding := (tigerBlockSize + 56 - int(c.len%tigerBlockSize)) % tigerBlockSize
+	if padding == 0 {
+		padding = tigerBlockSize
+	}
+	binary.LittleEndian.PutUint64(pad[padding:], c.len<<3)
+	c.Write(pad[:padding+8])
+	for _, s := range c.s {
+		b = binary.LittleEndian.AppendUint64(b, s)
+	}
+	return b
+}
+
+// tigerTable holds the 4 S-boxes of Tiger, generated like the reference implementation does.
+var tigerTable = genTigerTable()
+
+// genTigerTable generates the S-boxes of Tiger: starting from boxes whose entries repeat their index in each
+// byte, the bytes of each column are swapped with the ones chosen by the state of Tiger compressing a fixed
+// string with the boxes being generated, in 5 passes.
+func genTigerTable() [1024]uint64 {
+	var table [1024]uint64
+	for i := range table {
+		table[i] = uint64(i&255) * 0x0101010101010101
+	}
+	const seed = "Tiger - A Fast New Hash Function, by Ross Anderson and Eli Biham"
+	state := [3]uint64{0x0123456789abcdef, 0xfedcba9876543210, 0xf096a5b4c3b2e187}
+	abc := 2
+	for pass := 0; pass < 5; pass++ {
+		for i := 0; i < 256; i++ {
+			for sb := 0; sb < 1024; sb += 256 {
+				abc++
+				if abc == 3 {
+					abc = 0
+					tigerCompress(&state, []byte(seed), &table)
+				}
+				for col := 0; col < 8; col++ {
+					shift := 8 * uint(col)
+					j := sb + int(state[abc]>>shift&0xff)
+					a, b := table[sb+i]>>shift&0xff, table[j]>>shift&0xff
+					table[sb+i] = table[sb+i]&^(0xff<<shift) | b<<shift
+					table[j] = table[j]&^(0xff<<shift) | a<<shift
+				}
+			}
+		}
+	}
+	return table
+}
+
+// tigerCompress compresses the block p into the state s with the S-boxes t.
+func tigerCompress(s *[3]uint64, p []byte, t *[1024]uint64) {
+	var x [8]uint64
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint64(p[8*i:])
+	}
+	a, b, c := s[0], s[1], s[2]
+	round := func(a, b, c *uint64, x, mul uint64) {
+		*c ^= x
+		*a -= t[*c&0xff] ^ t[256+(*c>>16&0xff)] ^ t[512+(*c>>32&0xff)] ^ t[768+(*c>>48&0xff)]
+		*b += t[768+(*c>>8&0xff)] ^ t[512+(*c>>24&0xff)] ^ t[256+(*c>>40&0xff)] ^ t[*c>>56]
+		*b *= mul
+	}
+	pass := func(a, b, c *uint64, mul uint64) {
+		round(a, b, c, x[0], mul)
+		round(b, c, a, x[1], mul)
+		round(c, a, b, x[2], mul)
+		round(a, b, c, x[3], mul)
+		round(b, c, a, x[4], mul)
+		round(c, a, b, x[5], mul)
+		round(a, b, c, x[6], mul)
+		round(b, c, a, x[7], mul)
+	}
+	schedule := func() {
+		x[0] -= x[7] ^ 0xa5a5a5a5a5a5a5a5
+		x[1] ^= x[0]
+		x[2] += x[1]
+		x[3] -= x[2] ^ (^x[1] << 19)
+		x[4] ^= x[3]
+		x[5] += x[4]
+		x[6] -= x[5] ^ (^x[4] >> 23)
+		x[7] ^= x[6]
+		x[0] += x[7]
+		x[1] -= x[0] ^ (^x[7] << 19)
+		x[2] ^= x[1]
+		x[3] += x[2]
+		x[4] -= x[3] ^ (^x[2] >> 23)
+		x[5] ^= x[4]
+		x[6] += x[5]
+		x[7] -= x[6] ^ 0x0123456789abcdef
+	}
+	pass(&a, &b, &c, 5)
+	schedule()
+	pass(&c, &a, &b, 7)
+	schedule()
+	pass(&b, &c, &a, 9)
+	s[0] ^= a
+	s[1] = b - s[1]
+	s[2] += c
+}
@@ -0,0 +1,64 @@
+package hasher
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/hex"
+	"testing"
+)
+
+// TestTiger tests the Tiger hash against the test vectors of its authors.
+func TestTiger(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "3293ac630c13f0245f92bbb1766e16167a4e58492dde73f3"},
+		{"abc", "2aab1484e8c158f2bfb8c5ff41b57a525129131c957b5f93"},
+		{"Tiger", "dd00230799f5009fec6debc838bb6a27df2b9d6f110c7937"},
+		{"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+-", "f71c8583902afb879edfe610f82c0d4786a3a534504486b5"},
+	}
+	for _, tt := range tests {
+		d := newTiger()
+		d.Write([]byte(tt.input))
+		if got := hex.EncodeToString(d.Sum(nil)); got != tt.want {
+			t.Errorf("Tiger(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+// TestTTH tests the Tiger tree hashes against the base32 roots the clients show and the tree computed by hand.
+func TestTTH(t *testing.T) {
+	leaf := func(p []byte) []byte { sum := tthHash(0x00, p); return sum[:] }
+	node := func(l, r []byte) []byte { sum := tthHash(0x01, l, r); return sum[:] }
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3*tthLeafSize/16+5)
+	leaves := [][]byte{content[:tthLeafSize], content[tthLeafSize : 2*tthLeafSize], content[2*tthLeafSize : 3*tthLeafSize], content[3*tthLeafSize:]}
+	tests := []struct {
+		name    string
+		content []byte
+		want    []byte
+	}{
+		{"one leaf", content[:tthLeafSize], leaf(leaves[0])},
+		{"three leaves", content[:3*tthLeafSize], node(node(leaf(leaves[0]), leaf(leaves[1])), leaf(leaves[2]))},
+		{"four leaves", content, node(node(leaf(leaves[0]), leaf(leaves[1])), node(leaf(leaves[2]), leaf(leaves[3])))},
+	}
+	for _, tt := range tests {
+		h := newTTH()
+		h.Write(tt.content[:100])
+		h.Write(tt.content[100:])
+		if got := h.Sum(nil); !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: TTH = %x, want %x", tt.name, got, tt.want)
+		}
+	}
+	base32Root := func(p []byte) string {
+		h := newTTH()
+		h.Write(p)
+		return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(h.Sum(nil))
+	}
+	if got := base32Root(nil); got != "LWPNACQDBZRYXW3VHJVCJ64QBZNGHOHHHZWCLNQ" {
+		t.Errorf("TTH of the empty content = %s", got)
+	}
+	if got := base32Root([]byte{0}); got != "VK54ZIEEVTWNAUI5D5RDFIL37LX2IQNSTAXFKSA" {
+		t.Errorf("TTH of a zero byte = %s", got)
+	}
+}
@@ -0,0 +1,270 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFileMissing is matched with errors.Is by the error of a file to verify that does not exist.
+// It is fs.ErrNotExist, so the errors of local and remote filesystems match it alike.
+var ErrFileMissing = fs.ErrNotExist
+
+// HashMismatchError is returned when the content of a file does not match the hash of its manifest entry.
+// Actual is empty when the file was not read, its size being different from the one recorded.
+type HashMismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *HashMismatchError) Error() string {
+	if e.Actual == "" {
+		return e.Path + ": size changed, expected hash " + e.Expected
+	}
+	return e.Path + ": hash " + e.Actual + " instead of " + e.Expected
+}
+
+// MetadataError is returned when the content of a file matches its manifest entry but its permissions
+// or modification time, recorded with MetadataHash, changed.
+type MetadataError struct {
+	Path  string
+	Drift string // the changes, like "mode -rw------- instead of -rw-r--r--"
+}
+
+func (e *MetadataError) Error() string {
+	return e.Path + ": metadata changed: " + e.Drift
+}
+
+// VerifyResult is the result of the verification of a file against its manifest entry.
+// Err is nil when the file matches, otherwise it matches ErrFileMissing, is a *HashMismatchError,
+// a *MetadataError, the error met reading the file, or the error of the context when it is cancelled.
+type VerifyResult struct {
+	Entry   FileEntry // The entry verified, reported with its path as listed in the manifest
+	Path    string    // Where the file was looked for, set by VerifyFiles
+	Size    int64     // The number of bytes read from the file
+	Actual  string    // The hash computed, empty when the file was not read
+	Err     error
+	Elapsed time.Duration // The time taken to verify the file
+}
+
+// OK reports whether the file matches its manifest entry.
+func (r VerifyResult) OK() bool {
+	return r.Err == nil
+}
+
+// VerifyFile hashes the file at path and compares it to entry, path being where the file listed by
+// entry is found. The tree, quick, AWS and BitTorrent hashes of the entry are computed with their own block size,
+// and the recorded size and metadata, when there are any, are compared too.
+func (o Options) VerifyFile(ctx context.Context, path string, entry FileEntry) VerifyResult {
+	return o.verify(ctx, entry, func() (fs.FileInfo, error) { return os.Stat(path) },
+		func(o Options) Result { return o.HashFile(ctx, path) })
+}
+
+// VerifyFileFS works like VerifyFile for the file name in fsys.
+func (o Options) VerifyFileFS(ctx context.Context, fsys fs.FS, name string, entry FileEntry) VerifyResult {
+	return o.verify(ctx, entry, func() (fs.FileInfo, error) { return fs.Stat(fsys, name) },
+		func(o Options) Result { return o.HashFileFS(ctx, fsys, name) })
+}
+
+// verify does the actual work of VerifyFile and VerifyFileFS with the functions returning the
+// information of the file and hashing it.
+func (o Options) verify(ctx context.Context, entry FileEntry, stat func() (fs.FileInfo, error), hash func(Options) Result) VerifyResult {
+	start := time.Now()
+	if chunkSize, ok := ParseTreeHash(entry.Hash); ok {
+		o.TreeChunkSize = chunkSize
+	}
+	if sampleSize, ok := ParseQuickHash(entry.Hash); ok {
+		o.QuickSize = sampleSize
+	}
+	if partSize, ok := ParseETag(entry.Hash); ok {
+		o.ETagPartSize = partSize
+	}
+	if IsGlacierHash(entry.Hash) {
+		o.GlacierTree = true
+	}
+	if IsBTv2Hash(entry.Hash) {
+		o.BitTorrentV2 = true
+	}
+
+	// A file whose size changed since it was recorded in the manifest cannot match, it is not read
+	var info fs.FileInfo
+	if entry.HasSize || entry.HasMetadata {
+		var err error
+		if info, err = stat(); err != nil {
+			info = nil // reported when hashing the file
+		}
+		if info != nil && entry.HasSize && info.Mode().IsRegular() && info.Size() != entry.Size {
+			return VerifyResult{Entry: entry, Elapsed: time.Since(start),
+				Err: &HashMismatchError{Path: entry.FilePath, Expected: entry.Hash}}
+		}
+	}
+
+	hashResult := hash(o)
+	result := VerifyResult{Entry: entry, Size: hashResult.Size, Actual: hashResult.Hash, Err: hashResult.Err, Elapsed: time.Since(start)}
+	switch {
+	case result.Err != nil:
+		result.Actual = ""
+	case !strings.EqualFold(result.Actual, entry.Hash):
+		result.Err = &HashMismatchError{Path: entry.FilePath, Expected: entry.Hash, Actual: result.Actual}
+	case info != nil && entry.HasMetadata:
+		var drift []string
+		if mode := PermissionBits(info.Mode()); mode != entry.Mode {
+			drift = append(drift, fmt.Sprintf("mode %v instead of %v", mode, entry.Mode))
+		}
+		if !info.ModTime().Equal(entry.ModTime) {
+			drift = append(drift, fmt.Sprintf("modification time %s instead of %s", info.ModTime().Format(time.RFC3339Nano), entry.ModTime.Format(time.RFC3339Nano)))
+		}
+		if len(drift) > 0 {
+			result.Err = &MetadataError{Path: entry.FilePath, Drift: strings.Join(drift, ", ")}
+		}
+	}
+	return result
+}
+
+// Report sums up the verification of the entries of a manifest by VerifyEntries.
+type Report struct {
+	Results    []VerifyResult // One per entry verified, sorted by the path listed
+	Valid      int            // The files matching their entry
+	Mismatched int            // The files whose content, size or metadata changed
+	Missing    int            // The files that do not exist
+	Errors     int            // The files that could not be read
+}
+
+// OK reports whether every file verified matches its entry.
+func (r Report) OK() bool {
+	return r.Mismatched == 0 && r.Missing == 0 && r.Errors == 0
+}
+
+// add counts the result r in the report.
+func (r *Report) add(result VerifyResult) {
+	r.Results = append(r.Results, result)
+	var mismatch *HashMismatchError
+	var metadata *MetadataError
+	switch err := result.Err; {
+	case err == nil:
+		r.Valid++
+	case errors.As(err, &mismatch), errors.As(err, &metadata):
+		r.Mismatched++
+	case errors.Is(err, ErrFileMissing):
+		r.Missing++
+	default:
+		r.Errors++
+	}
+}
+
+// VerifyEntries verifies the files listed by entries as described by opts, using a pool of opts.Workers
+// goroutines. Relative paths are resolved from baseDir, absolute ones are used as is, and the members
+// of zip archives listed as archive.zip!/inner/path are looked for as inner/path. The failures are
+// counted in the Report, the error is only set when opts are invalid or when ctx is cancelled,
+// the results gathered so far being returned with ctx.Err().
+func VerifyEntries(ctx context.Context, baseDir string, entries []FileEntry, opts Options) (Report, error) {
+	if err := opts.Validate(); err != nil {
+		return Report{}, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	entriesChan := make(chan FileEntry, opts.workers())
+	go func() {
+		defer close(entriesChan)
+		for _, entry := range entries {
+			select {
+			case entriesChan <- entry:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	var report Report
+	for result := range opts.VerifyFiles(ctx, baseDir, entriesChan) {
+		if result.Err != nil && ctx.Err() != nil && errors.Is(result.Err, ctx.Err()) {
+			continue // interrupted while verifying this file
+		}
+		report.add(result)
+	}
+	sort.Slice(report.Results, func(i, j int) bool { return report.Results[i].Entry.FilePath < report.Results[j].Entry.FilePath })
+	return report, ctx.Err()
+}
+
+// VerifyFiles verifies the files listed by the entries received, resolving their paths like VerifyEntries,
+// using o.Workers goroutines, and sends a VerifyResult for each of them on the returned channel.
+// Like HashFiles, the channel is closed once entries is closed and all pending files are done,
+// or once ctx is cancelled.
+func (o Options) VerifyFiles(ctx context.Context, baseDir string, entries <-chan FileEntry) <-chan VerifyResult {
+	return o.verifyEntries(ctx, entries, func(ctx context.Context, entry FileEntry) VerifyResult {
+		path := filepath.Clean(entryPath(entry))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		path = o.lookupPath(path)
+		result := o.VerifyFile(ctx, path, entry)
+		result.Path = path
+		return result
+	})
+}
+
+// VerifyFilesFS works like VerifyFiles for the files of fsys, baseDir being a directory of fsys.
+// The paths listed starting with a slash are resolved from the root of fsys.
+func (o Options) VerifyFilesFS(ctx context.Context, fsys fs.FS, baseDir string, entries <-chan FileEntry) <-chan VerifyResult {
+	return o.verifyEntries(ctx, entries, func(ctx context.Context, entry FileEntry) VerifyResult {
+		name := entryPath(entry)
+		if !strings.HasPrefix(name, "/") {
+			name = baseDir + "/" + name
+		}
+		name = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "/")
+		if name == "" {
+			name = "."
+		}
+		result := o.VerifyFileFS(ctx, fsys, name, entry)
+		result.Path = name
+		return result
+	})
+}
+
+// entryPath returns the path of the file listed by entry, the members of archives being extracted.
+func entryPath(entry FileEntry) string {
+	if _, member, ok := SplitArchivePath(entry.FilePath); ok {
+		return member
+	}
+	return entry.FilePath
+}
+
+// verifyEntries runs the worker pool of VerifyFiles, each entry being verified with verifyOne.
+// Like hashPaths, only o.CPUWorkers workers compute digests at the same time and all of them
+// read at most o.MaxBandwidth bytes per second.
+func (o Options) verifyEntries(ctx context.Context, entries <-chan FileEntry, verifyOne func(ctx context.Context, entry FileEntry) VerifyResult) <-chan VerifyResult {
+	ctx = o.limitBandwidth(o.limitCPU(ctx))
+	workers := o.workers()
+	results := make(chan VerifyResult, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case entry, ok := <-entries:
+					if !ok {
+						return
+					}
+					results <- verifyOne(ctx, entry)
+				}
+			}
+		}()
+	}
+	// Close the result channel after all workers finish
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
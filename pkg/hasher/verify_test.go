@@ -0,0 +1,82 @@
+package hasher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestVerifyFile tests that each cause of failure is reported with its own error.
+func TestVerifyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fox.txt")
+	if err := os.WriteFile(path, []byte("The quick brown fox"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := GetSHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, opts := context.Background(), Options{}
+
+	if r := opts.VerifyFile(ctx, path, FileEntry{Hash: hash, FilePath: "fox.txt"}); !r.OK() || r.Actual != hash || r.Size != info.Size() {
+		t.Errorf("VerifyFile() = %+v, expected the file to match", r)
+	}
+	var mismatch *HashMismatchError
+	r := opts.VerifyFile(ctx, path, FileEntry{Hash: "00" + hash[2:], FilePath: "fox.txt"})
+	if !errors.As(r.Err, &mismatch) || mismatch.Actual != hash || mismatch.Path != "fox.txt" {
+		t.Errorf("VerifyFile() = %+v, expected a *HashMismatchError", r)
+	}
+	r = opts.VerifyFile(ctx, path, FileEntry{Hash: hash, FilePath: "fox.txt", Size: 3, HasSize: true})
+	if !errors.As(r.Err, &mismatch) || mismatch.Actual != "" || r.Size != 0 {
+		t.Errorf("VerifyFile() = %+v, expected a *HashMismatchError without reading the file", r)
+	}
+	var metadata *MetadataError
+	entry := FileEntry{Hash: hash, FilePath: "fox.txt", Size: info.Size(), HasSize: true,
+		ModTime: info.ModTime().Add(-time.Hour), Mode: PermissionBits(info.Mode()), HasMetadata: true}
+	if r = opts.VerifyFile(ctx, path, entry); !errors.As(r.Err, &metadata) {
+		t.Errorf("VerifyFile() = %+v, expected a *MetadataError", r)
+	}
+	if r = opts.VerifyFile(ctx, path+".missing", FileEntry{Hash: hash, FilePath: "gone.txt"}); !errors.Is(r.Err, ErrFileMissing) || r.Actual != "" {
+		t.Errorf("VerifyFile() = %+v, expected ErrFileMissing", r)
+	}
+}
+
+// TestVerifyEntries tests that the paths are resolved from the base directory and that each failure is counted.
+func TestVerifyEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var entries []FileEntry
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/c.txt"} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := GetSHA256(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, FileEntry{Hash: hash, FilePath: name})
+	}
+	entries[2].Hash = entries[1].Hash
+	entries = append(entries, FileEntry{Hash: entries[0].Hash, FilePath: "gone.txt"}, FileEntry{Hash: entries[0].Hash, FilePath: filepath.Join(dir, "a.txt")})
+
+	report, err := VerifyEntries(context.Background(), dir, entries, Options{Workers: 2})
+	if err != nil {
+		t.Fatalf("VerifyEntries() returned %v", err)
+	}
+	if report.Valid != 3 || report.Mismatched != 1 || report.Missing != 1 || report.Errors != 0 || report.OK() || len(report.Results) != 5 {
+		t.Errorf("VerifyEntries() = %+v, expected 3 valid, 1 mismatched and 1 missing files", report)
+	}
+	if r := report.Results[len(report.Results)-1]; r.Entry.FilePath != "sub/c.txt" || r.Path != filepath.Join(dir, "sub", "c.txt") {
+		t.Errorf("VerifyEntries() last result = %+v, expected sub/c.txt found in the base directory", r)
+	}
+}
@@ -0,0 +1,223 @@
+package hasher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Walker lists the files below a root directory, applying the include/exclude
+// patterns and, unless NoIgnore is set, the .hashignore files found in the tree.
+type Walker struct {
+	Filter   PathFilter
+	NoIgnore bool
+	// Symlinks tells what to do with the symlinks found below the root, see SymlinkPolicy.
+	Symlinks SymlinkPolicy
+	// FollowSymlinks is the same as Symlinks set to SymlinksFollow, when Symlinks is not set.
+	FollowSymlinks bool
+	// MaxDepth limits the walk to the files at most MaxDepth levels below the root,
+	// 1 keeping only the files of the root itself. There is no limit when < 1.
+	MaxDepth int
+	// OneFileSystem skips the directories on another filesystem than the root, like mount points.
+	// It is only available on unix systems.
+	OneFileSystem bool
+	// Streams also reports the NTFS alternate data streams of each regular file after it, as FILE:STREAM paths
+	// that can be opened like files. It is only available on Windows, OnError being called elsewhere.
+	Streams bool
+	// Special tells what to do with named pipes, sockets and devices, SpecialSkip when empty.
+	Special SpecialPolicy
+	// OnError is called for each path that cannot be accessed, the walk then continues.
+	// When nil, such paths are silently skipped.
+	OnError func(path string, err error)
+	// OnSpecial is called for each special file skipped with SpecialSkip, it may be nil.
+	OnSpecial func(path string, mode fs.FileMode)
+
+	rootDev    uint64 // filesystem of the root, set by Walk for OneFileSystem
+	hasRootDev bool
+}
+
+// Walk calls fn for every file found below root, in lexical order.
+// When root is a file, fn is called once with root, whatever the filters.
+// The walk stops when fn returns an error, which is then returned,
+// or when ctx is cancelled, in which case ctx.Err() is returned.
+func (w Walker) Walk(ctx context.Context, root string, fn func(path string) error) error {
+	var ignorer *Ignorer
+	if !w.NoIgnore {
+		ignorer = NewIgnorer(root)
+	}
+	// visited holds the real path of the directories entered through symlinks, to avoid loops
+	visited := make(map[string]bool)
+	realRoot := root
+	if real, err := filepath.EvalSymlinks(root); err == nil {
+		visited[real] = true
+		if info, err := os.Lstat(root); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			realRoot = real // a symlink given as root is always followed
+		}
+	}
+	if w.OneFileSystem {
+		if info, err := os.Stat(root); err == nil {
+			w.rootDev, w.hasRootDev = device(info)
+		}
+	}
+	return w.walkDir(ctx, root, root, realRoot, ignorer, visited, fn)
+}
+
+// walkDir walks the tree starting at dir, which is root or a symlinked directory below root.
+// realDir is the directory actually read, the paths given to fn and used for the filters
+// are the ones below dir, so symlinked directories appear at their link location.
+func (w Walker) walkDir(ctx context.Context, root, dir, realDir string, ignorer *Ignorer, visited map[string]bool, fn func(path string) error) error {
+	return filepath.WalkDir(realDir, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if realDir != dir {
+			if rel, relErr := filepath.Rel(realDir, path); relErr == nil {
+				path = filepath.Join(dir, rel)
+			}
+		}
+		if err != nil {
+			if w.OnError != nil {
+				w.OnError(path, err)
+			}
+			return nil // Don't stop the walk, just skip this file/dir
+		}
+		if path == root && !d.IsDir() {
+			return w.found(path, d.Type(), fn)
+		}
+		// Apply the include/exclude patterns and .hashignore rules relative to the walked directory
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			relPath = path
+		}
+		isDir := d.IsDir()
+		isSymlinkedDir := false
+		mode := d.Type()
+		var info fs.FileInfo // only needed to compare the filesystems
+		if d.Type()&fs.ModeSymlink != 0 {
+			switch w.symlinks() {
+			case SymlinksSkip:
+				return nil
+			case SymlinksRecord:
+				// the link itself is reported as a file, even when pointing to a directory or nowhere
+			default:
+				target, err := os.Stat(path)
+				if err != nil {
+					if w.OnError != nil {
+						w.OnError(path, err)
+					}
+					return nil
+				}
+				isDir = target.IsDir()
+				isSymlinkedDir = isDir
+				mode = target.Mode()
+				info = target
+			}
+		}
+		if isDir {
+			// SkipDir on a symlink would skip the rest of its parent directory
+			skipDir := filepath.SkipDir
+			if isSymlinkedDir {
+				skipDir = nil
+			}
+			if path != dir && (w.Filter.Excluded(relPath) || (ignorer != nil && ignorer.Ignored(relPath, true))) {
+				return skipDir
+			}
+			if path != dir && w.MaxDepth > 0 && depth(relPath) >= w.MaxDepth {
+				return skipDir // its files would be too deep
+			}
+			if path != dir && w.hasRootDev {
+				if info == nil {
+					if info, err = d.Info(); err != nil {
+						return skipDir
+					}
+				}
+				if dev, ok := device(info); ok && dev != w.rootDev {
+					return skipDir // mount point of another filesystem
+				}
+			}
+			if isSymlinkedDir {
+				if w.symlinks() != SymlinksFollow {
+					return nil
+				}
+				real, err := filepath.EvalSymlinks(path)
+				if err != nil || visited[real] {
+					return nil // broken or already walked, avoid infinite loops
+				}
+				if realParent, err := filepath.EvalSymlinks(filepath.Dir(path)); err != nil || isAncestor(real, realParent) {
+					return nil // a link to an ancestor would be walked forever
+				}
+				visited[real] = true
+				return w.walkDir(ctx, root, path, real, ignorer, visited, fn)
+			}
+			if ignorer != nil {
+				if err := ignorer.LoadDir(relPath); err != nil && w.OnError != nil {
+					w.OnError(filepath.Join(path, IgnoreFileName), err)
+				}
+			}
+			return nil
+		}
+		if ignorer != nil && ignorer.Ignored(relPath, false) {
+			return nil
+		}
+		if !w.Filter.Keep(relPath) {
+			return nil
+		}
+		if w.Filter.keepsInfo() && mode.IsRegular() {
+			if info == nil {
+				if info, err = d.Info(); err != nil {
+					if w.OnError != nil {
+						w.OnError(path, err)
+					}
+					return nil
+				}
+			}
+			if !w.Filter.KeepInfo(info) {
+				return nil
+			}
+		}
+		if isSpecial(mode) {
+			// reading a named pipe or a device could block forever
+			if w.Special == SpecialError {
+				if w.OnError != nil {
+					w.OnError(path, fmt.Errorf("%w: %s", ErrSpecialFile, specialKind(mode)))
+				}
+			} else if w.OnSpecial != nil {
+				w.OnSpecial(path, mode)
+			}
+			return nil
+		}
+		return w.found(path, mode, fn)
+	})
+}
+
+// found calls fn with the file at path and, with w.Streams, with each alternate data stream of a regular file.
+func (w Walker) found(path string, mode fs.FileMode, fn func(path string) error) error {
+	if err := fn(path); err != nil || !w.Streams || mode.Type() != 0 {
+		return err
+	}
+	streams, err := alternateStreams(path)
+	if err != nil {
+		if w.OnError != nil {
+			w.OnError(path, err)
+		}
+		return nil
+	}
+	for _, stream := range streams {
+		if err := fn(path + ":" + stream); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w Walker) symlinks() SymlinkPolicy {
+	return resolveSymlinks(w.Symlinks, w.FollowSymlinks)
+}
+
+// depth returns the number of levels of relPath below the walked root, 1 for its own entries.
+func depth(relPath string) int {
+	return strings.Count(filepath.ToSlash(relPath), "/") + 1
+}
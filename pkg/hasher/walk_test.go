@@ -0,0 +1,195 @@
+package hasher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWalkerFollowSymlinks tests that symlinked directories are only walked when asked, without looping.
+func TestWalkerFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "b.txt"), []byte("b"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "linked")); err != nil {
+		t.Skipf("Symlinks are not supported here: %v", err)
+	}
+	// a loop back to the root must not be walked forever
+	if err := os.Symlink(root, filepath.Join(outside, "back")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	walk := func(w Walker) string {
+		var found []string
+		err := w.Walk(context.Background(), root, func(path string) error {
+			rel, _ := filepath.Rel(root, path)
+			found = append(found, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() returned an error: %v", err)
+		}
+		sort.Strings(found)
+		return strings.Join(found, ",")
+	}
+
+	if got := walk(Walker{}); got != "a.txt" {
+		t.Errorf("Walk() without following symlinks found %q, expected %q", got, "a.txt")
+	}
+	if got := walk(Walker{FollowSymlinks: true}); got != "a.txt,linked/b.txt" {
+		t.Errorf("Walk() following symlinks found %q, expected %q", got, "a.txt,linked/b.txt")
+	}
+}
+
+// TestWalkerSymlinkPolicies tests that symlinks are skipped, followed without looping or recorded as links.
+func TestWalkerSymlinkPolicies(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "sub", "file-link")); err != nil {
+		t.Skipf("Symlinks are not supported here: %v", err)
+	}
+	// a link to its own directory must not be walked forever
+	if err := os.Symlink(".", filepath.Join(root, "sub", "self-link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	walk := func(policy SymlinkPolicy) string {
+		var found []string
+		err := Walker{Symlinks: policy}.Walk(context.Background(), root, func(path string) error {
+			rel, _ := filepath.Rel(root, path)
+			found = append(found, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() returned an error: %v", err)
+		}
+		sort.Strings(found)
+		return strings.Join(found, ",")
+	}
+
+	tests := []struct {
+		policy SymlinkPolicy
+		want   string
+	}{
+		{SymlinksDefault, "sub/a.txt,sub/file-link"},
+		{SymlinksSkip, "sub/a.txt"},
+		{SymlinksFollow, "sub/a.txt,sub/file-link"},
+		{SymlinksRecord, "sub/a.txt,sub/file-link,sub/self-link"},
+	}
+	for _, tt := range tests {
+		if got := walk(tt.policy); got != tt.want {
+			t.Errorf("Walk() with policy %q found %q, expected %q", tt.policy, got, tt.want)
+		}
+	}
+
+	// a recorded link is hashed as its target path
+	opts := NewOptions(WithSymlinks(SymlinksRecord))
+	got := opts.HashFile(context.Background(), filepath.Join(root, "sub", "file-link"))
+	want := opts.HashReader(context.Background(), strings.NewReader("a.txt"))
+	if got.Err != nil || got.Hash != want.Hash || got.Size != 5 {
+		t.Errorf("HashFile() of a recorded link = %+v, expected hash %s of size 5", got, want.Hash)
+	}
+
+	if _, err := ParseSymlinkPolicy("copy"); err == nil {
+		t.Error("ParseSymlinkPolicy() accepted an unknown policy")
+	}
+}
+
+// TestWalkerOneFileSystem tests that a tree on a single filesystem is fully walked with OneFileSystem.
+func TestWalkerOneFileSystem(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	count := 0
+	err := Walker{OneFileSystem: true}.Walk(context.Background(), root, func(path string) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 2 {
+		t.Errorf("Walk() with OneFileSystem found %d files, %v, expected 2", count, err)
+	}
+}
+
+// TestWalkerMaxDepth tests that the walk does not go deeper than MaxDepth.
+func TestWalkerMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, name := range []string{"1.txt", "a/2.txt", "a/b/3.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	for maxDepth, want := range []int{3, 1, 2, 3, 3} {
+		count := 0
+		err := Walker{MaxDepth: maxDepth}.Walk(context.Background(), root, func(path string) error {
+			count++
+			return nil
+		})
+		if err != nil || count != want {
+			t.Errorf("Walk() with MaxDepth %d found %d files, %v, expected %d", maxDepth, count, err, want)
+		}
+	}
+}
+
+// TestWalkerSizeLimits tests that the files outside of the size limits are skipped.
+func TestWalkerSizeLimits(t *testing.T) {
+	root := t.TempDir()
+	for name, size := range map[string]int{"empty.txt": 0, "small.txt": 10, "large.txt": 1000} {
+		if err := os.WriteFile(filepath.Join(root, name), make([]byte, size), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	var found []string
+	err := Walker{Filter: PathFilter{MinSize: 1, MaxSize: 100}}.Walk(context.Background(), root, func(path string) error {
+		found = append(found, filepath.Base(path))
+		return nil
+	})
+	if err != nil || strings.Join(found, ",") != "small.txt" {
+		t.Errorf("Walk() found %v, %v, expected small.txt", found, err)
+	}
+}
+
+// TestWalkerNewerThan tests that the files not modified after Filter.NewerThan are skipped.
+func TestWalkerNewerThan(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"old.txt": 48 * time.Hour, "new.txt": time.Hour} {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("Failed to set the modification time: %v", err)
+		}
+	}
+	var found []string
+	err := Walker{Filter: PathFilter{NewerThan: now.Add(-24 * time.Hour)}}.Walk(context.Background(), root, func(path string) error {
+		found = append(found, filepath.Base(path))
+		return nil
+	})
+	if err != nil || strings.Join(found, ",") != "new.txt" {
+		t.Errorf("Walk() found %v, %v, expected new.txt", found, err)
+	}
+}
@@ -0,0 +1,73 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xattrPrefix is the prefix of the extended attributes written by WriteTag, the ones of cshatag,
+// so files tagged by one tool can be verified by the other.
+const xattrPrefix = "user.shatag."
+
+// ErrNoTag is returned by ReadTag for a file that has no hash stored in its extended attributes.
+var ErrNoTag = errors.New("no hash stored in the extended attributes")
+
+// Tag is the hash of a file stored in its extended attributes, with the modification time the file had
+// when it was hashed. A file whose content no longer matches its Tag while its modification time is
+// unchanged was silently corrupted (bitrot), as programs writing a file update its modification time.
+type Tag struct {
+	Hash    string // uppercase hexadecimal digest
+	ModTime time.Time
+}
+
+// ReadTag returns the Tag of algorithm stored in the user.shatag.* extended attributes of the file at path,
+// or ErrNoTag when there is none. Extended attributes are only supported on Linux, errors.ErrUnsupported
+// being returned elsewhere or when the filesystem does not support them.
+func ReadTag(path string, algorithm Algorithm) (Tag, error) {
+	hash, err := getxattr(path, xattrPrefix+string(algorithm))
+	if err != nil {
+		return Tag{}, err
+	}
+	ts, err := getxattr(path, xattrPrefix+"ts")
+	if err != nil {
+		return Tag{}, err
+	}
+	modTime, err := parseTimestamp(string(ts))
+	if err != nil {
+		return Tag{}, fmt.Errorf("%s: invalid %sts attribute: %w", path, xattrPrefix, err)
+	}
+	return Tag{Hash: strings.ToUpper(strings.TrimSpace(string(hash))), ModTime: modTime}, nil
+}
+
+// WriteTag stores tag in the user.shatag.* extended attributes of the file at path, the hash in lowercase
+// and the modification time in seconds and nanoseconds like cshatag. It does not change the modification time.
+func WriteTag(path string, algorithm Algorithm, tag Tag) error {
+	if err := setxattr(path, xattrPrefix+string(algorithm), []byte(strings.ToLower(tag.Hash))); err != nil {
+		return err
+	}
+	ts := fmt.Sprintf("%d.%09d", tag.ModTime.Unix(), tag.ModTime.Nanosecond())
+	return setxattr(path, xattrPrefix+"ts", []byte(ts))
+}
+
+// parseTimestamp parses a timestamp written as seconds, optionally followed by a dot and the fraction of second.
+func parseTimestamp(s string) (time.Time, error) {
+	sec, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	seconds, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nanos int64
+	if frac != "" {
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		nanos, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(seconds, nanos), nil
+}
@@ -0,0 +1,34 @@
+package hasher
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+)
+
+// getxattr returns the value of the extended attribute name of the file at path, or ErrNoTag when it is not set.
+func getxattr(path, name string) ([]byte, error) {
+	buf := make([]byte, 128)
+	for {
+		n, err := syscall.Getxattr(path, name, buf)
+		if errors.Is(err, syscall.ERANGE) {
+			buf = make([]byte, len(buf)*2)
+			continue
+		}
+		if errors.Is(err, syscall.ENODATA) {
+			return nil, ErrNoTag
+		}
+		if err != nil {
+			return nil, &fs.PathError{Op: "getxattr", Path: path, Err: err}
+		}
+		return buf[:n], nil
+	}
+}
+
+// setxattr sets the extended attribute name of the file at path to value.
+func setxattr(path, name string, value []byte) error {
+	if err := syscall.Setxattr(path, name, value, 0); err != nil {
+		return &fs.PathError{Op: "setxattr", Path: path, Err: err}
+	}
+	return nil
+}
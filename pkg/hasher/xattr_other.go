@@ -0,0 +1,18 @@
+//go:build !linux
+
+package hasher
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// getxattr is not available on this platform, the standard library only exposing extended attributes on Linux.
+func getxattr(path, name string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "getxattr", Path: path, Err: errors.ErrUnsupported}
+}
+
+// setxattr is not available on this platform.
+func setxattr(path, name string, value []byte) error {
+	return &fs.PathError{Op: "setxattr", Path: path, Err: errors.ErrUnsupported}
+}
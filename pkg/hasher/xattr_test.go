@@ -0,0 +1,55 @@
+package hasher
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTag tests that a tag written to the extended attributes of a file is read back unchanged.
+func TestTag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := ReadTag(path, SHA256); errors.Is(err, errors.ErrUnsupported) {
+		t.Skipf("extended attributes are not supported here: %v", err)
+	} else if !errors.Is(err, ErrNoTag) {
+		t.Fatalf("ReadTag() of an untagged file returned %v, expected ErrNoTag", err)
+	}
+	tag := Tag{Hash: "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD", ModTime: time.Unix(1700000000, 12345)}
+	if err := WriteTag(path, SHA256, tag); errors.Is(err, errors.ErrUnsupported) {
+		t.Skipf("user extended attributes are not supported by this filesystem: %v", err)
+	} else if err != nil {
+		t.Fatalf("WriteTag() returned an error: %v", err)
+	}
+	got, err := ReadTag(path, SHA256)
+	if err != nil || got.Hash != tag.Hash || !got.ModTime.Equal(tag.ModTime) {
+		t.Errorf("ReadTag() = %+v, %v, expected %+v", got, err, tag)
+	}
+	if _, err := ReadTag(path, MD5); !errors.Is(err, ErrNoTag) {
+		t.Errorf("ReadTag() of another algorithm returned %v, expected ErrNoTag", err)
+	}
+}
+
+// TestParseTimestamp tests the parsing of the timestamps written by cshatag and older tools.
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		ts      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"1700000000.000012345", time.Unix(1700000000, 12345), false},
+		{"1700000000.5", time.Unix(1700000000, 500000000), false},
+		{"1700000000", time.Unix(1700000000, 0), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.ts)
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("parseTimestamp(%q) = %v, %v, expected %v", tt.ts, got, err, tt.want)
+		}
+	}
+}
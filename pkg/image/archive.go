@@ -0,0 +1,195 @@
+package image
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// member is a regular file of the tarball, read again from its offset.
+type member struct {
+	offset, size int64
+}
+
+// archive is the source of the blobs of a tarball, which members are found by a first pass over the headers.
+type archive struct {
+	f       *os.File
+	members map[string]member
+}
+
+// offsetReader reads f counting its offset, and seeks so tar skips the contents without reading them.
+type offsetReader struct {
+	f      *os.File
+	offset int64
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := r.f.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *offsetReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.f.Seek(offset, whence)
+	if err == nil {
+		r.offset = n
+	}
+	return n, err
+}
+
+// openArchive lists the members of the tarball f.
+func openArchive(f *os.File) (*archive, error) {
+	a := &archive{f: f, members: make(map[string]member)}
+	r := &offsetReader{f: f}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading the tarball: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			a.members[path.Clean(strings.TrimPrefix(hdr.Name, "./"))] = member{offset: r.offset, size: hdr.Size}
+		}
+	}
+	if len(a.members) == 0 {
+		return nil, errors.New("not a tarball of an image, it has no files")
+	}
+	return a, nil
+}
+
+func (a *archive) open(ctx context.Context, d descriptor) (io.ReadCloser, error) {
+	name := d.path
+	if name == "" {
+		algorithm, hex, _ := strings.Cut(d.Digest, ":")
+		name = path.Join("blobs", algorithm, hex)
+	}
+	m, ok := a.members[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrBlobNotFound)
+	}
+	return io.NopCloser(io.NewSectionReader(a.f, m.offset, m.size)), nil
+}
+
+// dockerManifest is an image of the manifest.json file written by docker save.
+type dockerManifest struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// VerifyArchive verifies the images of the tarball at p, written by docker save or holding an OCI layout like
+// the ones of skopeo copy oci-archive:, fn being given the content of their layers when not nil. The images of
+// the index.json of an OCI layout are verified from their manifest, and the ones of the manifest.json of docker
+// save from their config, the layers of older versions of Docker having no digest of their own.
+func VerifyArchive(ctx context.Context, p string, fn LayerFunc) ([]Image, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, 2)
+	if n, _ := f.ReadAt(magic, 0); n == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return nil, errors.New("the tarball is compressed, decompress it first, like with gunzip")
+	}
+	a, err := openArchive(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := a.members["index.json"]; ok {
+		var index manifest
+		b := readJSON(ctx, a, KindIndex, descriptor{path: "index.json", Size: -1}, &index)
+		if !b.OK() {
+			return nil, fmt.Errorf("reading index.json: %s", b.Problem())
+		}
+		return verifyIndex(ctx, a, "", index, "", false, fn), nil
+	}
+	if _, ok := a.members["manifest.json"]; !ok {
+		return nil, errors.New("not a tarball of an image, it has neither index.json nor manifest.json")
+	}
+	var manifests []dockerManifest
+	b := readJSON(ctx, a, KindManifest, descriptor{path: "manifest.json", Size: -1}, &manifests)
+	if !b.OK() {
+		return nil, fmt.Errorf("reading manifest.json: %s", b.Problem())
+	}
+	var images []Image
+	for _, dm := range manifests {
+		img := Image{Name: strings.Join(dm.RepoTags, ", ")}
+		m := manifest{Config: &descriptor{Digest: digestOfPath(dm.Config), Size: -1, path: dm.Config}}
+		for _, layer := range dm.Layers {
+			m.Layers = append(m.Layers, descriptor{Digest: digestOfPath(layer), Size: -1, path: layer})
+		}
+		verifyImage(ctx, a, &img, m, fn)
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// digestOfPath returns the digest named by the path of a blob in a docker save tarball, blobs/sha256/hex
+// for Docker 25 and later, and hex.json for the configs of the older versions, or "" when unknown.
+func digestOfPath(p string) string {
+	dir, base := path.Split(p)
+	base = strings.TrimSuffix(base, ".json")
+	if dir == "blobs/sha256/" || (dir == "" && len(base) == 64) {
+		return "sha256:" + base
+	}
+	if dir == "blobs/sha512/" {
+		return "sha512:" + base
+	}
+	return ""
+}
+
+// verifyIndex verifies the images of the OCI index or Docker manifest list, the manifests matching want
+// when it is not empty, like linux/amd64, named name unless their annotations tell their name. The manifests
+// of a nested index may be missing, like the ones of the other platforms when a single one was saved.
+func verifyIndex(ctx context.Context, src source, name string, index manifest, want string, nested bool, fn LayerFunc) []Image {
+	var images []Image
+	for _, d := range index.Manifests {
+		if want != "" && d.Platform != nil && !matchPlatform(*d.Platform, want) {
+			continue
+		}
+		if d.Platform != nil && d.Platform.Architecture == "unknown" {
+			continue // the attestations of buildx
+		}
+		imageName := name
+		if n := d.imageName(); n != "" {
+			imageName = n
+		}
+		if d.MediaType == "" {
+			d.MediaType = "application/vnd.oci.image.manifest.v1+json" // read from the manifests of the registries
+		}
+		var m manifest
+		img := Image{Name: imageName}
+		b := readJSON(ctx, src, KindManifest, d, &m)
+		img.Blobs = append(img.Blobs, b)
+		switch {
+		case nested && errors.Is(b.Err, ErrBlobNotFound):
+			continue
+		case !b.OK():
+			img.Err = fmt.Errorf("the manifest is not valid: %s", b.Problem())
+		case m.isIndex():
+			images = append(images, verifyIndex(ctx, src, imageName, m, want, true, fn)...)
+			continue
+		default:
+			verifyImage(ctx, src, &img, m, fn)
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// matchPlatform reports whether p is the platform os/arch[/variant] of want.
+func matchPlatform(p platform, want string) bool {
+	name := p.OS + "/" + p.Architecture
+	if p.Variant != "" && strings.Count(want, "/") == 2 {
+		name += "/" + p.Variant
+	}
+	return name == want
+}
@@ -0,0 +1,162 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testLayer returns a layer holding a single file, as a tar archive.
+func testLayer(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write([]byte(content))
+	tw.Close()
+	return buf.Bytes()
+}
+
+func gzipped(b []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(b)
+	gz.Close()
+	return buf.Bytes()
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func mustJSON(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// writeTar writes the files in order to a tarball in a temporary directory.
+func writeTar(t *testing.T, files [][2]string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "image.tar")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file[0], Mode: 0o644, Size: int64(len(file[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(file[1]))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return p
+}
+
+// dockerSaveTar returns a tarball like the ones of docker save before Docker 25, with a config named by its digest
+// and an uncompressed layer, which content is given by layer.
+func dockerSaveTar(t *testing.T, layer []byte, diffID string) string {
+	t.Helper()
+	cfg := mustJSON(t, map[string]any{"rootfs": map[string]any{"type": "layers", "diff_ids": []string{diffID}}})
+	configName := digestOf(cfg)[len("sha256:"):] + ".json"
+	m := mustJSON(t, []dockerManifest{{Config: configName, RepoTags: []string{"app:1.0"}, Layers: []string{"0123/layer.tar"}}})
+	return writeTar(t, [][2]string{{configName, string(cfg)}, {"0123/layer.tar", string(layer)}, {"manifest.json", string(m)}})
+}
+
+// TestVerifyArchiveDockerSave tests the tarballs of docker save, a layer being verified from the diff ID of the config.
+func TestVerifyArchiveDockerSave(t *testing.T) {
+	layer := testLayer(t, "etc/hostname", "box\n")
+	p := dockerSaveTar(t, layer, digestOf(layer))
+	var seen []byte
+	images, err := VerifyArchive(context.Background(), p, func(ctx context.Context, index int, diffID string, content io.Reader) error {
+		var err error
+		seen, err = io.ReadAll(content)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("VerifyArchive() returned an error: %v", err)
+	}
+	if len(images) != 1 || images[0].Name != "app:1.0" || !images[0].OK() || len(images[0].Blobs) != 2 {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+	if l := images[0].Blobs[1]; l.Kind != KindLayer || l.ActualDiffID != digestOf(layer) {
+		t.Errorf("unexpected layer: %+v", l)
+	}
+	if !bytes.Equal(seen, layer) {
+		t.Error("the LayerFunc was not given the content of the layer")
+	}
+
+	tampered := testLayer(t, "etc/hostname", "bad\n")
+	images, err = VerifyArchive(context.Background(), dockerSaveTar(t, tampered, digestOf(layer)), nil)
+	if err != nil {
+		t.Fatalf("VerifyArchive() returned an error: %v", err)
+	}
+	if images[0].OK() || images[0].Blobs[1].Problem() == "" {
+		t.Errorf("the tampered layer was not detected: %+v", images[0].Blobs[1])
+	}
+}
+
+// TestVerifyArchiveOCI tests the tarballs holding an OCI layout, with a gzip compressed layer.
+func TestVerifyArchiveOCI(t *testing.T) {
+	layer := testLayer(t, "bin/app", "#!/bin/sh\n")
+	compressed := gzipped(layer)
+	cfg := mustJSON(t, map[string]any{"rootfs": map[string]any{"type": "layers", "diff_ids": []string{digestOf(layer)}}})
+	m := mustJSON(t, manifest{MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Config: &descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: digestOf(cfg), Size: int64(len(cfg))},
+		Layers: []descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(compressed), Size: int64(len(compressed))}}})
+	index := mustJSON(t, manifest{Manifests: []descriptor{{MediaType: "application/vnd.oci.image.manifest.v1+json", Digest: digestOf(m), Size: int64(len(m)),
+		Annots: map[string]string{"org.opencontainers.image.ref.name": "1.0"}}}})
+	blob := func(b []byte) string { return "blobs/sha256/" + digestOf(b)[len("sha256:"):] }
+	files := [][2]string{{"oci-layout", `{"imageLayoutVersion":"1.0.0"}`}, {"index.json", string(index)},
+		{blob(m), string(m)}, {blob(cfg), string(cfg)}, {blob(compressed), string(compressed)}}
+
+	images, err := VerifyArchive(context.Background(), writeTar(t, files), nil)
+	if err != nil {
+		t.Fatalf("VerifyArchive() returned an error: %v", err)
+	}
+	if len(images) != 1 || images[0].Name != "1.0" || !images[0].OK() || len(images[0].Blobs) != 3 {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+	if l := images[0].Blobs[2]; l.Actual != digestOf(compressed) || l.ActualDiffID != digestOf(layer) {
+		t.Errorf("unexpected layer: %+v", l)
+	}
+
+	images, err = VerifyArchive(context.Background(), writeTar(t, files[:4]), nil)
+	if err != nil {
+		t.Fatalf("VerifyArchive() returned an error: %v", err)
+	}
+	if l := images[0].Blobs[2]; images[0].OK() || !errors.Is(l.Err, ErrBlobNotFound) {
+		t.Errorf("the missing layer was not detected: %+v", l)
+	}
+}
+
+// TestVerifyArchiveInvalid tests the files that are not tarballs of images.
+func TestVerifyArchiveInvalid(t *testing.T) {
+	if _, err := VerifyArchive(context.Background(), writeTar(t, [][2]string{{"a.txt", "abc"}}), nil); err == nil {
+		t.Error("VerifyArchive() returned no error for a tarball without manifest")
+	}
+	p := filepath.Join(t.TempDir(), "image.tar.gz")
+	os.WriteFile(p, gzipped([]byte("abc")), 0o644)
+	if _, err := VerifyArchive(context.Background(), p, nil); err == nil {
+		t.Error("VerifyArchive() returned no error for a compressed tarball")
+	}
+}
@@ -0,0 +1,307 @@
+// Package image verifies the integrity of container images: the digests of the manifest, the config and the
+// layers of an image saved with docker save or in an OCI layout tarball, or pulled from a registry, are compared
+// with the ones its manifest and its config record, the compressed layers being also checked against the digests
+// of their uncompressed content, the diff IDs of the config. The content of the layers can be read on the way,
+// to hash the files inside them.
+package image
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Kinds of the blobs of an image.
+const (
+	KindIndex    = "index"
+	KindManifest = "manifest"
+	KindConfig   = "config"
+	KindLayer    = "layer"
+)
+
+// ErrBlobNotFound is the error of the blobs listed by a manifest that are not in the archive or the registry.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// maxJSONSize limits the size of the manifests and configs read in memory.
+const maxJSONSize = 16 << 20
+
+// Blob is the outcome of the verification of a blob of an image.
+type Blob struct {
+	Kind         string
+	Name         string // path in the archive, or digest in the registry
+	Digest       string // expected, like sha256:hex, empty when the archive does not record it
+	Actual       string
+	Size         int64 // expected, -1 when unknown
+	ActualSize   int64
+	DiffID       string // of the layers, the expected digest of their uncompressed content listed in the config
+	ActualDiffID string // empty when the layer could not be decompressed, like zstd layers
+	Err          error
+}
+
+// OK reports whether the blob was read and matches its digests and its size.
+func (b Blob) OK() bool {
+	return b.Err == nil && (b.Digest == "" || b.Digest == b.Actual) && (b.Size < 0 || b.Size == b.ActualSize) &&
+		(b.DiffID == "" || b.ActualDiffID == "" || b.DiffID == b.ActualDiffID)
+}
+
+// Problem returns what is wrong with the blob, or "" when it is OK.
+func (b Blob) Problem() string {
+	switch {
+	case b.Err != nil:
+		return b.Err.Error()
+	case b.Digest != "" && b.Digest != b.Actual:
+		return "digest " + b.Actual + " instead of " + b.Digest
+	case b.Size >= 0 && b.Size != b.ActualSize:
+		return fmt.Sprintf("size %d instead of %d", b.ActualSize, b.Size)
+	case b.DiffID != "" && b.ActualDiffID != "" && b.DiffID != b.ActualDiffID:
+		return "uncompressed digest " + b.ActualDiffID + " instead of the diff ID " + b.DiffID
+	}
+	return ""
+}
+
+// Image is the outcome of the verification of an image.
+type Image struct {
+	Name  string // tag or reference, when known
+	Blobs []Blob
+	Err   error // the manifest or the config could not be read, the blobs being then incomplete
+}
+
+// OK reports whether every blob of the image is OK.
+func (i Image) OK() bool {
+	if i.Err != nil {
+		return false
+	}
+	for _, b := range i.Blobs {
+		if !b.OK() {
+			return false
+		}
+	}
+	return true
+}
+
+// LayerFunc is given the uncompressed content of each layer in order, a tar archive, to hash the files inside
+// it, the layer being verified from what it reads and what it leaves. diffID is the digest the config expects.
+type LayerFunc func(ctx context.Context, index int, diffID string, content io.Reader) error
+
+// descriptor points to a blob, like the descriptors of the OCI manifests.
+type descriptor struct {
+	MediaType string            `json:"mediaType"`
+	Digest    string            `json:"digest"`
+	Size      int64             `json:"size"`
+	Platform  *platform         `json:"platform,omitempty"`
+	Annots    map[string]string `json:"annotations,omitempty"`
+	path      string            // in the archive, when not blobs/<algorithm>/<hex>
+}
+
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// manifest is an OCI image manifest or index, or a Docker v2 manifest or manifest list, told apart by their fields.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *descriptor  `json:"config,omitempty"`
+	Layers    []descriptor `json:"layers,omitempty"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+}
+
+// config is the part of the image config listing the digests of the uncompressed layers.
+type config struct {
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// source reads the blobs of an image, from an archive or a registry.
+type source interface {
+	open(ctx context.Context, d descriptor) (io.ReadCloser, error)
+}
+
+// newDigester returns the hash computing digests like digest, sha256:hex or sha512:hex.
+func newDigester(digest string) (hash.Hash, error) {
+	algorithm, _, _ := strings.Cut(digest, ":")
+	switch algorithm {
+	case "sha256", "":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported digest %q", digest)
+}
+
+// formatDigest returns the digest computed by h, like the ones of digest.
+func formatDigest(h hash.Hash) string {
+	algorithm := "sha256"
+	if h.Size() == sha512.Size {
+		algorithm = "sha512"
+	}
+	return algorithm + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// readJSON reads the blob of d, verifies it and decodes it into v when it matches.
+func readJSON(ctx context.Context, src source, kind string, d descriptor, v any) Blob {
+	b := Blob{Kind: kind, Name: d.name(), Digest: d.Digest, Size: d.Size}
+	content, err := readBlob(ctx, src, d)
+	if err != nil {
+		b.Err = err
+		return b
+	}
+	b.Actual, b.ActualSize = content.digest, int64(len(content.data))
+	if b.OK() {
+		if err := json.Unmarshal(content.data, v); err != nil {
+			b.Err = fmt.Errorf("decoding the %s: %w", kind, err)
+		}
+	}
+	return b
+}
+
+type blobContent struct {
+	data   []byte
+	digest string
+}
+
+// readBlob reads the small blob of d in memory, with its digest.
+func readBlob(ctx context.Context, src source, d descriptor) (blobContent, error) {
+	h, err := newDigester(d.Digest)
+	if err != nil {
+		return blobContent{}, err
+	}
+	rc, err := src.open(ctx, d)
+	if err != nil {
+		return blobContent{}, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxJSONSize+1))
+	if err != nil {
+		return blobContent{}, err
+	}
+	if len(data) > maxJSONSize {
+		return blobContent{}, fmt.Errorf("larger than %d bytes", maxJSONSize)
+	}
+	h.Write(data)
+	return blobContent{data: data, digest: formatDigest(h)}, nil
+}
+
+// name returns the name of the blob of d in the results.
+func (d descriptor) name() string {
+	if d.path != "" {
+		return d.path
+	}
+	return d.Digest
+}
+
+// verifyImage verifies the config and the layers of the image manifest m, appending their blobs to img.
+func verifyImage(ctx context.Context, src source, img *Image, m manifest, fn LayerFunc) {
+	if m.Config == nil {
+		img.Err = errors.New("the manifest has no config")
+		return
+	}
+	var c config
+	b := readJSON(ctx, src, KindConfig, *m.Config, &c)
+	img.Blobs = append(img.Blobs, b)
+	if !b.OK() {
+		img.Err = fmt.Errorf("the config is not valid, its layers cannot be verified: %s", b.Problem())
+		return
+	}
+	if len(c.RootFS.DiffIDs) != len(m.Layers) {
+		img.Err = fmt.Errorf("the config lists %d layers, the manifest %d", len(c.RootFS.DiffIDs), len(m.Layers))
+		return
+	}
+	for i, d := range m.Layers {
+		if ctx.Err() != nil {
+			img.Err = ctx.Err()
+			return
+		}
+		img.Blobs = append(img.Blobs, verifyLayer(ctx, src, i, d, c.RootFS.DiffIDs[i], fn))
+	}
+}
+
+// verifyLayer reads the layer d, hashing its content as stored and once uncompressed, given to fn.
+func verifyLayer(ctx context.Context, src source, index int, d descriptor, diffID string, fn LayerFunc) Blob {
+	b := Blob{Kind: KindLayer, Name: d.name(), Digest: d.Digest, Size: d.Size, DiffID: diffID}
+	h, err := newDigester(d.Digest)
+	if err != nil {
+		b.Err = err
+		return b
+	}
+	diff, err := newDigester(diffID)
+	if err != nil {
+		b.Err = err
+		return b
+	}
+	rc, err := src.open(ctx, d)
+	if err != nil {
+		b.Err = err
+		return b
+	}
+	defer rc.Close()
+	counter := &countingWriter{}
+	r := bufio.NewReaderSize(io.TeeReader(rc, io.MultiWriter(h, counter)), 1<<20)
+	var content io.Reader
+	magic, _ := r.Peek(4)
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			b.Err = err
+			return b
+		}
+		defer gz.Close()
+		content = gz
+	case len(magic) == 4 && string(magic) == "\x28\xb5\x2f\xfd", strings.Contains(d.MediaType, "zstd"):
+		content = nil // zstd is not in the standard library, only the digest of the compressed layer is verified
+	default:
+		content = r
+	}
+	if content != nil {
+		tee := io.TeeReader(content, diff)
+		if fn != nil {
+			err = fn(ctx, index, diffID, tee)
+		}
+		if err == nil {
+			_, err = io.Copy(io.Discard, tee) // what fn left, like the padding of the tar archive
+		}
+		if err != nil {
+			b.Err = fmt.Errorf("reading the content of the layer: %w", err)
+			return b
+		}
+		b.ActualDiffID = formatDigest(diff)
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		b.Err = err
+		return b
+	}
+	b.Actual, b.ActualSize = formatDigest(h), counter.n
+	return b
+}
+
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// isIndex reports whether m lists manifests, an OCI index or a Docker manifest list.
+func (m manifest) isIndex() bool {
+	return m.Config == nil && m.Manifests != nil
+}
+
+// imageName returns the name of the image of an index descriptor, from its annotations.
+func (d descriptor) imageName() string {
+	if name := d.Annots["io.containerd.image.name"]; name != "" {
+		return name
+	}
+	return d.Annots["org.opencontainers.image.ref.name"]
+}
@@ -0,0 +1,338 @@
+package image
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// manifestTypes are the media types of the manifests accepted from the registries.
+var manifestTypes = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// Reference is an image in a registry, like nginx:1.27, ghcr.io/org/app@sha256:hex or localhost:5000/app.
+type Reference struct {
+	Registry   string // host[:port], registry-1.docker.io for Docker Hub
+	Repository string // library/nginx for the official images of Docker Hub
+	Tag        string // latest when neither a tag nor a digest is given
+	Digest     string
+}
+
+// ParseReference returns the Reference of an image named like docker pull does.
+func ParseReference(s string) (Reference, error) {
+	var r Reference
+	rest := s
+	if before, digest, ok := strings.Cut(rest, "@"); ok {
+		rest, r.Digest = before, digest
+		if _, err := newDigester(digest); err != nil || !strings.Contains(digest, ":") {
+			return r, fmt.Errorf("invalid digest in %q", s)
+		}
+	}
+	if i := strings.LastIndexByte(rest, ':'); i > strings.LastIndexByte(rest, '/') {
+		rest, r.Tag = rest[:i], rest[i+1:]
+	}
+	if first, repository, ok := strings.Cut(rest, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.Registry, rest = first, repository
+	} else {
+		r.Registry = "docker.io"
+	}
+	if rest == "" || rest != strings.ToLower(rest) || strings.ContainsAny(rest, " \t") {
+		return r, fmt.Errorf("invalid image reference %q", s)
+	}
+	if r.Registry == "docker.io" || r.Registry == "index.docker.io" {
+		r.Registry = "registry-1.docker.io"
+		if !strings.Contains(rest, "/") {
+			rest = "library/" + rest
+		}
+	}
+	r.Repository = rest
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// String returns the reference as docker pull takes it.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Registry reads the images of the registries speaking the OCI distribution API, anonymously or with the
+// credentials of docker login found in ~/.docker/config.json, credential helpers being not supported.
+type Registry struct {
+	Client   *http.Client // http.DefaultClient when nil
+	Platform string       // os/arch[/variant] of the image chosen in multi-platform indexes, linux/GOARCH when empty
+	// ConfigFile is the Docker configuration file holding the credentials, $DOCKER_CONFIG/config.json
+	// or ~/.docker/config.json when empty
+	ConfigFile string
+}
+
+// registrySource reads the blobs of a repository, with the token of the registry once authenticated.
+type registrySource struct {
+	registry Registry
+	ref      Reference
+	mu       sync.Mutex
+	auth     string // Authorization header
+}
+
+// Verify pulls the image of ref, verifying the digests of its manifest, its config and its layers as they are
+// downloaded, fn being given the content of the layers when not nil. The manifest must have the digest of ref,
+// when given, and the one the registry tells otherwise. For a multi-platform image, only the image of the
+// platform of r is verified.
+func (r Registry) Verify(ctx context.Context, ref Reference, fn LayerFunc) ([]Image, error) {
+	src := &registrySource{registry: r, ref: ref}
+	reference := ref.Digest
+	if reference == "" {
+		reference = ref.Tag
+	}
+	resp, err := src.get(ctx, "manifests/"+reference, manifestTypes)
+	if err != nil {
+		return nil, err
+	}
+	expected := ref.Digest
+	if expected == "" {
+		expected = resp.Header.Get("Docker-Content-Digest")
+	}
+	body := &bodySource{body: resp.Body}
+	d := descriptor{MediaType: resp.Header.Get("Content-Type"), Digest: expected, Size: -1, path: "manifest " + ref.String()}
+	var m manifest
+	b := readJSON(ctx, body, KindManifest, d, &m)
+	if !b.OK() {
+		return []Image{{Name: ref.String(), Blobs: []Blob{b}, Err: fmt.Errorf("the manifest is not valid: %s", b.Problem())}}, nil
+	}
+	if m.isIndex() {
+		want := r.Platform
+		if want == "" {
+			want = "linux/" + runtime.GOARCH
+		}
+		images := verifyIndex(ctx, src, ref.String(), m, want, false, fn)
+		if len(images) == 0 {
+			return nil, fmt.Errorf("the image has no manifest for the platform %s", want)
+		}
+		for i := range images {
+			images[i].Blobs = append([]Blob{b}, images[i].Blobs...)
+		}
+		return images, nil
+	}
+	img := Image{Name: ref.String(), Blobs: []Blob{b}}
+	verifyImage(ctx, src, &img, m, fn)
+	return []Image{img}, nil
+}
+
+// bodySource gives the body of a response as the blob of its descriptor.
+type bodySource struct {
+	body io.ReadCloser
+}
+
+func (s *bodySource) open(context.Context, descriptor) (io.ReadCloser, error) {
+	return s.body, nil
+}
+
+func (s *registrySource) open(ctx context.Context, d descriptor) (io.ReadCloser, error) {
+	kind, accept := "blobs/", ""
+	if strings.Contains(d.MediaType, "manifest") || strings.Contains(d.MediaType, "index") {
+		kind, accept = "manifests/", manifestTypes
+	}
+	resp, err := s.get(ctx, kind+d.Digest, accept)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// get requests path below the repository, authenticating when the registry asks for it.
+func (s *registrySource) get(ctx context.Context, path, accept string) (*http.Response, error) {
+	client := s.registry.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := "https://" + s.ref.Registry + "/v2/" + s.ref.Repository + "/" + path
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		s.mu.Lock()
+		if s.auth != "" {
+			req.Header.Set("Authorization", s.auth)
+		}
+		s.mu.Unlock()
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return resp, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := s.authenticate(ctx, client, challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %w", path, ErrBlobNotFound)
+		}
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+}
+
+// authenticate answers the challenge of the registry: Basic with the credentials of docker login,
+// or Bearer with a token pulling the repository, obtained with these credentials or anonymously.
+func (s *registrySource) authenticate(ctx context.Context, client *http.Client, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	credentials := s.registry.credentials(s.ref.Registry)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credentials == "" {
+			return fmt.Errorf("the registry %s needs credentials, run docker login %s", s.ref.Registry, s.ref.Registry)
+		}
+		s.mu.Lock()
+		s.auth = "Basic " + credentials
+		s.mu.Unlock()
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication %q of the registry %s", scheme, s.ref.Registry)
+	}
+	attributes := parseChallenge(params)
+	realm, err := url.Parse(attributes["realm"])
+	if err != nil || realm.Scheme == "" {
+		return fmt.Errorf("invalid authentication realm %q of the registry %s", attributes["realm"], s.ref.Registry)
+	}
+	query := realm.Query()
+	if service := attributes["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := attributes["scope"]
+	if scope == "" {
+		scope = "repository:" + s.ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if credentials != "" {
+		req.Header.Set("Authorization", "Basic "+credentials)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("getting a token from %s: %s", realm.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJSONSize)).Decode(&token); err != nil {
+		return fmt.Errorf("decoding the token of %s: %w", realm.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return fmt.Errorf("no token from %s", realm.Host)
+	}
+	s.mu.Lock()
+	s.auth = "Bearer " + token.Token
+	s.mu.Unlock()
+	return nil
+}
+
+// parseChallenge returns the attributes of a WWW-Authenticate challenge, like realm="https://auth",service="registry".
+func parseChallenge(params string) map[string]string {
+	attributes := make(map[string]string)
+	for params != "" {
+		key, rest, ok := strings.Cut(params, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		attributes[key] = value
+		params = strings.TrimLeft(rest, ", ")
+	}
+	return attributes
+}
+
+// credentials returns the base64 user:password of docker login for registry, or "" when none is stored.
+func (r Registry) credentials(registry string) string {
+	name := r.ConfigFile
+	if name == "" {
+		dir := os.Getenv("DOCKER_CONFIG")
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return ""
+			}
+			dir = filepath.Join(home, ".docker")
+		}
+		name = filepath.Join(dir, "config.json")
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if json.Unmarshal(content, &config) != nil {
+		return ""
+	}
+	keys := []string{registry, "https://" + registry}
+	if registry == "registry-1.docker.io" {
+		keys = append(keys, "https://index.docker.io/v1/", "index.docker.io", "docker.io")
+	}
+	for _, key := range keys {
+		if auth := config.Auths[key].Auth; auth != "" {
+			if _, err := base64.StdEncoding.DecodeString(auth); err == nil {
+				return auth
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseReference tests the names of the images, like the ones docker pull takes.
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		in       string
+		expected Reference
+	}{
+		{"nginx", Reference{Registry: "registry-1.docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"bitnami/redis:7.2", Reference{Registry: "registry-1.docker.io", Repository: "bitnami/redis", Tag: "7.2"}},
+		{"ghcr.io/org/app@" + digest, Reference{Registry: "ghcr.io", Repository: "org/app", Digest: digest}},
+		{"localhost:5000/app:v1@" + digest, Reference{Registry: "localhost:5000", Repository: "app", Tag: "v1", Digest: digest}},
+	}
+	for _, tt := range tests {
+		r, err := ParseReference(tt.in)
+		if err != nil || r != tt.expected {
+			t.Errorf("ParseReference(%q) = %+v, %v, expected %+v", tt.in, r, err, tt.expected)
+		}
+	}
+	for _, in := range []string{"", "App", "app@md5:abc"} {
+		if _, err := ParseReference(in); err == nil {
+			t.Errorf("ParseReference(%q) returned no error", in)
+		}
+	}
+}
+
+// TestParseChallenge tests the attributes of the WWW-Authenticate challenges.
+func TestParseChallenge(t *testing.T) {
+	a := parseChallenge(`realm="https://auth.example.com/token",service="registry.example.com",scope="repository:app:pull"`)
+	if a["realm"] != "https://auth.example.com/token" || a["service"] != "registry.example.com" || a["scope"] != "repository:app:pull" {
+		t.Errorf("parseChallenge() = %v", a)
+	}
+}
+
+// TestRegistryVerify tests an image pulled from a registry asking for a token, with a tampered layer.
+func TestRegistryVerify(t *testing.T) {
+	layer := testLayer(t, "bin/app", "#!/bin/sh\n")
+	compressed := gzipped(layer)
+	cfg := mustJSON(t, map[string]any{"rootfs": map[string]any{"type": "layers", "diff_ids": []string{digestOf(layer)}}})
+	m := mustJSON(t, manifest{MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Config: &descriptor{MediaType: "application/vnd.oci.image.config.v1+json", Digest: digestOf(cfg), Size: int64(len(cfg))},
+		Layers: []descriptor{{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(compressed), Size: int64(len(compressed))}}})
+	blobs := map[string][]byte{digestOf(cfg): cfg, digestOf(compressed): compressed}
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:org/app:pull" {
+				http.Error(w, "bad scope", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token":"secret"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v2/org/app/manifests/1.0":
+			w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+			w.Header().Set("Docker-Content-Digest", digestOf(m))
+			w.Write(m)
+		case strings.HasPrefix(r.URL.Path, "/v2/org/app/blobs/"):
+			b, ok := blobs[strings.TrimPrefix(r.URL.Path, "/v2/org/app/blobs/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(b)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ref := Reference{Registry: strings.TrimPrefix(srv.URL, "https://"), Repository: "org/app", Tag: "1.0"}
+	reg := Registry{Client: srv.Client(), ConfigFile: "/nonexistent/config.json"}
+	images, err := reg.Verify(context.Background(), ref, nil)
+	if err != nil {
+		t.Fatalf("Verify() returned an error: %v", err)
+	}
+	if len(images) != 1 || !images[0].OK() || len(images[0].Blobs) != 3 || images[0].Blobs[2].ActualDiffID != digestOf(layer) {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+
+	blobs[digestOf(compressed)] = gzipped(testLayer(t, "bin/app", "evil"))
+	images, err = reg.Verify(context.Background(), ref, nil)
+	if err != nil {
+		t.Fatalf("Verify() returned an error: %v", err)
+	}
+	if images[0].OK() || !strings.HasPrefix(images[0].Blobs[2].Problem(), "digest ") {
+		t.Errorf("the tampered layer was not detected: %+v", images[0].Blobs[2])
+	}
+
+	if _, err := reg.Verify(context.Background(), Reference{Registry: ref.Registry, Repository: "org/app", Tag: "2.0"}, nil); err == nil {
+		t.Error("Verify() returned no error for a missing tag")
+	}
+}
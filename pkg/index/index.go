@@ -0,0 +1,288 @@
+// Package index stores the results of successive scans in an index directory and answers
+// queries on them: duplicate files, files having a given hash and differences between two scans.
+//
+// Each scan is written as two tables of its entries, one sorted by path and one sorted by hash, with a
+// small index of their blocks, so a lookup of a hash reads a single block of each scan and the duplicates
+// and the differences between two scans are found by reading the tables once, in order, without loading
+// a scan in memory. The entries are sorted while the scan runs by runs of at most runSize entries,
+// merged when it ends, so writing a scan of millions of files only keeps one run in memory.
+//
+// A scan is only visible once its tables are complete: the files of the scan N are N.paths, N.hashes
+// and N.scan, written last, and the ones of a scan still running, or interrupted by a crash, are in the
+// N.tmp directory. Older scans are never rewritten.
+package index
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runSize is the number of entries of a scan sorted in memory before they are written to a run.
+const runSize = 250_000
+
+// Scan describes one run that recorded its results in the index.
+type Scan struct {
+	ID    int       `json:"id"`
+	Time  time.Time `json:"time"`
+	Roots []string  `json:"roots,omitempty"` // files and directories given to the scan
+	Files int       `json:"files"`           // number of entries recorded
+}
+
+// Entry is a file recorded by a scan.
+type Entry struct {
+	Scan    int
+	Path    string
+	Hash    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Writer records a new scan in an index directory. It is safe for concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	dir     string
+	tmp     string // the directory of the runs and of the tables until the scan is complete
+	scan    Scan
+	batch   []Entry
+	runs    int
+	runSize int
+}
+
+// scanFile returns the path of the file of the scan id with ext in the index directory dir.
+func scanFile(dir string, id int, ext string) string {
+	return filepath.Join(dir, strconv.Itoa(id)+ext)
+}
+
+// Create starts a new scan of roots in the index directory at path, creating it if needed.
+func Create(path string, roots []string) (*Writer, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	names, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	// The identifier follows the ones of the scans written and running, the directory of a scan
+	// being created atomically by the run taking its identifier
+	id := 1
+	for _, name := range names {
+		if n, err := strconv.Atoi(strings.TrimSuffix(name.Name(), filepath.Ext(name.Name()))); err == nil && n >= id {
+			id = n + 1
+		}
+	}
+	for {
+		err := os.Mkdir(scanFile(path, id, ".tmp"), 0o755)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		id++
+	}
+	return &Writer{dir: path, tmp: scanFile(path, id, ".tmp"), scan: Scan{ID: id, Time: time.Now().UTC(), Roots: roots}, runSize: runSize}, nil
+}
+
+// ScanID returns the identifier of the scan being written.
+func (iw *Writer) ScanID() int {
+	return iw.scan.ID
+}
+
+// Add records a file in the scan.
+func (iw *Writer) Add(e Entry) error {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	e.Scan = iw.scan.ID
+	iw.batch = append(iw.batch, e)
+	iw.scan.Files++
+	if len(iw.batch) >= iw.runSize {
+		return iw.spill()
+	}
+	return nil
+}
+
+// runFile returns the path of the run n of kind, paths or hashes.
+func (iw *Writer) runFile(kind string, n int) string {
+	return filepath.Join(iw.tmp, fmt.Sprintf("%s-%d", kind, n))
+}
+
+// spill writes the entries of the batch to a new run of each table.
+func (iw *Writer) spill() error {
+	for _, o := range []order{byPath, byHash} {
+		sort.Slice(iw.batch, func(i, j int) bool { return o.less(iw.batch[i], iw.batch[j]) })
+		tw, err := createTable(iw.runFile(o.name, iw.runs), o.key)
+		if err != nil {
+			return err
+		}
+		for _, e := range iw.batch {
+			if err := tw.add(e); err != nil {
+				tw.close(false)
+				return err
+			}
+		}
+		if err := tw.close(false); err != nil {
+			return err
+		}
+	}
+	iw.runs++
+	iw.batch = iw.batch[:0]
+	return nil
+}
+
+// Close merges the runs into the tables of the scan and makes it visible to the queries.
+// When it fails, the scan is dropped.
+func (iw *Writer) Close() error {
+	iw.mu.Lock()
+	defer iw.mu.Unlock()
+	if err := iw.close(); err != nil {
+		os.RemoveAll(iw.tmp)
+		return err
+	}
+	return os.RemoveAll(iw.tmp)
+}
+
+func (iw *Writer) close() error {
+	if len(iw.batch) > 0 || iw.runs == 0 {
+		if err := iw.spill(); err != nil {
+			return err
+		}
+	}
+	for _, o := range []order{byPath, byHash} {
+		merged := filepath.Join(iw.tmp, o.name)
+		if err := iw.merge(o, merged); err != nil {
+			return err
+		}
+		if err := os.Rename(merged, scanFile(iw.dir, iw.scan.ID, "."+o.name)); err != nil {
+			return err
+		}
+	}
+	// Written last, the description of the scan makes it complete
+	data, err := json.Marshal(iw.scan)
+	if err != nil {
+		return err
+	}
+	meta := filepath.Join(iw.tmp, "scan")
+	if err := os.WriteFile(meta, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(meta, scanFile(iw.dir, iw.scan.ID, ".scan"))
+}
+
+// merge merges the runs sorted in the order o into the table at path.
+func (iw *Writer) merge(o order, path string) error {
+	h := mergeHeap{less: o.less}
+	for n := range iw.runs {
+		t, err := openTable(iw.runFile(o.name, n), iw.scan.ID)
+		if err != nil {
+			return err
+		}
+		defer t.Close()
+		if it := t.iter(0); it.next() {
+			h.iters = append(h.iters, it)
+		} else if it.err != nil {
+			return it.err
+		}
+	}
+	heap.Init(&h)
+	tw, err := createTable(path, o.key)
+	if err != nil {
+		return err
+	}
+	for h.Len() > 0 {
+		it := h.iters[0]
+		if err := tw.add(it.entry); err != nil {
+			tw.close(false)
+			return err
+		}
+		if it.next() {
+			heap.Fix(&h, 0)
+		} else {
+			if it.err != nil {
+				tw.close(false)
+				return it.err
+			}
+			heap.Pop(&h)
+		}
+	}
+	return tw.close(true)
+}
+
+// mergeHeap orders the iterators on the runs by their current entry.
+type mergeHeap struct {
+	iters []*tableIter
+	less  func(a, b Entry) bool
+}
+
+func (h mergeHeap) Len() int           { return len(h.iters) }
+func (h mergeHeap) Less(i, j int) bool { return h.less(h.iters[i].entry, h.iters[j].entry) }
+func (h mergeHeap) Swap(i, j int)      { h.iters[i], h.iters[j] = h.iters[j], h.iters[i] }
+func (h *mergeHeap) Push(x any)        { h.iters = append(h.iters, x.(*tableIter)) }
+func (h *mergeHeap) Pop() any {
+	it := h.iters[len(h.iters)-1]
+	h.iters = h.iters[:len(h.iters)-1]
+	return it
+}
+
+// Scans returns the scans recorded in the index directory at path, oldest first.
+func Scans(path string) ([]Scan, error) {
+	names, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var scans []Scan
+	for _, name := range names {
+		if filepath.Ext(name.Name()) != ".scan" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, name.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var s Scan
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, fmt.Errorf("index %s: scan %s: %w", path, name.Name(), err)
+		}
+		scans = append(scans, s)
+	}
+	sort.Slice(scans, func(i, j int) bool { return scans[i].ID < scans[j].ID })
+	return scans, nil
+}
+
+// resolveScan returns id, or the identifier of the latest scan when id is 0.
+func resolveScan(path string, id int) (int, error) {
+	scans, err := Scans(path)
+	if err != nil {
+		return 0, err
+	}
+	if len(scans) == 0 {
+		return 0, fmt.Errorf("index %s does not contain any scan", path)
+	}
+	if id == 0 {
+		return scans[len(scans)-1].ID, nil
+	}
+	for _, s := range scans {
+		if s.ID == id {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("index %s does not contain scan %d", path, id)
+}
+
+// openScan opens the table of kind, paths or hashes, of the scan id (the latest one when 0).
+func openScan(path string, id int, kind string) (*table, error) {
+	id, err := resolveScan(path, id)
+	if err != nil {
+		return nil, err
+	}
+	return openTable(scanFile(path, id, "."+kind), id)
+}
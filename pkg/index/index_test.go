@@ -0,0 +1,126 @@
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeScan appends a scan made of entries to the index file at path.
+func writeScan(t *testing.T, path string, entries ...Entry) int {
+	w, err := Create(path, []string{"root"})
+	if err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+	for _, e := range entries {
+		if err := w.Add(e); err != nil {
+			t.Fatalf("Add() returned an error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+	return w.ScanID()
+}
+
+// TestIndexQueries tests the scans, duplicates, find and diff queries.
+func TestIndexQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.idx")
+	first := writeScan(t, path,
+		Entry{Path: "a.txt", Hash: "AAAA", Size: 10},
+		Entry{Path: "b.txt", Hash: "AAAA", Size: 10},
+		Entry{Path: "c.txt", Hash: "CCCC", Size: 5},
+		Entry{Path: "f.txt", Hash: "FFFF", Size: 3},
+	)
+	second := writeScan(t, path,
+		Entry{Path: "a.txt", Hash: "AAAA", Size: 10},
+		Entry{Path: "c.txt", Hash: "DDDD", Size: 6},
+		Entry{Path: "d.txt", Hash: "aaaa", Size: 10},
+		Entry{Path: "e.txt", Hash: "EEEE", Size: 4},
+	)
+	if first != 1 || second != 2 {
+		t.Fatalf("scan identifiers are %d and %d, expected 1 and 2", first, second)
+	}
+
+	scans, err := Scans(path)
+	if err != nil || len(scans) != 2 || scans[1].Files != 4 || scans[0].Roots[0] != "root" {
+		t.Fatalf("Scans() = %+v, %v, expected 2 scans of 4 files", scans, err)
+	}
+
+	groups, err := Duplicates(path, 0)
+	if err != nil || len(groups) != 1 || len(groups[0]) != 2 || groups[0][1].Path != "d.txt" || Wasted(groups[0]) != 10 {
+		t.Errorf("Duplicates() of the latest scan = %+v, %v, expected a.txt and d.txt", groups, err)
+	}
+
+	found, err := Find(path, "aaaa")
+	if err != nil || len(found) != 4 {
+		t.Errorf("Find() returned %d entries, %v, expected 4", len(found), err)
+	}
+
+	d, err := DiffScans(path, first, 0)
+	if err != nil {
+		t.Fatalf("DiffScans() returned an error: %v", err)
+	}
+	if len(d.Added) != 1 || d.Added[0].Path != "e.txt" || len(d.Removed) != 1 || d.Removed[0].Path != "f.txt" ||
+		len(d.Changed) != 1 || d.Changed[0][1].Hash != "DDDD" || len(d.Moved) != 1 || d.Moved[0][0].Path != "b.txt" || d.Moved[0][1].Path != "d.txt" {
+		t.Errorf("DiffScans() = %+v, expected e.txt added, f.txt removed, c.txt changed and b.txt moved to d.txt", d)
+	}
+
+	if _, err := DiffScans(path, 1, 7); err == nil {
+		t.Error("DiffScans() with an unknown scan did not return an error")
+	}
+}
+
+// TestIndexRuns tests a scan sorted in several runs, larger than a block of its tables, and that a scan
+// still running, or interrupted, is not visible but keeps its identifier.
+func TestIndexRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.idx")
+	w, err := Create(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.runSize = 1000
+	const files = 5500
+	mtime := time.Date(2024, 5, 1, 10, 0, 0, 123, time.UTC)
+	for i := range files {
+		// Written in reverse order, every 10 files sharing a hash
+		n := files - 1 - i
+		if err := w.Add(Entry{Path: fmt.Sprintf("dir/file%05d", n), Hash: fmt.Sprintf("%064x", n/10), Size: 100, ModTime: mtime}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	running, err := Create(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() returned an error: %v", err)
+	}
+	if running.ScanID() != 2 {
+		t.Errorf("the scan started while the first one was running has the identifier %d, want 2", running.ScanID())
+	}
+	scans, err := Scans(path)
+	if err != nil || len(scans) != 1 || scans[0].Files != files {
+		t.Fatalf("Scans() = %+v, %v, want only the complete scan of %d files", scans, err, files)
+	}
+	if next, err := Create(path, nil); err != nil || next.ScanID() != 3 {
+		t.Errorf("Create() after an unfinished scan = %v, want scan 3", err)
+	}
+
+	found, err := Find(path, fmt.Sprintf("%064X", 321))
+	if err != nil || len(found) != 10 || found[0].Path != "dir/file03210" || found[9].Path != "dir/file03219" || !found[0].ModTime.Equal(mtime) || found[0].Scan != 1 {
+		t.Errorf("Find() = %+v, %v, want the 10 files sharing the hash, sorted by path", found, err)
+	}
+	if found, err := Find(path, "missing"); err != nil || len(found) != 0 {
+		t.Errorf("Find() of an unknown hash = %v, %v, want nothing", found, err)
+	}
+	groups, err := Duplicates(path, 1)
+	if err != nil || len(groups) != files/10 || len(groups[0]) != 10 || groups[0][0].Path != "dir/file00000" {
+		t.Errorf("Duplicates() returned %d groups, %v, want %d groups of 10 files", len(groups), err, files/10)
+	}
+	d, err := DiffScans(path, 1, 1)
+	if err != nil || len(d.Added)+len(d.Removed)+len(d.Changed)+len(d.Moved) != 0 {
+		t.Errorf("DiffScans() of a scan with itself = %+v, %v, want no difference", d, err)
+	}
+}
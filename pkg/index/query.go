@@ -0,0 +1,167 @@
+package index
+
+import (
+	"sort"
+	"strings"
+)
+
+// Find returns the entries of every scan having the given hash, whatever its case, reading only the
+// block of the table sorted by hash of each scan where the hash would be.
+func Find(path, hash string) ([]Entry, error) {
+	scans, err := Scans(path)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.ToUpper(hash) + "\x00"
+	var found []Entry
+	for _, s := range scans {
+		t, err := openTable(scanFile(path, s.ID, ".hashes"), s.ID)
+		if err != nil {
+			return nil, err
+		}
+		it := t.iter(t.seek(prefix))
+		for it.next() {
+			key := hashKey(it.entry)
+			if key < prefix {
+				continue
+			}
+			if !strings.HasPrefix(key, prefix) {
+				break
+			}
+			found = append(found, it.entry)
+		}
+		t.Close()
+		if it.err != nil {
+			return nil, it.err
+		}
+	}
+	return found, nil
+}
+
+// Duplicates returns the groups of files having the same hash in the scan id (the latest one when 0),
+// read in the order of the table sorted by hash. Groups are sorted by decreasing wasted space, the files
+// of a group by path.
+func Duplicates(path string, id int) ([][]Entry, error) {
+	t, err := openScan(path, id, "hashes")
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	var groups [][]Entry
+	var group []Entry
+	flush := func() {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+		group = nil
+	}
+	it := t.iter(0)
+	for it.next() {
+		if len(group) > 0 && !strings.EqualFold(group[0].Hash, it.entry.Hash) {
+			flush()
+		}
+		group = append(group, it.entry)
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+	flush()
+	sort.SliceStable(groups, func(i, j int) bool {
+		wi, wj := Wasted(groups[i]), Wasted(groups[j])
+		if wi != wj {
+			return wi > wj
+		}
+		return groups[i][0].Path < groups[j][0].Path
+	})
+	return groups, nil
+}
+
+// Wasted returns the space used by the extra copies of a group of duplicates.
+func Wasted(group []Entry) int64 {
+	if len(group) < 2 {
+		return 0
+	}
+	return group[0].Size * int64(len(group)-1)
+}
+
+// Diff holds the differences between two scans, each list being sorted by path.
+type Diff struct {
+	Added   []Entry    // only in the newer scan
+	Removed []Entry    // only in the older scan
+	Changed [][2]Entry // in both scans with different hashes, older entry first
+	Moved   [][2]Entry // removed and added under another path with the same hash, older entry first
+}
+
+// DiffScans compares the scans older and newer of the index directory at path, 0 meaning the latest
+// scan, reading their tables sorted by path side by side. A file removed having the hash of a file added
+// is reported as moved, the paths being paired in order when there are several copies, except for empty files.
+func DiffScans(path string, older, newer int) (Diff, error) {
+	var d Diff
+	ta, err := openScan(path, older, "paths")
+	if err != nil {
+		return d, err
+	}
+	defer ta.Close()
+	tb, err := openScan(path, newer, "paths")
+	if err != nil {
+		return d, err
+	}
+	defer tb.Close()
+	a, b := ta.iter(0), tb.iter(0)
+	okA, okB := a.next(), b.next()
+	for okA || okB {
+		switch {
+		case !okB || (okA && a.entry.Path < b.entry.Path):
+			d.Removed = append(d.Removed, a.entry)
+			okA = a.next()
+		case !okA || b.entry.Path < a.entry.Path:
+			d.Added = append(d.Added, b.entry)
+			okB = b.next()
+		default:
+			if !strings.EqualFold(a.entry.Hash, b.entry.Hash) {
+				d.Changed = append(d.Changed, [2]Entry{a.entry, b.entry})
+			}
+			okA, okB = a.next(), b.next()
+		}
+	}
+	if a.err != nil {
+		return d, a.err
+	}
+	if b.err != nil {
+		return d, b.err
+	}
+	d.Removed, d.Added, d.Moved = pairMoves(d.Removed, d.Added)
+	return d, nil
+}
+
+// pairMoves pairs the removed entries with the added ones having the same non-empty content,
+// returning the entries left unpaired and the moves.
+func pairMoves(removed, added []Entry) ([]Entry, []Entry, [][2]Entry) {
+	byHash := make(map[string][]int)
+	for j, e := range added {
+		if e.Size > 0 {
+			key := strings.ToLower(e.Hash)
+			byHash[key] = append(byHash[key], j)
+		}
+	}
+	var moves [][2]Entry
+	var restRemoved []Entry
+	moved := make(map[int]bool)
+	for _, e := range removed {
+		key := strings.ToLower(e.Hash)
+		if candidates := byHash[key]; e.Size > 0 && len(candidates) > 0 {
+			moves = append(moves, [2]Entry{e, added[candidates[0]]})
+			moved[candidates[0]] = true
+			byHash[key] = candidates[1:]
+			continue
+		}
+		restRemoved = append(restRemoved, e)
+	}
+	var restAdded []Entry
+	for j, e := range added {
+		if !moved[j] {
+			restAdded = append(restAdded, e)
+		}
+	}
+	return restRemoved, restAdded, moves
+}
@@ -0,0 +1,296 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A table holds entries sorted by a key, the path or the hash, in blocks of about tableBlockSize bytes
+// followed by the index of the blocks, their first key and their offset, and a fixed size footer:
+//
+//	record  = uvarint len(path) | path | uvarint len(hash) | hash | varint size | varint mtime (Unix ns, 0 when unknown)
+//	index   = uvarint count | count * (uvarint len(key) | key | uvarint offset)
+//	footer  = uint64 index offset | uint64 entry count | tableMagic, big endian
+//
+// Only the index of the blocks is loaded to open a table, about one key per 64 KiB of entries, so a lookup
+// reads a single block of a table of millions of entries.
+
+// tableBlockSize is the size of the entries from which a new block starts.
+const tableBlockSize = 64 << 10
+
+// tableMagic ends every table, telling it apart from a truncated file.
+const tableMagic = "GDHIDX1\n"
+
+// footerSize is the size of the footer of a table.
+const footerSize = 8 + 8 + len(tableMagic)
+
+// pathKey sorts the entries by path.
+func pathKey(e Entry) string {
+	return e.Path
+}
+
+// hashKey sorts the entries by hash, whatever its case, then by path.
+func hashKey(e Entry) string {
+	return strings.ToUpper(e.Hash) + "\x00" + e.Path
+}
+
+// order is the order of the entries of a table: the key of an entry, used for the index of the blocks,
+// and the comparison of two entries in the same order, without building their keys.
+type order struct {
+	name string // the extension of the table
+	key  func(Entry) string
+	less func(a, b Entry) bool
+}
+
+// byPath and byHash are the orders of the two tables of a scan.
+var (
+	byPath = order{"paths", pathKey, func(a, b Entry) bool { return a.Path < b.Path }}
+	byHash = order{"hashes", hashKey, func(a, b Entry) bool {
+		if c := compareFold(a.Hash, b.Hash); c != 0 {
+			return c < 0
+		}
+		return a.Path < b.Path
+	}}
+)
+
+// compareFold compares a and b like strings.Compare of their ASCII uppercase forms.
+func compareFold(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ca, cb := a[i], b[i]
+		if 'a' <= ca && ca <= 'z' {
+			ca -= 'a' - 'A'
+		}
+		if 'a' <= cb && cb <= 'z' {
+			cb -= 'a' - 'A'
+		}
+		if ca != cb {
+			return int(ca) - int(cb)
+		}
+	}
+	return len(a) - len(b)
+}
+
+// blockKey is the first key of a block of a table, with its offset.
+type blockKey struct {
+	key    string
+	offset int64
+}
+
+// tableWriter writes a table of entries added in the order of key.
+type tableWriter struct {
+	f          *os.File
+	w          *bufio.Writer
+	key        func(Entry) string
+	offset     int64
+	blockStart int64
+	count      int64
+	blocks     []blockKey
+	buf        []byte
+}
+
+// createTable creates the table at path, its entries being sorted by key.
+func createTable(path string, key func(Entry) string) (*tableWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &tableWriter{f: f, w: bufio.NewWriterSize(f, tableBlockSize), key: key}, nil
+}
+
+// add writes e, which must not sort before the entries added before it.
+func (tw *tableWriter) add(e Entry) error {
+	if tw.count == 0 || tw.offset-tw.blockStart >= tableBlockSize {
+		tw.blocks = append(tw.blocks, blockKey{key: tw.key(e), offset: tw.offset})
+		tw.blockStart = tw.offset
+	}
+	var mtime int64
+	if !e.ModTime.IsZero() {
+		mtime = e.ModTime.UnixNano()
+	}
+	b := tw.buf[:0]
+	b = binary.AppendUvarint(b, uint64(len(e.Path)))
+	b = append(b, e.Path...)
+	b = binary.AppendUvarint(b, uint64(len(e.Hash)))
+	b = append(b, e.Hash...)
+	b = binary.AppendVarint(b, e.Size)
+	b = binary.AppendVarint(b, mtime)
+	tw.buf = b
+	tw.count++
+	tw.offset += int64(len(b))
+	_, err := tw.w.Write(b)
+	return err
+}
+
+// close writes the index of the blocks and the footer, syncing the table to disk when sync is set.
+func (tw *tableWriter) close(sync bool) error {
+	b := binary.AppendUvarint(nil, uint64(len(tw.blocks)))
+	for _, block := range tw.blocks {
+		b = binary.AppendUvarint(b, uint64(len(block.key)))
+		b = append(b, block.key...)
+		b = binary.AppendUvarint(b, uint64(block.offset))
+	}
+	b = binary.BigEndian.AppendUint64(b, uint64(tw.offset))
+	b = binary.BigEndian.AppendUint64(b, uint64(tw.count))
+	b = append(b, tableMagic...)
+	_, err := tw.w.Write(b)
+	if err == nil {
+		err = tw.w.Flush()
+	}
+	if err == nil && sync {
+		err = tw.f.Sync()
+	}
+	if closeErr := tw.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// table is a table opened for reading, its entries having the scan identifier scan.
+type table struct {
+	f      *os.File
+	path   string
+	scan   int
+	blocks []blockKey
+	end    int64 // the offset of the index, where the entries end
+	count  int64
+}
+
+// openTable opens the table at path, reading the index of its blocks.
+func openTable(path string, scan int) (*table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := readTable(f, path, scan)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func readTable(f *os.File, path string, scan int) (*table, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	footer := make([]byte, footerSize)
+	if info.Size() < int64(footerSize) {
+		return nil, fmt.Errorf("index table %s: truncated", path)
+	}
+	if _, err := f.ReadAt(footer, info.Size()-int64(footerSize)); err != nil {
+		return nil, err
+	}
+	if string(footer[16:]) != tableMagic {
+		return nil, fmt.Errorf("index table %s: not a table or truncated", path)
+	}
+	t := &table{f: f, path: path, scan: scan, end: int64(binary.BigEndian.Uint64(footer)), count: int64(binary.BigEndian.Uint64(footer[8:]))}
+	if t.end < 0 || t.end > info.Size()-int64(footerSize) {
+		return nil, fmt.Errorf("index table %s: invalid index offset %d", path, t.end)
+	}
+	r := bufio.NewReader(io.NewSectionReader(f, t.end, info.Size()-int64(footerSize)-t.end))
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("index table %s: %w", path, err)
+	}
+	for range n {
+		key, err := readString(r)
+		if err != nil {
+			return nil, fmt.Errorf("index table %s: %w", path, err)
+		}
+		offset, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("index table %s: %w", path, err)
+		}
+		t.blocks = append(t.blocks, blockKey{key: key, offset: int64(offset)})
+	}
+	return t, nil
+}
+
+// Close closes the file of the table.
+func (t *table) Close() error {
+	return t.f.Close()
+}
+
+// seek returns the offset of the block from which the entries of key or greater are found.
+func (t *table) seek(key string) int64 {
+	// The first block whose first key is not smaller, the entries equal to key possibly starting in the previous one
+	i := sort.Search(len(t.blocks), func(i int) bool { return t.blocks[i].key >= key })
+	if i == 0 {
+		return 0
+	}
+	return t.blocks[i-1].offset
+}
+
+// iter returns an iterator on the entries of the table from offset, one of a block or 0.
+func (t *table) iter(offset int64) *tableIter {
+	return &tableIter{t: t, r: bufio.NewReaderSize(io.NewSectionReader(t.f, offset, t.end-offset), tableBlockSize)}
+}
+
+// tableIter iterates on the entries of a table, in order.
+type tableIter struct {
+	t     *table
+	r     *bufio.Reader
+	entry Entry
+	err   error
+}
+
+// next reads the next entry, returning false at the end of the table or on error.
+func (it *tableIter) next() bool {
+	if it.err != nil {
+		return false
+	}
+	path, err := readString(it.r)
+	if err == io.EOF {
+		return false
+	}
+	e := Entry{Scan: it.t.scan, Path: path}
+	if err == nil {
+		e.Hash, err = readString(it.r)
+	}
+	if err == nil {
+		e.Size, err = binary.ReadVarint(it.r)
+	}
+	var mtime int64
+	if err == nil {
+		mtime, err = binary.ReadVarint(it.r)
+	}
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		it.err = fmt.Errorf("index table %s: %w", it.t.path, err)
+		return false
+	}
+	if mtime != 0 {
+		e.ModTime = time.Unix(0, mtime).UTC()
+	}
+	it.entry = e
+	return true
+}
+
+// readString reads a string written with its uvarint length.
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > 1<<20 {
+		return "", fmt.Errorf("invalid string length %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return string(b), nil
+}
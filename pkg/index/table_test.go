@@ -0,0 +1,64 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTable tests that the entries of a table of several blocks are read back in order, from the block
+// of a key, and that a truncated table is rejected.
+func TestTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "1.paths")
+	tw, err := createTable(path, pathKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 20000
+	for i := range count {
+		if err := tw.add(Entry{Path: fmt.Sprintf("file%05d", i), Hash: "ABCDEF", Size: int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.close(true); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := openTable(path, 7)
+	if err != nil {
+		t.Fatalf("openTable() returned an error: %v", err)
+	}
+	defer table.Close()
+	if table.count != count || len(table.blocks) < 2 {
+		t.Fatalf("table of %d entries in %d blocks, want %d in several blocks", table.count, len(table.blocks), count)
+	}
+	n := 0
+	for it := table.iter(0); it.next(); n++ {
+		if it.entry.Size != int64(n) || it.entry.Scan != 7 {
+			t.Fatalf("entry %d = %+v", n, it.entry)
+		}
+	}
+	if n != count {
+		t.Errorf("read %d entries, want %d", n, count)
+	}
+	for _, key := range []string{"file00000", "file12345", "file19999"} {
+		it := table.iter(table.seek(key))
+		for it.next() && it.entry.Path < key {
+		}
+		if it.entry.Path != key {
+			t.Errorf("seek(%s) found %q", key, it.entry.Path)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openTable(path, 1); err == nil {
+		t.Error("openTable() of a truncated table did not return an error")
+	}
+}
@@ -0,0 +1,174 @@
+// Package logging provides the log/slog handler used by goDirHasher for its diagnostics:
+// one line per record, made of the message followed by its attributes as key=value pairs,
+// without time or level since the messages already start with an emoji telling their kind.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// LevelTrace is the level of the very verbose messages, like one line per file, shown with -vv.
+const LevelTrace = slog.LevelDebug - 4
+
+// Level returns the minimum level to log for the command line verbosity flags,
+// quiet keeping only the warnings and errors.
+func Level(verbose, veryVerbose, quiet bool) slog.Level {
+	switch {
+	case veryVerbose:
+		return LevelTrace
+	case verbose:
+		return slog.LevelDebug
+	case quiet:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NewPlain returns a slog.Logger for machines: records are written in the stable logfmt-like format
+// of slog.TextHandler, without time and with the decorations removed from the messages, like
+//
+//	level=INFO msg="Successfully calculated hashes for 3 files."
+func NewPlain(out io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.TimeKey:
+				return slog.Attr{}
+			case slog.MessageKey:
+				return slog.String(slog.MessageKey, TrimDecoration(a.Value.String()))
+			}
+			return a
+		},
+	}))
+}
+
+// TrimDecoration removes the emojis and spaces starting msg.
+func TrimDecoration(msg string) string {
+	return strings.TrimLeftFunc(msg, isDecoration)
+}
+
+// isDecoration reports whether r is part of the emojis used to decorate the messages.
+func isDecoration(r rune) bool {
+	switch {
+	case unicode.IsSpace(r), unicode.Is(unicode.So, r):
+		return true
+	case r == '\u2139', r == '\u200d': // information source, zero width joiner
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f: // variation selectors
+		return true
+	case r >= 0xe0000 && r <= 0xe007f: // tags of flags
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // skin tones
+		return true
+	}
+	return false
+}
+
+// Handler is a slog.Handler writing human-readable lines. It is safe for concurrent use.
+type Handler struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  slog.Leveler
+	attrs  string // preformatted attributes added with WithAttrs
+	prefix string // group prefix of the keys
+}
+
+// NewHandler returns a Handler writing the records of at least level on out.
+func NewHandler(out io.Writer, level slog.Leveler) *Handler {
+	return &Handler{mu: &sync.Mutex{}, out: out, level: level}
+}
+
+// New returns a slog.Logger using a Handler on out.
+func New(out io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(NewHandler(out, level))
+}
+
+// Enabled reports whether records of level are written.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle writes the record on a single line.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(r.Message, "\n"))
+	sb.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&sb, h.prefix, a)
+		return true
+	})
+	sb.WriteByte('\n')
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, sb.String())
+	return err
+}
+
+// WithAttrs returns a Handler adding attrs to every record.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	var sb strings.Builder
+	for _, a := range attrs {
+		appendAttr(&sb, h.prefix, a)
+	}
+	h2.attrs += sb.String()
+	return &h2
+}
+
+// WithGroup returns a Handler prefixing the following keys with name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix += name + "."
+	return &h2
+}
+
+// appendAttr writes a as " key=value", groups being flattened with dotted keys.
+func appendAttr(sb *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(sb, prefix, ga)
+		}
+		return
+	}
+	fmt.Fprintf(sb, " %s%s=%s", prefix, a.Key, formatValue(a.Value))
+}
+
+// formatValue returns v as text, quoted when it would not be read back as a single word.
+func formatValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindDuration:
+		s = v.Duration().Round(time.Millisecond).String()
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339)
+	default:
+		s = v.String()
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\r\"=") || !strconv.CanBackquote(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,62 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestHandler tests the rendering of the records and the level filtering.
+func TestHandler(t *testing.T) {
+	var sb strings.Builder
+	logger := New(&sb, slog.LevelInfo)
+	logger.Info("✅ Done", "files", 3, "path", "my dir/a.txt")
+	logger.With("scan", 2).WithGroup("cache").Warn("⚠️ Slow", "hits", 1)
+	logger.Error("💥 💥 Error", "err", errors.New("open x: no such file"))
+	logger.Debug("hidden")
+	logger.Log(context.Background(), LevelTrace, "hidden too")
+
+	expected := "✅ Done files=3 path=\"my dir/a.txt\"\n" +
+		"⚠️ Slow scan=2 cache.hits=1\n" +
+		"💥 💥 Error err=\"open x: no such file\"\n"
+	if sb.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", sb.String(), expected)
+	}
+}
+
+// TestLevel tests the levels chosen from the verbosity flags.
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		verbose, veryVerbose, quiet bool
+		want                        slog.Level
+	}{
+		{false, false, false, slog.LevelInfo},
+		{true, false, false, slog.LevelDebug},
+		{false, true, false, LevelTrace},
+		{false, false, true, slog.LevelWarn},
+		{true, false, true, slog.LevelDebug},
+	}
+	for _, tt := range tests {
+		if got := Level(tt.verbose, tt.veryVerbose, tt.quiet); got != tt.want {
+			t.Errorf("Level(%v, %v, %v) = %v, expected %v", tt.verbose, tt.veryVerbose, tt.quiet, got, tt.want)
+		}
+	}
+}
+
+// TestNewPlain tests the machine-readable output without decorations.
+func TestNewPlain(t *testing.T) {
+	var sb strings.Builder
+	logger := NewPlain(&sb, slog.LevelInfo)
+	logger.Info("ℹ️ Writing output to standard output.")
+	logger.Warn("❌ ⚠️ 🔥 Directories differ", "different", 2)
+	logger.Info("🏴󠁲󠁯󠁩󠁦󠁿 Checking 🙂.txt")
+
+	expected := "level=INFO msg=\"Writing output to standard output.\"\n" +
+		"level=WARN msg=\"Directories differ\" different=2\n" +
+		"level=INFO msg=\"Checking 🙂.txt\"\n"
+	if sb.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", sb.String(), expected)
+	}
+}
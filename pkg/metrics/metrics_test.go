@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRegistryServeHTTP tests the text exposition format of the counters and gauges.
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	files := r.Counter("files_total", "Files hashed.")
+	duration := r.Gauge("duration_seconds", "Duration of the last scan.")
+	files.Inc()
+	files.Add(2)
+	duration.Set(1.5)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	want := "# HELP files_total Files hashed.\n# TYPE files_total counter\nfiles_total 3\n" +
+		"# HELP duration_seconds Duration of the last scan.\n# TYPE duration_seconds gauge\nduration_seconds 1.5\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("ServeHTTP() wrote %q, expected %q", got, want)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("ServeHTTP() Content-Type is %q, expected text/plain", ct)
+	}
+}
@@ -0,0 +1,83 @@
+// Package notify posts the failures found by a verification to a webhook as JSON,
+// so they can be forwarded to Slack, PagerDuty or any HTTP endpoint.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/stats"
+)
+
+// Failure statuses.
+const (
+	StatusMismatch = "mismatch" // the file content does not match the expected hash
+	StatusMissing  = "missing"  // the file does not exist
+	StatusError    = "error"    // the file could not be read
+	StatusMetadata = "metadata" // the content matches, but the modification time or the permissions changed
+	StatusExtra    = "extra"    // the file is not listed in the hash file, see -check-extra
+)
+
+// Failure describes a file that did not pass the verification.
+type Failure struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"` // empty when the file could not be read
+	Error    string `json:"error,omitempty"`
+}
+
+// Payload is the JSON document posted to the webhook.
+type Payload struct {
+	Host     string    `json:"host"`
+	Time     time.Time `json:"timestamp"`
+	HashFile string    `json:"hash_file"`
+	Checked  int       `json:"checked"` // number of files verified, failed ones included
+	Failures []Failure `json:"failures"`
+	// Stats holds the bytes read, the throughput and the slowest files of the verification
+	Stats *stats.Summary `json:"stats,omitempty"`
+}
+
+// NewPayload returns the Payload of the failures found while checking the files of hashFile,
+// filled with the host name and the current time.
+func NewPayload(hashFile string, checked int, failures []Failure) Payload {
+	host, _ := os.Hostname()
+	return Payload{Host: host, Time: time.Now().UTC(), HashFile: hashFile, Checked: checked, Failures: failures}
+}
+
+// DefaultTimeout bounds the time taken by Post when ctx has no deadline.
+const DefaultTimeout = 10 * time.Second
+
+// Post sends p as JSON to url with a POST request, any status other than 2xx being an error.
+func Post(ctx context.Context, url string, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // allow the connection to be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", url, resp.Status)
+	}
+	return nil
+}
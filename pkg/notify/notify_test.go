@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestPost tests that the payload is posted as JSON and that error statuses are reported.
+func TestPost(t *testing.T) {
+	var got Payload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("received %s request with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding the payload returned an error: %v", err)
+		}
+		if got.HashFile == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	p := NewPayload("hashes.txt", 3, []Failure{{Path: "a.txt", Status: StatusMismatch, Expected: "AA", Actual: "BB"}})
+	if err := Post(context.Background(), server.URL, p); err != nil {
+		t.Fatalf("Post() returned an error: %v", err)
+	}
+	if got.HashFile != "hashes.txt" || got.Checked != 3 || len(got.Failures) != 1 || got.Failures[0].Actual != "BB" || got.Time.IsZero() {
+		t.Errorf("the webhook received %+v", got)
+	}
+
+	p.HashFile = "fail"
+	if err := Post(context.Background(), server.URL, p); err == nil {
+		t.Error("Post() did not report the error status of the webhook")
+	}
+}
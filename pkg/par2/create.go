@@ -0,0 +1,238 @@
+package par2
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// Create writes to name a PAR2 file with recovery data for the files at paths, redundancy being the
+// number of recovery slices in percent of the input slices, like the -r option of par2cmdline: up to
+// this proportion of the data can be recovered, whatever the files it is in. The names of the files are
+// recorded relative to the directory of name. The recovery data costs one pass over the files for
+// the checksums, then one more pass for each 256 MiB of recovery slices.
+func Create(ctx context.Context, name string, paths []string, redundancy int) error {
+	if redundancy < 1 || redundancy > 100 {
+		return fmt.Errorf("redundancy of %d%%, it must be between 1 and 100%%", redundancy)
+	}
+	dir, err := filepath.Abs(filepath.Dir(name))
+	if err != nil {
+		return err
+	}
+	s := &recoverySet{}
+	lengths := make([]int64, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", path)
+		}
+		lengths[i] = info.Size()
+	}
+	s.sliceSize = chooseSliceSize(lengths)
+
+	// First pass, the checksums of the files and of their slices, the empty files having nothing to recover
+	handles := make(map[*file]string, len(paths))
+	names := make(map[string]bool, len(paths))
+	for i, path := range paths {
+		rel, err := relativeName(dir, path)
+		if err != nil {
+			return err
+		}
+		if lengths[i] == 0 || names[rel] {
+			continue
+		}
+		names[rel] = true
+		f := &file{name: rel}
+		if err := s.checksum(ctx, path, f); err != nil {
+			return err
+		}
+		s.files = append(s.files, f)
+		handles[f] = path
+	}
+	s.sortFiles()
+	s.id = md5.Sum(s.mainBody())
+	inputs := s.inputSlices()
+	if inputs == 0 {
+		return errors.New("no data to protect, the files are empty")
+	}
+	count := min((inputs*redundancy+99)/100, gfOrder)
+	for e := range count {
+		s.recovery = append(s.recovery, recoverySlice{exponent: e})
+	}
+
+	// The packets are written to a temporary file renamed once complete, the recovery data being
+	// filled in place chunk by chunk, and its MD5 written last
+	out, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		out.Close()
+		os.Remove(out.Name())
+	}()
+	header := s.headerPackets()
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+	offset := int64(len(header))
+	hashes := make([]hash.Hash, count)
+	for j := range s.recovery {
+		body := binary.LittleEndian.AppendUint32(nil, uint32(s.recovery[j].exponent))
+		packet := appendPacket(nil, s.id, typeRecovery, body)
+		binary.LittleEndian.PutUint64(packet[8:], uint64(len(packet))+uint64(s.sliceSize))
+		hashes[j] = md5.New()
+		hashes[j].Write(packet[32:])
+		if _, err := out.WriteAt(packet, offset); err != nil {
+			return err
+		}
+		s.recovery[j].offset = offset + int64(len(packet))
+		offset = s.recovery[j].offset + s.sliceSize
+	}
+	if err := out.Truncate(offset); err != nil {
+		return err
+	}
+	if err := s.computeRecovery(ctx, out, handles, hashes); err != nil {
+		return err
+	}
+	for j, r := range s.recovery {
+		if _, err := out.WriteAt(hashes[j].Sum(nil), r.offset-headerSize-4+16); err != nil {
+			return err
+		}
+	}
+	if err := out.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), name)
+}
+
+// checksum reads the file at path to fill the checksums of f and of its slices.
+func (s *recoverySet) checksum(ctx context.Context, path string, f *file) error {
+	h, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	whole := md5.New()
+	buf := make([]byte, s.sliceSize)
+	for k := 0; ; k++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(h, buf)
+		if n == 0 {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		whole.Write(buf[:n])
+		clear(buf[n:])
+		f.slices = append(f.slices, sliceChecksum{hash: md5.Sum(buf), crc: crc32.ChecksumIEEE(buf)})
+		f.length += int64(n)
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	if f.hash16k, err = md5Prefix(h, 16<<10); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	whole.Sum(f.hash[:0])
+	f.id = fileID(f.hash16k, f.length, f.name)
+	return nil
+}
+
+// md5Prefix returns the MD5 of the first n bytes of r.
+func md5Prefix(r io.ReaderAt, n int64) (sum [16]byte, err error) {
+	h := md5.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, n)); err != nil {
+		return sum, err
+	}
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// computeRecovery computes the data of the recovery slices of s, written in out, processing the slices
+// in chunks so all the recovery buffers fit in memoryBudget. The data is also written to hashes.
+func (s *recoverySet) computeRecovery(ctx context.Context, out *os.File, paths map[*file]string, hashes []hash.Hash) error {
+	constants := inputConstants(s.inputSlices())
+	chunk := s.chunkSize(len(s.recovery))
+	buffers := make([][]byte, len(s.recovery))
+	for j := range buffers {
+		buffers[j] = make([]byte, chunk)
+	}
+	input := make([]byte, chunk)
+	logs := make([]uint32, chunk/2)
+	for off := int64(0); off < s.sliceSize; off += chunk {
+		n := min(chunk, s.sliceSize-off)
+		for j := range buffers {
+			clear(buffers[j][:n])
+		}
+		i := 0
+		for _, f := range s.files {
+			h, err := os.Open(paths[f])
+			if err != nil {
+				return err
+			}
+			for k := range f.slices {
+				if err := ctx.Err(); err != nil {
+					h.Close()
+					return err
+				}
+				if err := s.readChunk(h, f, k, off, input[:n]); err != nil {
+					h.Close()
+					return fmt.Errorf("reading %s: %w", paths[f], err)
+				}
+				factors := make([]uint16, len(s.recovery))
+				for j, r := range s.recovery {
+					factors[j] = gfPow(constants[i], r.exponent)
+				}
+				mulAddAll(buffers, input[:n], factors, logs)
+				i++
+			}
+			h.Close()
+		}
+		for j, r := range s.recovery {
+			if _, err := out.WriteAt(buffers[j][:n], r.offset+off); err != nil {
+				return err
+			}
+			hashes[j].Write(buffers[j][:n])
+		}
+	}
+	return nil
+}
+
+// mulAddAll adds to each buffer the product of src by its factor, the buffers being shared between the CPUs.
+// logs holds the room for the logarithms of the words of src.
+func mulAddAll(buffers [][]byte, src []byte, factors []uint16, logs []uint32) {
+	logs = logWords(logs, src)
+	workers := min(runtime.NumCPU(), len(buffers))
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := w; j < len(buffers); j += workers {
+				mulAdd(buffers[j], logs, factors[j])
+			}
+		}()
+	}
+	wg.Wait()
+}
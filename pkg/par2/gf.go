@@ -0,0 +1,129 @@
+package par2
+
+// The Reed-Solomon code of PAR2 works in the Galois field GF(2^16) generated by the polynomial
+// x^16 + x^12 + x^3 + x + 1, adding being XOR and multiplying going through the logarithms in base 2.
+const (
+	gfPolynomial = 0x1100B
+	gfOrder      = 65535 // number of non-zero elements
+)
+
+var gfLog, gfExp = gfTables()
+
+// gfZeroLog stands for the logarithm of zero in the words prepared by logWords, gfExp being zero
+// from there so the products by zero need no test.
+const gfZeroLog = 2 * gfOrder
+
+// gfTables returns the logarithm and the exponential tables of the field, gfExp being doubled
+// so the sum of two logarithms does not have to be reduced, then followed by zeros, see gfZeroLog.
+func gfTables() (log [1 << 16]uint16, exp [3 * gfOrder]uint16) {
+	x := 1
+	for i := range gfOrder {
+		exp[i], exp[i+gfOrder] = uint16(x), uint16(x)
+		log[x] = uint16(i)
+		if x <<= 1; x&0x10000 != 0 {
+			x ^= gfPolynomial
+		}
+	}
+	return log, exp
+}
+
+// gfMul returns a * b.
+func gfMul(a, b uint16) uint16 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv returns 1 / a, a not being zero.
+func gfInv(a uint16) uint16 {
+	return gfExp[gfOrder-int(gfLog[a])]
+}
+
+// gfPow returns a to the power of n.
+func gfPow(a uint16, n int) uint16 {
+	if n == 0 {
+		return 1
+	}
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])*n%gfOrder]
+}
+
+// inputConstants returns the constants of the first n input slices: 2 to the power of the
+// successive exponents not multiple of 3, 5, 17 nor 257, the factors of 65535.
+func inputConstants(n int) []uint16 {
+	constants := make([]uint16, 0, n)
+	for e := 1; len(constants) < n; e++ {
+		if e%3 != 0 && e%5 != 0 && e%17 != 0 && e%257 != 0 {
+			constants = append(constants, gfExp[e])
+		}
+	}
+	return constants
+}
+
+// logWords returns in logs the logarithms of the little-endian 16-bit words of src, gfZeroLog for zero,
+// so multiplying them by several factors costs a single lookup per word.
+func logWords(logs []uint32, src []byte) []uint32 {
+	logs = logs[:len(src)/2]
+	for i := range logs {
+		if w := uint16(src[2*i]) | uint16(src[2*i+1])<<8; w != 0 {
+			logs[i] = uint32(gfLog[w])
+		} else {
+			logs[i] = gfZeroLog
+		}
+	}
+	return logs
+}
+
+// mulAdd adds to dst the product by factor of the words whose logarithms are logs.
+func mulAdd(dst []byte, logs []uint32, factor uint16) {
+	if factor == 0 {
+		return
+	}
+	l := uint32(gfLog[factor])
+	dst = dst[:2*len(logs)]
+	for i, w := range logs {
+		v := gfExp[l+w]
+		dst[2*i] ^= byte(v)
+		dst[2*i+1] ^= byte(v >> 8)
+	}
+}
+
+// gfInvert returns the inverse of the square matrix m, or false when it is singular.
+func gfInvert(m [][]uint16) ([][]uint16, bool) {
+	n := len(m)
+	a := make([][]uint16, n)
+	inv := make([][]uint16, n)
+	for i := range m {
+		a[i] = append([]uint16{}, m[i]...)
+		inv[i] = make([]uint16, n)
+		inv[i][i] = 1
+	}
+	for col := range n {
+		pivot := col
+		for pivot < n && a[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+		scale := gfInv(a[col][col])
+		for j := range n {
+			a[col][j] = gfMul(a[col][j], scale)
+			inv[col][j] = gfMul(inv[col][j], scale)
+		}
+		for row := range n {
+			if f := a[row][col]; row != col && f != 0 {
+				for j := range n {
+					a[row][j] ^= gfMul(f, a[col][j])
+					inv[row][j] ^= gfMul(f, inv[col][j])
+				}
+			}
+		}
+	}
+	return inv, true
+}
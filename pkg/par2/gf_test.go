@@ -0,0 +1,63 @@
+package par2
+
+import "testing"
+
+// TestGaloisField tests the multiplication, the inverses and the constants of the input slices.
+func TestGaloisField(t *testing.T) {
+	for _, a := range []uint16{1, 2, 3, 0x1234, 0x8000, 0xFFFF} {
+		if got := gfMul(a, gfInv(a)); got != 1 {
+			t.Errorf("%#x * 1/%#x = %#x, expected 1", a, a, got)
+		}
+		if got := gfMul(a, 0); got != 0 {
+			t.Errorf("%#x * 0 = %#x, expected 0", a, got)
+		}
+		if got, want := gfPow(a, 3), gfMul(a, gfMul(a, a)); got != want {
+			t.Errorf("%#x^3 = %#x, expected %#x", a, got, want)
+		}
+	}
+	// x^16 is reduced by the polynomial: x^12 + x^3 + x + 1
+	if got := gfMul(0x8000, 2); got != 0x100B {
+		t.Errorf("0x8000 * 2 = %#x, expected 0x100b", got)
+	}
+	// 2^1, 2^2, 2^4, 2^7, the exponents 3, 5 and 6 being multiples of 3 or 5
+	constants := inputConstants(4)
+	for i, want := range []uint16{2, 4, 16, 128} {
+		if constants[i] != want {
+			t.Errorf("inputConstants(4)[%d] = %d, expected %d", i, constants[i], want)
+		}
+	}
+}
+
+// TestGaloisInvert tests the inversion of a Vandermonde matrix of the input constants.
+func TestGaloisInvert(t *testing.T) {
+	constants := inputConstants(5)
+	m := make([][]uint16, len(constants))
+	for j := range m {
+		m[j] = make([]uint16, len(constants))
+		for i, c := range constants {
+			m[j][i] = gfPow(c, j)
+		}
+	}
+	inv, ok := gfInvert(m)
+	if !ok {
+		t.Fatal("gfInvert() reported a singular matrix")
+	}
+	for i := range m {
+		for j := range m {
+			var sum uint16
+			for k := range m {
+				sum ^= gfMul(m[i][k], inv[k][j])
+			}
+			want := uint16(0)
+			if i == j {
+				want = 1
+			}
+			if sum != want {
+				t.Errorf("(m * inv)[%d][%d] = %#x, expected %#x", i, j, sum, want)
+			}
+		}
+	}
+	if _, ok := gfInvert([][]uint16{{1, 2}, {2, 4}}); ok {
+		t.Error("gfInvert() inverted a singular matrix")
+	}
+}
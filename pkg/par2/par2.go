@@ -0,0 +1,220 @@
+// Package par2 creates, verifies and repairs PAR2 (Parity Volume Set Specification 2.0) recovery files,
+// so the files of an archive can not only be checked for bitrot but also recovered from it. The recovery
+// data is written in a single .par2 file holding every packet, readable by par2cmdline and MultiPar,
+// the damaged slices of the files being found through their checksums at their expected positions.
+package par2
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// maxInputSlices is the largest number of input slices in a recovery set, limited by the constants of
+// the code, and targetInputSlices the number aimed at when choosing the slice size, at least minSliceSize
+// so the headers of the packets stay small next to the slices.
+const (
+	maxInputSlices    = 32768
+	targetInputSlices = 2000
+	minSliceSize      = 4096
+)
+
+// memoryBudget is the memory used by the recovery buffers, the slices being processed in chunks above it.
+const memoryBudget = 256 << 20
+
+// Creator is recorded in the creator packet of the files written by Create.
+var Creator = "goDirHasher"
+
+var (
+	packetMagic  = []byte("PAR2\x00PKT")
+	typeMain     = packetType("PAR 2.0\x00Main\x00\x00\x00\x00")
+	typeFileDesc = packetType("PAR 2.0\x00FileDesc")
+	typeIFSC     = packetType("PAR 2.0\x00IFSC\x00\x00\x00\x00")
+	typeRecovery = packetType("PAR 2.0\x00RecvSlic")
+	typeCreator  = packetType("PAR 2.0\x00Creator\x00")
+)
+
+// ErrNotRepairable is returned by Repair when more slices are damaged than there are recovery slices.
+var ErrNotRepairable = errors.New("not enough recovery slices to repair the damaged files")
+
+// headerSize is the size of the header of every packet: magic, length, packet MD5, set ID and type.
+const headerSize = 64
+
+func packetType(s string) (t [16]byte) {
+	copy(t[:], s)
+	return t
+}
+
+// file is a file of a recovery set, its slices being the input of the code.
+type file struct {
+	id      [16]byte
+	hash    [16]byte // MD5 of the whole file
+	hash16k [16]byte // MD5 of its first 16 KiB
+	length  int64
+	name    string // slash separated, relative to the directory of the PAR2 file
+	slices  []sliceChecksum
+}
+
+// sliceChecksum is the checksum of a slice, padded with zeros to the slice size.
+type sliceChecksum struct {
+	hash [16]byte
+	crc  uint32
+}
+
+// recoverySlice is a recovery packet, its data being left in the PAR2 file.
+type recoverySlice struct {
+	exponent int
+	offset   int64 // of the data in the PAR2 file
+}
+
+// recoverySet is the content of a PAR2 file.
+type recoverySet struct {
+	id        [16]byte
+	sliceSize int64
+	files     []*file // in the order of the main packet, sorted by ID
+	recovery  []recoverySlice
+}
+
+// inputSlices returns the number of input slices of the set.
+func (s *recoverySet) inputSlices() int {
+	n := 0
+	for _, f := range s.files {
+		n += len(f.slices)
+	}
+	return n
+}
+
+// fileID returns the ID of a file, derived from the MD5 of its first 16 KiB, its length and its name.
+func fileID(hash16k [16]byte, length int64, name string) [16]byte {
+	h := md5.New()
+	h.Write(hash16k[:])
+	binary.Write(h, binary.LittleEndian, uint64(length))
+	io.WriteString(h, name)
+	var id [16]byte
+	h.Sum(id[:0])
+	return id
+}
+
+// sliceCount returns the number of slices of size sliceSize holding length bytes.
+func sliceCount(length, sliceSize int64) int {
+	return int((length + sliceSize - 1) / sliceSize)
+}
+
+// chooseSliceSize returns the size of the slices, a multiple of 4, so the files of lengths are split
+// in about targetInputSlices slices, never more than maxInputSlices.
+func chooseSliceSize(lengths []int64) int64 {
+	var total int64
+	for _, length := range lengths {
+		total += length
+	}
+	size := max(minSliceSize, (total/targetInputSlices+3)/4*4)
+	for {
+		n := 0
+		for _, length := range lengths {
+			n += sliceCount(length, size)
+		}
+		if n <= maxInputSlices {
+			return size
+		}
+		size *= 2
+	}
+}
+
+// appendPacket appends to b the packet of type t with body, its MD5 covering everything after it.
+func appendPacket(b []byte, setID, t [16]byte, body []byte) []byte {
+	start := len(b)
+	b = append(b, packetMagic...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(headerSize+len(body)))
+	b = append(b, make([]byte, 16)...) // MD5, below
+	b = append(b, setID[:]...)
+	b = append(b, t[:]...)
+	b = append(b, body...)
+	sum := md5.Sum(b[start+32:])
+	copy(b[start+16:], sum[:])
+	return b
+}
+
+// padded returns s as bytes padded with zeros to a multiple of 4.
+func padded(s string) []byte {
+	return append([]byte(s), make([]byte, (4-len(s)%4)%4)...)
+}
+
+// mainBody returns the body of the main packet, which MD5 is the ID of the set.
+func (s *recoverySet) mainBody() []byte {
+	body := binary.LittleEndian.AppendUint64(nil, uint64(s.sliceSize))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(s.files)))
+	for _, f := range s.files {
+		body = append(body, f.id[:]...)
+	}
+	return body
+}
+
+// headerPackets returns the packets of the set describing the files, before the recovery packets.
+func (s *recoverySet) headerPackets() []byte {
+	b := appendPacket(nil, s.id, typeMain, s.mainBody())
+	b = appendPacket(b, s.id, typeCreator, padded(Creator))
+	for _, f := range s.files {
+		body := append(append(append([]byte{}, f.id[:]...), f.hash[:]...), f.hash16k[:]...)
+		body = binary.LittleEndian.AppendUint64(body, uint64(f.length))
+		b = appendPacket(b, s.id, typeFileDesc, append(body, padded(f.name)...))
+		body = append([]byte{}, f.id[:]...)
+		for _, c := range f.slices {
+			body = binary.LittleEndian.AppendUint32(append(body, c.hash[:]...), c.crc)
+		}
+		b = appendPacket(b, s.id, typeIFSC, body)
+	}
+	return b
+}
+
+// relativeName returns the name of the file at path in a PAR2 file written in dir.
+func relativeName(dir, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// sortFiles sorts the files of the set by ID, the order of the main packet and of the input slices.
+func (s *recoverySet) sortFiles() {
+	sort.Slice(s.files, func(i, j int) bool { return bytes.Compare(s.files[i].id[:], s.files[j].id[:]) < 0 })
+}
+
+// readChunk reads in buf the bytes at offset off of the slice k of f, opened as r, the bytes after the
+// end of the file being zeros.
+func (s *recoverySet) readChunk(r io.ReaderAt, f *file, k int, off int64, buf []byte) error {
+	clear(buf)
+	start := int64(k)*s.sliceSize + off
+	if start >= f.length {
+		return nil
+	}
+	n := min(int64(len(buf)), f.length-start)
+	if _, err := r.ReadAt(buf[:n], start); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// chunkSize returns the size of the chunks of the slices processed at once by n buffers.
+func (s *recoverySet) chunkSize(n int) int64 {
+	return min(s.sliceSize, max(4, memoryBudget/int64(max(n, 1))/4*4))
+}
+
+// openFile opens the file f of the set in dir, with a descriptive error.
+func openFile(dir string, f *file, flag int) (*os.File, error) {
+	h, err := os.OpenFile(filepath.Join(dir, filepath.FromSlash(f.name)), flag, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", f.name, err)
+	}
+	return h, nil
+}
@@ -0,0 +1,143 @@
+package par2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files of random contents with the given sizes in dir, returning their paths and contents.
+func writeFiles(t *testing.T, dir string, sizes map[string]int) ([]string, map[string][]byte) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	var paths []string
+	contents := make(map[string][]byte)
+	for name, size := range sizes {
+		data := make([]byte, size)
+		rng.Read(data)
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		paths = append(paths, path)
+		contents[path] = data
+	}
+	return paths, contents
+}
+
+// TestCreateVerifyRepair tests that damaged, truncated and missing files are found and recovered.
+func TestCreateVerifyRepair(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	paths, contents := writeFiles(t, dir, map[string]int{"a.bin": 100_000, "sub/b.bin": 33_333, "c.txt": 1_234, "empty": 0})
+	name := filepath.Join(dir, "files.par2")
+	if err := Create(ctx, name, paths, 20); err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+	report, err := Verify(ctx, name)
+	if err != nil || !report.OK() || len(report.Files) != 3 || report.Recovery == 0 {
+		t.Fatalf("Verify() returned %+v, %v, expected 3 intact files with recovery slices", report, err)
+	}
+
+	// Flip bytes of a.bin, truncate sub/b.bin and remove c.txt
+	a, b, c := filepath.Join(dir, "a.bin"), filepath.Join(dir, "sub", "b.bin"), filepath.Join(dir, "c.txt")
+	damaged := bytes.Clone(contents[a])
+	damaged[10] ^= 0xFF
+	damaged[50_000] ^= 0x01
+	if err := os.WriteFile(a, damaged, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Truncate(b, 30_000); err != nil {
+		t.Fatalf("Failed to truncate file: %v", err)
+	}
+	if err := os.Remove(c); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	report, err = Verify(ctx, name)
+	if err != nil || report.OK() || !report.Repairable() {
+		t.Fatalf("Verify() returned %+v, %v, expected repairable damages", report, err)
+	}
+	for _, f := range report.Files {
+		if f.Damaged == 0 || (f.Name == "c.txt") != f.Missing {
+			t.Errorf("Verify() returned %+v, expected damaged slices, c.txt being missing", f)
+		}
+	}
+
+	report, err = Repair(ctx, name)
+	if err != nil || !report.OK() {
+		t.Fatalf("Repair() returned %+v, %v, expected repaired files", report, err)
+	}
+	for _, f := range report.Files {
+		if !f.Repaired {
+			t.Errorf("Repair() returned %+v, expected a repaired file", f)
+		}
+	}
+	for path, want := range contents {
+		if got, err := os.ReadFile(path); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s was not repaired: %d bytes, %v", path, len(got), err)
+		}
+	}
+}
+
+// TestRepairNotRepairable tests that too many damaged slices are reported without touching the files.
+func TestRepairNotRepairable(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	paths, _ := writeFiles(t, dir, map[string]int{"a.bin": 100_000, "b.bin": 100_000})
+	name := filepath.Join(dir, "files.par2")
+	if err := Create(ctx, name, paths, 5); err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+	if err := os.Remove(paths[0]); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if report, err := Repair(ctx, name); !errors.Is(err, ErrNotRepairable) || report.Repairable() {
+		t.Errorf("Repair() returned %+v, %v, expected ErrNotRepairable", report, err)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("Repair() created %s without being able to repair it", paths[0])
+	}
+}
+
+// TestDamagedPAR2File tests that the damaged packets of the PAR2 file itself are skipped.
+func TestDamagedPAR2File(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	paths, contents := writeFiles(t, dir, map[string]int{"a.bin": 50_000})
+	name := filepath.Join(dir, "files.par2")
+	if err := Create(ctx, name, paths, 20); err != nil {
+		t.Fatalf("Create() returned an error: %v", err)
+	}
+	before, err := Verify(ctx, name)
+	if err != nil {
+		t.Fatalf("Verify() returned an error: %v", err)
+	}
+	// Damage the last recovery slice and the file
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	damaged := bytes.Clone(contents[paths[0]])
+	damaged[0] ^= 0xFF
+	if err := os.WriteFile(paths[0], damaged, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	report, err := Repair(ctx, name)
+	if err != nil || report.Recovery != before.Recovery-1 {
+		t.Fatalf("Repair() returned %+v, %v, expected %d recovery slices", report, err, before.Recovery-1)
+	}
+	if got, err := os.ReadFile(paths[0]); err != nil || !bytes.Equal(got, contents[paths[0]]) {
+		t.Errorf("%s was not repaired: %v", paths[0], err)
+	}
+}
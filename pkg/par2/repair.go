@@ -0,0 +1,405 @@
+package par2
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FileStatus is the state of a file of a recovery set found by Verify or Repair.
+type FileStatus struct {
+	Name     string // slash separated, relative to the directory of the PAR2 file
+	Slices   int    // number of input slices of the file
+	Damaged  int    // number of damaged slices, all of them when the file is missing
+	Missing  bool
+	Repaired bool // the damaged slices were recovered by Repair
+}
+
+// Report is the outcome of Verify and Repair.
+type Report struct {
+	Files    []FileStatus // in the order of the recovery set
+	Damaged  int          // number of damaged input slices in all the files
+	Recovery int          // number of usable recovery slices
+}
+
+// OK reports whether no file was damaged.
+func (r Report) OK() bool {
+	return r.Damaged == 0
+}
+
+// Repairable reports whether there are enough recovery slices to repair the damaged files.
+func (r Report) Repairable() bool {
+	return r.Damaged <= r.Recovery
+}
+
+// Verify checks the files described in the PAR2 file name against the checksums of their slices,
+// reporting the damaged and missing files and whether they can be repaired.
+func Verify(ctx context.Context, name string) (Report, error) {
+	s, dir, err := readSet(name)
+	if err != nil {
+		return Report{}, err
+	}
+	report, _, err := s.verify(ctx, dir)
+	return report, err
+}
+
+// Repair verifies the files described in the PAR2 file name like Verify and recovers their damaged slices
+// from the recovery slices, recreating the missing files, then verifies them again. It returns
+// ErrNotRepairable when more slices are damaged than there are recovery slices.
+func Repair(ctx context.Context, name string) (Report, error) {
+	s, dir, err := readSet(name)
+	if err != nil {
+		return Report{}, err
+	}
+	report, damaged, err := s.verify(ctx, dir)
+	if err != nil || report.OK() {
+		return report, err
+	}
+	if !report.Repairable() {
+		return report, ErrNotRepairable
+	}
+	par, err := os.Open(name)
+	if err != nil {
+		return report, err
+	}
+	defer par.Close()
+	if err := s.repair(ctx, dir, par, damaged); err != nil {
+		return report, err
+	}
+	after, _, err := s.verify(ctx, dir)
+	if err != nil {
+		return after, err
+	}
+	for i := range after.Files {
+		after.Files[i].Repaired = report.Files[i].Damaged > 0 && after.Files[i].Damaged == 0
+	}
+	if !after.OK() {
+		return after, fmt.Errorf("%d slices still damaged after the repair", after.Damaged)
+	}
+	return after, nil
+}
+
+// readSet reads the packets of the PAR2 file name, skipping the damaged ones, and returns the recovery
+// set of its main packet with the absolute directory of its files.
+func readSet(name string) (*recoverySet, string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, "", err
+	}
+	var mainBody []byte
+	var setID [16]byte
+	descs := make(map[[16]byte]*file)
+	checksums := make(map[[16]byte][]sliceChecksum)
+	var recovery []recoverySlice
+	seen := make(map[int]bool)
+	sliceSizes := make(map[int64]bool) // of the recovery packets, checked against the main packet
+	header := make([]byte, headerSize)
+	for off := int64(0); off+headerSize <= info.Size(); {
+		if _, err := f.ReadAt(header, off); err != nil {
+			return nil, "", err
+		}
+		length := int64(binary.LittleEndian.Uint64(header[8:]))
+		if !bytes.Equal(header[:8], packetMagic) || length < headerSize || length%4 != 0 || off+length > info.Size() || !packetValid(f, off, length, header) {
+			next, err := findMagic(f, off+4, info.Size())
+			if err != nil {
+				return nil, "", err
+			}
+			off = next
+			continue
+		}
+		var id, t [16]byte
+		copy(id[:], header[32:48])
+		copy(t[:], header[48:64])
+		if mainBody != nil && id != setID {
+			off += length // another recovery set
+			continue
+		}
+		if t == typeRecovery {
+			if length >= headerSize+4 {
+				exp := make([]byte, 4)
+				if _, err := f.ReadAt(exp, off+headerSize); err != nil {
+					return nil, "", err
+				}
+				if e := int(binary.LittleEndian.Uint32(exp)); !seen[e] {
+					seen[e] = true
+					recovery = append(recovery, recoverySlice{exponent: e, offset: off + headerSize + 4})
+					sliceSizes[length-headerSize-4] = true
+				}
+			}
+			off += length
+			continue
+		}
+		body := make([]byte, length-headerSize)
+		if _, err := f.ReadAt(body, off+headerSize); err != nil {
+			return nil, "", err
+		}
+		switch t {
+		case typeMain:
+			if mainBody == nil && len(body) >= 12 {
+				mainBody, setID = body, id
+			}
+		case typeFileDesc:
+			if len(body) >= 56 {
+				d := &file{length: int64(binary.LittleEndian.Uint64(body[48:56])), name: string(bytes.TrimRight(body[56:], "\x00"))}
+				copy(d.id[:], body[:16])
+				copy(d.hash[:], body[16:32])
+				copy(d.hash16k[:], body[32:48])
+				descs[d.id] = d
+			}
+		case typeIFSC:
+			if len(body) >= 16 {
+				var fid [16]byte
+				copy(fid[:], body[:16])
+				var list []sliceChecksum
+				for p := body[16:]; len(p) >= 20; p = p[20:] {
+					var c sliceChecksum
+					copy(c.hash[:], p[:16])
+					c.crc = binary.LittleEndian.Uint32(p[16:20])
+					list = append(list, c)
+				}
+				checksums[fid] = list
+			}
+		}
+		off += length
+	}
+	if mainBody == nil {
+		return nil, "", fmt.Errorf("%s: no main packet, not a PAR2 file or too damaged", name)
+	}
+	s := &recoverySet{id: setID, sliceSize: int64(binary.LittleEndian.Uint64(mainBody))}
+	if s.sliceSize < 4 || s.sliceSize%4 != 0 {
+		return nil, "", fmt.Errorf("%s: invalid slice size %d", name, s.sliceSize)
+	}
+	count := int(binary.LittleEndian.Uint32(mainBody[8:]))
+	if len(mainBody) < 12+16*count {
+		return nil, "", fmt.Errorf("%s: truncated main packet", name)
+	}
+	for i := range count {
+		var fid [16]byte
+		copy(fid[:], mainBody[12+16*i:])
+		d, ok := descs[fid]
+		if !ok {
+			return nil, "", fmt.Errorf("%s: the description of a file is missing or damaged", name)
+		}
+		d.slices = checksums[fid]
+		if len(d.slices) != sliceCount(d.length, s.sliceSize) {
+			return nil, "", fmt.Errorf("%s: the slice checksums of %s are missing or damaged", name, d.name)
+		}
+		s.files = append(s.files, d)
+	}
+	for _, r := range recovery {
+		if r.exponent < gfOrder {
+			s.recovery = append(s.recovery, r)
+		}
+	}
+	if len(sliceSizes) > 1 || (len(sliceSizes) == 1 && !sliceSizes[s.sliceSize]) {
+		return nil, "", fmt.Errorf("%s: recovery slices of another size than the input slices", name)
+	}
+	sort.Slice(s.recovery, func(i, j int) bool { return s.recovery[i].exponent < s.recovery[j].exponent })
+	dir, err := filepath.Abs(filepath.Dir(name))
+	return s, dir, err
+}
+
+// packetValid reports whether the MD5 of the packet of length bytes at off, with header, is right.
+func packetValid(f *os.File, off, length int64, header []byte) bool {
+	h := md5.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, off+32, length-32)); err != nil {
+		return false
+	}
+	return bytes.Equal(h.Sum(nil), header[16:32])
+}
+
+// findMagic returns the offset of the next packet magic from off, or end when there is none.
+func findMagic(f *os.File, off, end int64) (int64, error) {
+	buf := make([]byte, 1<<20)
+	for ; off < end; off += int64(len(buf) - len(packetMagic)) {
+		n, err := f.ReadAt(buf, off)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.Index(buf[:n], packetMagic); i >= 0 {
+			return off + int64(i), nil
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return end, nil
+}
+
+// verify checks the slices of the files of s in dir, returning the report with the indexes of the
+// damaged input slices.
+func (s *recoverySet) verify(ctx context.Context, dir string) (Report, []int, error) {
+	report := Report{Recovery: len(s.recovery)}
+	var damaged []int
+	buf := make([]byte, s.sliceSize)
+	i := 0
+	for _, f := range s.files {
+		status := FileStatus{Name: f.name, Slices: len(f.slices)}
+		h, err := openFile(dir, f, os.O_RDONLY)
+		if err != nil {
+			status.Missing = true
+		}
+		for k, c := range f.slices {
+			if err := ctx.Err(); err != nil {
+				if h != nil {
+					h.Close()
+				}
+				return report, nil, err
+			}
+			if h == nil || s.readChunk(h, f, k, 0, buf) != nil || md5.Sum(buf) != c.hash {
+				status.Damaged++
+				damaged = append(damaged, i)
+			}
+			i++
+		}
+		if h != nil {
+			// The slices can be right with extra bytes after them
+			if info, err := h.Stat(); err == nil && info.Size() != f.length && status.Damaged == 0 && len(f.slices) > 0 {
+				status.Damaged++
+				damaged = append(damaged, i-1)
+			}
+			h.Close()
+		}
+		report.Damaged += status.Damaged
+		report.Files = append(report.Files, status)
+	}
+	return report, damaged, nil
+}
+
+// repair recovers the damaged input slices of s in dir from the recovery slices read in par.
+func (s *recoverySet) repair(ctx context.Context, dir string, par io.ReaderAt, damaged []int) error {
+	constants := inputConstants(s.inputSlices())
+	recovery := s.recovery[:len(damaged)]
+	// The recovery slice j is the sum of the input slices i multiplied by constant i to the power of
+	// exponent j: the damaged slices are the solution of a linear system of the recovery slices minus
+	// the products of the intact slices
+	matrix := make([][]uint16, len(recovery))
+	for j, r := range recovery {
+		matrix[j] = make([]uint16, len(damaged))
+		for b, i := range damaged {
+			matrix[j][b] = gfPow(constants[i], r.exponent)
+		}
+	}
+	inverse, ok := gfInvert(matrix)
+	if !ok {
+		return errors.New("the recovery slices cannot solve the damaged slices")
+	}
+	isDamaged := make(map[int]int, len(damaged)) // input slice to its index in damaged
+	for b, i := range damaged {
+		isDamaged[i] = b
+	}
+
+	// Open the files with damaged slices for writing, creating the missing ones
+	handles := make([]*os.File, len(s.files))
+	defer func() {
+		for _, h := range handles {
+			if h != nil {
+				h.Close()
+			}
+		}
+	}()
+	i := 0
+	for n, f := range s.files {
+		flag := os.O_RDONLY
+		for k := range f.slices {
+			if _, ok := isDamaged[i+k]; ok {
+				flag = os.O_RDWR | os.O_CREATE
+				if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, filepath.FromSlash(f.name))), 0o755); err != nil {
+					return err
+				}
+				break
+			}
+		}
+		i += len(f.slices)
+		if len(f.slices) == 0 {
+			continue
+		}
+		h, err := openFile(dir, f, flag)
+		if err != nil {
+			return err
+		}
+		handles[n] = h
+	}
+
+	chunk := s.chunkSize(2 * len(damaged))
+	syndromes := make([][]byte, len(recovery))
+	outputs := make([][]byte, len(damaged))
+	for j := range syndromes {
+		syndromes[j], outputs[j] = make([]byte, chunk), make([]byte, chunk)
+	}
+	input := make([]byte, chunk)
+	logs := make([]uint32, chunk/2)
+	for off := int64(0); off < s.sliceSize; off += chunk {
+		n := min(chunk, s.sliceSize-off)
+		for j, r := range recovery {
+			if _, err := par.ReadAt(syndromes[j][:n], r.offset+off); err != nil {
+				return fmt.Errorf("reading recovery slice %d: %w", r.exponent, err)
+			}
+		}
+		i := 0
+		for fi, f := range s.files {
+			for k := range f.slices {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if _, ok := isDamaged[i]; !ok {
+					if err := s.readChunk(handles[fi], f, k, off, input[:n]); err != nil {
+						return fmt.Errorf("reading %s: %w", f.name, err)
+					}
+					factors := make([]uint16, len(recovery))
+					for j, r := range recovery {
+						factors[j] = gfPow(constants[i], r.exponent)
+					}
+					mulAddAll(syndromes, input[:n], factors, logs)
+				}
+				i++
+			}
+		}
+		for b := range outputs {
+			clear(outputs[b][:n])
+		}
+		for j := range syndromes {
+			factors := make([]uint16, len(damaged))
+			for b := range damaged {
+				factors[b] = inverse[b][j]
+			}
+			mulAddAll(outputs, syndromes[j][:n], factors, logs)
+		}
+		i = 0
+		for fi, f := range s.files {
+			for k := range f.slices {
+				if b, ok := isDamaged[i]; ok {
+					start := int64(k)*s.sliceSize + off
+					if end := min(start+n, f.length); start < end {
+						if _, err := handles[fi].WriteAt(outputs[b][:end-start], start); err != nil {
+							return fmt.Errorf("writing %s: %w", f.name, err)
+						}
+					}
+				}
+				i++
+			}
+		}
+	}
+	for fi, f := range s.files {
+		if h := handles[fi]; h != nil {
+			if info, err := h.Stat(); err == nil && info.Size() != f.length {
+				if err := h.Truncate(f.length); err != nil {
+					return fmt.Errorf("truncating %s: %w", f.name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// refreshInterval is the delay between two redraws of the progress line
+const refreshInterval = 250 * time.Millisecond
+
+// Tracker counts the processed files and bytes and periodically draws a single
+// progress line with throughput and estimated time of arrival on its writer.
+// Totals may keep growing while files are still being discovered, the ETA is only
+// shown once SetTotalKnown has been called.
+type Tracker struct {
+	out        io.Writer
+	start      time.Time
+	totalFiles atomic.Int64
+	totalBytes atomic.Int64
+	doneFiles  atomic.Int64
+	doneBytes  atomic.Int64
+	totalKnown atomic.Bool
+	stop       chan struct{}
+	wg         sync.WaitGroup
+	lastWidth  int
+}
+
+// New returns a Tracker drawing on out, call Start to begin the display.
+func New(out io.Writer) *Tracker {
+	return &Tracker{out: out, stop: make(chan struct{})}
+}
+
+// IsTerminal reports whether f looks like an interactive terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Add records newly discovered work.
+func (t *Tracker) Add(files int64, bytes int64) {
+	t.totalFiles.Add(files)
+	t.totalBytes.Add(bytes)
+}
+
+// SetTotalKnown tells the Tracker that all the work has been discovered.
+func (t *Tracker) SetTotalKnown() {
+	t.totalKnown.Store(true)
+}
+
+// Done records a processed file of the given size.
+func (t *Tracker) Done(bytes int64) {
+	t.doneFiles.Add(1)
+	t.doneBytes.Add(bytes)
+}
+
+// Start launches the periodic redraw of the progress line.
+func (t *Tracker) Start() {
+	t.start = time.Now()
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.draw()
+			}
+		}
+	}()
+}
+
+// Stop ends the display and erases the progress line, so the final summary can be printed.
+func (t *Tracker) Stop() {
+	close(t.stop)
+	t.wg.Wait()
+	if t.lastWidth > 0 {
+		fmt.Fprintf(t.out, "\r%s\r", strings.Repeat(" ", t.lastWidth))
+	}
+}
+
+// draw renders the current state on a single line.
+func (t *Tracker) draw() {
+	line := t.Line(time.Since(t.start))
+	width := len([]rune(line))
+	pad := ""
+	if width < t.lastWidth {
+		pad = strings.Repeat(" ", t.lastWidth-width)
+	}
+	t.lastWidth = width
+	fmt.Fprintf(t.out, "\r%s%s", line, pad)
+}
+
+// Line returns the progress text after elapsed time.
+func (t *Tracker) Line(elapsed time.Duration) string {
+	doneFiles, doneBytes := t.doneFiles.Load(), t.doneBytes.Load()
+	totalFiles, totalBytes := t.totalFiles.Load(), t.totalBytes.Load()
+	known := t.totalKnown.Load()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⏳ %d/%d files", doneFiles, totalFiles)
+	if !known {
+		sb.WriteString(" (still searching)")
+	}
+	fmt.Fprintf(&sb, " | %s", FormatBytes(doneBytes))
+	if totalBytes > 0 {
+		fmt.Fprintf(&sb, "/%s", FormatBytes(totalBytes))
+	}
+	seconds := elapsed.Seconds()
+	if seconds > 0 {
+		fmt.Fprintf(&sb, " | %s/s", FormatBytes(int64(float64(doneBytes)/seconds)))
+	}
+	if known {
+		if eta, ok := ETA(elapsed, doneBytes, totalBytes, doneFiles, totalFiles); ok {
+			fmt.Fprintf(&sb, " | ETA %s", FormatDuration(eta))
+		}
+	}
+	return sb.String()
+}
+
+// ETA estimates the remaining time from the progress so far, using the bytes when
+// their total is known and the number of files otherwise.
+func ETA(elapsed time.Duration, doneBytes, totalBytes, doneFiles, totalFiles int64) (time.Duration, bool) {
+	done, total := doneBytes, totalBytes
+	if total <= 0 {
+		done, total = doneFiles, totalFiles
+	}
+	if done <= 0 || total <= 0 {
+		return 0, false
+	}
+	if done >= total {
+		return 0, true
+	}
+	remaining := float64(elapsed) * float64(total-done) / float64(done)
+	return time.Duration(remaining), true
+}
+
+// FormatBytes returns a human-readable size using binary units.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatDuration returns d as hh:mm:ss.
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	d -= h * time.Hour
+	m := d / time.Minute
+	d -= m * time.Minute
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, d/time.Second)
+}
@@ -0,0 +1,63 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFormatBytes tests the human-readable sizes.
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024, "5.0 MiB"},
+		{38 * 1024 * 1024 * 1024, "38.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, expected %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestETA tests the remaining time estimation.
+func TestETA(t *testing.T) {
+	// half of the bytes done in 10 seconds leaves 10 seconds
+	eta, ok := ETA(10*time.Second, 500, 1000, 1, 100)
+	if !ok || eta != 10*time.Second {
+		t.Errorf("ETA() = %v, %v, expected 10s, true", eta, ok)
+	}
+	// without bytes total, the files are used
+	eta, ok = ETA(10*time.Second, 0, 0, 25, 100)
+	if !ok || eta != 30*time.Second {
+		t.Errorf("ETA() = %v, %v, expected 30s, true", eta, ok)
+	}
+	if _, ok = ETA(10*time.Second, 0, 0, 0, 100); ok {
+		t.Error("ETA() without any progress should not be available")
+	}
+	if got := FormatDuration(3723 * time.Second); got != "01:02:03" {
+		t.Errorf("FormatDuration() = %q, expected %q", got, "01:02:03")
+	}
+}
+
+// TestTrackerLine tests the rendered progress line.
+func TestTrackerLine(t *testing.T) {
+	tr := New(&strings.Builder{})
+	tr.Add(4, 4096)
+	tr.Done(1024)
+	line := tr.Line(time.Second)
+	if !strings.Contains(line, "1/4 files (still searching)") || strings.Contains(line, "ETA") {
+		t.Errorf("Unexpected progress line while searching: %q", line)
+	}
+	tr.SetTotalKnown()
+	line = tr.Line(time.Second)
+	if !strings.Contains(line, "1.0 KiB/4.0 KiB") || !strings.Contains(line, "ETA 00:00:03") {
+		t.Errorf("Unexpected progress line: %q", line)
+	}
+}
@@ -0,0 +1,251 @@
+// Package report writes the full report of a verification, with the status of every file, the counts and
+// the timing, as a text, JSON or HTML file kept apart from the console output, like an artifact for auditors.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/stats"
+)
+
+// Report formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatHTML = "html"
+)
+
+// StatusOK is the status of the files matching their hash, the other ones being the failure statuses of package notify.
+const StatusOK = "ok"
+
+// ParseFormat returns the report format named s, or the one of the extension of path when s is empty,
+// text being the default.
+func ParseFormat(s, path string) (string, error) {
+	if s == "" {
+		s = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if s == "htm" {
+			s = FormatHTML
+		}
+		if s != FormatJSON && s != FormatHTML {
+			s = FormatText
+		}
+	}
+	switch s = strings.ToLower(s); s {
+	case FormatText, FormatJSON, FormatHTML:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown report format %q, use text, json or html", s)
+}
+
+// File is the outcome of the verification of one file.
+type File struct {
+	Path     string  `json:"path"`
+	Status   string  `json:"status"`
+	Expected string  `json:"expected,omitempty"`
+	Actual   string  `json:"actual,omitempty"` // empty when the file was not read
+	Error    string  `json:"error,omitempty"`
+	Bytes    int64   `json:"bytes"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// Suspect is a file that could not be read to the end, most often because of bad sectors of its disk,
+// listed apart so the files to restore and the disks to check are found at once.
+type Suspect struct {
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"` // where the read failed in the file
+	Error  string `json:"error"`
+}
+
+// Counts sums up the verification like the final summary line.
+type Counts struct {
+	Files     int `json:"files"` // entries read from the hash files
+	Valid     int `json:"valid"`
+	Invalid   int `json:"invalid"`
+	Missing   int `json:"missing"` // missing files ignored with -ignore-missing
+	Extra     int `json:"extra"`
+	Malformed int `json:"malformed"`
+	Conflicts int `json:"conflicts"`
+}
+
+// Report is the report of a verification.
+type Report struct {
+	Host        string         `json:"host"`
+	HashFiles   []string       `json:"hash_files"`
+	Started     time.Time      `json:"started"`
+	Finished    time.Time      `json:"finished"`
+	Passed      bool           `json:"passed"`
+	Interrupted bool           `json:"interrupted"`
+	Counts      Counts         `json:"counts"`
+	Stats       *stats.Summary `json:"stats,omitempty"`
+	Files       []File         `json:"files"`
+	Suspects    []Suspect      `json:"suspect_files,omitempty"`
+}
+
+// New returns the Report of the verification of hashFiles started now on this host.
+func New(hashFiles []string) *Report {
+	host, _ := os.Hostname()
+	return &Report{Host: host, HashFiles: hashFiles, Started: time.Now().UTC()}
+}
+
+// Add records the outcome of the verification of a file, it is not safe for concurrent use.
+func (r *Report) Add(f File) {
+	r.Files = append(r.Files, f)
+}
+
+// AddSuspect records a file whose read failed, besides its outcome added with Add. It is not safe for concurrent use.
+func (r *Report) AddSuspect(s Suspect) {
+	r.Suspects = append(r.Suspects, s)
+}
+
+// WriteFile writes the report to the file at path in format, the files sorted by path.
+func (r *Report) WriteFile(path, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes the report to w in format, the files and the suspect files sorted by path.
+func (r *Report) Write(w io.Writer, format string) error {
+	sort.SliceStable(r.Files, func(i, j int) bool { return r.Files[i].Path < r.Files[j].Path })
+	sort.SliceStable(r.Suspects, func(i, j int) bool { return r.Suspects[i].Path < r.Suspects[j].Path })
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatHTML:
+		return htmlReport.Execute(w, r)
+	case FormatText:
+		return r.writeText(w)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// Result returns PASSED, FAILED or INTERRUPTED.
+func (r *Report) Result() string {
+	switch {
+	case r.Interrupted:
+		return "INTERRUPTED"
+	case r.Passed:
+		return "PASSED"
+	}
+	return "FAILED"
+}
+
+// Duration returns the time taken by the verification.
+func (r *Report) Duration() time.Duration {
+	return r.Finished.Sub(r.Started).Round(time.Millisecond)
+}
+
+// writeText writes the report as aligned plain text.
+func (r *Report) writeText(w io.Writer) error {
+	c := r.Counts
+	fmt.Fprintf(w, "goDirHasher verification report\n\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Host:\t%s\n", r.Host)
+	fmt.Fprintf(tw, "Hash files:\t%s\n", strings.Join(r.HashFiles, ", "))
+	fmt.Fprintf(tw, "Started:\t%s\n", r.Started.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Finished:\t%s (%s)\n", r.Finished.Format(time.RFC3339), r.Duration())
+	fmt.Fprintf(tw, "Result:\t%s\n", r.Result())
+	fmt.Fprintf(tw, "Files:\t%d listed, %d valid, %d invalid, %d missing ignored, %d extra\n", c.Files, c.Valid, c.Invalid, c.Missing, c.Extra)
+	fmt.Fprintf(tw, "Hash lines:\t%d malformed, %d conflicting\n", c.Malformed, c.Conflicts)
+	if r.Stats != nil {
+		fmt.Fprintf(tw, "Read:\t%d bytes in %.3fs, %.1f MB/s\n", r.Stats.Bytes, r.Stats.Seconds, r.Stats.MBPerSecond)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tBYTES\tSECONDS\tPATH\tDETAILS")
+	for _, f := range r.Files {
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%s\t%s\n", f.Status, f.Bytes, f.Seconds, f.Path, f.details())
+	}
+	if err := tw.Flush(); err != nil || len(r.Suspects) == 0 {
+		return err
+	}
+	fmt.Fprintf(w, "\nSuspect files, read errors possibly from bad sectors:\n\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OFFSET\tPATH\tERROR")
+	for _, s := range r.Suspects {
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Offset, s.Path, s.Error)
+	}
+	return tw.Flush()
+}
+
+// details returns the hashes of a mismatched file or its error.
+func (f File) details() string {
+	switch {
+	case f.Error != "":
+		return f.Error
+	case f.Status != StatusOK && f.Actual != "":
+		return "expected " + f.Expected + ", got " + f.Actual
+	}
+	return ""
+}
+
+// htmlReport is the template of the HTML reports, a single page without external resources.
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>goDirHasher verification report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+td.num { text-align: right; }
+.ok { color: #1a7f37; }
+.mismatch, .missing, .error, .FAILED { color: #cf222e; font-weight: bold; }
+.metadata, .extra, .INTERRUPTED { color: #9a6700; font-weight: bold; }
+.PASSED { color: #1a7f37; font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>goDirHasher verification report</h1>
+<table>
+<tr><th>Host</th><td>{{.Host}}</td></tr>
+<tr><th>Hash files</th><td>{{range $i, $f := .HashFiles}}{{if $i}}, {{end}}{{$f}}{{end}}</td></tr>
+<tr><th>Started</th><td>{{time .Started}}</td></tr>
+<tr><th>Finished</th><td>{{time .Finished}} ({{.Duration}})</td></tr>
+<tr><th>Result</th><td class="{{.Result}}">{{.Result}}</td></tr>
+<tr><th>Files</th><td>{{.Counts.Files}} listed, {{.Counts.Valid}} valid, {{.Counts.Invalid}} invalid, {{.Counts.Missing}} missing ignored, {{.Counts.Extra}} extra</td></tr>
+<tr><th>Hash lines</th><td>{{.Counts.Malformed}} malformed, {{.Counts.Conflicts}} conflicting</td></tr>
+{{with .Stats}}<tr><th>Read</th><td>{{.Bytes}} bytes in {{printf "%.3f" .Seconds}}s, {{printf "%.1f" .MBPerSecond}} MB/s</td></tr>
+{{end}}</table>
+<h2>Files</h2>
+<table>
+<tr><th>Status</th><th>Bytes</th><th>Seconds</th><th>Path</th><th>Details</th></tr>
+{{range .Files}}<tr><td class="{{.Status}}">{{.Status}}</td><td class="num">{{.Bytes}}</td><td class="num">{{printf "%.3f" .Seconds}}</td><td>{{.Path}}</td><td>{{.Details}}</td></tr>
+{{end}}</table>
+{{with .Suspects}}<h2>Suspect files</h2>
+<p>Read errors possibly from bad sectors.</p>
+<table>
+<tr><th>Offset</th><th>Path</th><th>Error</th></tr>
+{{range .}}<tr><td class="num">{{.Offset}}</td><td>{{.Path}}</td><td class="error">{{.Error}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// Details returns the hashes of a mismatched file or its error, for the HTML template.
+func (f File) Details() string {
+	return f.details()
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseFormat tests that the format defaults to the extension of the report file.
+func TestParseFormat(t *testing.T) {
+	for _, tc := range []struct{ name, path, want string }{
+		{"", "report.json", FormatJSON},
+		{"", "report.HTM", FormatHTML},
+		{"", "report.log", FormatText},
+		{"JSON", "report.txt", FormatJSON},
+		{"text", "report.html", FormatText},
+	} {
+		if got, err := ParseFormat(tc.name, tc.path); err != nil || got != tc.want {
+			t.Errorf("ParseFormat(%q, %q) = %q, %v, expected %q", tc.name, tc.path, got, err, tc.want)
+		}
+	}
+	if _, err := ParseFormat("xml", "report.xml"); err == nil {
+		t.Error("ParseFormat() accepted an unknown format")
+	}
+}
+
+// TestWrite tests that every format lists the files sorted by path with their failures.
+func TestWrite(t *testing.T) {
+	r := New([]string{"SHA256SUMS"})
+	r.Finished = r.Started.Add(1500 * time.Millisecond)
+	r.Counts = Counts{Files: 2, Valid: 1, Invalid: 1, Extra: 1}
+	r.Add(File{Path: "b.txt", Status: "mismatch", Expected: "aa", Actual: "bb", Bytes: 3})
+	r.Add(File{Path: "a.txt", Status: StatusOK, Expected: "cc", Actual: "cc", Bytes: 5})
+	r.Add(File{Path: "<c>.txt", Status: "extra"})
+
+	var out bytes.Buffer
+	if err := r.Write(&out, FormatJSON); err != nil {
+		t.Fatalf("Write() returned %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Write() wrote invalid JSON: %v", err)
+	}
+	if len(decoded.Files) != 3 || decoded.Files[0].Path != "<c>.txt" || decoded.Files[1].Path != "a.txt" || decoded.Counts.Invalid != 1 {
+		t.Errorf("Write() wrote %+v, expected the 3 files sorted by path", decoded)
+	}
+
+	out.Reset()
+	if err := r.Write(&out, FormatText); err != nil {
+		t.Fatalf("Write() returned %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{"Result:      FAILED", "(1.5s)", "expected aa, got bb", "SHA256SUMS"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Write() text report does not contain %q:\n%s", want, text)
+		}
+	}
+
+	out.Reset()
+	r.Interrupted = true
+	if err := r.Write(&out, FormatHTML); err != nil {
+		t.Fatalf("Write() returned %v", err)
+	}
+	html := out.String()
+	if !strings.Contains(html, `<td class="mismatch">mismatch</td>`) || !strings.Contains(html, "&lt;c&gt;.txt") || !strings.Contains(html, "INTERRUPTED") {
+		t.Errorf("Write() HTML report is missing the statuses or the escaped paths:\n%s", html)
+	}
+}
+
+// TestWriteSuspects tests that the files whose read failed are listed in their own section, only when there are some.
+func TestWriteSuspects(t *testing.T) {
+	r := New([]string{"SHA256SUMS"})
+	r.Add(File{Path: "ok.txt", Status: StatusOK})
+	var out bytes.Buffer
+	if err := r.Write(&out, FormatText); err != nil || strings.Contains(out.String(), "Suspect files") {
+		t.Errorf("Write() = %v, want no suspect files section without read errors:\n%s", err, out.String())
+	}
+	r.AddSuspect(Suspect{Path: "video.mkv", Offset: 1048576, Error: "read error at offset 1048576: input/output error"})
+	r.AddSuspect(Suspect{Path: "archive.tar", Offset: 0, Error: "read error at offset 0: input/output error"})
+	for _, format := range []string{FormatText, FormatHTML, FormatJSON} {
+		out.Reset()
+		if err := r.Write(&out, format); err != nil {
+			t.Fatalf("Write(%s) returned %v", format, err)
+		}
+		text := out.String()
+		if !strings.Contains(text, "1048576") || strings.Index(text, "archive.tar") > strings.Index(text, "video.mkv") {
+			t.Errorf("Write(%s) does not list the suspect files sorted by path with their offset:\n%s", format, text)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultPartSize is the part size of the multipart uploads of the AWS CLI and most SDKs.
+const DefaultPartSize = 8 << 20
+
+// ETag returns the ETag S3 gives to the content of r when it is uploaded in parts of partSize bytes:
+// the MD5 of the content when it fits in a single part, otherwise the MD5 of the concatenated MD5 of
+// every part followed by a dash and the number of parts. A partSize of 0 computes the single part ETag.
+func ETag(ctx context.Context, r io.Reader, partSize int64) (string, error) {
+	return etag(ctx, r, partSize, false)
+}
+
+// etag computes the ETag of the content of r, always in the multipart form when multipart is set,
+// as a multipart upload of a single part gets an ETag ending with -1.
+func etag(ctx context.Context, r io.Reader, partSize int64, multipart bool) (string, error) {
+	whole := md5.New()
+	var sums []byte
+	parts := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		part := md5.New()
+		var n int64
+		var err error
+		if partSize > 0 {
+			n, err = io.CopyN(io.MultiWriter(part, whole), r, partSize)
+		} else {
+			n, err = io.Copy(whole, r)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		if n > 0 || parts == 0 {
+			sums = part.Sum(sums)
+			parts++
+		}
+		if partSize <= 0 || n < partSize {
+			break
+		}
+	}
+	if parts == 1 && !multipart {
+		return hex.EncodeToString(whole.Sum(nil)), nil
+	}
+	sum := md5.Sum(sums)
+	return hex.EncodeToString(sum[:]) + "-" + strconv.Itoa(parts), nil
+}
+
+// Parts returns the number of parts of a multipart ETag, 0 for the ETag of a single part upload.
+func Parts(tag string) int {
+	_, count, found := strings.Cut(strings.Trim(tag, `"`), "-")
+	if !found {
+		return 0
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// PartSizes returns the part sizes that split size bytes in the number of parts of the ETag tag,
+// partSize first when it does, then the smallest whole number of MiB, as chosen by most upload tools.
+func PartSizes(tag string, size, partSize int64) []int64 {
+	parts := int64(Parts(tag))
+	if parts == 0 {
+		return []int64{0}
+	}
+	fits := func(ps int64) bool {
+		return ps > 0 && (size+ps-1)/ps == parts
+	}
+	var sizes []int64
+	if fits(partSize) {
+		sizes = append(sizes, partSize)
+	}
+	const mib = 1 << 20
+	guess := ((size+parts-1)/parts + mib - 1) / mib * mib
+	if guess != partSize && fits(guess) {
+		sizes = append(sizes, guess)
+	}
+	return sizes
+}
+
+// MatchFile reports whether the local file at path has the ETag tag, trying the part sizes of PartSizes.
+// The ETags of objects encrypted with SSE-KMS or SSE-C are not MD5 digests and never match.
+func MatchFile(ctx context.Context, path, tag string, partSize int64) (bool, error) {
+	tag = strings.Trim(tag, `"`)
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	sizes := PartSizes(tag, info.Size(), partSize)
+	if len(sizes) == 0 {
+		return false, fmt.Errorf("no part size splits %d bytes in %d parts", info.Size(), Parts(tag))
+	}
+	for _, ps := range sizes {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		local, err := etag(ctx, f, ps, Parts(tag) > 0)
+		if err != nil {
+			return false, err
+		}
+		if strings.EqualFold(local, tag) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
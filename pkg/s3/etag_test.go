@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// multipartETag computes the multipart ETag of parts the straightforward way.
+func multipartETag(parts ...[]byte) string {
+	var sums []byte
+	for _, p := range parts {
+		sum := md5.Sum(p)
+		sums = append(sums, sum[:]...)
+	}
+	sum := md5.Sum(sums)
+	return hex.EncodeToString(sum[:]) + "-" + strconv.Itoa(len(parts))
+}
+
+// TestETag tests the single part and multipart ETags.
+func TestETag(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 25)
+	sum := md5.Sum(data)
+	single := hex.EncodeToString(sum[:])
+	tests := []struct {
+		name     string
+		partSize int64
+		want     string
+	}{
+		{"no part size", 0, single},
+		{"single part", 1000, single},
+		{"three parts", 100, multipartETag(data[:100], data[100:200], data[200:])},
+		{"exact parts", 125, multipartETag(data[:125], data[125:])},
+	}
+	for _, tt := range tests {
+		got, err := ETag(ctx, bytes.NewReader(data), tt.partSize)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: ETag() = %q, %v, expected %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// TestMatchFile tests that the part size is guessed from the number of parts of the ETag.
+func TestMatchFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "archive.tar")
+	data := bytes.Repeat([]byte{'x'}, 5<<20+10)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	// uploaded in parts of 3MiB, while the default part size of 8MiB would give a single part
+	uploaded := multipartETag(data[:3<<20], data[3<<20:])
+	if ok, err := MatchFile(ctx, path, `"`+uploaded+`"`, DefaultPartSize); !ok || err != nil {
+		t.Errorf("MatchFile() = %v, %v, expected the 3MiB parts to be found", ok, err)
+	}
+	if ok, err := MatchFile(ctx, path, multipartETag(data[:1<<20]), DefaultPartSize); ok || err != nil {
+		t.Errorf("MatchFile() of a wrong single part multipart ETag = %v, %v, expected a mismatch", ok, err)
+	}
+	sum := md5.Sum(data)
+	if ok, err := MatchFile(ctx, path, hex.EncodeToString(sum[:]), DefaultPartSize); !ok || err != nil {
+		t.Errorf("MatchFile() of the MD5 = %v, %v, expected a match", ok, err)
+	}
+}
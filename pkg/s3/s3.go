@@ -0,0 +1,271 @@
+// Package s3 lists and reads the objects of an Amazon S3 (or S3 compatible) bucket with the REST API,
+// signing the requests with AWS Signature Version 4, and computes the ETags S3 gives to uploaded files.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emptyPayloadHash is the SHA256 of an empty body, all the requests sent are GET ones.
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Client sends requests to S3. Requests are anonymous, for public buckets, when AccessKeyID is empty.
+type Client struct {
+	Region          string // us-east-1 when empty
+	Endpoint        string // e.g. http://localhost:9000 for MinIO, buckets are then addressed in the path
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string       // temporary credentials only
+	HTTPClient      *http.Client // http.DefaultClient when nil
+}
+
+// FromEnv returns a Client configured with the usual AWS environment variables: AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION (or AWS_DEFAULT_REGION) and AWS_ENDPOINT_URL.
+func FromEnv() *Client {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return &Client{
+		Region:          region,
+		Endpoint:        strings.TrimRight(os.Getenv("AWS_ENDPOINT_URL"), "/"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// IsURL reports whether s is an s3:// URL.
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "s3://")
+}
+
+// ParseURL splits an s3://bucket/prefix URL into its bucket and key prefix, which may be empty.
+func ParseURL(s string) (bucket, prefix string, err error) {
+	if !IsURL(s) {
+		return "", "", fmt.Errorf("%q is not an s3://bucket/prefix URL", s)
+	}
+	bucket, prefix, _ = strings.Cut(strings.TrimPrefix(s, "s3://"), "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("missing bucket name in %q", s)
+	}
+	return bucket, prefix, nil
+}
+
+// Object is an object listed in a bucket.
+type Object struct {
+	Key          string
+	Size         int64
+	ETag         string // without the surrounding quotes
+	LastModified time.Time
+}
+
+// Error is an error answered by S3.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("S3 answered %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("S3 answered %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// List calls fn for every object of bucket whose key starts with prefix, in key order,
+// stopping at the first error returned by fn.
+func (c *Client) List(ctx context.Context, bucket, prefix string, fn func(Object) error) error {
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		resp, err := c.get(ctx, bucket, "", query)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string
+				Size         int64
+				ETag         string
+				LastModified time.Time
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("decoding the objects of bucket %s: %w", bucket, err)
+		}
+		for _, o := range page.Contents {
+			if err := fn(Object{Key: o.Key, Size: o.Size, ETag: strings.Trim(o.ETag, `"`), LastModified: o.LastModified}); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Open returns the content of the object key of bucket, which must be closed after use.
+func (c *Client) Open(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	resp, err := c.get(ctx, bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// get sends a signed GET request for key in bucket, an answer other than 200 being returned as an *Error.
+func (c *Client) get(ctx context.Context, bucket, key string, query url.Values) (*http.Response, error) {
+	var u string
+	if c.Endpoint != "" {
+		u = c.Endpoint + "/" + bucket + "/" + escape(key, false)
+	} else {
+		u = "https://" + bucket + ".s3." + c.region() + ".amazonaws.com/" + escape(key, false)
+	}
+	if len(query) > 0 {
+		u += "?" + canonicalQuery(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.AccessKeyID != "" {
+		c.sign(req, time.Now())
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		s3Err := &Error{StatusCode: resp.StatusCode}
+		xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(s3Err)
+		return nil, s3Err
+	}
+	return resp, nil
+}
+
+func (c *Client) region() string {
+	if c.Region == "" {
+		return "us-east-1"
+	}
+	return c.Region
+}
+
+// sign adds the headers and the Authorization of AWS Signature Version 4 to req, sent at now.
+// Every header already set in req is signed with the host.
+func (c *Client) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := day + "/" + c.region() + "/s3/aws4_request"
+	digest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(digest[:])
+
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, part := range []string{day, c.region(), "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery returns the query string sorted by name with its names and values escaped as SigV4 expects.
+func canonicalQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var params []string
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, escape(name, true)+"="+escape(value, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// escape percent-encodes every byte of s except the unreserved characters of RFC 3986,
+// and the slashes unless encodeSlash is set.
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+// Package sbom writes the hashed files of a scan as the file entities of a software bill of materials,
+// an SPDX 2.3 or a CycloneDX 1.6 JSON document, so compliance tooling can ingest directory scans directly.
+package sbom
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+// Formats of the documents.
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// ParseFormat returns the document format named s (case-insensitive).
+func ParseFormat(s string) (string, error) {
+	switch f := strings.ToLower(strings.TrimSpace(s)); f {
+	case FormatSPDX, FormatCycloneDX:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown SBOM format %q, use spdx or cyclonedx", s)
+}
+
+// spdxAlgorithms and cycloneDXAlgorithms name the supported algorithms in each format, CRC32 having no name.
+var (
+	spdxAlgorithms      = map[hasher.Algorithm]string{hasher.MD5: "MD5", hasher.SHA1: "SHA1", hasher.SHA256: "SHA256", hasher.SHA512: "SHA512"}
+	cycloneDXAlgorithms = map[hasher.Algorithm]string{hasher.MD5: "MD5", hasher.SHA1: "SHA-1", hasher.SHA256: "SHA-256", hasher.SHA512: "SHA-512"}
+)
+
+// Supports reports whether the digests of algorithm can be written in the documents.
+func Supports(algorithm hasher.Algorithm) bool {
+	_, ok := spdxAlgorithms[algorithm]
+	return ok
+}
+
+// File is a hashed file of the document.
+type File struct {
+	Path   string // slash separated
+	Hashes map[hasher.Algorithm]string
+}
+
+// Document describes the scan the files come from.
+type Document struct {
+	Name    string // of the SPDX document, like the scanned directory
+	Tool    string // name of the tool creating the document
+	Version string // version of the tool
+	Created time.Time
+}
+
+// Write writes the document of files in format to w, the files being sorted by path.
+func Write(w io.Writer, format string, doc Document, files []File) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	var v any
+	switch format {
+	case FormatSPDX:
+		v = spdx(doc, files)
+	case FormatCycloneDX:
+		v = cycloneDX(doc, files)
+	default:
+		return fmt.Errorf("unknown SBOM format %q", format)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// algorithms returns the algorithms of hashes named in names, sorted so the documents are stable.
+func algorithms(hashes map[hasher.Algorithm]string, names map[hasher.Algorithm]string) []hasher.Algorithm {
+	var list []hasher.Algorithm
+	for algorithm := range hashes {
+		if _, ok := names[algorithm]; ok {
+			list = append(list, algorithm)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// uuid returns a random (version 4) UUID.
+func uuid() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxFile struct {
+	FileName  string         `json:"fileName"`
+	SPDXID    string         `json:"SPDXID"`
+	Checksums []spdxChecksum `json:"checksums"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Files []spdxFile `json:"files"`
+}
+
+// spdx returns the SPDX 2.3 document of files, named ./path like the SPDX file names relative to the root.
+// SPDX 2.3 expects a SHA1 checksum for each file, the caller choosing the algorithms.
+func spdx(doc Document, files []File) spdxDocument {
+	d := spdxDocument{SPDXVersion: "SPDX-2.3", DataLicense: "CC0-1.0", SPDXID: "SPDXRef-DOCUMENT", Name: doc.Name,
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + url.PathEscape(doc.Tool+"-"+doc.Name) + "-" + uuid()}
+	d.CreationInfo.Created = doc.Created.UTC().Format(time.RFC3339)
+	d.CreationInfo.Creators = []string{"Tool: " + doc.Tool + "-" + doc.Version}
+	d.Files = make([]spdxFile, len(files))
+	for i, f := range files {
+		name := f.Path
+		if !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "./") && !strings.HasPrefix(name, "../") {
+			name = "./" + name
+		}
+		d.Files[i] = spdxFile{FileName: name, SPDXID: fmt.Sprintf("SPDXRef-File-%d", i+1), Checksums: []spdxChecksum{}}
+		for _, algorithm := range algorithms(f.Hashes, spdxAlgorithms) {
+			d.Files[i].Checksums = append(d.Files[i].Checksums, spdxChecksum{Algorithm: spdxAlgorithms[algorithm], ChecksumValue: strings.ToLower(f.Hashes[algorithm])})
+		}
+	}
+	return d
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXComponent struct {
+	Type    string          `json:"type"`
+	BOMRef  string          `json:"bom-ref,omitempty"`
+	Name    string          `json:"name"`
+	Version string          `json:"version,omitempty"`
+	Hashes  []cycloneDXHash `json:"hashes,omitempty"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string `json:"timestamp"`
+		Tools     struct {
+			Components []cycloneDXComponent `json:"components"`
+		} `json:"tools"`
+	} `json:"metadata"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// cycloneDX returns the CycloneDX 1.6 document of files, each one being a component of type file.
+func cycloneDX(doc Document, files []File) cycloneDXDocument {
+	d := cycloneDXDocument{BOMFormat: "CycloneDX", SpecVersion: "1.6", SerialNumber: "urn:uuid:" + uuid(), Version: 1}
+	d.Metadata.Timestamp = doc.Created.UTC().Format(time.RFC3339)
+	d.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: doc.Tool, Version: doc.Version}}
+	d.Components = make([]cycloneDXComponent, len(files))
+	for i, f := range files {
+		d.Components[i] = cycloneDXComponent{Type: "file", BOMRef: fmt.Sprintf("file-%d", i+1), Name: f.Path}
+		for _, algorithm := range algorithms(f.Hashes, cycloneDXAlgorithms) {
+			d.Components[i].Hashes = append(d.Components[i].Hashes, cycloneDXHash{Alg: cycloneDXAlgorithms[algorithm], Content: strings.ToLower(f.Hashes[algorithm])})
+		}
+	}
+	return d
+}
@@ -0,0 +1,74 @@
+package sbom
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+var testFiles = []File{
+	{Path: "src/main.go", Hashes: map[hasher.Algorithm]string{hasher.SHA256: "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD", hasher.SHA1: "A9993E364706816ABA3E25717850C26C9CD0D89D"}},
+	{Path: "README.md", Hashes: map[hasher.Algorithm]string{hasher.SHA256: "E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855", hasher.SHA1: "DA39A3EE5E6B4B0D3255BFEF95601890AFD80709"}},
+}
+
+var testDoc = Document{Name: "project", Tool: "goDirHasher", Version: "1.0", Created: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+// TestWriteSPDX tests the file entities of an SPDX document, sorted by path with their checksums.
+func TestWriteSPDX(t *testing.T) {
+	var sb strings.Builder
+	if err := Write(&sb, FormatSPDX, testDoc, append([]File{}, testFiles...)); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+	var d spdxDocument
+	if err := json.Unmarshal([]byte(sb.String()), &d); err != nil {
+		t.Fatalf("Write() wrote invalid JSON: %v\n%s", err, sb.String())
+	}
+	if d.SPDXVersion != "SPDX-2.3" || d.CreationInfo.Created != "2026-01-02T03:04:05Z" || d.CreationInfo.Creators[0] != "Tool: goDirHasher-1.0" ||
+		!strings.HasPrefix(d.DocumentNamespace, "https://spdx.org/spdxdocs/goDirHasher-project-") {
+		t.Errorf("unexpected SPDX document: %+v", d)
+	}
+	if len(d.Files) != 2 || d.Files[0].FileName != "./README.md" || d.Files[1].SPDXID != "SPDXRef-File-2" {
+		t.Fatalf("unexpected SPDX files: %+v", d.Files)
+	}
+	expected := []spdxChecksum{{"SHA1", "a9993e364706816aba3e25717850c26c9cd0d89d"}, {"SHA256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}}
+	if got := d.Files[1].Checksums; len(got) != 2 || got[0] != expected[0] || got[1] != expected[1] {
+		t.Errorf("SPDX checksums = %+v, expected %+v", got, expected)
+	}
+}
+
+// TestWriteCycloneDX tests the file components of a CycloneDX document.
+func TestWriteCycloneDX(t *testing.T) {
+	var sb strings.Builder
+	if err := Write(&sb, FormatCycloneDX, testDoc, append([]File{}, testFiles...)); err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+	var d cycloneDXDocument
+	if err := json.Unmarshal([]byte(sb.String()), &d); err != nil {
+		t.Fatalf("Write() wrote invalid JSON: %v\n%s", err, sb.String())
+	}
+	if d.BOMFormat != "CycloneDX" || d.SpecVersion != "1.6" || len(d.SerialNumber) != len("urn:uuid:")+36 || d.Metadata.Tools.Components[0].Name != "goDirHasher" {
+		t.Errorf("unexpected CycloneDX document: %+v", d)
+	}
+	if len(d.Components) != 2 || d.Components[0].Name != "README.md" || d.Components[0].Type != "file" {
+		t.Fatalf("unexpected CycloneDX components: %+v", d.Components)
+	}
+	if got := d.Components[1].Hashes; len(got) != 2 || got[0].Alg != "SHA-1" || got[1] != (cycloneDXHash{"SHA-256", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"}) {
+		t.Errorf("CycloneDX hashes = %+v", got)
+	}
+}
+
+// TestParseFormat tests the names of the formats.
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat(" CycloneDX"); err != nil || f != FormatCycloneDX {
+		t.Errorf("ParseFormat(CycloneDX) = %q, %v", f, err)
+	}
+	if _, err := ParseFormat("swid"); err == nil {
+		t.Error("ParseFormat(swid) returned no error")
+	}
+	if Supports(hasher.CRC32) || !Supports(hasher.SHA512) {
+		t.Error("Supports() does not match the algorithms of the formats")
+	}
+}
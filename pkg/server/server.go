@@ -0,0 +1,446 @@
+// Package server implements the REST API of goDirHasher serve: hash jobs on server-side paths,
+// hashing of uploaded content and verification of stored manifests, all answered in JSON.
+//
+//	POST /jobs                 {"path": "data", "algo": "sha256"}            start a hash job
+//	POST /verify               {"manifest": "data.sha256", "base_dir": "."}  start a verification job
+//	GET  /jobs                                                               list the jobs
+//	GET  /jobs/{id}                                                          status of a job
+//	GET  /jobs/{id}/manifest   (?format=text for sha256sum lines)            files of a finished job
+//	POST /hash?algo=sha256     raw request body                              hash uploaded content
+//
+// Every path is relative to the root directory of the server and cannot escape it.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job kinds and statuses.
+const (
+	KindHash   = "hash"
+	KindVerify = "verify"
+
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed" // the job itself could not run, see Job.Error
+
+	// statuses of the files of a verification job
+	FileOK       = "ok"
+	FileMismatch = "mismatch"
+	FileMissing  = "missing"
+	FileError    = "error"
+)
+
+// FileResult is a file hashed by a job.
+type FileResult struct {
+	Path     string `json:"path"` // relative to the server root, slash separated
+	Hash     string `json:"hash,omitempty"`
+	Size     int64  `json:"size"`
+	Status   string `json:"status,omitempty"`   // verification jobs only
+	Expected string `json:"expected,omitempty"` // verification jobs only
+	Error    string `json:"error,omitempty"`
+}
+
+// Digest is the hash of uploaded content.
+type Digest struct {
+	Algorithm string `json:"algo"`
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+}
+
+// Job is the state of a hash or verification job, as returned by the API.
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Path       string     `json:"path"` // hashed path or verified manifest
+	Algorithm  string     `json:"algo"`
+	Status     string     `json:"status"`
+	Created    time.Time  `json:"created"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	Files      int        `json:"files"`
+	Bytes      int64      `json:"bytes"`
+	Errors     int        `json:"errors"`
+	Mismatches int        `json:"mismatches"`
+	Error      string     `json:"error,omitempty"`
+
+	results []FileResult
+}
+
+// Server runs the jobs submitted to its HTTP API. Jobs are kept in memory until the server stops.
+type Server struct {
+	root string
+	opts hasher.Options
+	ctx  context.Context
+	mux  *http.ServeMux
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	nextID int
+}
+
+// New returns a Server hashing the files below root as described by opts, the algorithm being
+// the default one of the jobs. The jobs are cancelled when ctx is.
+func New(ctx context.Context, root string, opts hasher.Options) (*Server, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	// The paths are compared to the root once their symlinks are resolved
+	if abs, err = evalSymlinks(abs); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	s := &Server{root: abs, opts: opts, ctx: ctx, mux: http.NewServeMux(), jobs: make(map[string]*Job)}
+	s.mux.HandleFunc("POST /jobs", s.handleHashJob)
+	s.mux.HandleFunc("POST /verify", s.handleVerifyJob)
+	s.mux.HandleFunc("GET /jobs", s.handleJobs)
+	s.mux.HandleFunc("GET /jobs/{id}", s.handleJob)
+	s.mux.HandleFunc("GET /jobs/{id}/manifest", s.handleManifest)
+	s.mux.HandleFunc("POST /hash", s.handleUpload)
+	return s, nil
+}
+
+// ServeHTTP answers the requests of the API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// resolve returns the absolute path of p, relative to the server root, refusing paths outside of it.
+func (s *Server) resolve(p string) (string, error) {
+	if p == "" {
+		return "", errors.New("missing path")
+	}
+	full := filepath.Join(s.root, filepath.FromSlash(p))
+	if !s.inside(full) {
+		return "", fmt.Errorf("path %q is outside of the server root", p)
+	}
+	return full, nil
+}
+
+// inside reports whether the clean absolute path full is the server root or below it, once its symlinks
+// are resolved, so a symlink below the root cannot give access to the files outside of it.
+func (s *Server) inside(full string) bool {
+	full, err := evalSymlinks(full)
+	if err != nil {
+		return false
+	}
+	return full == s.root || strings.HasPrefix(full, s.root+string(filepath.Separator))
+}
+
+// evalSymlinks returns path with its symlinks resolved, like filepath.EvalSymlinks, the names that
+// do not exist being kept as they are after the resolved path of their nearest existing parent.
+func evalSymlinks(path string) (string, error) {
+	real, err := filepath.EvalSymlinks(path)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return real, err
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path, nil
+	}
+	if real, err = evalSymlinks(parent); err != nil {
+		return "", err
+	}
+	return filepath.Join(real, filepath.Base(path)), nil
+}
+
+// relative returns full relative to the server root, slash separated.
+func (s *Server) relative(full string) string {
+	if rel, err := filepath.Rel(s.root, full); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(full)
+}
+
+// options returns the options of a job using the algorithm named algo, the default one when empty.
+func (s *Server) options(algo string) (hasher.Options, error) {
+	opts := s.opts
+	opts.ExtraAlgorithms = nil
+	if algo != "" {
+		a, err := hasher.ParseAlgorithm(algo)
+		if err != nil {
+			return opts, err
+		}
+		opts.Algorithm = a
+	}
+	if opts.Algorithm == "" {
+		opts.Algorithm = hasher.SHA256
+	}
+	return opts, nil
+}
+
+// start registers a new running job and runs it in the background with run.
+func (s *Server) start(kind, path string, opts hasher.Options, run func(ctx context.Context, job *Job) error) *Job {
+	s.mu.Lock()
+	s.nextID++
+	job := &Job{ID: strconv.Itoa(s.nextID), Kind: kind, Path: path, Algorithm: string(opts.Algorithm),
+		Status: StatusRunning, Created: time.Now().UTC()}
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+	go func() {
+		err := run(s.ctx, job)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		now := time.Now().UTC()
+		job.Finished = &now
+		job.Status = StatusDone
+		if err != nil {
+			job.Status, job.Error = StatusFailed, err.Error()
+		}
+		sort.Slice(job.results, func(i, j int) bool { return job.results[i].Path < job.results[j].Path })
+	}()
+	return job
+}
+
+// add records the result of a file in job.
+func (s *Server) add(job *Job, f FileResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.Files++
+	job.Bytes += f.Size
+	switch {
+	case f.Error != "":
+		job.Errors++
+	case f.Status == FileMismatch:
+		job.Mismatches++
+	}
+	job.results = append(job.results, f)
+}
+
+func (s *Server) handleHashJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Path string `json:"path"`
+		Algo string `json:"algo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	full, err := s.resolve(req.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts, err := s.options(req.Algo)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	job := s.start(KindHash, req.Path, opts, func(ctx context.Context, job *Job) error {
+		if _, err := os.Stat(full); err != nil {
+			return err
+		}
+		onError := func(path string, err error) {
+			s.add(job, FileResult{Path: s.relative(path), Error: err.Error()})
+		}
+		return opts.HashTree(ctx, full, opts.Walker(onError), func(res hasher.Result) {
+			f := FileResult{Path: s.relative(res.Path), Hash: res.Hash, Size: res.Size}
+			if res.Err != nil {
+				f.Error = res.Err.Error()
+			}
+			s.add(job, f)
+		})
+	})
+	writeJSON(w, http.StatusAccepted, s.snapshot(job))
+}
+
+func (s *Server) handleVerifyJob(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Manifest string `json:"manifest"`
+		BaseDir  string `json:"base_dir"` // directory of the manifest when empty
+		Algo     string `json:"algo"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	manifest, err := s.resolve(req.Manifest)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	baseDir := filepath.Dir(manifest)
+	if req.BaseDir != "" {
+		if baseDir, err = s.resolve(req.BaseDir); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	opts, err := s.options(req.Algo)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	job := s.start(KindVerify, req.Manifest, opts, func(ctx context.Context, job *Job) error {
+		f, err := os.Open(manifest)
+		if err != nil {
+			return err
+		}
+		entries, _, err := hasher.ParseHashFileDetailed(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		// The same file may be listed several times, it is only read once, its other hashes being
+		// checked against this read
+		entries, conflicts := hasher.MergeEntries(entries)
+		others := make(map[string][]string)
+		for _, c := range conflicts {
+			others[filepath.Clean(c.Path)] = append(others[filepath.Clean(c.Path)], c.Other)
+		}
+		entriesChan := make(chan hasher.FileEntry, opts.Workers)
+		go func() {
+			defer close(entriesChan)
+			for _, e := range entries {
+				full := filepath.Clean(e.FilePath)
+				if !filepath.IsAbs(full) {
+					full = filepath.Join(baseDir, full)
+				}
+				if !s.inside(full) {
+					s.add(job, FileResult{Path: e.FilePath, Expected: e.Hash, Status: FileError, Error: "path is outside of the server root"})
+					continue
+				}
+				select {
+				case entriesChan <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		for res := range opts.VerifyFiles(ctx, baseDir, entriesChan) {
+			if res.Err != nil && ctx.Err() != nil && errors.Is(res.Err, ctx.Err()) {
+				continue // interrupted while verifying this file
+			}
+			f := FileResult{Path: s.relative(res.Path), Hash: res.Actual, Size: res.Size, Expected: res.Entry.Hash, Status: FileOK}
+			var mismatch *hasher.HashMismatchError
+			var metadata *hasher.MetadataError
+			switch {
+			case res.Err == nil:
+			case errors.As(res.Err, &mismatch), errors.As(res.Err, &metadata):
+				f.Status = FileMismatch
+			case errors.Is(res.Err, hasher.ErrFileMissing):
+				f.Status, f.Error = FileMissing, res.Err.Error()
+			default:
+				f.Status, f.Error = FileError, res.Err.Error()
+			}
+			s.add(job, f)
+			for _, hash := range others[filepath.Clean(res.Entry.FilePath)] {
+				other := f
+				other.Expected = hash
+				if f.Hash != "" {
+					other.Status = FileOK
+					if !strings.EqualFold(f.Hash, hash) {
+						other.Status = FileMismatch
+					}
+				}
+				s.add(job, other)
+			}
+		}
+		return ctx.Err()
+	})
+	writeJSON(w, http.StatusAccepted, s.snapshot(job))
+}
+
+// snapshot returns a copy of job that can be encoded while it keeps running.
+func (s *Server) snapshot(job *Job) Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := *job
+	j.results = nil
+	return j
+}
+
+// job returns the job of the request path, writing a 404 error when it does not exist.
+func (s *Server) job(w http.ResponseWriter, r *http.Request) *Job {
+	s.mu.Lock()
+	job := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if job == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown job %q", r.PathValue("id")))
+	}
+	return job
+}
+
+func (s *Server) handleJobs(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+	list := make([]Job, len(jobs))
+	for i, job := range jobs {
+		list[i] = s.snapshot(job)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if job := s.job(w, r); job != nil {
+		writeJSON(w, http.StatusOK, s.snapshot(job))
+	}
+}
+
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+	s.mu.Lock()
+	status, results := job.Status, job.results
+	s.mu.Unlock()
+	if status == StatusRunning {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is still running", job.ID))
+		return
+	}
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, f := range results {
+			if f.Error == "" {
+				fmt.Fprint(w, hasher.FormatLine(f.Hash, f.Path, false))
+			}
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.options(r.URL.Query().Get("algo"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res := opts.HashReader(r.Context(), r.Body)
+	if res.Err != nil {
+		writeError(w, http.StatusBadRequest, res.Err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Digest{Algorithm: string(opts.Algorithm), Hash: res.Hash, Size: res.Size})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
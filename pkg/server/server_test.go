@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// call sends a request to the server and decodes its JSON answer into v, returning the status code.
+func call(t *testing.T, s *Server, method, target, body string, v any) int {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s %s answered %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+// wait polls the job until it is finished.
+func wait(t *testing.T, s *Server, id string) Job {
+	for i := 0; i < 500; i++ {
+		var job Job
+		call(t, s, "GET", "/jobs/"+id, "", &job)
+		if job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+// TestServer tests a hash job, the verification of its manifest and the hashing of uploaded content.
+func TestServer(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "data"), 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "data", "a.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	s, err := New(context.Background(), root, hasher.Options{})
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	const abcSHA256 = "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+
+	var job Job
+	if code := call(t, s, "POST", "/jobs", `{"path": "data"}`, &job); code != http.StatusAccepted {
+		t.Fatalf("POST /jobs answered %d", code)
+	}
+	job = wait(t, s, job.ID)
+	if job.Status != StatusDone || job.Files != 1 || job.Bytes != 3 {
+		t.Errorf("hash job = %+v, expected 1 file of 3 bytes", job)
+	}
+	var files []FileResult
+	call(t, s, "GET", "/jobs/"+job.ID+"/manifest", "", &files)
+	if len(files) != 1 || files[0].Path != "data/a.txt" || files[0].Hash != abcSHA256 {
+		t.Errorf("manifest = %+v, expected data/a.txt with the sha256 of abc", files)
+	}
+
+	manifest := hasher.FormatLine(abcSHA256, "data/a.txt", false) + hasher.FormatLine(abcSHA256, "data/b.txt", false)
+	if err := os.WriteFile(filepath.Join(root, "hashes.txt"), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	call(t, s, "POST", "/verify", `{"manifest": "hashes.txt"}`, &job)
+	job = wait(t, s, job.ID)
+	call(t, s, "GET", "/jobs/"+job.ID+"/manifest", "", &files)
+	if job.Files != 2 || job.Errors != 1 || len(files) != 2 || files[0].Status != FileOK || files[1].Status != FileMissing {
+		t.Errorf("verification job = %+v with files %+v, expected a.txt ok and b.txt missing", job, files)
+	}
+
+	var upload struct{ Hash string }
+	if code := call(t, s, "POST", "/hash?algo=sha256", "abc", &upload); code != http.StatusOK || upload.Hash != abcSHA256 {
+		t.Errorf("POST /hash answered %d with hash %s", code, upload.Hash)
+	}
+	if code := call(t, s, "POST", "/jobs", `{"path": "../etc"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /jobs outside of the root answered %d, expected %d", code, http.StatusBadRequest)
+	}
+	if code := call(t, s, "GET", "/jobs/42", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET of an unknown job answered %d, expected %d", code, http.StatusNotFound)
+	}
+}
+
+// TestServerSymlinks tests that a symlink below the root does not give access to the files outside of it,
+// and that a file listed again with another hash is checked against the same read.
+func TestServerSymlinks(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "out")); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+	s, err := New(context.Background(), root, hasher.Options{})
+	if err != nil {
+		t.Fatalf("New() returned an error: %v", err)
+	}
+	if code := call(t, s, "POST", "/jobs", `{"path": "out"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /jobs of a symlink to the outside answered %d, expected %d", code, http.StatusBadRequest)
+	}
+	if code := call(t, s, "POST", "/verify", `{"manifest": "out/secret.txt"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("POST /verify of a manifest outside answered %d, expected %d", code, http.StatusBadRequest)
+	}
+
+	const abcSHA256 = "BA7816BF8F01CFEA414140DE5DAE2223B00361A396177A9CB410FF61F20015AD"
+	other := strings.Repeat("0", 64)
+	manifest := hasher.FormatLine(abcSHA256, "out/secret.txt", false) + hasher.FormatLine(abcSHA256, "a.txt", false) +
+		hasher.FormatLine(other, "a.txt", false)
+	if err := os.WriteFile(filepath.Join(root, "hashes.txt"), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	var job Job
+	call(t, s, "POST", "/verify", `{"manifest": "hashes.txt"}`, &job)
+	job = wait(t, s, job.ID)
+	var files []FileResult
+	call(t, s, "GET", "/jobs/"+job.ID+"/manifest", "", &files)
+	statuses := make(map[string]string)
+	for _, f := range files {
+		statuses[f.Path+" "+f.Expected] = f.Status
+	}
+	if job.Files != 3 || job.Errors != 1 || job.Mismatches != 1 || statuses["out/secret.txt "+abcSHA256] != FileError ||
+		statuses["a.txt "+abcSHA256] != FileOK || statuses["a.txt "+other] != FileMismatch {
+		t.Errorf("verification job = %+v with files %+v, expected the secret refused and a.txt ok then mismatched", job, files)
+	}
+}
@@ -0,0 +1,103 @@
+package sftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// IsURL reports whether s is an sftp:// URL.
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "sftp://")
+}
+
+// Target is the remote tree of an sftp://[user@]host[:port]/path URL.
+type Target struct {
+	User string
+	Host string
+	Port string
+	Path string // absolute, / when the URL has no path
+}
+
+// ParseURL returns the Target of an sftp:// URL.
+func ParseURL(s string) (Target, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Target{}, err
+	}
+	if u.Scheme != "sftp" || u.Hostname() == "" {
+		return Target{}, fmt.Errorf("%q is not an sftp://[user@]host[:port]/path URL", s)
+	}
+	t := Target{User: u.User.Username(), Host: u.Hostname(), Port: u.Port(), Path: u.Path}
+	if t.Path == "" {
+		t.Path = "/"
+	}
+	return t, nil
+}
+
+// URL returns the sftp:// URL of the remote file name, an absolute path without its leading slash as in FS.
+func (t Target) URL(name string) string {
+	host := t.Host
+	if t.Port != "" {
+		host = net.JoinHostPort(t.Host, t.Port)
+	}
+	u := url.URL{Scheme: "sftp", Host: host, Path: "/" + name}
+	if t.User != "" {
+		u.User = url.User(t.User)
+	}
+	return u.String()
+}
+
+// Dial runs the ssh command (ssh when empty) to open the sftp subsystem of the host of t and starts a session.
+// Killing ssh when ctx is done breaks the session.
+func Dial(ctx context.Context, ssh string, t Target) (*Client, error) {
+	if ssh == "" {
+		ssh = "ssh"
+	}
+	args := []string{"-s"}
+	if t.Port != "" {
+		args = append(args, "-p", t.Port)
+	}
+	if t.User != "" {
+		args = append(args, "-l", t.User)
+	}
+	args = append(args, "--", t.Host, "sftp")
+	cmd := exec.CommandContext(ctx, ssh, args...)
+	cmd.Stderr = os.Stderr // host key prompts and authentication errors
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	c, err := NewClient(&command{cmd: cmd, Reader: stdout, WriteCloser: stdin})
+	if err != nil {
+		stdin.Close()
+		cmd.Wait()
+		return nil, fmt.Errorf("connecting to %s: %w", t.Host, err)
+	}
+	return c, nil
+}
+
+// command is the connection to the sftp subsystem through the standard input and output of ssh.
+type command struct {
+	cmd *exec.Cmd
+	io.Reader
+	io.WriteCloser
+}
+
+// Close closes the standard input of ssh, which ends the session, and waits for it to exit.
+func (c *command) Close() error {
+	c.WriteCloser.Close()
+	return c.cmd.Wait()
+}
@@ -0,0 +1,98 @@
+package sftp
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// FS returns the remote filesystem as an fs.FS for the hasher, the names being the absolute
+// remote paths without their leading slash, "." being the root directory.
+func (c *Client) FS() fs.FS {
+	return remoteFS{c}
+}
+
+type remoteFS struct {
+	c *Client
+}
+
+// remotePath returns the absolute remote path of name.
+func remotePath(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return "/", nil
+	}
+	return "/" + name, nil
+}
+
+// Open opens the file name for reading, its attributes are only requested when Stat is called.
+func (r remoteFS) Open(name string) (fs.File, error) {
+	p, err := remotePath("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.c.Open(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &remoteFile{File: f, name: name, path: p}, nil
+}
+
+func (r remoteFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := remotePath("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := r.c.Stat(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fileInfo{name: path.Base(p), attrs: attrs}, nil
+}
+
+// ReadDir lists the directory name sorted by file name, symbolic links are reported as such.
+func (r remoteFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := remotePath("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.c.ReadDir(p)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	list := make([]fs.DirEntry, len(entries))
+	for i, e := range entries {
+		list[i] = fs.FileInfoToDirEntry(fileInfo{name: e.Name, attrs: e.Attrs})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list, nil
+}
+
+type remoteFile struct {
+	*File
+	name, path string
+}
+
+func (f *remoteFile) Stat() (fs.FileInfo, error) {
+	attrs, err := f.c.Stat(f.path)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
+	}
+	return fileInfo{name: path.Base(f.path), attrs: attrs}, nil
+}
+
+// fileInfo is the fs.FileInfo of remote attributes.
+type fileInfo struct {
+	name  string
+	attrs Attrs
+}
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.attrs.Size }
+func (fi fileInfo) Mode() fs.FileMode  { return fi.attrs.Mode }
+func (fi fileInfo) ModTime() time.Time { return fi.attrs.ModTime }
+func (fi fileInfo) IsDir() bool        { return fi.attrs.Mode.IsDir() }
+func (fi fileInfo) Sys() any           { return nil }
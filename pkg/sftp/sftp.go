@@ -0,0 +1,563 @@
+// Package sftp is a minimal read-only client of the SSH File Transfer Protocol (version 3, the one of OpenSSH),
+// enough to walk and read a remote tree. The SSH connection itself is left to the ssh command, see Dial,
+// so the keys, agents, known hosts and ~/.ssh/config of the user apply without any dependency.
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Packet types of SFTP version 3.
+const (
+	fxpInit    = 1
+	fxpVersion = 2
+	fxpOpen    = 3
+	fxpClose   = 4
+	fxpRead    = 5
+	fxpLstat   = 7
+	fxpOpendir = 11
+	fxpReaddir = 12
+	fxpStat    = 17
+	fxpStatus  = 101
+	fxpHandle  = 102
+	fxpData    = 103
+	fxpName    = 104
+	fxpAttrs   = 105
+)
+
+// Flags of the OPEN request and of the attributes.
+const (
+	openRead = 0x1
+
+	attrSize        = 0x1
+	attrUIDGID      = 0x2
+	attrPermissions = 0x4
+	attrTimes       = 0x8
+	attrExtended    = 0x80000000
+)
+
+// Codes of the STATUS answers.
+const (
+	statusOK               = 0
+	statusEOF              = 1
+	statusNoSuchFile       = 2
+	statusPermissionDenied = 3
+)
+
+// maxPacket bounds the packets accepted from the server, OpenSSH sends at most 256KB.
+const maxPacket = 1 << 20
+
+// readSize is the number of bytes asked by each READ request, the size every server accepts.
+const readSize = 32 * 1024
+
+// maxReads bounds the READ requests of a file sent without waiting for their answers, like the 64 requests
+// of the sftp command of OpenSSH, so reading a file is not bound by the round trip time of the connection.
+const maxReads = 64
+
+// StatusError is an error status answered by the server.
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("sftp: %s (status %d)", e.Message, e.Code)
+}
+
+// Is makes errors.Is match fs.ErrNotExist and fs.ErrPermission with the corresponding statuses.
+func (e *StatusError) Is(target error) bool {
+	return (target == fs.ErrNotExist && e.Code == statusNoSuchFile) || (target == fs.ErrPermission && e.Code == statusPermissionDenied)
+}
+
+// Attrs are the attributes of a remote file.
+type Attrs struct {
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+}
+
+// packet is a packet received from the server, without its length and id.
+type packet struct {
+	typ  byte
+	data []byte
+}
+
+// Client sends requests to an SFTP server. It is safe for concurrent use,
+// the requests are multiplexed on the connection and answered in any order.
+type Client struct {
+	conn io.ReadWriteCloser
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan packet
+	err     error // set once the connection is broken
+}
+
+// NewClient starts an SFTP session on conn, the standard input and output of an sftp subsystem.
+func NewClient(conn io.ReadWriteCloser) (*Client, error) {
+	c := &Client{conn: conn, pending: make(map[uint32]chan packet)}
+	var b buffer
+	b.uint32(3)
+	if err := c.write(fxpInit, b); err != nil {
+		return nil, err
+	}
+	typ, _, err := readPacket(conn)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: no answer to the version request: %w", err)
+	}
+	if typ != fxpVersion {
+		return nil, fmt.Errorf("sftp: unexpected packet %d instead of the version", typ)
+	}
+	go c.receive()
+	return c, nil
+}
+
+// Close ends the session.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// receive routes the answers of the server to the pending requests until the connection breaks.
+func (c *Client) receive() {
+	for {
+		typ, data, err := readPacket(c.conn)
+		if err == nil && len(data) < 4 {
+			err = fmt.Errorf("sftp: packet %d without request id", typ)
+		}
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("sftp: connection lost: %w", err)
+			for id, ch := range c.pending {
+				close(ch)
+				delete(c.pending, id)
+			}
+			c.mu.Unlock()
+			return
+		}
+		id := binary.BigEndian.Uint32(data)
+		c.mu.Lock()
+		ch := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- packet{typ: typ, data: data[4:]}
+		}
+	}
+}
+
+// request sends a request of type typ with payload b, after its id, and waits for the answer.
+func (c *Client) request(typ byte, b buffer) (packet, error) {
+	ch, err := c.send(typ, b)
+	if err != nil {
+		return packet{}, err
+	}
+	return c.wait(ch)
+}
+
+// send sends a request of type typ with payload b, after its id, returning the channel of its answer.
+func (c *Client) send(typ byte, b buffer) (chan packet, error) {
+	ch := make(chan packet, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	var req buffer
+	req.uint32(id)
+	req = append(req, b...)
+	if err := c.write(typ, req); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// wait waits for the answer of a request sent by send.
+func (c *Client) wait(ch chan packet) (packet, error) {
+	p, ok := <-ch
+	if !ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return packet{}, c.err
+	}
+	return p, nil
+}
+
+func (c *Client) write(typ byte, payload buffer) error {
+	var b buffer
+	b.uint32(uint32(len(payload) + 1))
+	b = append(b, typ)
+	b = append(b, payload...)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(b)
+	return err
+}
+
+// readPacket reads the next packet of r.
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > maxPacket {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return header[4], data, nil
+}
+
+// status returns the error of a STATUS answer, nil for OK and io.EOF for EOF.
+func status(p packet) error {
+	d := &decoder{b: p.data}
+	code := d.uint32()
+	msg := d.string()
+	switch {
+	case d.err != nil:
+		return d.err
+	case code == statusOK:
+		return nil
+	case code == statusEOF:
+		return io.EOF
+	}
+	return &StatusError{Code: code, Message: msg}
+}
+
+// expect returns the data of p when it has the type typ, the error of the status otherwise.
+func expect(p packet, typ byte) (*decoder, error) {
+	if p.typ == typ {
+		return &decoder{b: p.data}, nil
+	}
+	if p.typ == fxpStatus {
+		if err := status(p); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("sftp: unexpected packet %d instead of %d", p.typ, typ)
+}
+
+// Stat returns the attributes of the file at path, following symbolic links.
+func (c *Client) Stat(path string) (Attrs, error) {
+	return c.stat(fxpStat, path)
+}
+
+// Lstat returns the attributes of the file at path, not following symbolic links.
+func (c *Client) Lstat(path string) (Attrs, error) {
+	return c.stat(fxpLstat, path)
+}
+
+func (c *Client) stat(typ byte, path string) (Attrs, error) {
+	var b buffer
+	b.string(path)
+	p, err := c.request(typ, b)
+	if err != nil {
+		return Attrs{}, err
+	}
+	d, err := expect(p, fxpAttrs)
+	if err != nil {
+		return Attrs{}, err
+	}
+	attrs := d.attrs()
+	return attrs, d.err
+}
+
+// Entry is a file listed by ReadDir, with the attributes of the file itself for symbolic links.
+type Entry struct {
+	Name string
+	Attrs
+}
+
+// ReadDir returns the entries of the directory at path, without . and ..
+func (c *Client) ReadDir(path string) ([]Entry, error) {
+	var b buffer
+	b.string(path)
+	p, err := c.request(fxpOpendir, b)
+	if err != nil {
+		return nil, err
+	}
+	h, err := handle(p)
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeHandle(h)
+	var entries []Entry
+	for {
+		var b buffer
+		b.string(h)
+		p, err := c.request(fxpReaddir, b)
+		if err != nil {
+			return entries, err
+		}
+		d, err := expect(p, fxpName)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			name := d.string()
+			d.string() // long name, as displayed by ls -l
+			attrs := d.attrs()
+			if name != "." && name != ".." {
+				entries = append(entries, Entry{Name: name, Attrs: attrs})
+			}
+		}
+		if d.err != nil {
+			return entries, d.err
+		}
+	}
+}
+
+// File is a remote file opened for reading.
+type File struct {
+	c      *Client
+	handle string
+	next   uint64    // the offset of the next READ request
+	reads  []readReq // the READ requests sent, in the order of their offsets
+	window int       // the number of READ requests kept in flight, doubled up to maxReads
+	data   []byte    // the bytes answered and not read yet
+	err    error     // the error ending the reads, io.EOF at the end of the file
+}
+
+// readReq is a READ request of length bytes at offset, waiting for its answer on ch.
+type readReq struct {
+	offset uint64
+	length uint32
+	ch     chan packet
+}
+
+// Open opens the file at path for reading.
+func (c *Client) Open(path string) (*File, error) {
+	var b buffer
+	b.string(path)
+	b.uint32(openRead)
+	b.uint32(0) // no attributes
+	p, err := c.request(fxpOpen, b)
+	if err != nil {
+		return nil, err
+	}
+	h, err := handle(p)
+	if err != nil {
+		return nil, err
+	}
+	return &File{c: c, handle: h}, nil
+}
+
+// Read reads the next bytes of the file, at most 32KB at a time. Like the sftp command of OpenSSH, several
+// READ requests are kept in flight, their number growing up to maxReads while the file is read, and their
+// answers are reassembled in order.
+func (f *File) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for len(f.data) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		f.fill()
+	}
+	n := copy(b, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+// fill sends the READ requests of the window and waits for the answer of the first one, setting data or err.
+func (f *File) fill() {
+	if f.window == 0 {
+		f.window = 1
+	}
+	for len(f.reads) < f.window {
+		if err := f.sendRead(f.next, readSize, len(f.reads)); err != nil {
+			f.err = err
+			return
+		}
+		f.next += readSize
+	}
+	req := f.reads[0]
+	f.reads = f.reads[1:]
+	p, err := f.c.wait(req.ch)
+	if err != nil {
+		f.err = err
+		return
+	}
+	d, err := expect(p, fxpData)
+	if err != nil {
+		// The requests still in flight are answered to channels nobody reads
+		f.err, f.reads = err, nil
+		return
+	}
+	data := d.string()
+	if d.err != nil {
+		f.err, f.reads = d.err, nil
+		return
+	}
+	if len(data) == 0 {
+		// Asking the same bytes again could get the same answer forever
+		f.err, f.reads = fmt.Errorf("empty answer to the READ request at offset %d: %w", req.offset, io.ErrUnexpectedEOF), nil
+		return
+	}
+	if n := uint32(len(data)); n < req.length {
+		// A short answer, like the ones of servers bounding the size of their packets: the rest of the
+		// request is asked again before the requests that follow it
+		if err := f.sendRead(req.offset+uint64(n), req.length-n, 0); err != nil {
+			f.err = err
+		}
+	}
+	f.window = min(f.window*2, maxReads)
+	f.data = []byte(data)
+}
+
+// sendRead sends a READ request of length bytes at offset, inserted at index i of the requests in flight.
+func (f *File) sendRead(offset uint64, length uint32, i int) error {
+	var b buffer
+	b.string(f.handle)
+	b.uint64(offset)
+	b.uint32(length)
+	ch, err := f.c.send(fxpRead, b)
+	if err != nil {
+		return err
+	}
+	f.reads = slices.Insert(f.reads, i, readReq{offset: offset, length: length, ch: ch})
+	return nil
+}
+
+// Close closes the remote handle of the file.
+func (f *File) Close() error {
+	return f.c.closeHandle(f.handle)
+}
+
+func (c *Client) closeHandle(h string) error {
+	var b buffer
+	b.string(h)
+	p, err := c.request(fxpClose, b)
+	if err != nil {
+		return err
+	}
+	return status(p)
+}
+
+// handle returns the handle of a HANDLE answer.
+func handle(p packet) (string, error) {
+	d, err := expect(p, fxpHandle)
+	if err != nil {
+		return "", err
+	}
+	h := d.string()
+	return h, d.err
+}
+
+// buffer encodes the fields of a packet.
+type buffer []byte
+
+func (b *buffer) uint32(v uint32) {
+	*b = binary.BigEndian.AppendUint32(*b, v)
+}
+
+func (b *buffer) uint64(v uint64) {
+	*b = binary.BigEndian.AppendUint64(*b, v)
+}
+
+func (b *buffer) string(s string) {
+	b.uint32(uint32(len(s)))
+	*b = append(*b, s...)
+}
+
+// decoder reads the fields of a packet, a truncated packet setting err.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.err = errors.New("sftp: truncated packet")
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uint32() uint32 {
+	if v := d.take(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if v := d.take(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	return string(d.take(int(d.uint32())))
+}
+
+func (d *decoder) attrs() Attrs {
+	var a Attrs
+	flags := d.uint32()
+	if flags&attrSize != 0 {
+		a.Size = int64(d.uint64())
+	}
+	if flags&attrUIDGID != 0 {
+		d.uint32()
+		d.uint32()
+	}
+	if flags&attrPermissions != 0 {
+		a.Mode = fileMode(d.uint32())
+	}
+	if flags&attrTimes != 0 {
+		d.uint32() // access time
+		a.ModTime = time.Unix(int64(d.uint32()), 0)
+	}
+	if flags&attrExtended != 0 {
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			d.string()
+			d.string()
+		}
+	}
+	return a
+}
+
+// fileMode converts the POSIX mode of a file to an fs.FileMode.
+func fileMode(mode uint32) fs.FileMode {
+	m := fs.FileMode(mode & 0o777)
+	switch mode & 0o170000 {
+	case 0o040000:
+		m |= fs.ModeDir
+	case 0o120000:
+		m |= fs.ModeSymlink
+	case 0o010000:
+		m |= fs.ModeNamedPipe
+	case 0o140000:
+		m |= fs.ModeSocket
+	case 0o020000:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	case 0o060000:
+		m |= fs.ModeDevice
+	}
+	return m
+}
@@ -0,0 +1,306 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer answers the SFTP requests received on conn as if / was root, each request in
+// its own goroutine so the answers come in any order, like a real server under load.
+type fakeServer struct {
+	root    string
+	conn    net.Conn
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	handles map[string]*os.File
+	// maxData bounds the bytes of each READ answer when set, and the READ requests being answered are counted
+	maxData     int
+	emptyData   bool // the READ requests are answered with no bytes
+	inflight    int
+	maxInflight int
+}
+
+func (s *fakeServer) serve(t *testing.T) {
+	defer s.conn.Close()
+	if typ, _, err := readPacket(s.conn); err != nil || typ != fxpInit {
+		t.Errorf("expected the init packet, got %d, %v", typ, err)
+		return
+	}
+	var version buffer
+	version.uint32(3)
+	s.write(fxpVersion, version)
+	for {
+		typ, data, err := readPacket(s.conn)
+		if err != nil {
+			return
+		}
+		go s.handle(typ, &decoder{b: data})
+	}
+}
+
+func (s *fakeServer) write(typ byte, payload buffer) {
+	var b buffer
+	b.uint32(uint32(len(payload) + 1))
+	b = append(b, typ)
+	b = append(b, payload...)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.conn.Write(b)
+}
+
+func (s *fakeServer) handle(typ byte, d *decoder) {
+	var b buffer
+	b.uint32(d.uint32())
+	status := func(err error) {
+		code := uint32(statusOK)
+		switch {
+		case errors.Is(err, io.EOF):
+			code = statusEOF
+		case errors.Is(err, fs.ErrNotExist):
+			code = statusNoSuchFile
+		case err != nil:
+			code = 4 // failure
+		}
+		b.uint32(code)
+		b.string(fmt.Sprint(err))
+		b.string("")
+		s.write(fxpStatus, b)
+	}
+	switch typ {
+	case fxpStat, fxpLstat:
+		info, err := os.Stat(filepath.Join(s.root, d.string()))
+		if err != nil {
+			status(err)
+			return
+		}
+		appendAttrs(&b, info)
+		s.write(fxpAttrs, b)
+	case fxpOpen, fxpOpendir:
+		f, err := os.Open(filepath.Join(s.root, d.string()))
+		if err != nil {
+			status(err)
+			return
+		}
+		s.mu.Lock()
+		h := strconv.Itoa(len(s.handles))
+		s.handles[h] = f
+		s.mu.Unlock()
+		b.string(h)
+		s.write(fxpHandle, b)
+	case fxpReaddir:
+		entries, err := s.file(d.string()).Readdir(2) // several batches
+		if err != nil {
+			status(err)
+			return
+		}
+		b.uint32(uint32(len(entries)))
+		for _, e := range entries {
+			b.string(e.Name())
+			b.string("-rw-r--r-- 1 user group " + e.Name())
+			appendAttrs(&b, e)
+		}
+		s.write(fxpName, b)
+	case fxpRead:
+		s.mu.Lock()
+		s.inflight++
+		s.maxInflight = max(s.maxInflight, s.inflight)
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inflight--
+			s.mu.Unlock()
+		}()
+		time.Sleep(time.Millisecond) // the round trip of a remote server
+		f := s.file(d.string())
+		offset, length := d.uint64(), d.uint32()
+		if s.maxData > 0 {
+			length = min(length, uint32(s.maxData))
+		}
+		data := make([]byte, length)
+		n, err := f.ReadAt(data, int64(offset))
+		if s.emptyData {
+			b.string("")
+			s.write(fxpData, b)
+			return
+		}
+		if n == 0 {
+			status(err)
+			return
+		}
+		b.string(string(data[:n]))
+		s.write(fxpData, b)
+	case fxpClose:
+		status(s.file(d.string()).Close())
+	}
+}
+
+func (s *fakeServer) file(h string) *os.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.handles[h]
+}
+
+func appendAttrs(b *buffer, info os.FileInfo) {
+	mode := uint32(info.Mode().Perm()) | 0o100000
+	if info.IsDir() {
+		mode = uint32(info.Mode().Perm()) | 0o040000
+	}
+	b.uint32(attrSize | attrPermissions | attrTimes)
+	b.uint64(uint64(info.Size()))
+	b.uint32(mode)
+	b.uint32(uint32(info.ModTime().Unix()))
+	b.uint32(uint32(info.ModTime().Unix()))
+}
+
+// TestClient tests the hashing of a remote tree through FS against the hashing of the same local tree.
+func TestClient(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"data/a.txt":     "abc",
+		"data/b.txt":     "",
+		"data/sub/c.bin": string(make([]byte, 100*1024)), // several READ requests
+		"data/sub/d.txt": "d",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	clientConn, serverConn := net.Pipe()
+	server := &fakeServer{root: root, conn: serverConn, handles: make(map[string]*os.File)}
+	go server.serve(t)
+	c, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	defer c.Close()
+
+	opts := hasher.NewOptions(hasher.WithWorkers(4))
+	remote, err := hasher.HashDirFS(context.Background(), c.FS(), "data", opts)
+	if err != nil {
+		t.Fatalf("HashDirFS() on the remote tree returned an error: %v", err)
+	}
+	local, err := hasher.HashDirFS(context.Background(), os.DirFS(root), "data", opts)
+	if err != nil {
+		t.Fatalf("HashDirFS() on the local tree returned an error: %v", err)
+	}
+	if len(remote) != len(files) || len(remote) != len(local) {
+		t.Fatalf("hashed %d remote files and %d local files, expected %d", len(remote), len(local), len(files))
+	}
+	for i := range remote {
+		if remote[i].Path != local[i].Path || remote[i].Hash != local[i].Hash || remote[i].Size != local[i].Size {
+			t.Errorf("remote result %+v differs from local result %+v", remote[i], local[i])
+		}
+	}
+
+	if _, err := c.Stat("/data/missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a missing file returned %v, expected fs.ErrNotExist", err)
+	}
+	info, err := fs.Stat(c.FS(), "data/a.txt")
+	if err != nil || info.Size() != 3 || info.IsDir() {
+		t.Errorf("fs.Stat() = %v, %v, expected a file of 3 bytes", info, err)
+	}
+}
+
+// TestParseURL tests the parsing of sftp:// URLs.
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Target
+		wantErr bool
+	}{
+		{"sftp://backup@nas.local/srv/data", Target{User: "backup", Host: "nas.local", Path: "/srv/data"}, false},
+		{"sftp://nas.local:2222", Target{Host: "nas.local", Port: "2222", Path: "/"}, false},
+		{"sftp:///srv/data", Target{}, true},
+		{"/srv/data", Target{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseURL(tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseURL(%q) = %+v, %v, expected %+v", tt.url, got, err, tt.want)
+		}
+		if !tt.wantErr && got.URL(got.Path[1:]) != tt.url && got.URL("") != tt.url+"/" {
+			t.Errorf("URL() of %+v = %q, expected %q", got, got.URL(got.Path[1:]), tt.url)
+		}
+	}
+}
+
+// TestFileRead tests that the READ requests of a file are sent without waiting for the answers of the previous
+// ones, and that the answers, coming in any order and possibly shorter than asked, are reassembled in order.
+func TestFileRead(t *testing.T) {
+	root := t.TempDir()
+	content := make([]byte, 3<<20+12345)
+	for i := range content {
+		content[i] = byte(i * 7 / 3)
+	}
+	if err := os.WriteFile(filepath.Join(root, "big.bin"), content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	for _, maxData := range []int{0, 10000} {
+		clientConn, serverConn := net.Pipe()
+		server := &fakeServer{root: root, conn: serverConn, handles: make(map[string]*os.File), maxData: maxData}
+		go server.serve(t)
+		c, err := NewClient(clientConn)
+		if err != nil {
+			t.Fatalf("NewClient() returned an error: %v", err)
+		}
+		f, err := c.Open("/big.bin")
+		if err != nil {
+			t.Fatalf("Open() returned an error: %v", err)
+		}
+		got, err := io.ReadAll(f)
+		if err != nil || !bytes.Equal(got, content) {
+			t.Errorf("ReadAll() with answers of at most %d bytes read %d bytes, %v, expected the %d bytes of the file", maxData, len(got), err, len(content))
+		}
+		if err := f.Close(); err != nil {
+			t.Errorf("Close() returned an error: %v", err)
+		}
+		c.Close()
+		server.mu.Lock()
+		if server.maxInflight < 2 || server.maxInflight > maxReads+1 {
+			t.Errorf("the server answered up to %d READ requests at once, expected several, at most %d", server.maxInflight, maxReads+1)
+		}
+		server.mu.Unlock()
+	}
+}
+
+// TestFileReadEmptyData tests that a server answering a READ request with no bytes gets an error instead of the
+// same request again and again.
+func TestFileReadEmptyData(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("abc"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	clientConn, serverConn := net.Pipe()
+	server := &fakeServer{root: root, conn: serverConn, handles: make(map[string]*os.File), emptyData: true}
+	go server.serve(t)
+	c, err := NewClient(clientConn)
+	if err != nil {
+		t.Fatalf("NewClient() returned an error: %v", err)
+	}
+	defer c.Close()
+	f, err := c.Open("/a.txt")
+	if err != nil {
+		t.Fatalf("Open() returned an error: %v", err)
+	}
+	defer f.Close()
+	if got, err := io.ReadAll(f); len(got) != 0 || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadAll() of empty answers read %q, %v, expected io.ErrUnexpectedEOF", got, err)
+	}
+}
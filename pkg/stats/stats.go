@@ -0,0 +1,81 @@
+// Package stats collects the bytes hashed by a run with its throughput and its slowest files,
+// to size the hardware and spot the files that are pathologically slow to read.
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// File is one of the slowest files of a run. A file on failing sectors is read at a crawl, with retries of
+// the drive, long before it cannot be read at all, so a low throughput is the first sign of a dying disk.
+type File struct {
+	Path        string  `json:"path"`
+	Bytes       int64   `json:"bytes"`
+	Seconds     float64 `json:"seconds"`
+	MBPerSecond float64 `json:"mb_per_second"` // throughput of the file, in decimal megabytes
+}
+
+// Throughput returns the throughput of bytes read in elapsed, in decimal megabytes per second, 0 when
+// elapsed is too short to measure.
+func Throughput(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) / 1e6 / elapsed.Seconds()
+}
+
+// Summary is the outcome of a run, written in the final summary and in the JSON reports.
+type Summary struct {
+	Files       int64   `json:"files"`
+	Bytes       int64   `json:"bytes"`
+	Seconds     float64 `json:"seconds"`       // wall time of the run
+	MBPerSecond float64 `json:"mb_per_second"` // aggregate throughput, in decimal megabytes
+	Slowest     []File  `json:"slowest,omitempty"`
+}
+
+// Collector counts the files hashed and keeps the slowest ones. It is safe for concurrent use.
+type Collector struct {
+	mu      sync.Mutex
+	start   time.Time
+	keep    int
+	files   int64
+	bytes   int64
+	slowest []File // sorted from the slowest
+}
+
+// New returns a Collector started now, keeping the slowest files up to slowest of them.
+func New(slowest int) *Collector {
+	return &Collector{start: time.Now(), keep: max(slowest, 0)}
+}
+
+// Add counts the file at path, whose size bytes were read in elapsed.
+func (c *Collector) Add(path string, size int64, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files++
+	c.bytes += size
+	if c.keep == 0 || (len(c.slowest) == c.keep && elapsed.Seconds() <= c.slowest[len(c.slowest)-1].Seconds) {
+		return
+	}
+	f := File{Path: path, Bytes: size, Seconds: elapsed.Seconds(), MBPerSecond: Throughput(size, elapsed)}
+	i := sort.Search(len(c.slowest), func(i int) bool { return c.slowest[i].Seconds < f.Seconds })
+	c.slowest = append(c.slowest, File{})
+	copy(c.slowest[i+1:], c.slowest[i:])
+	c.slowest[i] = f
+	if len(c.slowest) > c.keep {
+		c.slowest = c.slowest[:c.keep]
+	}
+}
+
+// Summary returns the counters of the files added so far, with the wall time since New.
+func (c *Collector) Summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := Summary{Files: c.files, Bytes: c.bytes, Seconds: time.Since(c.start).Seconds(), Slowest: append([]File(nil), c.slowest...)}
+	if s.Seconds > 0 {
+		s.MBPerSecond = float64(s.Bytes) / 1e6 / s.Seconds
+	}
+	return s
+}
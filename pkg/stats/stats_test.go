@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestCollector tests that every file is counted and only the slowest ones are kept, slowest first.
+func TestCollector(t *testing.T) {
+	c := New(3)
+	for i, ms := range []int{5, 50, 1, 20, 40, 2} {
+		c.Add(fmt.Sprintf("file%d", i), 1000, time.Duration(ms)*time.Millisecond)
+	}
+	s := c.Summary()
+	if s.Files != 6 || s.Bytes != 6000 {
+		t.Errorf("Summary() counted %d files and %d bytes, want 6 and 6000", s.Files, s.Bytes)
+	}
+	var paths []string
+	for _, f := range s.Slowest {
+		paths = append(paths, f.Path)
+	}
+	if fmt.Sprint(paths) != "[file1 file4 file3]" {
+		t.Errorf("Summary().Slowest = %v, want [file1 file4 file3]", paths)
+	}
+	if f := s.Slowest[0]; f.Seconds != 0.05 || f.MBPerSecond != 0.02 {
+		t.Errorf("Summary().Slowest[0] = %v seconds at %v MB/s, want 0.05 at 0.02", f.Seconds, f.MBPerSecond)
+	}
+	if s.Seconds <= 0 || s.MBPerSecond <= 0 {
+		t.Errorf("Summary() = %v seconds at %v MB/s, want positive values", s.Seconds, s.MBPerSecond)
+	}
+	c = New(0)
+	c.Add("file", 1, time.Second)
+	if slowest := c.Summary().Slowest; len(slowest) != 0 {
+		t.Errorf("New(0) kept %v, want no slowest files", slowest)
+	}
+}
+
+// TestThroughput tests the throughput of a file, in decimal megabytes per second.
+func TestThroughput(t *testing.T) {
+	for _, tt := range []struct {
+		bytes   int64
+		elapsed time.Duration
+		want    float64
+	}{
+		{10_000_000, 2 * time.Second, 5},
+		{1_000_000, 250 * time.Millisecond, 4},
+		{1000, 0, 0},
+	} {
+		if got := Throughput(tt.bytes, tt.elapsed); got != tt.want {
+			t.Errorf("Throughput(%d, %v) = %v, want %v", tt.bytes, tt.elapsed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package torrent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Encode writes the bencoding of v to w: strings and byte slices as byte strings, integers, lists ([]any)
+// and dictionaries (map[string]any), their keys being sorted as raw strings like BEP 3 requires.
+func Encode(w io.Writer, v any) error {
+	bw := bufio.NewWriter(w)
+	if err := encode(bw, v); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func encode(w *bufio.Writer, v any) error {
+	switch v := v.(type) {
+	case string:
+		w.WriteString(strconv.Itoa(len(v)) + ":" + v)
+	case []byte:
+		w.WriteString(strconv.Itoa(len(v)) + ":")
+		w.Write(v)
+	case int:
+		w.WriteString("i" + strconv.Itoa(v) + "e")
+	case int64:
+		w.WriteString("i" + strconv.FormatInt(v, 10) + "e")
+	case []any:
+		w.WriteByte('l')
+		for _, item := range v {
+			if err := encode(w, item); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('e')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		w.WriteByte('d')
+		for _, key := range keys {
+			encode(w, key)
+			if err := encode(w, v[key]); err != nil {
+				return err
+			}
+		}
+		w.WriteByte('e')
+	default:
+		return fmt.Errorf("cannot bencode %T", v)
+	}
+	return nil
+}
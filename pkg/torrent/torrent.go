@@ -0,0 +1,156 @@
+// Package torrent writes BitTorrent v2 torrents (BEP 52): the pieces roots and the piece layers of the files
+// are computed by the merkle trees of hasher.BTv2Tree, so the roots listed in the torrent are the ones of the
+// manifests written with hasher.WithBitTorrentV2, and a dataset can be distributed and verified with both.
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+// MaxPieceLength is the largest piece length chosen by DefaultPieceLength.
+const MaxPieceLength = 16 << 20
+
+// File is a file of a torrent.
+type File struct {
+	Path   string // slash separated, relative to the directory of the torrent or its name for a single file
+	Length int64
+	Root   []byte // pieces root, nil for an empty file
+	Layer  []byte // piece layer, nil unless the file is larger than a piece
+}
+
+// HashFile returns the File of the file at p for a torrent with pieces of pieceLength bytes, named name in it.
+func HashFile(ctx context.Context, p, name string, pieceLength int64) (File, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	tree := hasher.NewBTv2Tree(pieceLength)
+	buf := make([]byte, 1<<20)
+	for {
+		if err := ctx.Err(); err != nil {
+			return File{}, err
+		}
+		n, err := f.Read(buf)
+		tree.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return File{}, err
+		}
+	}
+	file := File{Path: name, Length: tree.Size()}
+	if file.Length > 0 {
+		file.Root, file.Layer = tree.Sum()
+	}
+	return file, nil
+}
+
+// ValidatePieceLength checks that pieceLength is a power of two of at least 16 KiB, as BEP 52 requires.
+func ValidatePieceLength(pieceLength int64) error {
+	if pieceLength < hasher.BTv2BlockSize || pieceLength&(pieceLength-1) != 0 {
+		return fmt.Errorf("the piece length must be a power of two of at least 16 KiB, not %d bytes", pieceLength)
+	}
+	return nil
+}
+
+// DefaultPieceLength returns the piece length giving about 1000 pieces for total bytes, between 16 KiB and
+// MaxPieceLength, so the piece layers stay small without making the pieces too large to share early.
+func DefaultPieceLength(total int64) int64 {
+	pieceLength := int64(hasher.BTv2BlockSize)
+	for pieceLength < MaxPieceLength && total/pieceLength > 1000 {
+		pieceLength <<= 1
+	}
+	return pieceLength
+}
+
+// Torrent is the metainfo of a v2 torrent.
+type Torrent struct {
+	Name        string // suggested name of the directory, or of the file for a single file
+	PieceLength int64
+	Announce    []string // URLs of the trackers, none for a trackerless torrent
+	Comment     string
+	CreatedBy   string
+	Created     time.Time
+	Private     bool
+	Files       []File
+}
+
+// Write writes the .torrent file of t to w, returning its v2 info hash, the SHA256 of its info dictionary.
+func (t Torrent) Write(w io.Writer) ([]byte, error) {
+	if err := ValidatePieceLength(t.PieceLength); err != nil {
+		return nil, err
+	}
+	if t.Name == "" || strings.Contains(t.Name, "/") {
+		return nil, fmt.Errorf("invalid torrent name %q", t.Name)
+	}
+	fileTree := make(map[string]any)
+	layers := make(map[string]any)
+	for _, f := range t.Files {
+		node := fileTree
+		parts := strings.Split(f.Path, "/")
+		for _, dir := range parts[:len(parts)-1] {
+			child, ok := node[dir].(map[string]any)
+			if _, isFile := child[""]; isFile {
+				return nil, fmt.Errorf("the path %q of the torrent is below a file", f.Path)
+			}
+			if !ok {
+				child = make(map[string]any)
+				node[dir] = child
+			}
+			node = child
+		}
+		entry := map[string]any{"length": f.Length}
+		if f.Length > 0 {
+			entry["pieces root"] = f.Root
+		}
+		if _, exists := node[parts[len(parts)-1]]; exists || f.Path == "" {
+			return nil, fmt.Errorf("invalid or duplicate path %q in the torrent", f.Path)
+		}
+		node[parts[len(parts)-1]] = map[string]any{"": entry}
+		if f.Layer != nil {
+			layers[string(f.Root)] = f.Layer
+		}
+	}
+	info := map[string]any{"name": t.Name, "piece length": t.PieceLength, "meta version": 2, "file tree": fileTree}
+	if t.Private {
+		info["private"] = 1
+	}
+	var encodedInfo bytes.Buffer
+	if err := Encode(&encodedInfo, info); err != nil {
+		return nil, err
+	}
+	metainfo := map[string]any{"info": info, "piece layers": layers, "creation date": t.Created.Unix()}
+	if len(t.Announce) > 0 {
+		metainfo["announce"] = t.Announce[0]
+	}
+	if len(t.Announce) > 1 {
+		tiers := make([]any, len(t.Announce))
+		for i, url := range t.Announce {
+			tiers[i] = []any{url}
+		}
+		metainfo["announce-list"] = tiers
+	}
+	if t.Comment != "" {
+		metainfo["comment"] = t.Comment
+	}
+	if t.CreatedBy != "" {
+		metainfo["created by"] = t.CreatedBy
+	}
+	// The bencoding being canonical, the info dictionary is written as the bytes hashed
+	if err := Encode(w, metainfo); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(encodedInfo.Bytes())
+	return sum[:], nil
+}
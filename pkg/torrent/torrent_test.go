@@ -0,0 +1,80 @@
+package torrent
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+// TestEncode tests the bencoding of the values of the torrents, the keys of the dictionaries being sorted.
+func TestEncode(t *testing.T) {
+	var buf bytes.Buffer
+	err := Encode(&buf, map[string]any{"spam": []any{"a", int64(-3)}, "cow": []byte("moo"), "n": 42, "": map[string]any{}})
+	if want := "d0:de3:cow3:moo1:ni42e4:spaml1:ai-3eee"; err != nil || buf.String() != want {
+		t.Errorf("Encode() = %q, %v, expected %q", buf.String(), err, want)
+	}
+	if err := Encode(&buf, 3.14); err == nil {
+		t.Error("Encode(float64) returned no error")
+	}
+}
+
+// TestWrite tests the metainfo of a torrent with a file larger than a piece, a small one and an empty one.
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	big := bytes.Repeat([]byte("x"), 3*hasher.BTv2BlockSize+5)
+	os.WriteFile(filepath.Join(dir, "big.bin"), big, 0o644)
+	os.WriteFile(filepath.Join(dir, "empty"), nil, 0o644)
+	var files []File
+	for _, name := range []string{"big.bin", "empty"} {
+		f, err := HashFile(context.Background(), filepath.Join(dir, name), "data/"+name, 2*hasher.BTv2BlockSize)
+		if err != nil {
+			t.Fatalf("HashFile(%s) returned an error: %v", name, err)
+		}
+		files = append(files, f)
+	}
+	if files[1].Root != nil || len(files[0].Layer) != 2*sha256.Size {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	tr := Torrent{Name: "set", PieceLength: 2 * hasher.BTv2BlockSize, Announce: []string{"udp://tracker:6969"},
+		CreatedBy: "goDirHasher", Created: time.Unix(1700000000, 0), Files: files}
+	var buf bytes.Buffer
+	infoHash, err := tr.Write(&buf)
+	if err != nil {
+		t.Fatalf("Write() returned an error: %v", err)
+	}
+	info := "d9:file treed4:datad7:big.bind0:d6:lengthi49157e11:pieces root32:" + string(files[0].Root) + "ee5:emptyd0:d6:lengthi0eeeee" +
+		"12:meta versioni2e4:name3:set12:piece lengthi32768ee"
+	want := "d8:announce18:udp://tracker:696910:created by11:goDirHasher13:creation datei1700000000e4:info" + info +
+		"12:piece layersd32:" + string(files[0].Root) + "64:" + string(files[0].Layer) + "ee"
+	if buf.String() != want {
+		t.Errorf("Write() = %q\nexpected %q", buf.String(), want)
+	}
+	if sum := sha256.Sum256([]byte(info)); !bytes.Equal(infoHash, sum[:]) {
+		t.Errorf("Write() returned the info hash %x, expected %x", infoHash, sum)
+	}
+
+	tr.PieceLength = 3 * hasher.BTv2BlockSize
+	if _, err := tr.Write(&buf); err == nil || !strings.Contains(err.Error(), "power of two") {
+		t.Errorf("Write() with an invalid piece length returned %v", err)
+	}
+	tr.PieceLength, tr.Files = 2*hasher.BTv2BlockSize, append(files, File{Path: "data/empty/inner", Length: 1})
+	if _, err := tr.Write(&buf); err == nil {
+		t.Error("Write() accepted a path below a file")
+	}
+}
+
+// TestDefaultPieceLength tests the piece lengths chosen for the sizes of the torrents.
+func TestDefaultPieceLength(t *testing.T) {
+	for total, want := range map[int64]int64{0: 16 << 10, 100 << 20: 128 << 10, 1 << 40: MaxPieceLength} {
+		if got := DefaultPieceLength(total); got != want {
+			t.Errorf("DefaultPieceLength(%d) = %d, expected %d", total, got, want)
+		}
+	}
+}
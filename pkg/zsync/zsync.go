@@ -0,0 +1,174 @@
+// Package zsync writes the control files of zsync, the rsync-like tool downloading over HTTP only the blocks
+// of a file that differ from a local copy: for each block, the rolling checksum of rsync finding the blocks
+// the local copy already has at any offset, and the MD4 confirming them, along with the SHA-1 of the file.
+// The control files are the ones of zsyncmake, so zsync and the other delta transfer tools reading them can
+// plan a synchronization from them.
+package zsync
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+// Ext is the extension of the control files, written next to the files they describe.
+const Ext = ".zsync"
+
+// version is the version of zsync whose control files are written.
+const version = "0.6.2"
+
+// DefaultBlockSize returns the block size zsyncmake chooses for a file of length bytes.
+func DefaultBlockSize(length int64) int {
+	if length < 100_000_000 {
+		return 2048
+	}
+	return 4096
+}
+
+// ValidateBlockSize checks that blockSize is a power of two of at least 256 bytes, as zsync requires.
+func ValidateBlockSize(blockSize int) error {
+	if blockSize < 256 || blockSize&(blockSize-1) != 0 {
+		return fmt.Errorf("the zsync block size must be a power of two of at least 256 bytes, not %d", blockSize)
+	}
+	return nil
+}
+
+// Rsum returns the rolling checksum of block, its a and b sums of 16 bits being the high and low halves.
+// a is the sum of the bytes and b the sum of each byte times its distance from the end of block.
+func Rsum(block []byte) uint32 {
+	var a, b uint16
+	for _, c := range block {
+		a += uint16(c)
+		b += a
+	}
+	return uint32(a)<<16 | uint32(b)
+}
+
+// Roll returns the rolling checksum of the block of blockSize bytes after the one whose checksum is sum,
+// out leaving it and in entering it, without reading the block again.
+func Roll(sum uint32, out, in byte, blockSize int) uint32 {
+	a, b := uint16(sum>>16), uint16(sum)
+	a += uint16(in) - uint16(out)
+	b += a - uint16(out)*uint16(blockSize)
+	return uint32(a)<<16 | uint32(b)
+}
+
+// HashLengths returns the numbers zsyncmake writes in the Hash-Lengths header for a file of length bytes:
+// the number of consecutive blocks that must match, and the bytes of the rolling checksum and of the MD4
+// stored for each block, fewer bytes being enough to tell the blocks of a small file apart.
+func HashLengths(length int64, blockSize int) (seqMatches, rsumLen, checksumLen int) {
+	seqMatches = 1
+	if length > int64(blockSize) {
+		seqMatches = 2
+	}
+	blocks := float64(1 + length/int64(blockSize))
+	rsumLen = min(max(int(math.Ceil((math.Log2(float64(length))+math.Log2(float64(blockSize))-8.6)/float64(seqMatches)/8)), 2), 4)
+	if length == 0 {
+		rsumLen = 2
+	}
+	checksumLen = int((7.9 + (20 + math.Log2(blocks))) / 8)
+	if length > 0 {
+		checksumLen = max(checksumLen, int(math.Ceil((20+math.Log2(float64(length))+math.Log2(blocks))/float64(seqMatches)/8)))
+	}
+	return seqMatches, rsumLen, min(checksumLen, 16)
+}
+
+// Write reads the content of the file named name from r and writes its control file to w, with blocks
+// of blockSize bytes, the last one being padded with zeros. The checksums of the blocks are kept in memory
+// until the SHA-1 of the whole content is known, 20 bytes per block.
+func Write(ctx context.Context, w io.Writer, r io.Reader, name string, mtime time.Time, blockSize int) error {
+	if err := ValidateBlockSize(blockSize); err != nil {
+		return err
+	}
+	whole := sha1.New()
+	md4 := hasher.NewMD4()
+	block := make([]byte, blockSize)
+	var sums []byte // rolling checksum and MD4 of each block
+	var length int64
+	br := bufio.NewReaderSize(r, 1<<20)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(br, block)
+		if n == 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		whole.Write(block[:n])
+		length += int64(n)
+		clear(block[n:])
+		sums = binary.BigEndian.AppendUint32(sums, Rsum(block))
+		md4.Reset()
+		md4.Write(block)
+		sums = md4.Sum(sums)
+		if n < blockSize {
+			break
+		}
+	}
+	seqMatches, rsumLen, checksumLen := HashLengths(length, blockSize)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "zsync: %s\n", version)
+	fmt.Fprintf(bw, "Filename: %s\n", name)
+	if !mtime.IsZero() {
+		fmt.Fprintf(bw, "MTime: %s\n", mtime.UTC().Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	}
+	fmt.Fprintf(bw, "Blocksize: %d\n", blockSize)
+	fmt.Fprintf(bw, "Length: %d\n", length)
+	fmt.Fprintf(bw, "Hash-Lengths: %d,%d,%d\n", seqMatches, rsumLen, checksumLen)
+	fmt.Fprintf(bw, "URL: %s\n", name)
+	fmt.Fprintf(bw, "SHA-1: %x\n\n", whole.Sum(nil))
+	// Only the low bytes of the rolling checksum and the first bytes of the MD4 are kept
+	for i := 0; i < len(sums); i += 20 {
+		bw.Write(sums[i+4-rsumLen : i+4])
+		bw.Write(sums[i+4 : i+4+checksumLen])
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes the control file of the file at path next to it, as path.zsync, with blocks of blockSize
+// bytes, or the size zsyncmake would choose when blockSize is 0. The file is downloaded from the URL of its
+// name relative to the control file, the way zsyncmake writes it by default.
+func WriteFile(ctx context.Context, path string, blockSize int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize(info.Size())
+	}
+	// Written under a temporary name, so an interrupted run never leaves a truncated control file
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*"+Ext)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := Write(ctx, tmp, f, filepath.Base(path), info.ModTime(), blockSize); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path+Ext)
+}
@@ -0,0 +1,117 @@
+package zsync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lao-tseu-is-alive/goDirHasher/pkg/hasher"
+)
+
+// TestRoll tests that rolling the checksum over a content gives the checksum of each block.
+func TestRoll(t *testing.T) {
+	content := []byte(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 40))
+	const blockSize = 256
+	sum := Rsum(content[:blockSize])
+	for i := 1; i+blockSize <= len(content); i++ {
+		sum = Roll(sum, content[i-1], content[i+blockSize-1], blockSize)
+		if want := Rsum(content[i : i+blockSize]); sum != want {
+			t.Fatalf("Roll at offset %d = %08x, want %08x", i, sum, want)
+		}
+	}
+	if got := Rsum([]byte{1, 2, 3}); got != 6<<16|(3+2*2+3*1) {
+		t.Errorf("Rsum = %08x", got)
+	}
+}
+
+// TestHashLengths tests the lengths against the formulas of zsyncmake computed by hand.
+func TestHashLengths(t *testing.T) {
+	tests := []struct {
+		length    int64
+		blockSize int
+		want      [3]int
+	}{
+		{0, 2048, [3]int{1, 2, 3}},
+		{1000, 2048, [3]int{1, 2, 4}},
+		{10000, 2048, [3]int{2, 2, 3}},
+		{1 << 30, 4096, [3]int{2, 3, 5}},
+	}
+	for _, tt := range tests {
+		seq, rsum, checksum := HashLengths(tt.length, tt.blockSize)
+		if got := [3]int{seq, rsum, checksum}; got != tt.want {
+			t.Errorf("HashLengths(%d, %d) = %v, want %v", tt.length, tt.blockSize, got, tt.want)
+		}
+	}
+}
+
+// TestWriteFile tests the headers and the checksums of the blocks of a control file.
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.bin")
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1000) // 7 full blocks of 2048 bytes and a partial one
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	mtime := time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("Failed to set the modification time: %v", err)
+	}
+	if err := WriteFile(context.Background(), path, 0); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	control, err := os.ReadFile(path + Ext)
+	if err != nil {
+		t.Fatalf("Failed to read the control file: %v", err)
+	}
+	r := bufio.NewReader(bytes.NewReader(control))
+	var headers []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the headers: %v", err)
+		}
+		if line == "\n" {
+			break
+		}
+		headers = append(headers, strings.TrimSuffix(line, "\n"))
+	}
+	want := []string{
+		"zsync: 0.6.2",
+		"Filename: data.bin",
+		"MTime: Fri, 31 May 2024 22:00:00 +0000",
+		"Blocksize: 2048",
+		"Length: 16000",
+		"Hash-Lengths: 2,2,3",
+		"URL: data.bin",
+		fmt.Sprintf("SHA-1: %x", sha1.Sum(content)),
+	}
+	if strings.Join(headers, "\n") != strings.Join(want, "\n") {
+		t.Errorf("headers = %q, want %q", headers, want)
+	}
+	var blocks []byte
+	for i := 0; i < len(content); i += 2048 {
+		block := make([]byte, 2048)
+		copy(block, content[i:])
+		sum := Rsum(block)
+		md4 := hasher.NewMD4()
+		md4.Write(block)
+		blocks = append(blocks, byte(sum>>8), byte(sum))
+		blocks = append(blocks, md4.Sum(nil)[:3]...)
+	}
+	if rest := control[len(control)-r.Buffered():]; !bytes.Equal(rest, blocks) {
+		t.Errorf("block checksums = %x, want %x", rest, blocks)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, ".*")); len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+	if err := WriteFile(context.Background(), path, 1000); err == nil {
+		t.Error("WriteFile accepted a block size that is not a power of two")
+	}
+}